@@ -2,6 +2,7 @@ package backend
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/gousb"
@@ -221,7 +222,7 @@ func (d *Db) ImprimirRecibo(factura Factura) error {
 
 	// Detalles
 	for _, item := range factura.Detalles {
-		linea := formatItemLine(item.Cantidad, item.Producto.Nombre, item.PrecioTotal)
+		linea := formatItemLine(item.Cantidad, nombreConPresentacion(item.Producto.Nombre, item.Presentacion), item.PrecioTotal)
 		if err := send(left()); err != nil {
 			return err
 		}
@@ -250,6 +251,52 @@ func (d *Db) ImprimirRecibo(factura Factura) error {
 		return err
 	}
 
+	// Desglose de impuestos por tarifa
+	if len(factura.Impuestos) > 0 {
+		if err := send(left()); err != nil {
+			return err
+		}
+		if err := sendEncoded(formatTaxLine("Tarifa", "Base", "IVA")); err != nil {
+			return err
+		}
+		if err := send(lineBreak()); err != nil {
+			return err
+		}
+		for _, imp := range factura.Impuestos {
+			linea := formatTaxLine(fmt.Sprintf("%g%%", imp.Tarifa), formatCurrency(imp.Base), formatCurrency(imp.Valor))
+			if err := sendEncoded(linea); err != nil {
+				return err
+			}
+			if err := send(lineBreak()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := send(right()); err != nil {
+		return err
+	}
+	if factura.Descuento > 0 {
+		if err := sendEncoded(fmt.Sprintf("Descuento: -%s", formatCurrency(factura.Descuento))); err != nil {
+			return err
+		}
+		if err := send(lineBreak()); err != nil {
+			return err
+		}
+	}
+	if err := sendEncoded(fmt.Sprintf("Subtotal: %s", formatCurrency(factura.Subtotal))); err != nil {
+		return err
+	}
+	if err := send(lineBreak()); err != nil {
+		return err
+	}
+	if err := sendEncoded(fmt.Sprintf("IVA: %s", formatCurrency(factura.IVA))); err != nil {
+		return err
+	}
+	if err := send(lineBreak()); err != nil {
+		return err
+	}
+
 	// Total:
 	totalStr := fmt.Sprintf("TOTAL: %s", formatCurrency(factura.Total))
 	if err := send(boldOn()); err != nil {
@@ -279,6 +326,102 @@ func (d *Db) ImprimirRecibo(factura Factura) error {
 		return err
 	}
 
+	// Medios de pago y cambio
+	if len(factura.Pagos) > 0 {
+		if err := send(right()); err != nil {
+			return err
+		}
+		for _, pago := range factura.Pagos {
+			linea := fmt.Sprintf("%s: %s", strings.ToUpper(pago.MetodoPago), formatCurrency(pago.Monto))
+			if pago.Referencia != "" {
+				linea = fmt.Sprintf("%s (%s)", linea, pago.Referencia)
+			}
+			if err := sendEncoded(linea); err != nil {
+				return err
+			}
+			if err := send(lineBreak()); err != nil {
+				return err
+			}
+			if pago.Cambio > 0 {
+				if err := sendEncoded(fmt.Sprintf("Recibido: %s  Cambio: %s", formatCurrency(pago.EfectivoRecibido), formatCurrency(pago.Cambio))); err != nil {
+					return err
+				}
+				if err := send(lineBreak()); err != nil {
+					return err
+				}
+			}
+		}
+		if err := send(lineBreak()); err != nil {
+			return err
+		}
+	}
+
+	// Programa de puntos del cliente
+	if factura.Cliente.NumeroID != ConsumidorFinalID && (factura.PuntosGanados > 0 || factura.PuntosRedimidos > 0 || factura.SaldoPuntos != 0) {
+		if err := send(left()); err != nil {
+			return err
+		}
+		if factura.PuntosRedimidos > 0 {
+			if err := sendEncoded(fmt.Sprintf("Puntos redimidos: %d", factura.PuntosRedimidos)); err != nil {
+				return err
+			}
+			if err := send(lineBreak()); err != nil {
+				return err
+			}
+		}
+		if factura.PuntosGanados > 0 {
+			if err := sendEncoded(fmt.Sprintf("Puntos ganados: %d", factura.PuntosGanados)); err != nil {
+				return err
+			}
+			if err := send(lineBreak()); err != nil {
+				return err
+			}
+		}
+		if err := sendEncoded(fmt.Sprintf("Saldo de puntos: %d", factura.SaldoPuntos)); err != nil {
+			return err
+		}
+		if err := send(lineBreak()); err != nil {
+			return err
+		}
+		if err := send(lineBreak()); err != nil {
+			return err
+		}
+	}
+
+	// Fórmula médica: lo prescrito, lo entregado y lo que el paciente puede reclamar después
+	if factura.Formula != nil {
+		formula := factura.Formula
+		if err := send(left()); err != nil {
+			return err
+		}
+		lineas := []string{
+			fmt.Sprintf("Fórmula médica No. %s (%s)", formula.NumeroFormula, formula.FechaFormula),
+			fmt.Sprintf("Médico: %s  RM: %s", formula.Medico, formula.RegistroMedico),
+			strings.TrimSpace(fmt.Sprintf("Paciente: %s %s", formula.PacienteID, formula.PacienteNombre)),
+		}
+		for _, it := range formula.Items {
+			linea := fmt.Sprintf("%s: entregado %d de %d", it.Nombre, it.CantidadDispensada, it.CantidadPrescrita)
+			if it.CantidadPendiente > 0 {
+				linea = fmt.Sprintf("%s, pendiente %d", linea, it.CantidadPendiente)
+			}
+			lineas = append(lineas, linea)
+		}
+		if formula.Estado != FormulaCompleta {
+			lineas = append(lineas, "Fórmula con entregas pendientes")
+		}
+		for _, linea := range lineas {
+			if err := sendEncoded(linea); err != nil {
+				return err
+			}
+			if err := send(lineBreak()); err != nil {
+				return err
+			}
+		}
+		if err := send(lineBreak()); err != nil {
+			return err
+		}
+	}
+
 	// Mensaje final:
 	// El caracter especial al inicio probablemente era un error de codificación de la '¡' o de un caracter invisible.
 	// Al usar `sendEncoded` y el nuevo `center()`, esto debería corregirse.
@@ -304,6 +447,175 @@ func (d *Db) ImprimirRecibo(factura Factura) error {
 	return nil
 }
 
+// ImprimirCierreCaja imprime el arqueo de una sesión de caja cerrada: base, ventas y,
+// por cada método de pago, lo esperado, lo contado y el sobrante o faltante.
+func (d *Db) ImprimirCierreCaja(sesionUUID string) error {
+	sesion, err := d.ObtenerDetalleSesionCaja(sesionUUID)
+	if err != nil {
+		return err
+	}
+	if sesion.Estado != SesionCajaCerrada {
+		return fmt.Errorf("la caja debe estar cerrada para imprimir el arqueo")
+	}
+
+	ctx := gousb.NewContext()
+	defer ctx.Close()
+
+	dev, err := ctx.OpenDeviceWithVIDPID(vendorID, productID)
+	if err != nil {
+		return fmt.Errorf("no se pudo abrir el dispositivo: %w", err)
+	}
+	if dev == nil {
+		return fmt.Errorf("impresora POS58 no encontrada")
+	}
+	defer dev.Close()
+
+	epOut, close, err := setupEndpoint(dev)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	send := func(data []byte) error {
+		if _, err := epOut.Write(data); err != nil {
+			return fmt.Errorf("error al escribir en la impresora: %w", err)
+		}
+		return nil
+	}
+	sendEncoded := func(text string) error {
+		data, err := encodeText(text)
+		if err != nil {
+			return fmt.Errorf("error al codificar texto '%s': %w", text, err)
+		}
+		if _, err := epOut.Write(data); err != nil {
+			return fmt.Errorf("error al escribir datos codificados: %w", err)
+		}
+		return nil
+	}
+	// sendLine imprime una línea con la alineación indicada.
+	sendLine := func(align []byte, text string) error {
+		if err := send(align); err != nil {
+			return err
+		}
+		if err := sendEncoded(text); err != nil {
+			return err
+		}
+		return send(lineBreak())
+	}
+	separador := "- - - - - - - - - - - - - - - -"
+
+	if err := send([]byte("\x1B@")); err != nil {
+		return err
+	}
+	if err := send(selectCodePage(CODE_PAGE_PC858_N)); err != nil {
+		return err
+	}
+
+	if err := send(boldOn()); err != nil {
+		return err
+	}
+	if err := sendLine(center(), "CIERRE DE CAJA"); err != nil {
+		return err
+	}
+	if err := send(boldOff()); err != nil {
+		return err
+	}
+	if err := sendLine(center(), "DROGUERIA LUNA"); err != nil {
+		return err
+	}
+	if err := send(lineBreak()); err != nil {
+		return err
+	}
+
+	encabezado := []string{
+		fmt.Sprintf("Vendedor: %s %s", sesion.Vendedor.Nombre, sesion.Vendedor.Apellido),
+		fmt.Sprintf("Apertura: %s", sesion.FechaApertura.Format("02/01/2006 03:04 PM")),
+	}
+	if sesion.FechaCierre != nil {
+		encabezado = append(encabezado, fmt.Sprintf("Cierre: %s", sesion.FechaCierre.Format("02/01/2006 03:04 PM")))
+	}
+	encabezado = append(encabezado,
+		fmt.Sprintf("Base: %s", formatCurrency(sesion.MontoApertura)),
+		fmt.Sprintf("Ventas: %d", sesion.NumeroVentas),
+	)
+	for _, linea := range encabezado {
+		if err := sendLine(left(), linea); err != nil {
+			return err
+		}
+	}
+	if err := sendLine(left(), separador); err != nil {
+		return err
+	}
+
+	// Arqueo por método de pago
+	for _, c := range sesion.Conteos {
+		if err := send(boldOn()); err != nil {
+			return err
+		}
+		if err := sendLine(left(), strings.ToUpper(c.MetodoPago)); err != nil {
+			return err
+		}
+		if err := send(boldOff()); err != nil {
+			return err
+		}
+		for _, linea := range []string{
+			fmt.Sprintf("Esperado: %s", formatCurrency(c.Esperado)),
+			fmt.Sprintf("Contado: %s", formatCurrency(c.Contado)),
+			fmt.Sprintf("Diferencia: %s", formatCurrency(c.Diferencia)),
+		} {
+			if err := sendLine(right(), linea); err != nil {
+				return err
+			}
+		}
+	}
+	if err := sendLine(left(), separador); err != nil {
+		return err
+	}
+
+	if err := sendLine(right(), fmt.Sprintf("Total esperado: %s", formatCurrency(sesion.TotalEsperado))); err != nil {
+		return err
+	}
+	if err := sendLine(right(), fmt.Sprintf("Total contado: %s", formatCurrency(sesion.TotalContado))); err != nil {
+		return err
+	}
+
+	resultado := "CUADRE EXACTO"
+	if sesion.Diferencia > 0 {
+		resultado = fmt.Sprintf("SOBRANTE: %s", formatCurrency(sesion.Diferencia))
+	} else if sesion.Diferencia < 0 {
+		resultado = fmt.Sprintf("FALTANTE: %s", formatCurrency(-sesion.Diferencia))
+	}
+	if err := send(boldOn()); err != nil {
+		return err
+	}
+	if err := sendLine(right(), resultado); err != nil {
+		return err
+	}
+	if err := send(boldOff()); err != nil {
+		return err
+	}
+
+	if sesion.Observaciones != "" {
+		if err := send(lineBreak()); err != nil {
+			return err
+		}
+		if err := sendLine(left(), sesion.Observaciones); err != nil {
+			return err
+		}
+	}
+
+	// Salto de papel y corte
+	if err := send([]byte("\n\n\n")); err != nil {
+		return err
+	}
+	if err := send([]byte("\x1D\x56\x42\x00")); err != nil {
+		return err
+	}
+
+	d.Log.Infof("Cierre de caja %s enviado a la impresora correctamente.", sesionUUID)
+	return nil
+}
+
 func setupEndpoint(dev *gousb.Device) (*gousb.OutEndpoint, func(), error) {
 	cfg, err := dev.Config(1)
 	if err != nil {
@@ -358,6 +670,11 @@ func formatItemLine(qty int, name string, total float64) string {
 	return line
 }
 
+// formatTaxLine arma una fila de 32 columnas para el desglose de impuestos.
+func formatTaxLine(tarifa, base, iva string) string {
+	return fmt.Sprintf("%-8s%12s%12s", tarifa, base, iva)
+}
+
 func formatCurrency(val float64) string {
 	return fmt.Sprintf("$%d", int(val))
 }
@@ -140,7 +140,7 @@ func (d *Db) SincronizarTodasLasOperacionesHaciaRemoto() error {
 	}
 
 	// 1. Leer TODAS las operaciones de stock de la base de datos local.
-	query := `SELECT uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, timestamp FROM operacion_stocks`
+	query := `SELECT uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, COALESCE(lote_uuid, ''), timestamp FROM operacion_stocks`
 	rows, err := d.LocalDB.QueryContext(d.ctx, query)
 	if err != nil {
 		return fmt.Errorf("error al leer todas las operaciones de stock locales: %w", err)
@@ -154,7 +154,7 @@ func (d *Db) SincronizarTodasLasOperacionesHaciaRemoto() error {
 		var stockResultante sql.NullInt64
 		var facturaUUID sql.NullString
 
-		if err := rows.Scan(&op.UUID, &op.ProductoUUID, &op.TipoOperacion, &op.CantidadCambio, &stockResultante, &op.VendedorUUID, &facturaUUID, &op.Timestamp); err != nil {
+		if err := rows.Scan(&op.UUID, &op.ProductoUUID, &op.TipoOperacion, &op.CantidadCambio, &stockResultante, &op.VendedorUUID, &facturaUUID, &op.LoteUUID, &op.Timestamp); err != nil {
 			d.Log.Warnf("Omitiendo operación de stock con error de escaneo: %v", err)
 			continue
 		}
@@ -188,16 +188,17 @@ func (d *Db) SincronizarTodasLasOperacionesHaciaRemoto() error {
 
 	batch := &pgx.Batch{}
 	upsertSQL := `
-		INSERT INTO operacion_stocks (uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO operacion_stocks (uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, lote_uuid, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (uuid) DO UPDATE SET
 			tipo_operacion = EXCLUDED.tipo_operacion,
 			cantidad_cambio = EXCLUDED.cantidad_cambio,
 			stock_resultante = EXCLUDED.stock_resultante,
+			lote_uuid = EXCLUDED.lote_uuid,
 			timestamp = EXCLUDED.timestamp;
 	`
 	for _, op := range ops {
-		batch.Queue(upsertSQL, op.UUID, op.ProductoUUID, op.TipoOperacion, op.CantidadCambio, op.StockResultante, op.VendedorUUID, op.FacturaUUID, op.Timestamp)
+		batch.Queue(upsertSQL, op.UUID, op.ProductoUUID, op.TipoOperacion, op.CantidadCambio, op.StockResultante, op.VendedorUUID, op.FacturaUUID, nullableString(op.LoteUUID), op.Timestamp)
 	}
 
 	br := rtx.SendBatch(d.ctx, batch)
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"image/png"
 	"net/http"
 	"strings"
@@ -16,6 +17,17 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Roles de vendedor. SUPERVISOR y ADMIN pueden autorizar operaciones restringidas.
+const (
+	RolVendedor   = "VENDEDOR"
+	RolSupervisor = "SUPERVISOR"
+	RolAdmin      = "ADMIN"
+)
+
+func rolValido(rol string) bool {
+	return rol == RolVendedor || rol == RolSupervisor || rol == RolAdmin
+}
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
@@ -217,3 +229,42 @@ func (d *Db) VerificarLoginMFA(tempToken string, code string) (LoginResponse, er
 
 	return response, nil
 }
+
+// validarAutorizacionSupervisor comprueba las credenciales de un supervisor y devuelve su UUID.
+// Debe llamarse antes de abrir una transacción local, ya que consulta la base de datos directamente.
+func (d *Db) validarAutorizacionSupervisor(auth *AutorizacionSupervisor) (string, error) {
+	return d.validarAutorizacion(auth, "supervisor", RolSupervisor, RolAdmin)
+}
+
+// validarAutorizacionAdmin comprueba las credenciales de un ADMIN, el único que puede cambiar roles.
+func (d *Db) validarAutorizacionAdmin(auth *AutorizacionSupervisor) (string, error) {
+	return d.validarAutorizacion(auth, "administrador", RolAdmin)
+}
+
+func (d *Db) validarAutorizacion(auth *AutorizacionSupervisor, cargo string, roles ...string) (string, error) {
+	if auth == nil || strings.TrimSpace(auth.Email) == "" {
+		return "", fmt.Errorf("la operación requiere autorización de un %s", cargo)
+	}
+
+	var autorizadorUUID, hash, rol string
+	err := d.LocalDB.QueryRow(
+		"SELECT uuid, contrasena, rol FROM vendedors WHERE email = ? AND deleted_at IS NULL",
+		strings.ToLower(strings.TrimSpace(auth.Email)),
+	).Scan(&autorizadorUUID, &hash, &rol)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("credenciales de %s inválidas", cargo)
+		}
+		return "", err
+	}
+
+	if !CheckPasswordHash(auth.Contrasena, hash) {
+		return "", fmt.Errorf("credenciales de %s inválidas", cargo)
+	}
+	for _, r := range roles {
+		if rol == r {
+			return autorizadorUUID, nil
+		}
+	}
+	return "", fmt.Errorf("el usuario no tiene permisos de %s", cargo)
+}
@@ -0,0 +1,497 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Estados de una sesión de caja.
+const (
+	SesionCajaAbierta = "ABIERTA"
+	SesionCajaCerrada = "CERRADA"
+)
+
+// AbrirCaja inicia la sesión de caja de un vendedor con su base en efectivo.
+// Un vendedor solo puede tener una sesión abierta a la vez.
+func (d *Db) AbrirCaja(req AperturaCajaRequest) (SesionCaja, error) {
+	if req.VendedorUUID == "" {
+		return SesionCaja{}, fmt.Errorf("se requiere el vendedor que abre la caja")
+	}
+	if req.MontoApertura < 0 {
+		return SesionCaja{}, fmt.Errorf("el monto de apertura no puede ser negativo")
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return SesionCaja{}, fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[AbrirCaja] rollback: %v", rErr)
+		}
+	}()
+
+	var existe int
+	if err := tx.QueryRow(`SELECT COUNT(1) FROM vendedors WHERE uuid = ? AND deleted_at IS NULL`, req.VendedorUUID).Scan(&existe); err != nil {
+		return SesionCaja{}, fmt.Errorf("error validando vendedor: %w", err)
+	}
+	if existe == 0 {
+		return SesionCaja{}, fmt.Errorf("vendedor [%s] no encontrado", req.VendedorUUID)
+	}
+
+	abierta, err := sesionCajaAbierta(tx, req.VendedorUUID)
+	if err != nil {
+		return SesionCaja{}, err
+	}
+	if abierta != "" {
+		return SesionCaja{}, fmt.Errorf("el vendedor ya tiene una caja abierta; ciérrela antes de abrir una nueva")
+	}
+
+	now := time.Now()
+	sesionUUID := uuid.New().String()
+	_, err = tx.Exec(`
+		INSERT INTO sesiones_caja (uuid, vendedor_uuid, fecha_apertura, monto_apertura, estado, observaciones, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		sesionUUID, req.VendedorUUID, now, redondearMoneda(req.MontoApertura), SesionCajaAbierta,
+		strings.TrimSpace(req.Observaciones), now, now)
+	if err != nil {
+		return SesionCaja{}, fmt.Errorf("error insertando sesión de caja: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SesionCaja{}, fmt.Errorf("error confirmando apertura de caja: %w", err)
+	}
+	d.Log.Infof("[CAJA] Caja %s abierta por vendedor %s con base %.2f", sesionUUID, req.VendedorUUID, req.MontoApertura)
+
+	go func() {
+		if err := d.syncSesionCajaToRemote(sesionUUID); err != nil {
+			d.Log.Errorf("[SYNC] Error sincronizando sesión de caja %s: %v", sesionUUID, err)
+		}
+	}()
+
+	return d.ObtenerDetalleSesionCaja(sesionUUID)
+}
+
+// ObtenerSesionCajaActiva devuelve la caja abierta del vendedor. El conteo es ciego:
+// mientras la caja esté abierta no se exponen los totales esperados.
+func (d *Db) ObtenerSesionCajaActiva(vendedorUUID string) (SesionCaja, error) {
+	var sesionUUID string
+	err := d.LocalDB.QueryRow(`
+		SELECT uuid FROM sesiones_caja
+		WHERE vendedor_uuid = ? AND estado = ? AND deleted_at IS NULL
+		ORDER BY fecha_apertura DESC LIMIT 1`, vendedorUUID, SesionCajaAbierta).Scan(&sesionUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SesionCaja{}, fmt.Errorf("el vendedor no tiene una caja abierta")
+		}
+		return SesionCaja{}, fmt.Errorf("error consultando caja abierta: %w", err)
+	}
+	return d.ObtenerDetalleSesionCaja(sesionUUID)
+}
+
+// CerrarCaja registra el arqueo ciego de la sesión: el vendedor informa lo contado por método de pago
+// y el sistema calcula lo esperado y el sobrante o faltante de cada uno.
+func (d *Db) CerrarCaja(req CierreCajaRequest) (SesionCaja, error) {
+	if req.SesionCajaUUID == "" || req.VendedorUUID == "" {
+		return SesionCaja{}, fmt.Errorf("se requiere la sesión de caja y el vendedor que la cierra")
+	}
+
+	contado := make(map[string]float64, len(req.Conteos))
+	for _, c := range req.Conteos {
+		metodo := strings.ToLower(strings.TrimSpace(c.MetodoPago))
+		if metodo == "" {
+			return SesionCaja{}, fmt.Errorf("cada conteo debe indicar su método de pago")
+		}
+		if c.Contado < 0 {
+			return SesionCaja{}, fmt.Errorf("el valor contado en %s no puede ser negativo", metodo)
+		}
+		contado[metodo] += c.Contado
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return SesionCaja{}, fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[CerrarCaja] rollback: %v", rErr)
+		}
+	}()
+
+	// 1️⃣ Validar la sesión
+	var vendedorUUID, estado string
+	var montoApertura float64
+	err = tx.QueryRow(`SELECT vendedor_uuid, estado, monto_apertura FROM sesiones_caja WHERE uuid = ? AND deleted_at IS NULL`,
+		req.SesionCajaUUID).Scan(&vendedorUUID, &estado, &montoApertura)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SesionCaja{}, fmt.Errorf("sesión de caja [%s] no encontrada", req.SesionCajaUUID)
+		}
+		return SesionCaja{}, fmt.Errorf("error consultando sesión de caja: %w", err)
+	}
+	if estado != SesionCajaAbierta {
+		return SesionCaja{}, fmt.Errorf("la sesión de caja ya se encuentra %s", strings.ToLower(estado))
+	}
+	if vendedorUUID != req.VendedorUUID {
+		return SesionCaja{}, fmt.Errorf("solo el vendedor que abrió la caja puede cerrarla")
+	}
+
+	// 2️⃣ Calcular lo esperado por método de pago
+	esperado, err := calcularEsperadoCaja(tx, req.SesionCajaUUID, montoApertura)
+	if err != nil {
+		return SesionCaja{}, err
+	}
+
+	metodos := make([]string, 0, len(esperado)+len(contado))
+	for metodo := range esperado {
+		metodos = append(metodos, metodo)
+	}
+	for metodo := range contado {
+		if _, ok := esperado[metodo]; !ok {
+			metodos = append(metodos, metodo)
+		}
+	}
+	sort.Strings(metodos)
+
+	// 3️⃣ Registrar el conteo de cada método
+	now := time.Now()
+	stmtConteo, err := tx.Prepare(`
+		INSERT INTO conteos_caja (uuid, sesion_caja_uuid, metodo_pago, esperado, contado, diferencia, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return SesionCaja{}, fmt.Errorf("error preparando statement conteos_caja: %w", err)
+	}
+	defer stmtConteo.Close()
+
+	var totalEsperado, totalContado float64
+	for _, metodo := range metodos {
+		e := redondearMoneda(esperado[metodo])
+		c := redondearMoneda(contado[metodo])
+		if _, err := stmtConteo.Exec(uuid.New().String(), req.SesionCajaUUID, metodo, e, c, redondearMoneda(c-e), now, now); err != nil {
+			return SesionCaja{}, fmt.Errorf("error insertando conteo de caja en %s: %w", metodo, err)
+		}
+		totalEsperado += e
+		totalContado += c
+	}
+	totalEsperado = redondearMoneda(totalEsperado)
+	totalContado = redondearMoneda(totalContado)
+
+	// 4️⃣ Cerrar la sesión
+	_, err = tx.Exec(`
+		UPDATE sesiones_caja
+		SET estado = ?, fecha_cierre = ?, total_esperado = ?, total_contado = ?, diferencia = ?,
+			observaciones = TRIM(COALESCE(observaciones, '') || ' ' || ?), updated_at = ?, sincronizado = 0
+		WHERE uuid = ?`,
+		SesionCajaCerrada, now, totalEsperado, totalContado, redondearMoneda(totalContado-totalEsperado),
+		strings.TrimSpace(req.Observaciones), now, req.SesionCajaUUID)
+	if err != nil {
+		return SesionCaja{}, fmt.Errorf("error cerrando sesión de caja: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SesionCaja{}, fmt.Errorf("error confirmando cierre de caja: %w", err)
+	}
+	d.Log.Infof("[CAJA] Caja %s cerrada. Esperado %.2f, contado %.2f", req.SesionCajaUUID, totalEsperado, totalContado)
+
+	go func() {
+		if err := d.syncSesionCajaToRemote(req.SesionCajaUUID); err != nil {
+			d.Log.Errorf("[SYNC] Error sincronizando sesión de caja %s: %v", req.SesionCajaUUID, err)
+		}
+	}()
+
+	return d.ObtenerDetalleSesionCaja(req.SesionCajaUUID)
+}
+
+// sesionCajaAbierta devuelve el UUID de la caja abierta del vendedor, o "" si no tiene.
+func sesionCajaAbierta(tx *sql.Tx, vendedorUUID string) (string, error) {
+	var sesionUUID string
+	err := tx.QueryRow(`
+		SELECT uuid FROM sesiones_caja
+		WHERE vendedor_uuid = ? AND estado = ? AND deleted_at IS NULL
+		ORDER BY fecha_apertura DESC LIMIT 1`, vendedorUUID, SesionCajaAbierta).Scan(&sesionUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error consultando caja abierta: %w", err)
+	}
+	return sesionUUID, nil
+}
+
+// calcularEsperadoCaja suma los pagos de las facturas de la sesión por método de pago, sin la parte
+// vendida a crédito ni la pagada con puntos, más los abonos de cartera recibidos en la sesión. Las facturas
+// anuladas restan sus pagos en la caja que reembolsó la anulación, que puede ser otra sesión; las anuladas
+// sin esa caja registrada (anteriores a su registro) no cuentan. En efectivo se suma la base de apertura y
+// se descuentan las devoluciones reembolsadas en la sesión.
+func calcularEsperadoCaja(tx *sql.Tx, sesionUUID string, montoApertura float64) (map[string]float64, error) {
+	esperado := map[string]float64{MetodoPagoEfectivo: montoApertura}
+
+	rows, err := tx.Query(`
+		SELECT LOWER(pf.metodo_pago), COALESCE(SUM(pf.monto), 0)
+		FROM pagos_factura pf
+		JOIN facturas f ON f.uuid = pf.factura_uuid
+		WHERE f.sesion_caja_uuid = ? AND (COALESCE(f.estado, '') != 'ANULADA' OR f.sesion_caja_anulacion_uuid IS NOT NULL)
+			AND pf.deleted_at IS NULL AND LOWER(pf.metodo_pago) NOT IN (?, ?)
+		GROUP BY LOWER(pf.metodo_pago)
+		UNION ALL
+		SELECT LOWER(pf.metodo_pago), -COALESCE(SUM(pf.monto), 0)
+		FROM pagos_factura pf
+		JOIN facturas f ON f.uuid = pf.factura_uuid
+		WHERE f.sesion_caja_anulacion_uuid = ? AND f.estado = 'ANULADA'
+			AND pf.deleted_at IS NULL AND LOWER(pf.metodo_pago) NOT IN (?, ?)
+		GROUP BY LOWER(pf.metodo_pago)
+		UNION ALL
+		SELECT LOWER(metodo_pago), COALESCE(SUM(monto), 0)
+		FROM abonos
+		WHERE sesion_caja_uuid = ? AND deleted_at IS NULL
+		GROUP BY LOWER(metodo_pago)`, sesionUUID, MetodoPagoCredito, MetodoPagoPuntos,
+		sesionUUID, MetodoPagoCredito, MetodoPagoPuntos, sesionUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error calculando pagos de la sesión: %w", err)
+	}
+	for rows.Next() {
+		var metodo string
+		var monto float64
+		if err := rows.Scan(&metodo, &monto); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error escaneando pagos de la sesión: %w", err)
+		}
+		esperado[metodo] += monto
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var devoluciones float64
+	if err := tx.QueryRow(`SELECT COALESCE(SUM(total - aplicado_cartera), 0) FROM notas_credito WHERE sesion_caja_uuid = ?`, sesionUUID).Scan(&devoluciones); err != nil {
+		return nil, fmt.Errorf("error calculando devoluciones de la sesión: %w", err)
+	}
+	esperado[MetodoPagoEfectivo] -= devoluciones
+
+	return esperado, nil
+}
+
+// ObtenerSesionesCajaPaginado lista las sesiones de caja con filtro por vendedor o estado.
+func (d *Db) ObtenerSesionesCajaPaginado(page, pageSize int, search, sortBy, sortOrder string) (PaginatedResult, error) {
+	var (
+		sesiones []SesionCaja
+		args     []any
+		where    = " WHERE s.deleted_at IS NULL"
+	)
+
+	baseQuery := `
+		FROM sesiones_caja s
+		JOIN vendedors v ON s.vendedor_uuid = v.uuid
+	`
+
+	if search != "" {
+		searchTerm := "%" + strings.ToLower(search) + "%"
+		where += ` AND (LOWER(v.nombre) LIKE ? OR LOWER(v.apellido) LIKE ? OR LOWER(s.estado) LIKE ?)`
+		args = append(args, searchTerm, searchTerm, searchTerm)
+	}
+
+	var total int64
+	if err := d.LocalDB.QueryRow("SELECT COUNT(s.uuid) "+baseQuery+where, args...).Scan(&total); err != nil {
+		return PaginatedResult{}, fmt.Errorf("Error contando sesiones de caja: %w", err)
+	}
+
+	allowedSortBy := map[string]string{
+		"FechaApertura": "s.fecha_apertura",
+		"FechaCierre":   "s.fecha_cierre",
+		"Vendedor":      "v.nombre",
+		"Estado":        "s.estado",
+		"Diferencia":    "s.diferencia",
+	}
+
+	orderBy := "ORDER BY s.fecha_apertura DESC"
+	if col, ok := allowedSortBy[sortBy]; ok {
+		order := "ASC"
+		if strings.ToLower(sortOrder) == "desc" {
+			order = "DESC"
+		}
+		orderBy = fmt.Sprintf("ORDER BY %s %s", col, order)
+	}
+
+	offset := (page - 1) * pageSize
+	pagination := fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize, offset)
+
+	selectQuery := `
+		SELECT
+			s.uuid, s.fecha_apertura, s.monto_apertura, s.fecha_cierre, s.estado,
+			s.total_esperado, s.total_contado, s.diferencia,
+			(SELECT COUNT(1) FROM facturas f WHERE f.sesion_caja_uuid = s.uuid),
+			v.uuid, v.nombre, v.apellido
+	` + baseQuery + where + " " + orderBy + pagination
+
+	rows, err := d.LocalDB.Query(selectQuery, args...)
+	if err != nil {
+		return PaginatedResult{}, fmt.Errorf("Error realizando consulta sesiones de caja: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s SesionCaja
+		var fechaCierre sql.NullTime
+		if err := rows.Scan(
+			&s.UUID, &s.FechaApertura, &s.MontoApertura, &fechaCierre, &s.Estado,
+			&s.TotalEsperado, &s.TotalContado, &s.Diferencia, &s.NumeroVentas,
+			&s.Vendedor.UUID, &s.Vendedor.Nombre, &s.Vendedor.Apellido,
+		); err != nil {
+			return PaginatedResult{}, err
+		}
+		if fechaCierre.Valid {
+			s.FechaCierre = &fechaCierre.Time
+		}
+		s.VendedorUUID = s.Vendedor.UUID
+		sesiones = append(sesiones, s)
+	}
+
+	return PaginatedResult{Records: sesiones, TotalRecords: total}, nil
+}
+
+// ObtenerDetalleSesionCaja devuelve una sesión con su arqueo. Las sesiones abiertas no incluyen conteos.
+func (d *Db) ObtenerDetalleSesionCaja(sesionUUID string) (SesionCaja, error) {
+	var s SesionCaja
+	var fechaCierre sql.NullTime
+	err := d.LocalDB.QueryRow(`
+		SELECT
+			s.uuid, s.vendedor_uuid, s.fecha_apertura, s.monto_apertura, s.fecha_cierre, s.estado,
+			s.total_esperado, s.total_contado, s.diferencia, COALESCE(s.observaciones, ''),
+			s.created_at, s.updated_at,
+			(SELECT COUNT(1) FROM facturas f WHERE f.sesion_caja_uuid = s.uuid),
+			v.uuid, v.nombre, v.apellido
+		FROM sesiones_caja s
+		JOIN vendedors v ON s.vendedor_uuid = v.uuid
+		WHERE s.uuid = ?`, sesionUUID).Scan(
+		&s.UUID, &s.VendedorUUID, &s.FechaApertura, &s.MontoApertura, &fechaCierre, &s.Estado,
+		&s.TotalEsperado, &s.TotalContado, &s.Diferencia, &s.Observaciones,
+		&s.CreatedAt, &s.UpdatedAt, &s.NumeroVentas,
+		&s.Vendedor.UUID, &s.Vendedor.Nombre, &s.Vendedor.Apellido)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SesionCaja{}, fmt.Errorf("sesión de caja [%s] no encontrada", sesionUUID)
+		}
+		return SesionCaja{}, fmt.Errorf("error al obtener sesión de caja %s: %w", sesionUUID, err)
+	}
+	if fechaCierre.Valid {
+		s.FechaCierre = &fechaCierre.Time
+	}
+
+	s.Conteos = make([]ConteoCaja, 0)
+	rows, err := d.LocalDB.Query(`
+		SELECT uuid, sesion_caja_uuid, metodo_pago, esperado, contado, diferencia, created_at, updated_at
+		FROM conteos_caja
+		WHERE sesion_caja_uuid = ?
+		ORDER BY metodo_pago ASC`, sesionUUID)
+	if err != nil {
+		return s, fmt.Errorf("error consultando conteos de caja: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c ConteoCaja
+		if err := rows.Scan(&c.UUID, &c.SesionCajaUUID, &c.MetodoPago, &c.Esperado, &c.Contado, &c.Diferencia, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return s, fmt.Errorf("error escaneando conteo de caja: %w", err)
+		}
+		s.Conteos = append(s.Conteos, c)
+	}
+	return s, rows.Err()
+}
+
+// syncSesionCajaToRemote sube la sesión y su arqueo al remoto. La apertura y el cierre se envían
+// por separado; el remoto conserva la versión más reciente de la sesión. Sin conexión la sesión queda
+// con sincronizado = 0 y la sube SincronizarSesionesCajaHaciaRemoto.
+func (d *Db) syncSesionCajaToRemote(sesionUUID string) error {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return nil
+	}
+	ctx := d.ctx
+	runtime.EventsEmit(d.ctx, "sync:start", sesionUUID)
+
+	s, err := d.ObtenerDetalleSesionCaja(sesionUUID)
+	if err != nil {
+		return fmt.Errorf("[LOCAL] - sesión de caja no encontrada localmente: %w", err)
+	}
+
+	rtx, err := d.RemoteDB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("[REMOTO] - error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := rtx.Rollback(ctx); rErr != nil && !errors.Is(rErr, pgx.ErrTxClosed) {
+			d.Log.Errorf("[REMOTO] - Error durante [syncSesionCajaToRemote] rollback %v", rErr)
+		}
+	}()
+
+	_, err = rtx.Exec(ctx, `
+		INSERT INTO sesiones_caja (uuid, vendedor_uuid, fecha_apertura, monto_apertura, fecha_cierre, estado,
+			total_esperado, total_contado, diferencia, observaciones, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (uuid) DO UPDATE SET
+			fecha_cierre = EXCLUDED.fecha_cierre,
+			estado = EXCLUDED.estado,
+			total_esperado = EXCLUDED.total_esperado,
+			total_contado = EXCLUDED.total_contado,
+			diferencia = EXCLUDED.diferencia,
+			observaciones = EXCLUDED.observaciones,
+			updated_at = EXCLUDED.updated_at
+		WHERE sesiones_caja.updated_at < EXCLUDED.updated_at`,
+		s.UUID, s.VendedorUUID, s.FechaApertura, s.MontoApertura, s.FechaCierre, s.Estado,
+		s.TotalEsperado, s.TotalContado, s.Diferencia, s.Observaciones, s.CreatedAt, s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("[REMOTO] - error sincronizando sesión de caja: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, c := range s.Conteos {
+		batch.Queue(`
+			INSERT INTO conteos_caja (uuid, sesion_caja_uuid, metodo_pago, esperado, contado, diferencia, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (uuid) DO NOTHING`,
+			c.UUID, c.SesionCajaUUID, c.MetodoPago, c.Esperado, c.Contado, c.Diferencia, c.CreatedAt, c.UpdatedAt)
+	}
+	if err := rtx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("[REMOTO] - Error ejecutando batch de conteos_caja: %w", err)
+	}
+
+	if err := rtx.Commit(ctx); err != nil {
+		return fmt.Errorf("Error confirmando transacción remota: %w", err)
+	}
+
+	// Solo se marca si la sesión no cambió de estado mientras se subía (ej. se cerró durante la apertura)
+	if _, err := d.LocalDB.ExecContext(ctx, `UPDATE sesiones_caja SET sincronizado = 1 WHERE uuid = ? AND estado = ?`, s.UUID, s.Estado); err != nil {
+		return fmt.Errorf("[LOCAL] - error marcando sesión de caja sincronizada: %w", err)
+	}
+	d.Log.Infof("[LOCAL -> REMOTO] - Sesión de caja %s sincronizada correctamente.", s.UUID)
+	runtime.EventsEmit(d.ctx, "sync:finish", sesionUUID)
+	return nil
+}
+
+// SincronizarSesionesCajaHaciaRemoto sube las aperturas y cierres de caja que quedaron pendientes por
+// falta de conexión.
+func (d *Db) SincronizarSesionesCajaHaciaRemoto() {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] Base de datos remota no disponible, omitiendo sincronización de sesiones de caja.")
+		return
+	}
+	pendientes, err := d.uuidsPendientes(`SELECT uuid FROM sesiones_caja WHERE sincronizado = 0 ORDER BY fecha_apertura ASC`)
+	if err != nil {
+		d.Log.Errorf("[SYNC CAJA] Error leyendo sesiones de caja pendientes: %v", err)
+		return
+	}
+	for _, sesionUUID := range pendientes {
+		if err := d.syncSesionCajaToRemote(sesionUUID); err != nil {
+			d.Log.Errorf("[SYNC CAJA] Error sincronizando sesión de caja %s: %v", sesionUUID, err)
+		}
+	}
+}
@@ -0,0 +1,570 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Estados de una factura según su cobro. Las ventas con una parte a crédito quedan PENDIENTE
+// hasta que los abonos (o las devoluciones) cubren el saldo.
+const (
+	EstadoFacturaPagada    = "PAGADA"
+	EstadoFacturaPendiente = "PENDIENTE"
+)
+
+// sqlSaldoFactura calcula el saldo de la factura con alias f: lo financiado a crédito menos los
+// abonos aplicados y lo descontado por notas crédito. El saldo no se guarda para que no se
+// desincronice entre terminales.
+const sqlSaldoFactura = `(
+	(SELECT COALESCE(SUM(pf.monto), 0) FROM pagos_factura pf
+		WHERE pf.factura_uuid = f.uuid AND LOWER(pf.metodo_pago) = '` + MetodoPagoCredito + `' AND pf.deleted_at IS NULL)
+	- (SELECT COALESCE(SUM(af.monto), 0) FROM abono_facturas af JOIN abonos a ON a.uuid = af.abono_uuid
+		WHERE af.factura_uuid = f.uuid AND a.deleted_at IS NULL)
+	- (SELECT COALESCE(SUM(nc.aplicado_cartera), 0) FROM notas_credito nc WHERE nc.factura_uuid = f.uuid))`
+
+// sqlSaldoCliente suma el saldo de las facturas pendientes del cliente con alias c.
+const sqlSaldoCliente = `(SELECT COALESCE(SUM(` + sqlSaldoFactura + `), 0)
+	FROM facturas f WHERE f.cliente_uuid = c.uuid AND f.estado = '` + EstadoFacturaPendiente + `')`
+
+// montoCredito devuelve la parte de la venta que queda financiada al cliente.
+func montoCredito(pagos []PagoFactura) float64 {
+	var credito float64
+	for _, p := range pagos {
+		if strings.EqualFold(p.MetodoPago, MetodoPagoCredito) {
+			credito += p.Monto
+		}
+	}
+	return redondearMoneda(credito)
+}
+
+// validarCupoCredito verifica que el cliente pueda comprar a crédito y que la nueva deuda
+// quepa en su cupo. Un límite en 0 significa que el cliente no tiene crédito.
+func validarCupoCredito(tx *sql.Tx, clienteUUID string, monto float64) error {
+	var nombre, numeroID string
+	var limite, saldo float64
+	err := tx.QueryRow(`
+		SELECT c.nombre || ' ' || c.apellido, c.numero_id, c.limite_credito, `+sqlSaldoCliente+`
+		FROM clientes c WHERE c.uuid = ? AND c.deleted_at IS NULL`, clienteUUID).Scan(&nombre, &numeroID, &limite, &saldo)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("cliente [%s] no encontrado", clienteUUID)
+		}
+		return fmt.Errorf("error consultando cupo de crédito del cliente: %w", err)
+	}
+	if numeroID == ConsumidorFinalID {
+		return fmt.Errorf("no se puede vender a crédito a consumidor final")
+	}
+	if limite <= 0 {
+		return fmt.Errorf("el cliente %s no tiene cupo de crédito asignado", strings.TrimSpace(nombre))
+	}
+	if disponible := redondearMoneda(limite - saldo); monto > disponible+0.005 {
+		return fmt.Errorf("la venta a crédito (%.2f) supera el cupo disponible del cliente %s (%.2f de %.2f)",
+			monto, strings.TrimSpace(nombre), math.Max(disponible, 0), limite)
+	}
+	return nil
+}
+
+// saldoFactura devuelve el saldo a crédito pendiente de una factura.
+func saldoFactura(tx *sql.Tx, facturaUUID string) (float64, error) {
+	var saldo float64
+	if err := tx.QueryRow(`SELECT `+sqlSaldoFactura+` FROM facturas f WHERE f.uuid = ?`, facturaUUID).Scan(&saldo); err != nil {
+		return 0, fmt.Errorf("error calculando saldo de la factura: %w", err)
+	}
+	return redondearMoneda(saldo), nil
+}
+
+// RegistrarAbono recibe un pago a la cuenta de un cliente y lo reparte entre sus facturas
+// pendientes, en el orden indicado o de la más antigua a la más reciente. Las facturas que
+// quedan sin saldo pasan a PAGADA. El dinero entra a la caja abierta de quien lo recibe.
+func (d *Db) RegistrarAbono(req AbonoRequest) (Abono, error) {
+	if req.ClienteUUID == "" || req.VendedorUUID == "" {
+		return Abono{}, fmt.Errorf("se requiere el cliente y el vendedor que recibe el abono")
+	}
+	monto := redondearMoneda(req.Monto)
+	if monto <= 0 {
+		return Abono{}, fmt.Errorf("el monto del abono debe ser mayor que cero")
+	}
+	metodo := strings.TrimSpace(req.MetodoPago)
+	if metodo == "" {
+		metodo = MetodoPagoEfectivo
+	}
+	if strings.EqualFold(metodo, MetodoPagoCredito) || strings.EqualFold(metodo, MetodoPagoMixto) {
+		return Abono{}, fmt.Errorf("método de pago no válido para un abono: %s", metodo)
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return Abono{}, fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[RegistrarAbono] rollback: %v", rErr)
+		}
+	}()
+
+	// 1️⃣ El abono entra a la caja abierta del vendedor
+	sesionCajaUUID, err := sesionCajaAbierta(tx, req.VendedorUUID)
+	if err != nil {
+		return Abono{}, err
+	}
+	if sesionCajaUUID == "" {
+		return Abono{}, fmt.Errorf("el vendedor no tiene una caja abierta: debe abrir caja antes de recibir abonos")
+	}
+
+	// 2️⃣ Facturas pendientes del cliente, de la más antigua a la más reciente
+	pendientes, err := facturasPendientes(tx, req.ClienteUUID)
+	if err != nil {
+		return Abono{}, err
+	}
+	if len(req.FacturaUUIDs) > 0 {
+		porUUID := make(map[string]FacturaPendiente, len(pendientes))
+		for _, p := range pendientes {
+			porUUID[p.FacturaUUID] = p
+		}
+		seleccion := make([]FacturaPendiente, 0, len(req.FacturaUUIDs))
+		for _, facturaUUID := range req.FacturaUUIDs {
+			p, ok := porUUID[facturaUUID]
+			if !ok {
+				return Abono{}, fmt.Errorf("la factura [%s] no tiene saldo pendiente para este cliente", facturaUUID)
+			}
+			delete(porUUID, facturaUUID)
+			seleccion = append(seleccion, p)
+		}
+		pendientes = seleccion
+	}
+
+	var totalPendiente float64
+	for _, p := range pendientes {
+		totalPendiente += p.Saldo
+	}
+	totalPendiente = redondearMoneda(totalPendiente)
+	if totalPendiente <= 0 {
+		return Abono{}, fmt.Errorf("el cliente no tiene facturas con saldo pendiente")
+	}
+	if monto > totalPendiente+0.005 {
+		return Abono{}, fmt.Errorf("el abono (%.2f) supera el saldo pendiente (%.2f)", monto, totalPendiente)
+	}
+
+	numeroAbono, err := generarConsecutivo(tx, "abonos", "numero_abono", d.prefijoDocumentoTerminal("AB-"))
+	if err != nil {
+		return Abono{}, fmt.Errorf("error al generar número de abono: %w", err)
+	}
+
+	now := time.Now()
+	abono := Abono{
+		UUID:           uuid.New().String(),
+		NumeroAbono:    numeroAbono,
+		ClienteUUID:    req.ClienteUUID,
+		VendedorUUID:   req.VendedorUUID,
+		SesionCajaUUID: sesionCajaUUID,
+		Fecha:          now,
+		Monto:          monto,
+		MetodoPago:     metodo,
+		Referencia:     strings.TrimSpace(req.Referencia),
+		Observaciones:  strings.TrimSpace(req.Observaciones),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	// 3️⃣ Repartir el abono entre las facturas
+	var pagadas []string
+	restante := monto
+	for _, p := range pendientes {
+		if restante <= 0.005 {
+			break
+		}
+		aplicado := redondearMoneda(math.Min(restante, p.Saldo))
+		restante = redondearMoneda(restante - aplicado)
+		abono.Facturas = append(abono.Facturas, AbonoFactura{
+			UUID:          uuid.New().String(),
+			AbonoUUID:     abono.UUID,
+			FacturaUUID:   p.FacturaUUID,
+			NumeroFactura: p.NumeroFactura,
+			Monto:         aplicado,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+		if p.Saldo-aplicado <= 0.005 {
+			pagadas = append(pagadas, p.FacturaUUID)
+		}
+	}
+
+	// 4️⃣ Insertar abono y su aplicación
+	_, err = tx.Exec(`
+		INSERT INTO abonos (uuid, numero_abono, cliente_uuid, vendedor_uuid, sesion_caja_uuid, fecha, monto, metodo_pago,
+			referencia, observaciones, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		abono.UUID, abono.NumeroAbono, abono.ClienteUUID, abono.VendedorUUID, abono.SesionCajaUUID, abono.Fecha, abono.Monto,
+		abono.MetodoPago, nullableString(abono.Referencia), nullableString(abono.Observaciones), now, now)
+	if err != nil {
+		return Abono{}, fmt.Errorf("error insertando abono: %w", err)
+	}
+	for _, af := range abono.Facturas {
+		if _, err := tx.Exec(`
+			INSERT INTO abono_facturas (uuid, abono_uuid, factura_uuid, monto, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			af.UUID, af.AbonoUUID, af.FacturaUUID, af.Monto, now, now); err != nil {
+			return Abono{}, fmt.Errorf("error aplicando abono a la factura %s: %w", af.NumeroFactura, err)
+		}
+	}
+
+	// 5️⃣ Las facturas cubiertas quedan pagadas
+	for _, facturaUUID := range pagadas {
+		if _, err := tx.Exec(`UPDATE facturas SET estado = ?, updated_at = ? WHERE uuid = ?`,
+			EstadoFacturaPagada, now, facturaUUID); err != nil {
+			return Abono{}, fmt.Errorf("error actualizando estado de la factura: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Abono{}, fmt.Errorf("error confirmando transacción de abono: %w", err)
+	}
+	d.Log.Infof("[CARTERA] Abono %s de %.2f del cliente %s aplicado a %d facturas (%d pagadas)",
+		abono.NumeroAbono, abono.Monto, abono.ClienteUUID, len(abono.Facturas), len(pagadas))
+
+	go func() {
+		if err := d.syncAbonoToRemote(abono.UUID); err != nil {
+			d.Log.Errorf("[SYNC] Error sincronizando abono %s: %v", abono.UUID, err)
+		}
+	}()
+
+	return d.ObtenerAbono(abono.UUID)
+}
+
+// ObtenerAbono devuelve un abono con las facturas a las que se aplicó.
+func (d *Db) ObtenerAbono(abonoUUID string) (Abono, error) {
+	var a Abono
+	err := d.LocalDB.QueryRow(`
+		SELECT uuid, numero_abono, cliente_uuid, vendedor_uuid, COALESCE(sesion_caja_uuid, ''), fecha, monto, metodo_pago,
+			COALESCE(referencia, ''), COALESCE(observaciones, ''), created_at, updated_at, deleted_at
+		FROM abonos WHERE uuid = ?`, abonoUUID).Scan(
+		&a.UUID, &a.NumeroAbono, &a.ClienteUUID, &a.VendedorUUID, &a.SesionCajaUUID, &a.Fecha, &a.Monto, &a.MetodoPago,
+		&a.Referencia, &a.Observaciones, &a.CreatedAt, &a.UpdatedAt, &a.DeletedAt)
+	if err != nil {
+		return Abono{}, fmt.Errorf("error al obtener abono %s: %w", abonoUUID, err)
+	}
+
+	rows, err := d.LocalDB.Query(`
+		SELECT af.uuid, af.abono_uuid, af.factura_uuid, f.numero_factura, af.monto, af.created_at, af.updated_at
+		FROM abono_facturas af
+		JOIN facturas f ON f.uuid = af.factura_uuid
+		WHERE af.abono_uuid = ?
+		ORDER BY f.fecha_emision ASC`, abonoUUID)
+	if err != nil {
+		return Abono{}, fmt.Errorf("error consultando facturas del abono: %w", err)
+	}
+	defer rows.Close()
+
+	a.Facturas = make([]AbonoFactura, 0)
+	for rows.Next() {
+		var af AbonoFactura
+		if err := rows.Scan(&af.UUID, &af.AbonoUUID, &af.FacturaUUID, &af.NumeroFactura, &af.Monto, &af.CreatedAt, &af.UpdatedAt); err != nil {
+			return Abono{}, fmt.Errorf("error escaneando factura del abono: %w", err)
+		}
+		a.Facturas = append(a.Facturas, af)
+	}
+	return a, rows.Err()
+}
+
+// ObtenerFacturasPendientesCliente lista las facturas a crédito del cliente que aún tienen saldo.
+func (d *Db) ObtenerFacturasPendientesCliente(clienteUUID string) ([]FacturaPendiente, error) {
+	return facturasPendientes(d.LocalDB, clienteUUID)
+}
+
+// facturasPendientes se usa dentro de la transacción del abono y fuera de ella en las consultas.
+func facturasPendientes(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, clienteUUID string) ([]FacturaPendiente, error) {
+	rows, err := q.Query(`
+		SELECT f.uuid, f.numero_factura, f.fecha_emision,
+			(SELECT COALESCE(SUM(pf.monto), 0) FROM pagos_factura pf
+				WHERE pf.factura_uuid = f.uuid AND LOWER(pf.metodo_pago) = '`+MetodoPagoCredito+`' AND pf.deleted_at IS NULL),
+			`+sqlSaldoFactura+`
+		FROM facturas f
+		WHERE f.cliente_uuid = ? AND f.estado = ?
+		ORDER BY f.fecha_emision ASC`, clienteUUID, EstadoFacturaPendiente)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando facturas pendientes: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	pendientes := make([]FacturaPendiente, 0)
+	for rows.Next() {
+		var p FacturaPendiente
+		if err := rows.Scan(&p.FacturaUUID, &p.NumeroFactura, &p.FechaEmision, &p.ValorCredito, &p.Saldo); err != nil {
+			return nil, fmt.Errorf("error escaneando factura pendiente: %w", err)
+		}
+		p.Saldo = redondearMoneda(p.Saldo)
+		if p.Saldo <= 0.005 {
+			continue
+		}
+		p.Abonado = redondearMoneda(p.ValorCredito - p.Saldo)
+		p.Dias = int(now.Sub(p.FechaEmision).Hours() / 24)
+		pendientes = append(pendientes, p)
+	}
+	return pendientes, rows.Err()
+}
+
+// ObtenerCarteraPorEdades agrupa el saldo pendiente de cada cliente según la antigüedad de sus
+// facturas: 0-30, 31-60, 61-90 y más de 90 días desde la emisión.
+func (d *Db) ObtenerCarteraPorEdades() (ReporteCartera, error) {
+	rows, err := d.LocalDB.Query(`
+		SELECT c.uuid, c.nombre || ' ' || c.apellido, c.numero_id, c.limite_credito, f.fecha_emision, `+sqlSaldoFactura+`
+		FROM facturas f
+		JOIN clientes c ON c.uuid = f.cliente_uuid
+		WHERE f.estado = ?
+		ORDER BY c.nombre, c.apellido, f.fecha_emision`, EstadoFacturaPendiente)
+	if err != nil {
+		return ReporteCartera{}, fmt.Errorf("error consultando cartera: %w", err)
+	}
+	defer rows.Close()
+
+	reporte := ReporteCartera{FechaCorte: time.Now(), Clientes: make([]CarteraCliente, 0)}
+	indice := make(map[string]int)
+	for rows.Next() {
+		var c CarteraCliente
+		var fechaEmision time.Time
+		var saldo float64
+		if err := rows.Scan(&c.ClienteUUID, &c.Nombre, &c.NumeroID, &c.LimiteCredito, &fechaEmision, &saldo); err != nil {
+			return ReporteCartera{}, fmt.Errorf("error escaneando cartera: %w", err)
+		}
+		if saldo = redondearMoneda(saldo); saldo <= 0.005 {
+			continue
+		}
+
+		i, ok := indice[c.ClienteUUID]
+		if !ok {
+			c.Nombre = strings.TrimSpace(c.Nombre)
+			reporte.Clientes = append(reporte.Clientes, c)
+			i = len(reporte.Clientes) - 1
+			indice[c.ClienteUUID] = i
+		}
+		acumularEdad(&reporte.Clientes[i], int(reporte.FechaCorte.Sub(fechaEmision).Hours()/24), saldo)
+		acumularEdad(&reporte.Totales, int(reporte.FechaCorte.Sub(fechaEmision).Hours()/24), saldo)
+	}
+	return reporte, rows.Err()
+}
+
+func acumularEdad(c *CarteraCliente, dias int, saldo float64) {
+	switch {
+	case dias <= 30:
+		c.De0a30 = redondearMoneda(c.De0a30 + saldo)
+	case dias <= 60:
+		c.De31a60 = redondearMoneda(c.De31a60 + saldo)
+	case dias <= 90:
+		c.De61a90 = redondearMoneda(c.De61a90 + saldo)
+	default:
+		c.Mas90 = redondearMoneda(c.Mas90 + saldo)
+	}
+	c.Total = redondearMoneda(c.Total + saldo)
+}
+
+// ObtenerEstadoCuentaCliente devuelve los cargos (ventas a crédito) y abonos del cliente entre
+// dos fechas (AAAA-MM-DD) con el saldo acumulado. Sin fechas se toma el mes en curso.
+func (d *Db) ObtenerEstadoCuentaCliente(clienteUUID, fechaInicio, fechaFin string) (EstadoCuentaCliente, error) {
+	now := time.Now()
+	if fechaInicio == "" {
+		fechaInicio = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+	}
+	if fechaFin == "" {
+		fechaFin = now.Format("2006-01-02")
+	}
+	inicio, err := time.ParseInLocation("2006-01-02", fechaInicio, time.Local)
+	if err != nil {
+		return EstadoCuentaCliente{}, fmt.Errorf("formato de fecha inválido: %w", err)
+	}
+	fin, err := time.ParseInLocation("2006-01-02", fechaFin, time.Local)
+	if err != nil {
+		return EstadoCuentaCliente{}, fmt.Errorf("formato de fecha inválido: %w", err)
+	}
+	if fin.Before(inicio) {
+		return EstadoCuentaCliente{}, fmt.Errorf("la fecha final (%s) es anterior a la inicial (%s)", fechaFin, fechaInicio)
+	}
+	fin = fin.Add(24*time.Hour - time.Nanosecond)
+
+	cliente, err := d.ObtenerClientePorID(clienteUUID)
+	if err != nil {
+		return EstadoCuentaCliente{}, err
+	}
+
+	movimientos, err := d.obtenerMovimientosCuenta(clienteUUID)
+	if err != nil {
+		return EstadoCuentaCliente{}, err
+	}
+
+	estado := EstadoCuentaCliente{
+		Cliente:     cliente,
+		FechaInicio: fechaInicio,
+		FechaFin:    fechaFin,
+		Movimientos: make([]MovimientoCuenta, 0),
+	}
+	var saldo float64
+	for _, m := range movimientos {
+		if m.Fecha.After(fin) {
+			break
+		}
+		saldo = redondearMoneda(saldo + m.Cargo - m.Abono)
+		if m.Fecha.Before(inicio) {
+			estado.SaldoInicial = saldo
+			continue
+		}
+		m.Saldo = saldo
+		estado.Movimientos = append(estado.Movimientos, m)
+	}
+	estado.SaldoFinal = saldo
+
+	if estado.FacturasPendientes, err = facturasPendientes(d.LocalDB, clienteUUID); err != nil {
+		return EstadoCuentaCliente{}, err
+	}
+	estado.CupoDisponible = redondearMoneda(math.Max(cliente.LimiteCredito-cliente.Saldo, 0))
+	return estado, nil
+}
+
+// obtenerMovimientosCuenta reúne en orden cronológico las ventas a crédito, los abonos y las
+// notas crédito descontadas de la cartera de un cliente.
+func (d *Db) obtenerMovimientosCuenta(clienteUUID string) ([]MovimientoCuenta, error) {
+	consultas := []struct {
+		tipo  string
+		query string
+	}{
+		{"FACTURA", `
+			SELECT f.fecha_emision, f.numero_factura, '', SUM(pf.monto), 0
+			FROM facturas f
+			JOIN pagos_factura pf ON pf.factura_uuid = f.uuid
+			WHERE f.cliente_uuid = ? AND COALESCE(f.estado, '') != 'ANULADA'
+				AND LOWER(pf.metodo_pago) = '` + MetodoPagoCredito + `' AND pf.deleted_at IS NULL
+			GROUP BY f.uuid, f.fecha_emision, f.numero_factura`},
+		{"ABONO", `
+			SELECT fecha, numero_abono, TRIM(metodo_pago || ' ' || COALESCE(referencia, '')), 0, monto
+			FROM abonos
+			WHERE cliente_uuid = ? AND deleted_at IS NULL`},
+		{"NOTA_CREDITO", `
+			SELECT n.fecha_emision, n.numero_nota, f.numero_factura, 0, n.aplicado_cartera
+			FROM notas_credito n
+			JOIN facturas f ON f.uuid = n.factura_uuid
+			WHERE n.cliente_uuid = ? AND n.aplicado_cartera > 0`},
+	}
+
+	movimientos := make([]MovimientoCuenta, 0)
+	for _, c := range consultas {
+		rows, err := d.LocalDB.Query(c.query, clienteUUID)
+		if err != nil {
+			return nil, fmt.Errorf("error consultando movimientos (%s): %w", c.tipo, err)
+		}
+		for rows.Next() {
+			m := MovimientoCuenta{Tipo: c.tipo}
+			if err := rows.Scan(&m.Fecha, &m.Documento, &m.Referencia, &m.Cargo, &m.Abono); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error escaneando movimiento (%s): %w", c.tipo, err)
+			}
+			movimientos = append(movimientos, m)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(movimientos, func(i, j int) bool { return movimientos[i].Fecha.Before(movimientos[j].Fecha) })
+	return movimientos, nil
+}
+
+// syncAbonoToRemote sube un abono y su aplicación al remoto. Antes se sincronizan las facturas
+// abonadas para que existan allá y reciban su nuevo estado. Sin conexión el abono queda con
+// sincronizado = 0 y lo sube SincronizarAbonosHaciaRemoto.
+func (d *Db) syncAbonoToRemote(abonoUUID string) error {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return nil
+	}
+	ctx := d.ctx
+	runtime.EventsEmit(d.ctx, "sync:start", abonoUUID)
+
+	abono, err := d.ObtenerAbono(abonoUUID)
+	if err != nil {
+		return fmt.Errorf("[LOCAL] - abono no encontrado localmente: %w", err)
+	}
+
+	for _, af := range abono.Facturas {
+		if err := d.syncVentaToRemote(af.FacturaUUID); err != nil {
+			return fmt.Errorf("error sincronizando factura %s del abono: %w", af.NumeroFactura, err)
+		}
+	}
+
+	rtx, err := d.RemoteDB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("[REMOTO] - error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := rtx.Rollback(ctx); rErr != nil && !errors.Is(rErr, pgx.ErrTxClosed) {
+			d.Log.Errorf("[REMOTO] - Error durante [syncAbonoToRemote] rollback %v", rErr)
+		}
+	}()
+
+	_, err = rtx.Exec(ctx, `
+		INSERT INTO abonos (uuid, numero_abono, cliente_uuid, vendedor_uuid, sesion_caja_uuid, fecha, monto, metodo_pago,
+			referencia, observaciones, created_at, updated_at, deleted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (uuid) DO UPDATE SET
+			deleted_at = EXCLUDED.deleted_at,
+			updated_at = EXCLUDED.updated_at
+		WHERE abonos.updated_at < EXCLUDED.updated_at`,
+		abono.UUID, abono.NumeroAbono, abono.ClienteUUID, abono.VendedorUUID, nullableString(abono.SesionCajaUUID), abono.Fecha,
+		abono.Monto, abono.MetodoPago, nullableString(abono.Referencia), nullableString(abono.Observaciones),
+		abono.CreatedAt, abono.UpdatedAt, abono.DeletedAt)
+	if err != nil {
+		return fmt.Errorf("[REMOTO] - error insertando abono: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, af := range abono.Facturas {
+		batch.Queue(`
+			INSERT INTO abono_facturas (uuid, abono_uuid, factura_uuid, monto, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (uuid) DO NOTHING`,
+			af.UUID, af.AbonoUUID, af.FacturaUUID, af.Monto, af.CreatedAt, af.UpdatedAt)
+	}
+	if err := rtx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("[REMOTO] - Error ejecutando batch de abono_facturas: %w", err)
+	}
+
+	if err := rtx.Commit(ctx); err != nil {
+		return fmt.Errorf("Error confirmando transacción remota: %w", err)
+	}
+
+	if _, err := d.LocalDB.ExecContext(ctx, `UPDATE abonos SET sincronizado = 1 WHERE uuid = ?`, abono.UUID); err != nil {
+		return fmt.Errorf("[LOCAL] - error marcando abono sincronizado: %w", err)
+	}
+	d.Log.Infof("[LOCAL -> REMOTO] - Abono %s sincronizado correctamente.", abono.NumeroAbono)
+	runtime.EventsEmit(d.ctx, "sync:finish", abonoUUID)
+	return nil
+}
+
+// SincronizarAbonosHaciaRemoto sube los abonos que quedaron pendientes por falta de conexión, para que
+// las demás terminales vean el saldo real de cartera del cliente.
+func (d *Db) SincronizarAbonosHaciaRemoto() {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] Base de datos remota no disponible, omitiendo sincronización de abonos.")
+		return
+	}
+	pendientes, err := d.uuidsPendientes(`SELECT uuid FROM abonos WHERE sincronizado = 0 ORDER BY fecha ASC`)
+	if err != nil {
+		d.Log.Errorf("[SYNC ABONOS] Error leyendo abonos pendientes: %v", err)
+		return
+	}
+	for _, abonoUUID := range pendientes {
+		if err := d.syncAbonoToRemote(abonoUUID); err != nil {
+			d.Log.Errorf("[SYNC ABONOS] Error sincronizando abono %s: %v", abonoUUID, err)
+		}
+	}
+}
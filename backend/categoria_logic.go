@@ -0,0 +1,182 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sqlSubarbolCategorias lista los nombres de las categorías que coinciden con un patrón LIKE y de todas
+// sus subcategorías.
+const sqlSubarbolCategorias = `
+	WITH RECURSIVE arbol(nombre) AS (
+		SELECT nombre FROM categorias_producto WHERE LOWER(nombre) LIKE ? AND deleted_at IS NULL
+		UNION
+		SELECT c.nombre FROM categorias_producto c JOIN arbol a ON c.categoria_padre = a.nombre WHERE c.deleted_at IS NULL
+	)
+	SELECT nombre FROM arbol`
+
+// ObtenerCategorias devuelve el árbol de categorías de productos, ordenado por nombre en cada nivel.
+func (d *Db) ObtenerCategorias() ([]CategoriaProducto, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT uuid, nombre, COALESCE(categoria_padre, ''), created_at, updated_at
+		FROM categorias_producto
+		WHERE deleted_at IS NULL
+		ORDER BY nombre ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener categorías: %w", err)
+	}
+	defer rows.Close()
+
+	hijos := make(map[string][]CategoriaProducto)
+	existentes := make(map[string]bool)
+	for rows.Next() {
+		var c CategoriaProducto
+		if err := rows.Scan(&c.UUID, &c.Nombre, &c.CategoriaPadre, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear categoría: %w", err)
+		}
+		hijos[c.CategoriaPadre] = append(hijos[c.CategoriaPadre], c)
+		existentes[c.Nombre] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Una categoría cuyo padre fue eliminado se muestra como raíz
+	raices := hijos[""]
+	for padre, cats := range hijos {
+		if padre != "" && !existentes[padre] {
+			raices = append(raices, cats...)
+		}
+	}
+	var armar func(cats []CategoriaProducto) []CategoriaProducto
+	armar = func(cats []CategoriaProducto) []CategoriaProducto {
+		arbol := make([]CategoriaProducto, 0, len(cats))
+		for _, c := range cats {
+			c.Subcategorias = armar(hijos[c.Nombre])
+			arbol = append(arbol, c)
+		}
+		return arbol
+	}
+	return armar(raices), nil
+}
+
+// GuardarCategoria crea o actualiza (por nombre) una categoría y la ubica bajo CategoriaPadre.
+func (d *Db) GuardarCategoria(cat CategoriaProducto) (CategoriaProducto, error) {
+	cat.Nombre = normalizarCategoria(cat.Nombre)
+	cat.CategoriaPadre = normalizarCategoria(cat.CategoriaPadre)
+	if cat.Nombre == "" {
+		return CategoriaProducto{}, errors.New("el nombre de la categoría es obligatorio")
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return CategoriaProducto{}, fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[GuardarCategoria] rollback: %v", rErr)
+		}
+	}()
+
+	// El padre debe existir y no puede ser la misma categoría ni una de sus subcategorías
+	if cat.CategoriaPadre != "" {
+		var existe int
+		if err := tx.QueryRow(`SELECT COUNT(1) FROM categorias_producto WHERE nombre = ? AND deleted_at IS NULL`, cat.CategoriaPadre).Scan(&existe); err != nil {
+			return CategoriaProducto{}, fmt.Errorf("error al validar categoría padre: %w", err)
+		}
+		if existe == 0 {
+			return CategoriaProducto{}, fmt.Errorf("la categoría padre '%s' no existe", cat.CategoriaPadre)
+		}
+		visitados := make(map[string]bool)
+		for ancestro := cat.CategoriaPadre; ancestro != "" && !visitados[ancestro]; {
+			visitados[ancestro] = true
+			if ancestro == cat.Nombre {
+				return CategoriaProducto{}, fmt.Errorf("la categoría '%s' no puede quedar dentro de sí misma", cat.Nombre)
+			}
+			if err := tx.QueryRow(`SELECT COALESCE(categoria_padre, '') FROM categorias_producto WHERE nombre = ?`, ancestro).Scan(&ancestro); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					break
+				}
+				return CategoriaProducto{}, fmt.Errorf("error al recorrer categorías: %w", err)
+			}
+		}
+	}
+
+	now := time.Now()
+	var existente string
+	err = tx.QueryRow(`SELECT uuid FROM categorias_producto WHERE nombre = ?`, cat.Nombre).Scan(&existente)
+	switch {
+	case err == nil:
+		cat.UUID = existente
+		_, err = tx.Exec(`UPDATE categorias_producto SET categoria_padre = ?, deleted_at = NULL, updated_at = ? WHERE uuid = ?`,
+			nullableString(cat.CategoriaPadre), now, cat.UUID)
+	case errors.Is(err, sql.ErrNoRows):
+		cat.UUID = uuid.New().String()
+		cat.CreatedAt = now
+		_, err = tx.Exec(`INSERT INTO categorias_producto (uuid, nombre, categoria_padre, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+			cat.UUID, cat.Nombre, nullableString(cat.CategoriaPadre), now, now)
+	}
+	if err != nil {
+		return CategoriaProducto{}, fmt.Errorf("error al guardar categoría %s: %w", cat.Nombre, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return CategoriaProducto{}, fmt.Errorf("error al confirmar categoría: %w", err)
+	}
+	cat.UpdatedAt = now
+
+	go d.syncCategoriaToRemote(cat.UUID)
+	return cat, nil
+}
+
+// EliminarCategoria borra lógicamente una categoría sin subcategorías. Los productos conservan el
+// nombre de la categoría.
+func (d *Db) EliminarCategoria(nombre string) error {
+	nombre = normalizarCategoria(nombre)
+	var hijas int
+	if err := d.LocalDB.QueryRow(`SELECT COUNT(1) FROM categorias_producto WHERE categoria_padre = ? AND deleted_at IS NULL`, nombre).Scan(&hijas); err != nil {
+		return fmt.Errorf("error al consultar subcategorías: %w", err)
+	}
+	if hijas > 0 {
+		return fmt.Errorf("la categoría '%s' tiene %d subcategorías: muévalas o elimínelas primero", nombre, hijas)
+	}
+
+	var catUUID string
+	if err := d.LocalDB.QueryRow(`SELECT uuid FROM categorias_producto WHERE nombre = ? AND deleted_at IS NULL`, nombre).Scan(&catUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("la categoría '%s' no existe", nombre)
+		}
+		return fmt.Errorf("error al buscar categoría: %w", err)
+	}
+	now := time.Now()
+	if _, err := d.LocalDB.Exec(`UPDATE categorias_producto SET deleted_at = ?, updated_at = ? WHERE uuid = ?`, now, now, catUUID); err != nil {
+		return fmt.Errorf("error al eliminar categoría: %w", err)
+	}
+
+	go d.syncCategoriaToRemote(catUUID)
+	return nil
+}
+
+// asegurarCategoria registra como raíz la categoría de un producto que aún no está en el árbol.
+// Devuelve el UUID de la categoría creada, o vacío si ya existía.
+func asegurarCategoria(tx *sql.Tx, nombre string, now time.Time) (string, error) {
+	if nombre == "" {
+		return "", nil
+	}
+	var existe int
+	if err := tx.QueryRow(`SELECT COUNT(1) FROM categorias_producto WHERE nombre = ?`, nombre).Scan(&existe); err != nil {
+		return "", fmt.Errorf("error al validar categoría: %w", err)
+	}
+	if existe > 0 {
+		return "", nil
+	}
+	catUUID := uuid.New().String()
+	if _, err := tx.Exec(`INSERT INTO categorias_producto (uuid, nombre, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+		catUUID, nombre, now, now); err != nil {
+		return "", fmt.Errorf("error al registrar categoría %s: %w", nombre, err)
+	}
+	return catUUID, nil
+}
@@ -22,6 +22,10 @@ func (d *Db) RegistrarCliente(cliente Cliente) (Cliente, error) {
 		}
 	}()
 
+	if cliente.LimiteCredito < 0 {
+		return Cliente{}, fmt.Errorf("el límite de crédito no puede ser negativo")
+	}
+
 	var txTimestamp time.Time = time.Now()
 	cliente.Email = strings.ToLower(cliente.Email)
 	cliente.UUID = uuid.New().String()
@@ -43,8 +47,8 @@ func (d *Db) RegistrarCliente(cliente Cliente) (Cliente, error) {
 			d.Log.Infof("Restaurando cliente eliminado con UUID: %s", existente.UUID.String)
 			cliente.UUID = existente.UUID.String
 			_, err := tx.ExecContext(d.ctx,
-				`UPDATE clientes SET nombre=?, apellido=?, tipo_id=?, telefono=?, email=?, direccion=?, deleted_at=NULL, updated_at=? WHERE uuid=?`,
-				cliente.Nombre, cliente.Apellido, cliente.TipoID, cliente.Telefono, cliente.Email, cliente.Direccion, cliente.UpdatedAt, cliente.UUID,
+				`UPDATE clientes SET nombre=?, apellido=?, tipo_id=?, telefono=?, email=?, direccion=?, limite_credito=?, deleted_at=NULL, updated_at=? WHERE uuid=?`,
+				cliente.Nombre, cliente.Apellido, cliente.TipoID, cliente.Telefono, cliente.Email, cliente.Direccion, cliente.LimiteCredito, cliente.UpdatedAt, cliente.UUID,
 			)
 			if err != nil {
 				return Cliente{}, fmt.Errorf("error al restaurar cliente: %w", err)
@@ -54,8 +58,8 @@ func (d *Db) RegistrarCliente(cliente Cliente) (Cliente, error) {
 		}
 	} else {
 		_, err := tx.ExecContext(d.ctx,
-			`INSERT INTO clientes (uuid, nombre, apellido, tipo_id, numero_id, telefono, email, direccion, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			cliente.UUID, cliente.Nombre, cliente.Apellido, cliente.TipoID, cliente.NumeroID, cliente.Telefono, cliente.Email, cliente.Direccion, cliente.CreatedAt, cliente.UpdatedAt,
+			`INSERT INTO clientes (uuid, nombre, apellido, tipo_id, numero_id, telefono, email, direccion, limite_credito, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			cliente.UUID, cliente.Nombre, cliente.Apellido, cliente.TipoID, cliente.NumeroID, cliente.Telefono, cliente.Email, cliente.Direccion, cliente.LimiteCredito, cliente.CreatedAt, cliente.UpdatedAt,
 		)
 		if err != nil {
 			return Cliente{}, fmt.Errorf("error al registrar nuevo cliente: %w", err)
@@ -75,6 +79,9 @@ func (d *Db) ActualizarCliente(cliente Cliente) (string, error) {
 	if cliente.UUID == "" {
 		return "", errors.New("se requiere un UUID de cliente válido")
 	}
+	if cliente.LimiteCredito < 0 {
+		return "", errors.New("el límite de crédito no puede ser negativo")
+	}
 
 	// Sentencia SQL para actualizar todos los campos relevantes.
 	query := `
@@ -86,6 +93,7 @@ func (d *Db) ActualizarCliente(cliente Cliente) (string, error) {
 			telefono = ?, 
 			email = ?, 
 			direccion = ?, 
+			limite_credito = ?, 
 			updated_at = ? 
 		WHERE uuid = ?`
 
@@ -97,6 +105,7 @@ func (d *Db) ActualizarCliente(cliente Cliente) (string, error) {
 		cliente.Telefono,
 		strings.ToLower(cliente.Email),
 		cliente.Direccion,
+		cliente.LimiteCredito,
 		time.Now(),
 		cliente.UUID,
 	)
@@ -142,9 +151,9 @@ func (d *Db) ObtenerClientesPaginado(page, pageSize int, search, sortBy, sortOrd
 	}
 
 	var queryArgs []interface{}
-	query := "SELECT uuid, nombre, apellido, tipo_id, numero_id, telefono, email, direccion FROM clientes WHERE deleted_at IS NULL"
+	query := "SELECT c.uuid, c.nombre, c.apellido, c.tipo_id, c.numero_id, c.telefono, c.email, c.direccion, c.limite_credito, " + sqlSaldoCliente + ", " + sqlPuntosCliente + " FROM clientes c WHERE c.deleted_at IS NULL"
 	if search != "" {
-		query += " AND (LOWER(nombre) LIKE ? OR LOWER(apellido) LIKE ? OR numero_id LIKE ?)"
+		query += " AND (LOWER(c.nombre) LIKE ? OR LOWER(c.apellido) LIKE ? OR c.numero_id LIKE ?)"
 		searchTerm := "%" + strings.ToLower(search) + "%"
 		queryArgs = append(queryArgs, searchTerm, searchTerm, searchTerm)
 	}
@@ -153,11 +162,11 @@ func (d *Db) ObtenerClientesPaginado(page, pageSize int, search, sortBy, sortOrd
 		col := ""
 		switch sortBy {
 		case "Nombre":
-			col = "nombre"
+			col = "c.nombre"
 		case "Documento":
-			col = "numero_id"
+			col = "c.numero_id"
 		case "Email":
-			col = "email"
+			col = "c.email"
 		}
 
 		if col != "" {
@@ -181,7 +190,7 @@ func (d *Db) ObtenerClientesPaginado(page, pageSize int, search, sortBy, sortOrd
 
 	for rows.Next() {
 		var c Cliente
-		if err := rows.Scan(&c.UUID, &c.Nombre, &c.Apellido, &c.TipoID, &c.NumeroID, &c.Telefono, &c.Email, &c.Direccion); err != nil {
+		if err := rows.Scan(&c.UUID, &c.Nombre, &c.Apellido, &c.TipoID, &c.NumeroID, &c.Telefono, &c.Email, &c.Direccion, &c.LimiteCredito, &c.Saldo, &c.Puntos); err != nil {
 			return PaginatedResult{}, fmt.Errorf("error al escanear cliente: %w", err)
 		}
 		clientes = append(clientes, c)
@@ -193,9 +202,9 @@ func (d *Db) ObtenerClientesPaginado(page, pageSize int, search, sortBy, sortOrd
 // ObtenerClientePorID busca un cliente por su ID.
 func (d *Db) ObtenerClientePorID(uuid string) (Cliente, error) {
 	var c Cliente
-	query := "SELECT uuid, tipo_id, numero_id, nombre, direccion, telefono, email FROM clientes WHERE uuid = ? AND deleted_at IS NULL"
+	query := "SELECT c.uuid, c.tipo_id, c.numero_id, c.nombre, c.direccion, c.telefono, c.email, c.limite_credito, " + sqlSaldoCliente + ", " + sqlPuntosCliente + " FROM clientes c WHERE c.uuid = ? AND c.deleted_at IS NULL"
 
-	err := d.LocalDB.QueryRow(query, uuid).Scan(&c.UUID, &c.TipoID, &c.NumeroID, &c.Nombre, &c.Direccion, &c.Telefono, &c.Email)
+	err := d.LocalDB.QueryRow(query, uuid).Scan(&c.UUID, &c.TipoID, &c.NumeroID, &c.Nombre, &c.Direccion, &c.Telefono, &c.Email, &c.LimiteCredito, &c.Saldo, &c.Puntos)
 	if err != nil {
 		return Cliente{}, fmt.Errorf("error al buscar cliente por ID %s: %w", uuid, err)
 	}
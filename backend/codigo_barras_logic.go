@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ObtenerCodigosBarrasProducto lista los códigos de barras adicionales vigentes de un producto.
+func (d *Db) ObtenerCodigosBarrasProducto(productoUUID string) ([]CodigoBarrasProducto, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT cb.uuid, cb.codigo, cb.producto_uuid, COALESCE(cb.presentacion_uuid, ''), COALESCE(pp.nombre, ?),
+			COALESCE(cb.descripcion, ''), cb.created_at, cb.updated_at
+		FROM codigos_barras_producto cb
+		LEFT JOIN presentaciones_producto pp ON pp.uuid = cb.presentacion_uuid
+		WHERE cb.producto_uuid = ? AND cb.deleted_at IS NULL
+		ORDER BY cb.created_at ASC`, PresentacionUnidad, productoUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener códigos de barras del producto: %w", err)
+	}
+	defer rows.Close()
+
+	codigos := make([]CodigoBarrasProducto, 0)
+	for rows.Next() {
+		var cb CodigoBarrasProducto
+		if err := rows.Scan(&cb.UUID, &cb.Codigo, &cb.ProductoUUID, &cb.PresentacionUUID, &cb.Presentacion,
+			&cb.Descripcion, &cb.CreatedAt, &cb.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear código de barras: %w", err)
+		}
+		codigos = append(codigos, cb)
+	}
+	return codigos, rows.Err()
+}
+
+// GuardarCodigoBarras registra un código de barras adicional del producto o actualiza el existente si
+// trae UUID. Si se escanea un código GS1 se guarda su GTIN.
+func (d *Db) GuardarCodigoBarras(cb CodigoBarrasProducto) (CodigoBarrasProducto, error) {
+	cb.Codigo = strings.TrimSpace(cb.Codigo)
+	cb.Descripcion = strings.TrimSpace(cb.Descripcion)
+	if esCodigoGS1(cb.Codigo) {
+		gs1, err := parsearGS1(cb.Codigo)
+		if err != nil {
+			return CodigoBarrasProducto{}, err
+		}
+		cb.Codigo = gs1.GTIN
+	}
+	if cb.ProductoUUID == "" || cb.Codigo == "" {
+		return CodigoBarrasProducto{}, errors.New("se requiere el producto y el código de barras")
+	}
+
+	var precio float64
+	err := d.LocalDB.QueryRowContext(d.ctx, `SELECT precio_venta FROM productos WHERE uuid = ? AND deleted_at IS NULL`, cb.ProductoUUID).Scan(&precio)
+	if errors.Is(err, sql.ErrNoRows) {
+		return CodigoBarrasProducto{}, fmt.Errorf("producto [%s] no encontrado", cb.ProductoUUID)
+	}
+	if err != nil {
+		return CodigoBarrasProducto{}, fmt.Errorf("error verificando producto: %w", err)
+	}
+	pp, err := resolverPresentacion(d.LocalDB, cb.ProductoUUID, cb.PresentacionUUID, precio)
+	if err != nil {
+		return CodigoBarrasProducto{}, err
+	}
+	cb.Presentacion = pp.Nombre
+
+	if err := d.validarCodigoBarrasLibre(cb.Codigo, "", cb.UUID); err != nil {
+		return CodigoBarrasProducto{}, err
+	}
+
+	// Un código eliminado se reactiva en lugar de insertarse otra vez, porque la columna es única
+	if cb.UUID == "" {
+		err := d.LocalDB.QueryRowContext(d.ctx, `SELECT uuid FROM codigos_barras_producto WHERE codigo = ? AND deleted_at IS NOT NULL`, cb.Codigo).Scan(&cb.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return CodigoBarrasProducto{}, fmt.Errorf("error verificando código de barras: %w", err)
+		}
+	}
+
+	now := time.Now()
+	if cb.UUID == "" {
+		cb.UUID = uuid.New().String()
+		cb.CreatedAt = now
+		_, err = d.LocalDB.ExecContext(d.ctx, `
+			INSERT INTO codigos_barras_producto (uuid, codigo, producto_uuid, presentacion_uuid, descripcion, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			cb.UUID, cb.Codigo, cb.ProductoUUID, nullableString(cb.PresentacionUUID), nullableString(cb.Descripcion), now, now)
+	} else {
+		var res sql.Result
+		res, err = d.LocalDB.ExecContext(d.ctx, `
+			UPDATE codigos_barras_producto SET codigo = ?, producto_uuid = ?, presentacion_uuid = ?, descripcion = ?, deleted_at = NULL, updated_at = ?
+			WHERE uuid = ?`,
+			cb.Codigo, cb.ProductoUUID, nullableString(cb.PresentacionUUID), nullableString(cb.Descripcion), now, cb.UUID)
+		if err == nil {
+			if n, _ := res.RowsAffected(); n == 0 {
+				return CodigoBarrasProducto{}, fmt.Errorf("código de barras [%s] no encontrado", cb.UUID)
+			}
+		}
+	}
+	if err != nil {
+		return CodigoBarrasProducto{}, fmt.Errorf("error al guardar código de barras %s: %w", cb.Codigo, err)
+	}
+	cb.UpdatedAt = now
+
+	go d.syncCodigoBarrasToRemote(cb.UUID)
+	return cb, nil
+}
+
+// EliminarCodigoBarras realiza un borrado lógico del código de barras adicional.
+func (d *Db) EliminarCodigoBarras(codigoUUID string) error {
+	now := time.Now()
+	if _, err := d.LocalDB.Exec(`UPDATE codigos_barras_producto SET deleted_at = ?, updated_at = ? WHERE uuid = ?`, now, now, codigoUUID); err != nil {
+		return fmt.Errorf("error al eliminar código de barras: %w", err)
+	}
+	go d.syncCodigoBarrasToRemote(codigoUUID)
+	return nil
+}
+
+// validarCodigoBarrasLibre verifica que el código no identifique ya a otro producto, presentación o
+// código adicional. Los UUID indican el registro que se está editando.
+func (d *Db) validarCodigoBarrasLibre(codigo, presentacionUUID, codigoBarrasUUID string) error {
+	var usos int
+	err := d.LocalDB.QueryRowContext(d.ctx, `
+		SELECT (SELECT COUNT(*) FROM productos WHERE codigo = ?)
+			+ (SELECT COUNT(*) FROM presentaciones_producto WHERE codigo_barras = ? AND uuid != ?)
+			+ (SELECT COUNT(*) FROM codigos_barras_producto WHERE codigo = ? AND uuid != ? AND deleted_at IS NULL)`,
+		codigo, codigo, presentacionUUID, codigo, codigoBarrasUUID).Scan(&usos)
+	if err != nil {
+		return fmt.Errorf("error verificando código de barras: %w", err)
+	}
+	if usos > 0 {
+		return fmt.Errorf("el código de barras %s ya está en uso", codigo)
+	}
+	return nil
+}
+
+// BuscarCodigoEscaneado resuelve lo leído por el lector del POS. Un código GS1 se resuelve por su GTIN
+// y, si trae lote, al lote registrado del producto. Las diferencias con el inventario (lote desconocido,
+// otro vencimiento, lote retirado o vencido) se devuelven como advertencias sin impedir la venta.
+func (d *Db) BuscarCodigoEscaneado(codigo string) (CodigoEscaneado, error) {
+	codigo = strings.TrimSpace(codigo)
+	escaneado := CodigoEscaneado{Codigo: codigo, Advertencias: []string{}}
+	if !esCodigoGS1(codigo) {
+		pp, err := d.BuscarPresentacionPorCodigo(codigo)
+		if err != nil {
+			return CodigoEscaneado{}, err
+		}
+		escaneado.Presentacion = pp
+		return escaneado, nil
+	}
+
+	gs1, err := parsearGS1(codigo)
+	if err != nil {
+		// Un código interno largo que empieza por 01 puede no ser GS1
+		if pp, errCodigo := d.BuscarPresentacionPorCodigo(codigo); errCodigo == nil {
+			escaneado.Presentacion = pp
+			return escaneado, nil
+		}
+		return CodigoEscaneado{}, err
+	}
+	escaneado.GS1 = &gs1
+	if escaneado.Presentacion, err = d.BuscarPresentacionPorCodigo(gs1.GTIN); err != nil {
+		return CodigoEscaneado{}, err
+	}
+
+	if gs1.Lote != "" {
+		lotes, err := d.consultarLotes(sqlLotes+`
+			WHERE l.producto_uuid = ? AND l.numero_lote = ? AND l.deleted_at IS NULL`,
+			escaneado.Presentacion.ProductoUUID, gs1.Lote)
+		if err != nil {
+			return CodigoEscaneado{}, err
+		}
+		if len(lotes) == 0 {
+			escaneado.Advertencias = append(escaneado.Advertencias, fmt.Sprintf("el lote %s no está registrado en el inventario", gs1.Lote))
+		} else {
+			escaneado.Lote = &lotes[0]
+		}
+	}
+
+	if l := escaneado.Lote; l != nil {
+		if gs1.Vencimiento != "" && gs1.Vencimiento != l.FechaVencimiento {
+			escaneado.Advertencias = append(escaneado.Advertencias, fmt.Sprintf("el empaque indica vencimiento %s pero el lote %s está registrado con %s",
+				gs1.Vencimiento, l.NumeroLote, l.FechaVencimiento))
+		}
+		if l.Estado != LoteVigente {
+			escaneado.Advertencias = append(escaneado.Advertencias, fmt.Sprintf("el lote %s está en %s", l.NumeroLote, l.Estado))
+		} else if l.Vencido {
+			escaneado.Advertencias = append(escaneado.Advertencias, fmt.Sprintf("el lote %s venció el %s", l.NumeroLote, l.FechaVencimiento))
+		}
+	} else if gs1.Vencimiento != "" && gs1.Vencimiento < time.Now().Format("2006-01-02") {
+		escaneado.Advertencias = append(escaneado.Advertencias, fmt.Sprintf("el empaque venció el %s", gs1.Vencimiento))
+	}
+	return escaneado, nil
+}
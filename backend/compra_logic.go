@@ -0,0 +1,303 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegistrarCompra ingresa mercancía de un proveedor. Cada línea puede venir en cualquier presentación
+// del producto; el stock se incrementa en unidades mínimas a nombre del vendedor que registra la compra.
+func (d *Db) RegistrarCompra(req CompraRequest) (Compra, error) {
+	if req.ProveedorUUID == "" || req.VendedorUUID == "" {
+		return Compra{}, errors.New("se requiere el proveedor y el vendedor que registra la compra")
+	}
+	if len(req.Productos) == 0 {
+		return Compra{}, errors.New("la compra no tiene productos")
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return Compra{}, fmt.Errorf("error al iniciar transacción de compra: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[LOCAL] - Error durante [RegistrarCompra] rollback %v", rErr)
+		}
+	}()
+
+	var existe int
+	if err := tx.QueryRow(`SELECT COUNT(1) FROM vendedors WHERE uuid = ? AND deleted_at IS NULL`, req.VendedorUUID).Scan(&existe); err != nil {
+		return Compra{}, fmt.Errorf("error validando vendedor: %w", err)
+	}
+	if existe == 0 {
+		return Compra{}, fmt.Errorf("vendedor [%s] no encontrado", req.VendedorUUID)
+	}
+	if err := tx.QueryRow(`SELECT COUNT(1) FROM proveedors WHERE uuid = ? AND deleted_at IS NULL`, req.ProveedorUUID).Scan(&existe); err != nil {
+		return Compra{}, fmt.Errorf("error validando proveedor: %w", err)
+	}
+	if existe == 0 {
+		return Compra{}, fmt.Errorf("proveedor [%s] no encontrado", req.ProveedorUUID)
+	}
+
+	now := time.Now()
+	compra := Compra{
+		UUID:          uuid.New().String(),
+		Fecha:         now,
+		ProveedorUUID: req.ProveedorUUID,
+		VendedorUUID:  req.VendedorUUID,
+		FacturaNumero: strings.TrimSpace(req.FacturaNumero),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	// 1️⃣ Resolver la presentación de cada línea (el precio de compra viene por presentación)
+	for _, p := range req.Productos {
+		if p.Cantidad <= 0 {
+			return Compra{}, fmt.Errorf("la cantidad comprada del producto [%s] debe ser mayor que cero", p.ProductoUUID)
+		}
+		if p.PrecioCompraUnitario < 0 {
+			return Compra{}, fmt.Errorf("el precio de compra del producto [%s] no puede ser negativo", p.ProductoUUID)
+		}
+		presentacion, err := resolverPresentacion(tx, p.ProductoUUID, p.PresentacionUUID, 0)
+		if err != nil {
+			return Compra{}, err
+		}
+		lote, err := resolverLoteCompra(tx, p.ProductoUUID, p.NumeroLote, p.FechaVencimiento, now)
+		if err != nil {
+			return Compra{}, err
+		}
+		compra.Detalles = append(compra.Detalles, DetalleCompra{
+			UUID:                 uuid.New().String(),
+			CompraUUID:           compra.UUID,
+			ProductoUUID:         p.ProductoUUID,
+			Cantidad:             p.Cantidad,
+			PrecioCompraUnitario: p.PrecioCompraUnitario,
+			PresentacionUUID:     presentacion.UUID,
+			FactorConversion:     presentacion.Factor,
+			LoteUUID:             lote.UUID,
+			NumeroLote:           lote.NumeroLote,
+			FechaVencimiento:     lote.FechaVencimiento,
+		})
+		compra.Total += p.PrecioCompraUnitario * float64(p.Cantidad)
+	}
+	compra.Total = redondearMoneda(compra.Total)
+
+	_, err = tx.Exec(`
+		INSERT INTO compras (uuid, fecha, proveedor_uuid, vendedor_uuid, factura_numero, total, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		compra.UUID, compra.Fecha, compra.ProveedorUUID, compra.VendedorUUID, compra.FacturaNumero, compra.Total, now, now)
+	if err != nil {
+		return Compra{}, fmt.Errorf("error al crear la compra: %w", err)
+	}
+
+	// El pedido que llega con esta compra deja de contar como mercancía en camino
+	pedidoUUID := strings.TrimSpace(req.PedidoUUID)
+	if pedidoUUID != "" {
+		if err := recibirPedidoProveedor(tx, pedidoUUID, compra, now); err != nil {
+			return Compra{}, err
+		}
+	}
+
+	// Preparar statements para inserciones masivas
+	stmtDetalles, err := tx.Prepare(`
+		INSERT INTO detalle_compras (uuid, compra_uuid, producto_uuid, cantidad, precio_compra_unitario, presentacion_uuid, factor_conversion, lote_uuid)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return Compra{}, err
+	}
+	defer stmtDetalles.Close()
+
+	for _, det := range compra.Detalles {
+		// 2️⃣ Insertar detalle de compra
+		_, err := stmtDetalles.Exec(det.UUID, compra.UUID, det.ProductoUUID, det.Cantidad, det.PrecioCompraUnitario,
+			nullableString(det.PresentacionUUID), det.FactorConversion, nullableString(det.LoteUUID))
+		if err != nil {
+			return Compra{}, fmt.Errorf("error al crear detalle de compra: %w", err)
+		}
+
+		// 3️⃣ Recalcular el costo promedio con el stock previo a la compra
+		if err := actualizarCostoPromedio(tx, det, now); err != nil {
+			return Compra{}, err
+		}
+
+		// 4️⃣ Ingresar al inventario las unidades mínimas compradas, en su lote si lo tiene
+		if err := d.crearOperacionStockLote(tx, det.ProductoUUID, "COMPRA", det.Cantidad*det.FactorConversion, req.VendedorUUID, nil, det.LoteUUID, nil); err != nil {
+			return Compra{}, fmt.Errorf("error creando operación de stock por compra: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Compra{}, fmt.Errorf("error al confirmar transacción de compra: %w", err)
+	}
+	d.Log.Infof("[COMPRA] Compra %s registrada por %.2f", compra.UUID, compra.Total)
+
+	go func() {
+		// Los lotes deben existir en remoto antes que los detalles y operaciones que los referencian
+		for _, det := range compra.Detalles {
+			if det.LoteUUID != "" {
+				d.syncLoteToRemote(det.LoteUUID)
+			}
+		}
+		d.syncCompraToRemote(compra.UUID)
+		if pedidoUUID != "" {
+			d.syncPedidoProveedorToRemote(pedidoUUID)
+		}
+		// El costo promedio viaja con los datos maestros del producto
+		sincronizados := make(map[string]bool, len(compra.Detalles))
+		for _, det := range compra.Detalles {
+			if !sincronizados[det.ProductoUUID] {
+				sincronizados[det.ProductoUUID] = true
+				d.syncProductoToRemote(det.ProductoUUID)
+			}
+		}
+	}()
+
+	return d.ObtenerDetalleCompra(compra.UUID)
+}
+
+// ObtenerComprasPaginado lista las compras con búsqueda por número de factura del proveedor,
+// proveedor o vendedor, y ordenamiento por columnas permitidas.
+func (d *Db) ObtenerComprasPaginado(page, pageSize int, search, sortBy, sortOrder string) (PaginatedResult, error) {
+	var (
+		compras []Compra
+		args    []any
+		where   string
+	)
+
+	baseQuery := `
+		FROM compras co
+		JOIN proveedors pr ON co.proveedor_uuid = pr.uuid
+		LEFT JOIN vendedors v ON co.vendedor_uuid = v.uuid
+		WHERE co.deleted_at IS NULL
+	`
+
+	if search != "" {
+		searchTerm := "%" + strings.ToLower(search) + "%"
+		where = `
+			AND (LOWER(COALESCE(co.factura_numero, '')) LIKE ?
+			   OR LOWER(pr.nombre) LIKE ?
+			   OR LOWER(COALESCE(v.nombre, '')) LIKE ?)
+		`
+		args = append(args, searchTerm, searchTerm, searchTerm)
+	}
+
+	var total int64
+	countQuery := "SELECT COUNT(co.uuid) " + baseQuery + where
+	if err := d.LocalDB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return PaginatedResult{}, fmt.Errorf("error contando compras: %w", err)
+	}
+
+	allowedSortBy := map[string]string{
+		"FacturaNumero": "co.factura_numero",
+		"Fecha":         "co.fecha",
+		"Proveedor":     "pr.nombre",
+		"Vendedor":      "v.nombre",
+		"Total":         "co.total",
+	}
+
+	orderBy := "ORDER BY co.fecha DESC"
+	if col, ok := allowedSortBy[sortBy]; ok {
+		order := "ASC"
+		if strings.ToLower(sortOrder) == "desc" {
+			order = "DESC"
+		}
+		orderBy = fmt.Sprintf("ORDER BY %s %s", col, order)
+	}
+
+	offset := (page - 1) * pageSize
+	pagination := fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize, offset)
+
+	selectQuery := `
+		SELECT
+			co.uuid,
+			co.fecha,
+			COALESCE(co.factura_numero, ''),
+			COALESCE(co.total, 0),
+			pr.uuid,
+			pr.nombre,
+			COALESCE(v.uuid, ''),
+			COALESCE(v.nombre, '')
+	` + baseQuery + where + " " + orderBy + pagination
+
+	rows, err := d.LocalDB.Query(selectQuery, args...)
+	if err != nil {
+		return PaginatedResult{}, fmt.Errorf("error realizando consulta compras: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Compra
+		if err := rows.Scan(
+			&c.UUID, &c.Fecha, &c.FacturaNumero, &c.Total,
+			&c.Proveedor.UUID, &c.Proveedor.Nombre,
+			&c.Vendedor.UUID, &c.Vendedor.Nombre,
+		); err != nil {
+			return PaginatedResult{}, err
+		}
+		c.ProveedorUUID = c.Proveedor.UUID
+		c.VendedorUUID = c.Vendedor.UUID
+		compras = append(compras, c)
+	}
+
+	return PaginatedResult{Records: compras, TotalRecords: total}, nil
+}
+
+// ObtenerDetalleCompra devuelve la compra con su proveedor, el vendedor que la registró y sus líneas.
+func (d *Db) ObtenerDetalleCompra(compraUUID string) (Compra, error) {
+	var compra Compra
+	err := d.LocalDB.QueryRow(`
+		SELECT
+			co.uuid, co.fecha, COALESCE(co.factura_numero, ''), COALESCE(co.total, 0), co.created_at, co.updated_at,
+			pr.uuid, pr.nombre, COALESCE(pr.telefono, ''), COALESCE(pr.email, ''),
+			COALESCE(v.uuid, ''), COALESCE(v.nombre, ''), COALESCE(v.apellido, '')
+		FROM compras co
+		JOIN proveedors pr ON co.proveedor_uuid = pr.uuid
+		LEFT JOIN vendedors v ON co.vendedor_uuid = v.uuid
+		WHERE co.uuid = ?`, compraUUID).Scan(
+		&compra.UUID, &compra.Fecha, &compra.FacturaNumero, &compra.Total, &compra.CreatedAt, &compra.UpdatedAt,
+		&compra.Proveedor.UUID, &compra.Proveedor.Nombre, &compra.Proveedor.Telefono, &compra.Proveedor.Email,
+		&compra.Vendedor.UUID, &compra.Vendedor.Nombre, &compra.Vendedor.Apellido,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Compra{}, fmt.Errorf("compra [%s] no encontrada", compraUUID)
+	}
+	if err != nil {
+		return Compra{}, fmt.Errorf("error al obtener compra %s: %w", compraUUID, err)
+	}
+	compra.ProveedorUUID = compra.Proveedor.UUID
+	compra.VendedorUUID = compra.Vendedor.UUID
+
+	rows, err := d.LocalDB.Query(`
+		SELECT
+			dc.uuid, dc.compra_uuid, dc.cantidad, dc.precio_compra_unitario,
+			COALESCE(dc.presentacion_uuid, ''), dc.factor_conversion,
+			COALESCE(dc.lote_uuid, ''), COALESCE(l.numero_lote, ''), COALESCE(strftime('%Y-%m-%d', l.fecha_vencimiento), ''),
+			p.uuid, p.codigo, p.nombre
+		FROM detalle_compras dc
+		JOIN productos p ON p.uuid = dc.producto_uuid
+		LEFT JOIN lotes l ON l.uuid = dc.lote_uuid
+		WHERE dc.compra_uuid = ?`, compraUUID)
+	if err != nil {
+		return Compra{}, fmt.Errorf("error consultando detalles de compra: %w", err)
+	}
+	defer rows.Close()
+
+	compra.Detalles = make([]DetalleCompra, 0)
+	for rows.Next() {
+		var det DetalleCompra
+		if err := rows.Scan(&det.UUID, &det.CompraUUID, &det.Cantidad, &det.PrecioCompraUnitario,
+			&det.PresentacionUUID, &det.FactorConversion,
+			&det.LoteUUID, &det.NumeroLote, &det.FechaVencimiento,
+			&det.Producto.UUID, &det.Producto.Codigo, &det.Producto.Nombre); err != nil {
+			return Compra{}, fmt.Errorf("error escaneando detalle de compra: %w", err)
+		}
+		det.ProductoUUID = det.Producto.UUID
+		compra.Detalles = append(compra.Detalles, det)
+	}
+	return compra, rows.Err()
+}
@@ -0,0 +1,586 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Estados de un conteo de inventario.
+const (
+	ConteoAbierto   = "ABIERTO"
+	ConteoAprobado  = "APROBADO"
+	ConteoCancelado = "CANCELADO"
+)
+
+// columnasRegistrosConteo son las columnas que se sincronizan de los registros de conteo; también se
+// descargan al aprobar para incluir lo contado desde otros equipos.
+var columnasRegistrosConteo = []string{"created_at", "updated_at", "deleted_at", "uuid", "conteo_uuid", "producto_uuid", "lote_uuid", "cantidad", "vendedor_uuid", "dispositivo"}
+
+const sqlConteos = `
+	SELECT uuid, descripcion, COALESCE(categoria, ''), estado, vendedor_uuid, COALESCE(aprobado_por, ''),
+		fecha_apertura, fecha_cierre, COALESCE(observacion, ''), created_at, updated_at
+	FROM conteos_inventario`
+
+// sqlItemsConteo une lo esperado al abrir el conteo con lo contado en los registros no anulados. Un lote
+// contado que no estaba en la apertura tenía stock esperado cero y toma el costo de su producto.
+// Parámetros: conteo (cinco veces) y producto (dos veces, vacío para todos).
+const sqlItemsConteo = `
+	WITH claves AS (
+		SELECT producto_uuid, lote_uuid FROM items_conteo_inventario WHERE conteo_uuid = ? AND deleted_at IS NULL
+		UNION
+		SELECT producto_uuid, lote_uuid FROM registros_conteo_inventario WHERE conteo_uuid = ? AND deleted_at IS NULL
+	)
+	SELECT k.producto_uuid, p.codigo, p.nombre, k.lote_uuid, COALESCE(l.numero_lote, ''),
+		COALESCE(strftime('%Y-%m-%d', l.fecha_vencimiento), ''), COALESCE(i.stock_esperado, 0),
+		COALESCE(i.costo_unitario, (
+			SELECT MAX(ip.costo_unitario) FROM items_conteo_inventario ip WHERE ip.conteo_uuid = ? AND ip.producto_uuid = k.producto_uuid
+		), 0),
+		COUNT(r.uuid), COALESCE(SUM(r.cantidad), 0)
+	FROM claves k
+	JOIN productos p ON p.uuid = k.producto_uuid
+	LEFT JOIN items_conteo_inventario i ON i.conteo_uuid = ? AND i.producto_uuid = k.producto_uuid AND i.lote_uuid = k.lote_uuid
+	LEFT JOIN lotes l ON l.uuid = k.lote_uuid
+	LEFT JOIN registros_conteo_inventario r ON r.conteo_uuid = ? AND r.producto_uuid = k.producto_uuid
+		AND r.lote_uuid = k.lote_uuid AND r.deleted_at IS NULL
+	WHERE (? = '' OR k.producto_uuid = ?)
+	GROUP BY k.producto_uuid, k.lote_uuid
+	ORDER BY p.nombre ASC, l.fecha_vencimiento ASC`
+
+// AbrirConteoInventario abre una sesión de toma física y fija el stock esperado de cada producto (de
+// la categoría y sus subcategorías, si se indica): una línea por lote con existencias y otra para el
+// stock sin lote. Solo puede haber un conteo abierto a la vez.
+func (d *Db) AbrirConteoInventario(req ConteoInventarioRequest) (ReporteConteo, error) {
+	req.Descripcion = strings.TrimSpace(req.Descripcion)
+	req.Categoria = normalizarCategoria(req.Categoria)
+	if req.Descripcion == "" || req.VendedorUUID == "" {
+		return ReporteConteo{}, errors.New("se requiere la descripción del conteo y el vendedor que lo abre")
+	}
+	if err := d.verificarConteoAbiertoRemoto(); err != nil {
+		return ReporteConteo{}, err
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return ReporteConteo{}, fmt.Errorf("error al iniciar transacción del conteo: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[LOCAL] - Error durante [AbrirConteoInventario] rollback %v", rErr)
+		}
+	}()
+
+	var existe int
+	if err := tx.QueryRow(`SELECT COUNT(1) FROM vendedors WHERE uuid = ? AND deleted_at IS NULL`, req.VendedorUUID).Scan(&existe); err != nil {
+		return ReporteConteo{}, fmt.Errorf("error validando vendedor: %w", err)
+	}
+	if existe == 0 {
+		return ReporteConteo{}, fmt.Errorf("vendedor [%s] no encontrado", req.VendedorUUID)
+	}
+	var abierto string
+	err = tx.QueryRow(`SELECT descripcion FROM conteos_inventario WHERE estado = ? AND deleted_at IS NULL LIMIT 1`, ConteoAbierto).Scan(&abierto)
+	if err == nil {
+		return ReporteConteo{}, fmt.Errorf("ya hay un conteo abierto (%s): apruébelo o cancélelo primero", abierto)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return ReporteConteo{}, fmt.Errorf("error consultando conteos abiertos: %w", err)
+	}
+
+	// 1️⃣ Productos del conteo con su costo actual
+	query := `SELECT uuid, COALESCE(costo_promedio, 0) FROM productos WHERE deleted_at IS NULL`
+	var args []any
+	if req.Categoria != "" {
+		query += ` AND (LOWER(categoria) = ? OR categoria IN (` + sqlSubarbolCategorias + `))`
+		args = append(args, strings.ToLower(req.Categoria), strings.ToLower(req.Categoria))
+	}
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return ReporteConteo{}, fmt.Errorf("error obteniendo productos del conteo: %w", err)
+	}
+	costos := make(map[string]float64)
+	var productos []string
+	for rows.Next() {
+		var productoUUID string
+		var costo float64
+		if err := rows.Scan(&productoUUID, &costo); err != nil {
+			rows.Close()
+			return ReporteConteo{}, fmt.Errorf("error al escanear producto: %w", err)
+		}
+		costos[productoUUID] = costo
+		productos = append(productos, productoUUID)
+	}
+	rows.Close()
+	if len(productos) == 0 {
+		return ReporteConteo{}, errors.New("no hay productos para contar")
+	}
+
+	// 2️⃣ Stock real por producto y lote según las operaciones de stock
+	rows, err = tx.Query(`
+		SELECT producto_uuid, COALESCE(lote_uuid, ''), SUM(cantidad_cambio)
+		FROM operacion_stocks
+		GROUP BY producto_uuid, COALESCE(lote_uuid, '')`)
+	if err != nil {
+		return ReporteConteo{}, fmt.Errorf("error obteniendo stock por lote: %w", err)
+	}
+	stocks := make(map[string]map[string]int)
+	for rows.Next() {
+		var productoUUID, loteUUID string
+		var stock int
+		if err := rows.Scan(&productoUUID, &loteUUID, &stock); err != nil {
+			rows.Close()
+			return ReporteConteo{}, fmt.Errorf("error al escanear stock por lote: %w", err)
+		}
+		if stocks[productoUUID] == nil {
+			stocks[productoUUID] = make(map[string]int)
+		}
+		stocks[productoUUID][loteUUID] = stock
+	}
+	rows.Close()
+
+	now := time.Now()
+	conteo := ConteoInventario{
+		UUID:          uuid.New().String(),
+		Descripcion:   req.Descripcion,
+		Categoria:     req.Categoria,
+		Estado:        ConteoAbierto,
+		VendedorUUID:  req.VendedorUUID,
+		FechaApertura: now,
+		Observacion:   strings.TrimSpace(req.Observacion),
+	}
+	_, err = tx.Exec(`
+		INSERT INTO conteos_inventario (uuid, descripcion, categoria, estado, vendedor_uuid, fecha_apertura, observacion, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		conteo.UUID, conteo.Descripcion, nullableString(conteo.Categoria), conteo.Estado, conteo.VendedorUUID, now,
+		nullableString(conteo.Observacion), now, now)
+	if err != nil {
+		return ReporteConteo{}, fmt.Errorf("error al crear el conteo: %w", err)
+	}
+
+	// 3️⃣ Fijar lo esperado: cada lote con existencias y el stock sin lote, que siempre se cuenta en
+	// productos sin lotes para que los agotados también aparezcan
+	stmt, err := tx.Prepare(`
+		INSERT INTO items_conteo_inventario (uuid, conteo_uuid, producto_uuid, lote_uuid, stock_esperado, costo_unitario, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return ReporteConteo{}, err
+	}
+	defer stmt.Close()
+	for _, productoUUID := range productos {
+		conLotes := false
+		for loteUUID, stock := range stocks[productoUUID] {
+			if loteUUID == "" || stock == 0 {
+				continue
+			}
+			conLotes = true
+			if _, err := stmt.Exec(uuid.New().String(), conteo.UUID, productoUUID, loteUUID, stock, costos[productoUUID], now, now); err != nil {
+				return ReporteConteo{}, fmt.Errorf("error al registrar stock esperado: %w", err)
+			}
+		}
+		if sinLote := stocks[productoUUID][""]; sinLote != 0 || !conLotes {
+			if _, err := stmt.Exec(uuid.New().String(), conteo.UUID, productoUUID, "", sinLote, costos[productoUUID], now, now); err != nil {
+				return ReporteConteo{}, fmt.Errorf("error al registrar stock esperado: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ReporteConteo{}, fmt.Errorf("error al confirmar el conteo: %w", err)
+	}
+	d.Log.Infof("[CONTEO] Conteo %s abierto con %d productos", conteo.UUID, len(productos))
+
+	go d.syncConteoInventarioToRemote(conteo.UUID)
+	return d.ObtenerReporteConteo(conteo.UUID)
+}
+
+// RegistrarConteo agrega una cantidad contada de un producto o lote. Los registros se suman, así que
+// cada equipo o estante registra lo suyo; devuelve el ítem con el total contado hasta ahora.
+func (d *Db) RegistrarConteo(req RegistroConteoRequest) (ItemConteo, error) {
+	if req.ConteoUUID == "" || req.ProductoUUID == "" || req.VendedorUUID == "" {
+		return ItemConteo{}, errors.New("se requiere el conteo, el producto y el vendedor que cuenta")
+	}
+	if req.Cantidad < 0 {
+		return ItemConteo{}, errors.New("la cantidad contada no puede ser negativa: anule el registro equivocado")
+	}
+	if _, err := conteoAbierto(d.LocalDB, req.ConteoUUID); err != nil {
+		return ItemConteo{}, err
+	}
+
+	var existe int
+	if err := d.LocalDB.QueryRow(`SELECT COUNT(1) FROM items_conteo_inventario WHERE conteo_uuid = ? AND producto_uuid = ?`,
+		req.ConteoUUID, req.ProductoUUID).Scan(&existe); err != nil {
+		return ItemConteo{}, fmt.Errorf("error validando producto del conteo: %w", err)
+	}
+	if existe == 0 {
+		return ItemConteo{}, fmt.Errorf("el producto [%s] no hace parte de este conteo", req.ProductoUUID)
+	}
+	if req.LoteUUID != "" {
+		if err := d.LocalDB.QueryRow(`SELECT COUNT(1) FROM lotes WHERE uuid = ? AND producto_uuid = ?`, req.LoteUUID, req.ProductoUUID).Scan(&existe); err != nil {
+			return ItemConteo{}, fmt.Errorf("error validando lote: %w", err)
+		}
+		if existe == 0 {
+			return ItemConteo{}, fmt.Errorf("el lote [%s] no pertenece al producto [%s]", req.LoteUUID, req.ProductoUUID)
+		}
+	}
+
+	now := time.Now()
+	registroUUID := uuid.New().String()
+	_, err := d.LocalDB.Exec(`
+		INSERT INTO registros_conteo_inventario (uuid, conteo_uuid, producto_uuid, lote_uuid, cantidad, vendedor_uuid, dispositivo, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		registroUUID, req.ConteoUUID, req.ProductoUUID, req.LoteUUID, req.Cantidad, req.VendedorUUID,
+		nullableString(strings.TrimSpace(req.Dispositivo)), now, now)
+	if err != nil {
+		return ItemConteo{}, fmt.Errorf("error al registrar conteo: %w", err)
+	}
+	go d.syncRegistroConteoToRemote(registroUUID)
+
+	items, err := itemsConteo(d.LocalDB, req.ConteoUUID, req.ProductoUUID)
+	if err != nil {
+		return ItemConteo{}, err
+	}
+	for _, it := range items {
+		if it.LoteUUID == req.LoteUUID {
+			return it, nil
+		}
+	}
+	return ItemConteo{}, fmt.Errorf("conteo del producto [%s] no encontrado", req.ProductoUUID)
+}
+
+// AnularRegistroConteo descarta un registro equivocado mientras el conteo sigue abierto.
+func (d *Db) AnularRegistroConteo(registroUUID string) error {
+	var conteoUUID string
+	err := d.LocalDB.QueryRow(`SELECT conteo_uuid FROM registros_conteo_inventario WHERE uuid = ? AND deleted_at IS NULL`, registroUUID).Scan(&conteoUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("registro de conteo [%s] no encontrado", registroUUID)
+	}
+	if err != nil {
+		return fmt.Errorf("error consultando registro de conteo: %w", err)
+	}
+	if _, err := conteoAbierto(d.LocalDB, conteoUUID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if _, err := d.LocalDB.Exec(`UPDATE registros_conteo_inventario SET deleted_at = ?, updated_at = ? WHERE uuid = ?`, now, now, registroUUID); err != nil {
+		return fmt.Errorf("error al anular registro de conteo: %w", err)
+	}
+	go d.syncRegistroConteoToRemote(registroUUID)
+	return nil
+}
+
+// ObtenerRegistrosConteo lista los registros vigentes de un producto en el conteo, con el equipo y el
+// vendedor que los hizo, para revisar una diferencia.
+func (d *Db) ObtenerRegistrosConteo(conteoUUID, productoUUID string) ([]RegistroConteo, error) {
+	rows, err := d.LocalDB.Query(`
+		SELECT uuid, conteo_uuid, producto_uuid, lote_uuid, cantidad, vendedor_uuid, COALESCE(dispositivo, ''), created_at
+		FROM registros_conteo_inventario
+		WHERE conteo_uuid = ? AND producto_uuid = ? AND deleted_at IS NULL
+		ORDER BY created_at ASC`, conteoUUID, productoUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener registros de conteo: %w", err)
+	}
+	defer rows.Close()
+
+	registros := make([]RegistroConteo, 0)
+	for rows.Next() {
+		var r RegistroConteo
+		if err := rows.Scan(&r.UUID, &r.ConteoUUID, &r.ProductoUUID, &r.LoteUUID, &r.Cantidad, &r.VendedorUUID, &r.Dispositivo, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear registro de conteo: %w", err)
+		}
+		registros = append(registros, r)
+	}
+	return registros, rows.Err()
+}
+
+// ObtenerConteosInventario lista los conteos en un estado (todos si es vacío), los más recientes primero.
+func (d *Db) ObtenerConteosInventario(estado string) ([]ConteoInventario, error) {
+	rows, err := d.LocalDB.Query(sqlConteos+`
+		WHERE deleted_at IS NULL AND (? = '' OR estado = ?)
+		ORDER BY fecha_apertura DESC`, estado, estado)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener conteos: %w", err)
+	}
+	defer rows.Close()
+
+	conteos := make([]ConteoInventario, 0)
+	for rows.Next() {
+		c, err := escanearConteo(rows)
+		if err != nil {
+			return nil, err
+		}
+		conteos = append(conteos, c)
+	}
+	return conteos, rows.Err()
+}
+
+// ObtenerReporteConteo devuelve las diferencias del conteo por producto y lote, en unidades y al costo
+// fijado al abrirlo, con los totales de sobrantes y faltantes. Los ítems sin contar no suman.
+func (d *Db) ObtenerReporteConteo(conteoUUID string) (ReporteConteo, error) {
+	conteo, err := escanearConteo(d.LocalDB.QueryRow(sqlConteos+` WHERE uuid = ?`, conteoUUID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ReporteConteo{}, fmt.Errorf("conteo [%s] no encontrado", conteoUUID)
+	}
+	if err != nil {
+		return ReporteConteo{}, err
+	}
+	items, err := itemsConteo(d.LocalDB, conteoUUID, "")
+	if err != nil {
+		return ReporteConteo{}, err
+	}
+
+	reporte := ReporteConteo{Conteo: conteo, Items: items}
+	for _, it := range items {
+		switch {
+		case it.Registros == 0:
+			reporte.ItemsSinContar++
+		case it.Diferencia > 0:
+			reporte.UnidadesSobrantes += it.Diferencia
+			reporte.ValorSobrante += it.ValorDiferencia
+		case it.Diferencia < 0:
+			reporte.UnidadesFaltantes += -it.Diferencia
+			reporte.ValorFaltante += -it.ValorDiferencia
+		}
+	}
+	reporte.ValorSobrante = redondearMoneda(reporte.ValorSobrante)
+	reporte.ValorFaltante = redondearMoneda(reporte.ValorFaltante)
+	reporte.ValorNeto = redondearMoneda(reporte.ValorSobrante - reporte.ValorFaltante)
+	return reporte, nil
+}
+
+// AprobarConteoInventario cierra el conteo y ajusta el inventario con la diferencia entre lo contado y
+// lo esperado al abrirlo: AJUSTE_POSITIVO o AJUSTE_NEGATIVO por producto y lote, a nombre del supervisor
+// que autoriza. Las ventas y compras registradas durante el conteo ya movieron el stock, por eso se
+// aplica la diferencia y no lo contado. Los ítems sin contar no se ajustan.
+func (d *Db) AprobarConteoInventario(req AprobacionConteoRequest) (ReporteConteo, error) {
+	supervisorUUID, err := d.validarAutorizacionSupervisor(req.Autorizacion)
+	if err != nil {
+		return ReporteConteo{}, err
+	}
+
+	if _, err := conteoAbierto(d.LocalDB, req.ConteoUUID); err != nil {
+		return ReporteConteo{}, err
+	}
+	observacion := strings.TrimSpace(req.Observacion)
+	now := time.Now()
+
+	// Con servidor, lo contado desde otros equipos llega con la sincronización de sus registros y la
+	// aprobación se reserva en el remoto, para que dos equipos no apliquen los mismos ajustes. Sin los
+	// registros de los demás equipos el conteo no se aprueba.
+	aprobado := false
+	if d.RemoteDB != nil {
+		if !d.isRemoteDBAvailable() {
+			return ReporteConteo{}, errors.New("sin conexión con el servidor no se puede aprobar el conteo: faltarían los registros de otros equipos")
+		}
+		if err := d.syncGenericModel(d.ctx, "registros_conteo_inventario", "uuid", columnasRegistrosConteo); err != nil {
+			return ReporteConteo{}, fmt.Errorf("no se pudieron descargar los registros de otros equipos: %w", err)
+		}
+		if err := d.reservarAprobacionConteoRemoto(req.ConteoUUID, supervisorUUID, observacion, now); err != nil {
+			return ReporteConteo{}, err
+		}
+		defer func() {
+			if !aprobado {
+				d.liberarAprobacionConteoRemoto(req.ConteoUUID, supervisorUUID)
+			}
+		}()
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return ReporteConteo{}, fmt.Errorf("error al iniciar transacción del conteo: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[LOCAL] - Error durante [AprobarConteoInventario] rollback %v", rErr)
+		}
+	}()
+
+	if _, err := conteoAbierto(tx, req.ConteoUUID); err != nil {
+		return ReporteConteo{}, err
+	}
+	items, err := itemsConteo(tx, req.ConteoUUID, "")
+	if err != nil {
+		return ReporteConteo{}, err
+	}
+
+	ajustes := 0
+	for _, it := range items {
+		if it.Registros == 0 || it.Diferencia == 0 {
+			continue
+		}
+		tipo, unidades := "AJUSTE_POSITIVO", it.Diferencia
+		if it.Diferencia < 0 {
+			tipo, unidades = "AJUSTE_NEGATIVO", -it.Diferencia
+		}
+		if it.LoteUUID == "" {
+			err = d.CrearOperacionStock(tx, it.ProductoUUID, tipo, unidades, supervisorUUID, nil)
+		} else {
+			err = d.crearOperacionStockLote(tx, it.ProductoUUID, tipo, unidades, supervisorUUID, nil, it.LoteUUID, nil)
+		}
+		if err != nil {
+			return ReporteConteo{}, fmt.Errorf("error ajustando %s: %w", nombreConLote(it.ProductoNombre, it.NumeroLote), err)
+		}
+		ajustes++
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE conteos_inventario SET estado = ?, aprobado_por = ?, fecha_cierre = ?, observacion = COALESCE(?, observacion), updated_at = ?
+		WHERE uuid = ?`,
+		ConteoAprobado, supervisorUUID, now, nullableString(observacion), now, req.ConteoUUID); err != nil {
+		return ReporteConteo{}, fmt.Errorf("error al aprobar el conteo: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ReporteConteo{}, fmt.Errorf("error al confirmar la aprobación del conteo: %w", err)
+	}
+	aprobado = true
+	d.Log.Infof("[CONTEO] Conteo %s aprobado por %s con %d ajustes", req.ConteoUUID, supervisorUUID, ajustes)
+
+	go func() {
+		d.syncConteoInventarioToRemote(req.ConteoUUID)
+		d.SincronizarOperacionesStockHaciaRemoto()
+		d.SincronizarLibroControladosHaciaRemoto()
+	}()
+	return d.ObtenerReporteConteo(req.ConteoUUID)
+}
+
+// CancelarConteoInventario descarta un conteo abierto sin tocar el inventario.
+func (d *Db) CancelarConteoInventario(conteoUUID string) error {
+	now := time.Now()
+	res, err := d.LocalDB.Exec(`UPDATE conteos_inventario SET estado = ?, fecha_cierre = ?, updated_at = ? WHERE uuid = ? AND estado = ?`,
+		ConteoCancelado, now, now, conteoUUID, ConteoAbierto)
+	if err != nil {
+		return fmt.Errorf("error al cancelar el conteo: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("el conteo [%s] no existe o ya está cerrado", conteoUUID)
+	}
+	go d.syncConteoInventarioToRemote(conteoUUID)
+	return nil
+}
+
+// verificarConteoAbiertoRemoto impide abrir un conteo mientras otro equipo tiene uno abierto. Sin
+// conexión solo se valida el estado local.
+func (d *Db) verificarConteoAbiertoRemoto() error {
+	if !d.isRemoteDBAvailable() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(d.ctx, 5*time.Second)
+	defer cancel()
+	var abierto string
+	err := d.RemoteDB.QueryRow(ctx, `SELECT descripcion FROM conteos_inventario WHERE estado = $1 AND deleted_at IS NULL LIMIT 1`, ConteoAbierto).Scan(&abierto)
+	if err == nil {
+		return fmt.Errorf("ya hay un conteo abierto (%s): apruébelo o cancélelo primero", abierto)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("[REMOTO] - error consultando conteos abiertos: %w", err)
+	}
+	return nil
+}
+
+// reservarAprobacionConteoRemoto aprueba el conteo en el remoto solo si allí sigue abierto; si otro
+// equipo ya lo cerró, la aprobación se rechaza. El conteo se sube antes por si aún no se sincronizó.
+func (d *Db) reservarAprobacionConteoRemoto(conteoUUID, supervisorUUID, observacion string, now time.Time) error {
+	d.syncConteoInventarioToRemote(conteoUUID)
+
+	ctx, cancel := context.WithTimeout(d.ctx, 5*time.Second)
+	defer cancel()
+	tag, err := d.RemoteDB.Exec(ctx, `
+		UPDATE conteos_inventario SET estado = $1, aprobado_por = $2, fecha_cierre = $3, observacion = COALESCE($4, observacion), updated_at = $3
+		WHERE uuid = $5 AND estado = $6 AND deleted_at IS NULL`,
+		ConteoAprobado, supervisorUUID, now, nullableString(observacion), conteoUUID, ConteoAbierto)
+	if err != nil {
+		return fmt.Errorf("[REMOTO] - error al aprobar el conteo: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return nil
+	}
+
+	var estado string
+	err = d.RemoteDB.QueryRow(ctx, `SELECT estado FROM conteos_inventario WHERE uuid = $1 AND deleted_at IS NULL`, conteoUUID).Scan(&estado)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("el conteo [%s] no está en el servidor: sincronícelo antes de aprobarlo", conteoUUID)
+	}
+	if err != nil {
+		return fmt.Errorf("[REMOTO] - error consultando el conteo: %w", err)
+	}
+	return fmt.Errorf("el conteo ya está %s desde otro equipo", estado)
+}
+
+// liberarAprobacionConteoRemoto reabre en el remoto un conteo reservado cuya aprobación local falló.
+func (d *Db) liberarAprobacionConteoRemoto(conteoUUID, supervisorUUID string) {
+	ctx, cancel := context.WithTimeout(d.ctx, 5*time.Second)
+	defer cancel()
+	if _, err := d.RemoteDB.Exec(ctx, `
+		UPDATE conteos_inventario SET estado = $1, aprobado_por = NULL, fecha_cierre = NULL, updated_at = $2
+		WHERE uuid = $3 AND estado = $4 AND aprobado_por = $5`,
+		ConteoAbierto, time.Now(), conteoUUID, ConteoAprobado, supervisorUUID); err != nil {
+		d.Log.Errorf("[CONTEO] no se pudo reabrir en el remoto el conteo %s: %v", conteoUUID, err)
+	}
+}
+
+// conteoAbierto devuelve el conteo si sigue abierto.
+func conteoAbierto(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, conteoUUID string) (ConteoInventario, error) {
+	conteo, err := escanearConteo(q.QueryRow(sqlConteos+` WHERE uuid = ? AND deleted_at IS NULL`, conteoUUID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ConteoInventario{}, fmt.Errorf("conteo [%s] no encontrado", conteoUUID)
+	}
+	if err != nil {
+		return ConteoInventario{}, err
+	}
+	if conteo.Estado != ConteoAbierto {
+		return ConteoInventario{}, fmt.Errorf("el conteo '%s' está %s", conteo.Descripcion, conteo.Estado)
+	}
+	return conteo, nil
+}
+
+func escanearConteo(s interface{ Scan(dest ...any) error }) (ConteoInventario, error) {
+	var c ConteoInventario
+	err := s.Scan(&c.UUID, &c.Descripcion, &c.Categoria, &c.Estado, &c.VendedorUUID, &c.AprobadoPor,
+		&c.FechaApertura, &c.FechaCierre, &c.Observacion, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ConteoInventario{}, fmt.Errorf("error al escanear conteo: %w", err)
+	}
+	return c, err
+}
+
+// itemsConteo se usa dentro de la transacción de aprobación y fuera de ella en los reportes.
+func itemsConteo(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, conteoUUID, productoUUID string) ([]ItemConteo, error) {
+	rows, err := q.Query(sqlItemsConteo, conteoUUID, conteoUUID, conteoUUID, conteoUUID, conteoUUID, productoUUID, productoUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando ítems del conteo: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]ItemConteo, 0)
+	for rows.Next() {
+		var it ItemConteo
+		if err := rows.Scan(&it.ProductoUUID, &it.ProductoCodigo, &it.ProductoNombre, &it.LoteUUID, &it.NumeroLote,
+			&it.FechaVencimiento, &it.StockEsperado, &it.CostoUnitario, &it.Registros, &it.CantidadContada); err != nil {
+			return nil, fmt.Errorf("error al escanear ítem del conteo: %w", err)
+		}
+		if it.Registros > 0 {
+			it.Diferencia = it.CantidadContada - it.StockEsperado
+			it.ValorDiferencia = redondearMoneda(float64(it.Diferencia) * it.CostoUnitario)
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// nombreConLote identifica un ítem del conteo en los mensajes ("Acetaminofén lote L123").
+func nombreConLote(nombre, numeroLote string) string {
+	if numeroLote == "" {
+		return nombre
+	}
+	return nombre + " lote " + numeroLote
+}
@@ -0,0 +1,355 @@
+package backend
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// MovimientoControladoSaldoInicial abre el libro de un producto con el stock que tenía al empezar a controlarse.
+const MovimientoControladoSaldoInicial = "SALDO_INICIAL"
+
+// sqlMovimientosControlados lista asientos del libro con el lote y el número de factura (alias lc).
+const sqlMovimientosControlados = `
+	SELECT lc.uuid, lc.producto_uuid, COALESCE(lc.operacion_stock_uuid, ''), lc.tipo_operacion, lc.cantidad, lc.saldo,
+		COALESCE(lc.lote_uuid, ''), COALESCE(l.numero_lote, ''), COALESCE(lc.factura_uuid, ''), COALESCE(f.numero_factura, ''),
+		COALESCE(lc.vendedor_uuid, ''), COALESCE(lc.prescriptor, ''), COALESCE(lc.registro_prescriptor, ''),
+		COALESCE(lc.paciente_id, ''), COALESCE(lc.numero_formula, ''), lc.fecha
+	FROM libro_controlados lc
+	LEFT JOIN lotes l ON l.uuid = lc.lote_uuid
+	LEFT JOIN facturas f ON f.uuid = lc.factura_uuid`
+
+// validarFormulaMedica exige los datos de la prescripción para dispensar un medicamento de control.
+func validarFormulaMedica(formula *FormulaMedica, nombre string) (*FormulaMedica, error) {
+	if formula == nil {
+		return nil, fmt.Errorf("%s es un medicamento de control y requiere los datos de la fórmula médica", nombre)
+	}
+	f := FormulaMedica{
+		Prescriptor:         strings.TrimSpace(formula.Prescriptor),
+		RegistroPrescriptor: strings.TrimSpace(formula.RegistroPrescriptor),
+		PacienteID:          strings.TrimSpace(formula.PacienteID),
+		NumeroFormula:       strings.TrimSpace(formula.NumeroFormula),
+	}
+	var faltantes []string
+	if f.Prescriptor == "" {
+		faltantes = append(faltantes, "prescriptor")
+	}
+	if f.RegistroPrescriptor == "" {
+		faltantes = append(faltantes, "registro del prescriptor")
+	}
+	if f.PacienteID == "" {
+		faltantes = append(faltantes, "identificación del paciente")
+	}
+	if f.NumeroFormula == "" {
+		faltantes = append(faltantes, "número de fórmula")
+	}
+	if len(faltantes) > 0 {
+		return nil, fmt.Errorf("la fórmula de %s está incompleta: falta %s", nombre, strings.Join(faltantes, ", "))
+	}
+	return &f, nil
+}
+
+// asentarLibroControlado registra una operación de stock en el libro si el producto es de control. El
+// primer asiento de un producto es su saldo inicial. Las ventas llevan su fórmula; las anulaciones y
+// devoluciones repiten la fórmula de la venta que revierten.
+func asentarLibroControlado(tx *sql.Tx, mov MovimientoControlado, stockPrevio int, formula *FormulaMedica) error {
+	var controlado bool
+	if err := tx.QueryRow(`SELECT controlado FROM productos WHERE uuid = ?`, mov.ProductoUUID).Scan(&controlado); err != nil {
+		return fmt.Errorf("error consultando si el producto [%s] es controlado: %w", mov.ProductoUUID, err)
+	}
+	if !controlado {
+		return nil
+	}
+
+	var asientos int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM libro_controlados WHERE producto_uuid = ?`, mov.ProductoUUID).Scan(&asientos); err != nil {
+		return fmt.Errorf("error consultando libro de controlados: %w", err)
+	}
+	now := time.Now()
+	if asientos == 0 && stockPrevio != 0 {
+		apertura := MovimientoControlado{
+			ProductoUUID:  mov.ProductoUUID,
+			TipoOperacion: MovimientoControladoSaldoInicial,
+			Cantidad:      stockPrevio,
+			Saldo:         stockPrevio,
+			VendedorUUID:  mov.VendedorUUID,
+		}
+		if err := insertarMovimientoControlado(tx, apertura, now); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case mov.TipoOperacion == "VENTA":
+		if formula == nil {
+			return fmt.Errorf("la venta del medicamento de control [%s] requiere fórmula médica", mov.ProductoUUID)
+		}
+		mov.FormulaMedica = *formula
+	case mov.FacturaUUID != "":
+		err := tx.QueryRow(`
+			SELECT COALESCE(prescriptor, ''), COALESCE(registro_prescriptor, ''), COALESCE(paciente_id, ''), COALESCE(numero_formula, '')
+			FROM libro_controlados
+			WHERE factura_uuid = ? AND producto_uuid = ? AND tipo_operacion = 'VENTA'
+			LIMIT 1`, mov.FacturaUUID, mov.ProductoUUID).Scan(
+			&mov.Prescriptor, &mov.RegistroPrescriptor, &mov.PacienteID, &mov.NumeroFormula)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error consultando fórmula de la venta: %w", err)
+		}
+	}
+	return insertarMovimientoControlado(tx, mov, now)
+}
+
+func insertarMovimientoControlado(tx *sql.Tx, mov MovimientoControlado, fecha time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO libro_controlados (
+			uuid, producto_uuid, operacion_stock_uuid, tipo_operacion, cantidad, saldo, lote_uuid, factura_uuid, vendedor_uuid,
+			prescriptor, registro_prescriptor, paciente_id, numero_formula, fecha
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		uuid.New().String(), mov.ProductoUUID, nullableString(mov.OperacionStockUUID), mov.TipoOperacion, mov.Cantidad, mov.Saldo,
+		nullableString(mov.LoteUUID), nullableString(mov.FacturaUUID), nullableString(mov.VendedorUUID),
+		nullableString(mov.Prescriptor), nullableString(mov.RegistroPrescriptor), nullableString(mov.PacienteID),
+		nullableString(mov.NumeroFormula), fecha)
+	if err != nil {
+		return fmt.Errorf("error registrando movimiento en el libro de controlados: %w", err)
+	}
+	return nil
+}
+
+// ObtenerLibroControlado devuelve los asientos del libro de un medicamento de control en orden cronológico.
+func (d *Db) ObtenerLibroControlado(productoUUID string) ([]MovimientoControlado, error) {
+	return d.consultarMovimientosControlados(sqlMovimientosControlados+`
+		WHERE lc.producto_uuid = ?
+		ORDER BY lc.fecha ASC, lc.rowid ASC`, productoUUID)
+}
+
+func (d *Db) consultarMovimientosControlados(query string, args ...any) ([]MovimientoControlado, error) {
+	rows, err := d.LocalDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener libro de controlados: %w", err)
+	}
+	defer rows.Close()
+
+	movimientos := make([]MovimientoControlado, 0)
+	for rows.Next() {
+		var m MovimientoControlado
+		if err := rows.Scan(&m.UUID, &m.ProductoUUID, &m.OperacionStockUUID, &m.TipoOperacion, &m.Cantidad, &m.Saldo,
+			&m.LoteUUID, &m.NumeroLote, &m.FacturaUUID, &m.NumeroFactura, &m.VendedorUUID,
+			&m.Prescriptor, &m.RegistroPrescriptor, &m.PacienteID, &m.NumeroFormula, &m.Fecha); err != nil {
+			return nil, fmt.Errorf("error al escanear movimiento de controlados: %w", err)
+		}
+		movimientos = append(movimientos, m)
+	}
+	return movimientos, rows.Err()
+}
+
+// ObtenerReporteControlados arma el informe mensual de medicamentos de control: por producto el saldo
+// anterior, las entradas, las salidas, el saldo final y el detalle de cada movimiento. Sin año o mes se
+// reporta el mes en curso.
+func (d *Db) ObtenerReporteControlados(anio, mes int) (ReporteControlados, error) {
+	now := time.Now()
+	if anio == 0 || mes == 0 {
+		anio, mes = now.Year(), int(now.Month())
+	}
+	if mes < 1 || mes > 12 {
+		return ReporteControlados{}, fmt.Errorf("mes inválido: %d", mes)
+	}
+	inicio := time.Date(anio, time.Month(mes), 1, 0, 0, 0, 0, time.Local)
+	fin := inicio.AddDate(0, 1, 0).Add(-time.Nanosecond)
+
+	// 1️⃣ Productos con libro abierto antes del fin del mes y su saldo al cierre del mes anterior
+	rows, err := d.LocalDB.Query(`
+		SELECT p.uuid, p.codigo, p.nombre,
+			COALESCE((SELECT x.saldo FROM libro_controlados x
+				WHERE x.producto_uuid = p.uuid AND x.fecha < ?
+				ORDER BY x.fecha DESC, x.rowid DESC LIMIT 1), 0)
+		FROM productos p
+		WHERE p.uuid IN (SELECT producto_uuid FROM libro_controlados WHERE fecha <= ?)
+		ORDER BY p.nombre ASC`, inicio, fin)
+	if err != nil {
+		return ReporteControlados{}, fmt.Errorf("error al obtener medicamentos de control: %w", err)
+	}
+	reporte := ReporteControlados{Anio: anio, Mes: mes, Productos: make([]ResumenControlado, 0)}
+	indice := make(map[string]int)
+	for rows.Next() {
+		r := ResumenControlado{Movimientos: make([]MovimientoControlado, 0)}
+		if err := rows.Scan(&r.ProductoUUID, &r.Codigo, &r.Nombre, &r.SaldoAnterior); err != nil {
+			rows.Close()
+			return ReporteControlados{}, fmt.Errorf("error al escanear medicamento de control: %w", err)
+		}
+		r.SaldoFinal = r.SaldoAnterior
+		indice[r.ProductoUUID] = len(reporte.Productos)
+		reporte.Productos = append(reporte.Productos, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ReporteControlados{}, err
+	}
+
+	// 2️⃣ Movimientos del mes
+	movimientos, err := d.consultarMovimientosControlados(sqlMovimientosControlados+`
+		WHERE lc.fecha BETWEEN ? AND ?
+		ORDER BY lc.fecha ASC, lc.rowid ASC`, inicio, fin)
+	if err != nil {
+		return ReporteControlados{}, err
+	}
+	for _, m := range movimientos {
+		i, ok := indice[m.ProductoUUID]
+		if !ok {
+			continue
+		}
+		r := &reporte.Productos[i]
+		if m.Cantidad >= 0 {
+			r.Entradas += m.Cantidad
+		} else {
+			r.Salidas -= m.Cantidad
+		}
+		r.SaldoFinal = m.Saldo
+		r.Movimientos = append(r.Movimientos, m)
+	}
+	return reporte, nil
+}
+
+// ExportarReporteControlados guarda el informe mensual en CSV en la ruta que elija el usuario. Cada
+// producto abre con su saldo anterior, sigue con sus movimientos y cierra con los totales del mes.
+// Devuelve la ruta del archivo, o vacío si se canceló el diálogo.
+func (d *Db) ExportarReporteControlados(anio, mes int) (string, error) {
+	reporte, err := d.ObtenerReporteControlados(anio, mes)
+	if err != nil {
+		return "", err
+	}
+
+	ruta, err := runtime.SaveFileDialog(d.ctx, runtime.SaveDialogOptions{
+		Title:           "Guardar informe de medicamentos de control",
+		DefaultFilename: fmt.Sprintf("controlados_%04d_%02d.csv", reporte.Anio, reporte.Mes),
+		Filters:         []runtime.FileFilter{{DisplayName: "CSV (*.csv)", Pattern: "*.csv"}},
+	})
+	if err != nil || ruta == "" {
+		return "", err
+	}
+
+	archivo, err := os.Create(ruta)
+	if err != nil {
+		return "", fmt.Errorf("error creando el archivo %s: %w", ruta, err)
+	}
+	defer archivo.Close()
+
+	w := csv.NewWriter(archivo)
+	w.Comma = ';' // Excel en español usa punto y coma como separador
+	registros := [][]string{{
+		"Código", "Producto", "Fecha", "Movimiento", "Lote", "Factura", "Entradas", "Salidas", "Saldo",
+		"Prescriptor", "Registro prescriptor", "Paciente", "Fórmula",
+	}}
+	for _, r := range reporte.Productos {
+		registros = append(registros, []string{r.Codigo, r.Nombre, "", "SALDO ANTERIOR", "", "", "", "", strconv.Itoa(r.SaldoAnterior), "", "", "", ""})
+		for _, m := range r.Movimientos {
+			entrada, salida := "", ""
+			if m.Cantidad >= 0 {
+				entrada = strconv.Itoa(m.Cantidad)
+			} else {
+				salida = strconv.Itoa(-m.Cantidad)
+			}
+			registros = append(registros, []string{
+				r.Codigo, r.Nombre, m.Fecha.In(time.Local).Format("2006-01-02 15:04"), m.TipoOperacion, m.NumeroLote, m.NumeroFactura,
+				entrada, salida, strconv.Itoa(m.Saldo), m.Prescriptor, m.RegistroPrescriptor, m.PacienteID, m.NumeroFormula,
+			})
+		}
+		registros = append(registros, []string{
+			r.Codigo, r.Nombre, "", "TOTAL MES", "", "",
+			strconv.Itoa(r.Entradas), strconv.Itoa(r.Salidas), strconv.Itoa(r.SaldoFinal), "", "", "", "",
+		})
+	}
+	if err := w.WriteAll(registros); err != nil {
+		return "", fmt.Errorf("error escribiendo el informe en %s: %w", ruta, err)
+	}
+	return ruta, nil
+}
+
+// SincronizarLibroControladosHaciaRemoto sube los asientos locales pendientes del libro de controlados.
+func (d *Db) SincronizarLibroControladosHaciaRemoto() {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] Base de datos remota no disponible, omitiendo sincronización del libro de controlados.")
+		return
+	}
+	ctx := d.ctx
+
+	rows, err := d.LocalDB.QueryContext(ctx, `
+		SELECT uuid, producto_uuid, COALESCE(operacion_stock_uuid, ''), tipo_operacion, cantidad, saldo,
+			COALESCE(lote_uuid, ''), COALESCE(factura_uuid, ''), COALESCE(vendedor_uuid, ''), COALESCE(prescriptor, ''),
+			COALESCE(registro_prescriptor, ''), COALESCE(paciente_id, ''), COALESCE(numero_formula, ''), fecha
+		FROM libro_controlados
+		WHERE sincronizado = 0
+		ORDER BY fecha ASC`)
+	if err != nil {
+		d.Log.Errorf("[SYNC CONTROLADOS] Error leyendo libro local: %v", err)
+		return
+	}
+	var pendientes []MovimientoControlado
+	for rows.Next() {
+		var m MovimientoControlado
+		if err := rows.Scan(&m.UUID, &m.ProductoUUID, &m.OperacionStockUUID, &m.TipoOperacion, &m.Cantidad, &m.Saldo,
+			&m.LoteUUID, &m.FacturaUUID, &m.VendedorUUID, &m.Prescriptor, &m.RegistroPrescriptor, &m.PacienteID,
+			&m.NumeroFormula, &m.Fecha); err != nil {
+			d.Log.Warnf("[SYNC CONTROLADOS] Error leyendo asiento, saltando: %v", err)
+			continue
+		}
+		pendientes = append(pendientes, m)
+	}
+	rows.Close()
+
+	if len(pendientes) == 0 {
+		return
+	}
+
+	rtx, err := d.RemoteDB.Begin(ctx)
+	if err != nil {
+		d.Log.Errorf("[SYNC CONTROLADOS] No se pudo iniciar transacción remota: %v", err)
+		return
+	}
+	defer func() {
+		if rErr := rtx.Rollback(ctx); rErr != nil && !errors.Is(rErr, pgx.ErrTxClosed) {
+			d.Log.Errorf("[REMOTO] - Error durante [SincronizarLibroControladosHaciaRemoto] rollback %v", rErr)
+		}
+	}()
+
+	batch := &pgx.Batch{}
+	for _, m := range pendientes {
+		batch.Queue(`
+			INSERT INTO libro_controlados (uuid, producto_uuid, operacion_stock_uuid, tipo_operacion, cantidad, saldo, lote_uuid,
+				factura_uuid, vendedor_uuid, prescriptor, registro_prescriptor, paciente_id, numero_formula, fecha)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			ON CONFLICT (uuid) DO NOTHING`,
+			m.UUID, m.ProductoUUID, nullableString(m.OperacionStockUUID), m.TipoOperacion, m.Cantidad, m.Saldo,
+			nullableString(m.LoteUUID), nullableString(m.FacturaUUID), nullableString(m.VendedorUUID),
+			nullableString(m.Prescriptor), nullableString(m.RegistroPrescriptor), nullableString(m.PacienteID),
+			nullableString(m.NumeroFormula), m.Fecha)
+	}
+	if err := rtx.SendBatch(ctx, batch).Close(); err != nil {
+		d.Log.Errorf("[SYNC CONTROLADOS] Error ejecutando batch de libro_controlados: %v", err)
+		return
+	}
+	if err := rtx.Commit(ctx); err != nil {
+		d.Log.Errorf("[SYNC CONTROLADOS] Error al confirmar la transacción remota: %v", err)
+		return
+	}
+
+	ids := make([]any, len(pendientes))
+	placeholders := make([]string, len(pendientes))
+	for i, m := range pendientes {
+		ids[i] = m.UUID
+		placeholders[i] = "?"
+	}
+	localUpdate := fmt.Sprintf("UPDATE libro_controlados SET sincronizado = 1 WHERE uuid IN (%s)", strings.Join(placeholders, ","))
+	if _, err := d.LocalDB.ExecContext(ctx, localUpdate, ids...); err != nil {
+		d.Log.Errorf("[SYNC CONTROLADOS] Error actualizando flag de sincronización local: %v", err)
+	}
+	d.Log.Infof("[SYNC CONTROLADOS] %d asientos del libro de controlados sincronizados", len(pendientes))
+}
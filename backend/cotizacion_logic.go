@@ -0,0 +1,423 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Estados de una cotización. VENCIDA no se guarda: se calcula con la fecha de vencimiento.
+const (
+	CotizacionVigente    = "VIGENTE"
+	CotizacionConvertida = "CONVERTIDA"
+	CotizacionAnulada    = "ANULADA"
+	CotizacionVencida    = "VENCIDA"
+)
+
+// DiasVigenciaCotizacionPorDefecto se usa cuando la cotización no indica su vigencia.
+const DiasVigenciaCotizacionPorDefecto = 15
+
+// CrearCotizacion registra una cotización con numeración propia (COT-). No mueve inventario.
+func (d *Db) CrearCotizacion(req CotizacionRequest) (Cotizacion, error) {
+	if req.ClienteUUID == "" || req.VendedorUUID == "" {
+		return Cotizacion{}, fmt.Errorf("se requiere el cliente y el vendedor de la cotización")
+	}
+	if len(req.Productos) == 0 {
+		return Cotizacion{}, fmt.Errorf("la cotización no contiene productos")
+	}
+	if req.DiasVigencia < 0 {
+		return Cotizacion{}, fmt.Errorf("los días de vigencia no pueden ser negativos")
+	}
+	if req.DiasVigencia == 0 {
+		req.DiasVigencia = DiasVigenciaCotizacionPorDefecto
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return Cotizacion{}, fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[CrearCotizacion] rollback: %v", rErr)
+		}
+	}()
+
+	// 1️⃣ Generar número de cotización
+	numero, err := generarConsecutivo(tx, "cotizaciones", "numero_cotizacion", "COT-")
+	if err != nil {
+		return Cotizacion{}, fmt.Errorf("error al generar número de cotización: %w", err)
+	}
+
+	now := time.Now()
+	cot := Cotizacion{
+		UUID:             uuid.New().String(),
+		NumeroCotizacion: numero,
+		FechaEmision:     now,
+		FechaVencimiento: now.AddDate(0, 0, req.DiasVigencia),
+		ClienteUUID:      req.ClienteUUID,
+		VendedorUUID:     req.VendedorUUID,
+		Estado:           CotizacionVigente,
+		Observaciones:    strings.TrimSpace(req.Observaciones),
+	}
+
+	// 2️⃣ Valorizar las líneas con el impuesto de cada producto
+	stmtProd, err := tx.Prepare(`
+		SELECT p.nombre, COALESCE(p.precio_venta, 0), COALESCE(p.impuesto_codigo, ''), COALESCE(i.tarifa, 0)
+		FROM productos p
+		LEFT JOIN impuestos i ON i.codigo = p.impuesto_codigo AND i.deleted_at IS NULL
+		WHERE p.uuid = ? AND p.deleted_at IS NULL`)
+	if err != nil {
+		return Cotizacion{}, fmt.Errorf("error preparando consulta productos: %w", err)
+	}
+	defer stmtProd.Close()
+
+	var subtotal, iva float64
+	for _, item := range req.Productos {
+		if item.Cantidad <= 0 {
+			return Cotizacion{}, fmt.Errorf("la cantidad del producto [%s] debe ser mayor que cero", item.ProductoUUID)
+		}
+		var nombre, impuestoCodigo string
+		var precioVenta, tarifa float64
+		if err := stmtProd.QueryRow(item.ProductoUUID).Scan(&nombre, &precioVenta, &impuestoCodigo, &tarifa); err != nil {
+			return Cotizacion{}, fmt.Errorf("producto [%s] no encontrado: %w", item.ProductoUUID, err)
+		}
+		pp, err := resolverPresentacion(tx, item.ProductoUUID, item.PresentacionUUID, precioVenta)
+		if err != nil {
+			return Cotizacion{}, err
+		}
+
+		precio := item.PrecioUnitario
+		if precio <= 0 {
+			precio = pp.PrecioVenta
+		}
+		total := redondearMoneda(float64(item.Cantidad) * precio)
+		base, valorImpuesto := calcularImpuestoIncluido(total, tarifa)
+		subtotal += base
+		iva += valorImpuesto
+
+		cot.Detalles = append(cot.Detalles, DetalleCotizacion{
+			UUID:             uuid.New().String(),
+			CotizacionUUID:   cot.UUID,
+			ProductoUUID:     item.ProductoUUID,
+			Cantidad:         item.Cantidad,
+			PrecioUnitario:   precio,
+			PrecioTotal:      total,
+			ImpuestoCodigo:   impuestoCodigo,
+			TarifaImpuesto:   tarifa,
+			BaseImpuesto:     base,
+			ValorImpuesto:    valorImpuesto,
+			PresentacionUUID: item.PresentacionUUID,
+			Presentacion:     pp.Nombre,
+			FactorConversion: pp.Factor,
+		})
+	}
+	cot.Subtotal = redondearMoneda(subtotal)
+	cot.IVA = redondearMoneda(iva)
+	cot.Total = cot.Subtotal + cot.IVA
+
+	// 3️⃣ Insertar cotización y detalles
+	_, err = tx.Exec(`
+		INSERT INTO cotizaciones (
+			uuid, numero_cotizacion, fecha_emision, fecha_vencimiento, cliente_uuid, vendedor_uuid,
+			subtotal, iva, total, estado, observaciones, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		cot.UUID, cot.NumeroCotizacion, cot.FechaEmision, cot.FechaVencimiento, cot.ClienteUUID, cot.VendedorUUID,
+		cot.Subtotal, cot.IVA, cot.Total, cot.Estado, nullableString(cot.Observaciones), now, now)
+	if err != nil {
+		return Cotizacion{}, fmt.Errorf("error insertando cotización: %w", err)
+	}
+
+	stmtDet, err := tx.Prepare(`
+		INSERT INTO detalle_cotizaciones (
+			uuid, cotizacion_uuid, producto_uuid, cantidad, precio_unitario, precio_total,
+			impuesto_codigo, tarifa_impuesto, base_impuesto, valor_impuesto,
+			presentacion_uuid, presentacion, factor_conversion, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return Cotizacion{}, fmt.Errorf("error preparando statement detalle_cotizaciones: %w", err)
+	}
+	defer stmtDet.Close()
+
+	for _, det := range cot.Detalles {
+		if _, err := stmtDet.Exec(det.UUID, cot.UUID, det.ProductoUUID, det.Cantidad, det.PrecioUnitario, det.PrecioTotal,
+			nullableString(det.ImpuestoCodigo), det.TarifaImpuesto, det.BaseImpuesto, det.ValorImpuesto,
+			nullableString(det.PresentacionUUID), det.Presentacion, det.FactorConversion, now, now); err != nil {
+			return Cotizacion{}, fmt.Errorf("error insertando detalle de cotización %s: %w", det.ProductoUUID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Cotizacion{}, fmt.Errorf("error confirmando transacción de cotización: %w", err)
+	}
+	d.Log.Infof("[COTIZACION] Cotización %s creada por %.2f, vigente hasta %s", cot.NumeroCotizacion, cot.Total, cot.FechaVencimiento.Format("2006-01-02"))
+
+	return d.ObtenerDetalleCotizacion(cot.UUID)
+}
+
+// ObtenerCotizacionesPaginado lista las cotizaciones con búsqueda por número, cliente o vendedor.
+func (d *Db) ObtenerCotizacionesPaginado(page, pageSize int, search, sortBy, sortOrder string) (PaginatedResult, error) {
+	var (
+		cotizaciones []Cotizacion
+		args         []any
+		where        = " WHERE q.deleted_at IS NULL"
+	)
+
+	baseQuery := `
+		FROM cotizaciones q
+		JOIN clientes c ON q.cliente_uuid = c.uuid
+		JOIN vendedors v ON q.vendedor_uuid = v.uuid
+	`
+
+	if search != "" {
+		searchTerm := "%" + strings.ToLower(search) + "%"
+		where += `
+			AND (LOWER(q.numero_cotizacion) LIKE ?
+			   OR LOWER(c.nombre) LIKE ?
+			   OR LOWER(v.nombre) LIKE ?)
+		`
+		args = append(args, searchTerm, searchTerm, searchTerm)
+	}
+
+	var total int64
+	if err := d.LocalDB.QueryRow("SELECT COUNT(q.uuid) "+baseQuery+where, args...).Scan(&total); err != nil {
+		return PaginatedResult{}, fmt.Errorf("Error contando cotizaciones: %w", err)
+	}
+
+	allowedSortBy := map[string]string{
+		"NumeroCotizacion": "q.numero_cotizacion",
+		"FechaEmision":     "q.fecha_emision",
+		"FechaVencimiento": "q.fecha_vencimiento",
+		"Cliente":          "c.nombre",
+		"Vendedor":         "v.nombre",
+		"Total":            "q.total",
+		"Estado":           "q.estado",
+	}
+
+	orderBy := "ORDER BY q.fecha_emision DESC, q.numero_cotizacion DESC"
+	if col, ok := allowedSortBy[sortBy]; ok {
+		order := "ASC"
+		if strings.ToLower(sortOrder) == "desc" {
+			order = "DESC"
+		}
+		orderBy = fmt.Sprintf("ORDER BY %s %s", col, order)
+	}
+
+	offset := (page - 1) * pageSize
+	pagination := fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize, offset)
+
+	selectQuery := `
+		SELECT
+			q.uuid, q.numero_cotizacion, q.fecha_emision, q.fecha_vencimiento, q.total, q.estado,
+			COALESCE(q.factura_uuid, ''),
+			c.uuid, c.nombre,
+			v.uuid, v.nombre
+	` + baseQuery + where + " " + orderBy + pagination
+
+	rows, err := d.LocalDB.Query(selectQuery, args...)
+	if err != nil {
+		return PaginatedResult{}, fmt.Errorf("Error realizando consulta cotizaciones: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	for rows.Next() {
+		var q Cotizacion
+		if err := rows.Scan(
+			&q.UUID, &q.NumeroCotizacion, &q.FechaEmision, &q.FechaVencimiento, &q.Total, &q.Estado,
+			&q.FacturaUUID,
+			&q.Cliente.UUID, &q.Cliente.Nombre,
+			&q.Vendedor.UUID, &q.Vendedor.Nombre,
+		); err != nil {
+			return PaginatedResult{}, err
+		}
+		q.ClienteUUID = q.Cliente.UUID
+		q.VendedorUUID = q.Vendedor.UUID
+		q.Estado = estadoCotizacion(q.Estado, q.FechaVencimiento, now)
+		cotizaciones = append(cotizaciones, q)
+	}
+
+	return PaginatedResult{Records: cotizaciones, TotalRecords: total}, nil
+}
+
+// ObtenerDetalleCotizacion devuelve una cotización con sus líneas, cliente y vendedor.
+func (d *Db) ObtenerDetalleCotizacion(cotizacionUUID string) (Cotizacion, error) {
+	var q Cotizacion
+	err := d.LocalDB.QueryRow(`
+		SELECT
+			q.uuid, q.numero_cotizacion, q.fecha_emision, q.fecha_vencimiento,
+			q.subtotal, q.iva, q.total, q.estado, COALESCE(q.observaciones, ''), COALESCE(q.factura_uuid, ''),
+			q.created_at, q.updated_at,
+			c.uuid, c.nombre, c.apellido, c.numero_id,
+			v.uuid, v.nombre, v.apellido
+		FROM cotizaciones q
+		JOIN clientes c ON q.cliente_uuid = c.uuid
+		JOIN vendedors v ON q.vendedor_uuid = v.uuid
+		WHERE q.uuid = ?`, cotizacionUUID).Scan(
+		&q.UUID, &q.NumeroCotizacion, &q.FechaEmision, &q.FechaVencimiento,
+		&q.Subtotal, &q.IVA, &q.Total, &q.Estado, &q.Observaciones, &q.FacturaUUID,
+		&q.CreatedAt, &q.UpdatedAt,
+		&q.Cliente.UUID, &q.Cliente.Nombre, &q.Cliente.Apellido, &q.Cliente.NumeroID,
+		&q.Vendedor.UUID, &q.Vendedor.Nombre, &q.Vendedor.Apellido)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Cotizacion{}, fmt.Errorf("cotización [%s] no encontrada", cotizacionUUID)
+		}
+		return Cotizacion{}, fmt.Errorf("error al obtener cotización %s: %w", cotizacionUUID, err)
+	}
+	q.ClienteUUID = q.Cliente.UUID
+	q.VendedorUUID = q.Vendedor.UUID
+	q.Estado = estadoCotizacion(q.Estado, q.FechaVencimiento, time.Now())
+
+	q.Detalles = make([]DetalleCotizacion, 0)
+	rows, err := d.LocalDB.Query(`
+		SELECT dq.uuid, dq.cotizacion_uuid, dq.cantidad, dq.precio_unitario, dq.precio_total,
+			COALESCE(dq.impuesto_codigo, ''), dq.tarifa_impuesto, dq.base_impuesto, dq.valor_impuesto,
+			COALESCE(dq.presentacion_uuid, ''), COALESCE(dq.presentacion, ?), dq.factor_conversion,
+			dq.created_at, dq.updated_at,
+			p.uuid, p.codigo, p.nombre, COALESCE(p.precio_venta, 0), COALESCE(p.stock, 0)
+		FROM detalle_cotizaciones dq
+		JOIN productos p ON p.uuid = dq.producto_uuid
+		WHERE dq.cotizacion_uuid = ?`, PresentacionUnidad, cotizacionUUID)
+	if err != nil {
+		return q, fmt.Errorf("error consultando detalles de cotización: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var det DetalleCotizacion
+		if err := rows.Scan(&det.UUID, &det.CotizacionUUID, &det.Cantidad, &det.PrecioUnitario, &det.PrecioTotal,
+			&det.ImpuestoCodigo, &det.TarifaImpuesto, &det.BaseImpuesto, &det.ValorImpuesto,
+			&det.PresentacionUUID, &det.Presentacion, &det.FactorConversion,
+			&det.CreatedAt, &det.UpdatedAt,
+			&det.Producto.UUID, &det.Producto.Codigo, &det.Producto.Nombre, &det.Producto.PrecioVenta, &det.Producto.Stock); err != nil {
+			return q, fmt.Errorf("error escaneando detalle de cotización: %w", err)
+		}
+		det.ProductoUUID = det.Producto.UUID
+		q.Detalles = append(q.Detalles, det)
+	}
+	return q, rows.Err()
+}
+
+// AnularCotizacion descarta una cotización que aún no se ha convertido en venta.
+func (d *Db) AnularCotizacion(cotizacionUUID string) error {
+	res, err := d.LocalDB.Exec(`UPDATE cotizaciones SET estado = ?, updated_at = ? WHERE uuid = ? AND estado = ?`,
+		CotizacionAnulada, time.Now(), cotizacionUUID, CotizacionVigente)
+	if err != nil {
+		return fmt.Errorf("error anulando cotización: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("la cotización [%s] no existe o ya no está vigente", cotizacionUUID)
+	}
+	d.Log.Infof("[COTIZACION] Cotización %s anulada", cotizacionUUID)
+	return nil
+}
+
+// ConvertirCotizacionEnVenta factura una cotización vigente a los precios cotizados a través de RegistrarVenta.
+// Antes de facturar se revalida el stock y se advierte cuando el precio actual difiere del cotizado;
+// en ese caso la venta se registra como cambio de precio y requiere autorización de supervisor.
+func (d *Db) ConvertirCotizacionEnVenta(req ConversionCotizacionRequest) (ConversionCotizacionResultado, error) {
+	cot, err := d.ObtenerDetalleCotizacion(req.CotizacionUUID)
+	if err != nil {
+		return ConversionCotizacionResultado{}, err
+	}
+
+	// 1️⃣ Validar estado y vigencia
+	switch cot.Estado {
+	case CotizacionVigente:
+	case CotizacionVencida:
+		return ConversionCotizacionResultado{}, fmt.Errorf("la cotización %s venció el %s", cot.NumeroCotizacion, cot.FechaVencimiento.Format("02/01/2006"))
+	default:
+		return ConversionCotizacionResultado{}, fmt.Errorf("la cotización %s se encuentra %s", cot.NumeroCotizacion, strings.ToLower(cot.Estado))
+	}
+
+	// 2️⃣ Revalidar stock y precios contra el catálogo actual
+	resultado := ConversionCotizacionResultado{Advertencias: make([]string, 0)}
+	solicitado := make(map[string]int, len(cot.Detalles))
+	var faltantes []string
+	venta := VentaRequest{
+		ClienteUUID:  cot.ClienteUUID,
+		VendedorUUID: req.VendedorUUID,
+		MetodoPago:   req.MetodoPago,
+		Pagos:        req.Pagos,
+		Autorizacion: req.Autorizacion,
+		FormulaUUID:  req.FormulaUUID,
+		Formula:      req.Formula,
+	}
+	if venta.VendedorUUID == "" {
+		venta.VendedorUUID = cot.VendedorUUID
+	}
+	for _, det := range cot.Detalles {
+		// El precio de lista es el de la presentación cotizada y el stock se compara en unidades mínimas
+		pp, err := resolverPresentacion(d.LocalDB, det.ProductoUUID, det.PresentacionUUID, det.Producto.PrecioVenta)
+		if err != nil {
+			return ConversionCotizacionResultado{}, fmt.Errorf("no se puede facturar la cotización %s: %w", cot.NumeroCotizacion, err)
+		}
+		nombre := nombreConPresentacion(det.Producto.Nombre, pp.Nombre)
+		solicitado[det.ProductoUUID] += det.Cantidad * pp.Factor
+		if solicitado[det.ProductoUUID] > det.Producto.Stock {
+			faltantes = append(faltantes, fmt.Sprintf("%s (disponible %d, cotizado %d)", det.Producto.Nombre, det.Producto.Stock, solicitado[det.ProductoUUID]))
+		}
+		if !esPrecioDeLista(det.PrecioUnitario, pp.PrecioVenta) {
+			resultado.Advertencias = append(resultado.Advertencias,
+				fmt.Sprintf("El precio actual de %s (%.2f) difiere del cotizado (%.2f); se factura al precio cotizado",
+					nombre, pp.PrecioVenta, det.PrecioUnitario))
+		}
+		// Facturar a un precio cotizado distinto al de lista es un cambio de precio: requiere supervisor.
+		linea := ProductoVenta{
+			ProductoUUID:       det.ProductoUUID,
+			Cantidad:           det.Cantidad,
+			PrecioUnitario:     det.PrecioUnitario,
+			MotivoCambioPrecio: "Precio cotizado en " + cot.NumeroCotizacion,
+			PresentacionUUID:   det.PresentacionUUID,
+		}
+		if formula, ok := req.Formulas[det.ProductoUUID]; ok {
+			linea.Formula = &formula
+		}
+		venta.Productos = append(venta.Productos, linea)
+	}
+	if len(faltantes) > 0 {
+		return ConversionCotizacionResultado{}, fmt.Errorf("stock insuficiente para facturar la cotización: %s", strings.Join(faltantes, ", "))
+	}
+
+	// 3️⃣ Reservar la cotización para que no se facture dos veces
+	now := time.Now()
+	res, err := d.LocalDB.Exec(`UPDATE cotizaciones SET estado = ?, updated_at = ? WHERE uuid = ? AND estado = ?`,
+		CotizacionConvertida, now, cot.UUID, CotizacionVigente)
+	if err != nil {
+		return ConversionCotizacionResultado{}, fmt.Errorf("error actualizando cotización: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ConversionCotizacionResultado{}, fmt.Errorf("la cotización %s ya fue convertida o anulada", cot.NumeroCotizacion)
+	}
+
+	// 4️⃣ Facturar; si la venta falla la cotización vuelve a quedar vigente
+	factura, err := d.RegistrarVenta(venta)
+	if err != nil {
+		if _, rErr := d.LocalDB.Exec(`UPDATE cotizaciones SET estado = ?, updated_at = ? WHERE uuid = ?`,
+			CotizacionVigente, time.Now(), cot.UUID); rErr != nil {
+			d.Log.Errorf("[COTIZACION] No se pudo restablecer la cotización %s: %v", cot.NumeroCotizacion, rErr)
+		}
+		return ConversionCotizacionResultado{}, err
+	}
+
+	if _, err := d.LocalDB.Exec(`UPDATE cotizaciones SET factura_uuid = ?, updated_at = ? WHERE uuid = ?`,
+		factura.UUID, time.Now(), cot.UUID); err != nil {
+		d.Log.Errorf("[COTIZACION] Factura %s generada pero no asociada a la cotización %s: %v", factura.NumeroFactura, cot.NumeroCotizacion, err)
+	}
+
+	d.Log.Infof("[COTIZACION] Cotización %s convertida en factura %s", cot.NumeroCotizacion, factura.NumeroFactura)
+	resultado.Factura = factura
+	return resultado, nil
+}
+
+// estadoCotizacion devuelve VENCIDA para las cotizaciones vigentes cuya fecha de vencimiento ya pasó.
+func estadoCotizacion(estado string, vencimiento, now time.Time) string {
+	if estado == CotizacionVigente && now.After(vencimiento) {
+		return CotizacionVencida
+	}
+	return estado
+}
@@ -21,15 +21,35 @@ type VendedorRendimiento struct {
 	TotalVendido   float64 `json:"totalVendido"`
 }
 
+type ImpuestoResumen struct {
+	Tarifa float64 `json:"tarifa"`
+	Base   float64 `json:"base"`
+	Valor  float64 `json:"valor"`
+}
+
+// ProductoSinStock es un producto agotado con los equivalentes en stock que se pueden ofrecer en su lugar.
+type ProductoSinStock struct {
+	Producto
+	Sustitutos []Producto `json:"sustitutos"`
+}
+
 type DashboardData struct {
-	TotalVentasDia     float64                  `json:"totalVentasDia"`
-	NumeroVentasDia    int64                    `json:"numeroVentasDia"`
-	TicketPromedioDia  float64                  `json:"ticketPromedioDia"`
-	VentasIndividuales []VentaIndividual        `json:"ventasIndividuales"`
-	TopProductos       []ProductoVendido        `json:"topProductos"`
-	ProductosSinStock  []Producto               `json:"productosSinStock"`
-	TopVendedor        VendedorRendimiento      `json:"topVendedor"`
-	MetodosPago        []map[string]interface{} `json:"metodosPago"`
+	TotalVentasDia        float64                  `json:"totalVentasDia"`
+	NumeroVentasDia       int64                    `json:"numeroVentasDia"`
+	TicketPromedioDia     float64                  `json:"ticketPromedioDia"`
+	TotalDevolucionesDia  float64                  `json:"totalDevolucionesDia"`
+	NumeroDevolucionesDia int64                    `json:"numeroDevolucionesDia"`
+	TotalNetoDia          float64                  `json:"totalNetoDia"`
+	TotalDescuentosDia    float64                  `json:"totalDescuentosDia"`
+	RentabilidadDia       Rentabilidad             `json:"rentabilidadDia"`
+	ImpuestosDia          []ImpuestoResumen        `json:"impuestosDia"`
+	VentasIndividuales    []VentaIndividual        `json:"ventasIndividuales"`
+	TopProductos          []ProductoVendido        `json:"topProductos"`
+	ProductosSinStock     []ProductoSinStock       `json:"productosSinStock"`
+	LotesPorVencer        []Lote                   `json:"lotesPorVencer"`
+	PorReabastecer        []NivelReorden           `json:"porReabastecer"`
+	TopVendedor           VendedorRendimiento      `json:"topVendedor"`
+	MetodosPago           []map[string]interface{} `json:"metodosPago"`
 }
 
 func (d *Db) ObtenerDatosDashboard(fechaStr string) (DashboardData, error) {
@@ -53,11 +73,13 @@ func (d *Db) ObtenerDatosDashboard(fechaStr string) (DashboardData, error) {
 	// Inicializar slices para evitar `null` en la respuesta JSON.
 	data.VentasIndividuales = make([]VentaIndividual, 0)
 	data.TopProductos = make([]ProductoVendido, 0)
-	data.ProductosSinStock = make([]Producto, 0)
+	data.ProductosSinStock = make([]ProductoSinStock, 0)
 	data.MetodosPago = make([]map[string]interface{}, 0)
+	data.ImpuestosDia = make([]ImpuestoResumen, 0)
 
-	queryTotalVentas := "SELECT COALESCE(SUM(total), 0), COUNT(uuid) FROM facturas WHERE fecha_emision BETWEEN ? AND ?"
-	err = d.LocalDB.QueryRow(queryTotalVentas, inicioDelDia, finDelDia).Scan(&data.TotalVentasDia, &data.NumeroVentasDia)
+	// Las facturas anuladas no cuentan en ninguno de los totales del dashboard.
+	queryTotalVentas := "SELECT COALESCE(SUM(total), 0), COUNT(uuid), COALESCE(SUM(descuento), 0) FROM facturas WHERE fecha_emision BETWEEN ? AND ? AND COALESCE(estado, '') != 'ANULADA'"
+	err = d.LocalDB.QueryRow(queryTotalVentas, inicioDelDia, finDelDia).Scan(&data.TotalVentasDia, &data.NumeroVentasDia, &data.TotalDescuentosDia)
 	if err != nil {
 		return data, fmt.Errorf("error al obtener total de ventas: %w", err)
 	}
@@ -65,7 +87,26 @@ func (d *Db) ObtenerDatosDashboard(fechaStr string) (DashboardData, error) {
 		data.TicketPromedioDia = data.TotalVentasDia / float64(data.NumeroVentasDia)
 	}
 
-	queryVentasInd := "SELECT strftime('%Y-%m-%d %H:%M:%S', datetime(fecha_emision, 'localtime')), total FROM facturas WHERE fecha_emision BETWEEN ? AND ? ORDER BY fecha_emision ASC"
+	// Notas crédito (devoluciones) emitidas en el día. Las de facturas anuladas no se restan: la venta
+	// ya salió completa de los totales al anularse.
+	queryDevoluciones := `
+		SELECT COALESCE(SUM(nc.total), 0), COUNT(nc.uuid)
+		FROM notas_credito nc
+		JOIN facturas f ON f.uuid = nc.factura_uuid
+		WHERE nc.fecha_emision BETWEEN ? AND ? AND COALESCE(f.estado, '') != 'ANULADA'`
+	err = d.LocalDB.QueryRow(queryDevoluciones, inicioDelDia, finDelDia).Scan(&data.TotalDevolucionesDia, &data.NumeroDevolucionesDia)
+	if err != nil {
+		return data, fmt.Errorf("error al obtener total de devoluciones: %w", err)
+	}
+	data.TotalNetoDia = data.TotalVentasDia - data.TotalDevolucionesDia
+
+	// Utilidad bruta del día según el costo registrado en cada venta.
+	data.RentabilidadDia, err = d.rentabilidadEntre(inicioDelDia, finDelDia)
+	if err != nil {
+		return data, err
+	}
+
+	queryVentasInd := "SELECT strftime('%Y-%m-%d %H:%M:%S', datetime(fecha_emision, 'localtime')), total FROM facturas WHERE fecha_emision BETWEEN ? AND ? AND COALESCE(estado, '') != 'ANULADA' ORDER BY fecha_emision ASC"
 	rows, err := d.LocalDB.Query(queryVentasInd, inicioDelDia, finDelDia)
 	if err != nil {
 		return data, fmt.Errorf("error al obtener ventas individuales: %w", err)
@@ -84,7 +125,7 @@ func (d *Db) ObtenerDatosDashboard(fechaStr string) (DashboardData, error) {
 		FROM detalle_facturas df
 		JOIN productos p ON p.uuid = df.producto_uuid
 		JOIN facturas f ON f.uuid = df.factura_uuid
-		WHERE f.fecha_emision BETWEEN ? AND ?
+		WHERE f.fecha_emision BETWEEN ? AND ? AND COALESCE(f.estado, '') != 'ANULADA'
 		GROUP BY p.nombre
 		ORDER BY cantidad DESC
 		LIMIT 5`
@@ -101,8 +142,14 @@ func (d *Db) ObtenerDatosDashboard(fechaStr string) (DashboardData, error) {
 		data.TopProductos = append(data.TopProductos, p)
 	}
 
-	// 5. Obtener distribución de Métodos de Pago.
-	queryMetodos := "SELECT metodo_pago, COUNT(*) as count FROM facturas WHERE fecha_emision BETWEEN ? AND ? GROUP BY metodo_pago"
+	// 5. Obtener distribución de Métodos de Pago (suma de lo recaudado por cada medio).
+	queryMetodos := `
+		SELECT pf.metodo_pago, COALESCE(SUM(pf.monto), 0) as total, COUNT(DISTINCT pf.factura_uuid) as count
+		FROM pagos_factura pf
+		JOIN facturas f ON f.uuid = pf.factura_uuid
+		WHERE f.fecha_emision BETWEEN ? AND ? AND COALESCE(f.estado, '') != 'ANULADA' AND pf.deleted_at IS NULL
+		GROUP BY pf.metodo_pago
+		ORDER BY total DESC`
 	rows, err = d.LocalDB.Query(queryMetodos, inicioDelDia, finDelDia)
 	if err != nil {
 		return data, fmt.Errorf("error al obtener métodos de pago: %w", err)
@@ -110,34 +157,87 @@ func (d *Db) ObtenerDatosDashboard(fechaStr string) (DashboardData, error) {
 	defer rows.Close()
 	for rows.Next() {
 		var metodo string
+		var total float64
 		var count int
-		if err := rows.Scan(&metodo, &count); err != nil {
+		if err := rows.Scan(&metodo, &total, &count); err != nil {
 			return data, err
 		}
-		data.MetodosPago = append(data.MetodosPago, map[string]interface{}{"metodo_pago": metodo, "count": count})
+		data.MetodosPago = append(data.MetodosPago, map[string]interface{}{"metodo_pago": metodo, "total": total, "count": count})
+	}
+
+	// 5.b Desglose de base e IVA por tarifa.
+	queryImpuestos := `
+		SELECT df.tarifa_impuesto, COALESCE(SUM(df.base_impuesto), 0), COALESCE(SUM(df.valor_impuesto), 0)
+		FROM detalle_facturas df
+		JOIN facturas f ON f.uuid = df.factura_uuid
+		WHERE f.fecha_emision BETWEEN ? AND ? AND COALESCE(f.estado, '') != 'ANULADA'
+		GROUP BY df.tarifa_impuesto
+		ORDER BY df.tarifa_impuesto DESC`
+	rows, err = d.LocalDB.Query(queryImpuestos, inicioDelDia, finDelDia)
+	if err != nil {
+		return data, fmt.Errorf("error al obtener desglose de impuestos: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r ImpuestoResumen
+		if err := rows.Scan(&r.Tarifa, &r.Base, &r.Valor); err != nil {
+			return data, err
+		}
+		data.ImpuestosDia = append(data.ImpuestosDia, r)
 	}
 
 	// 6. Obtener Top 5 Productos sin stock.
-	querySinStock := "SELECT uuid, codigo, nombre, precio_venta, stock FROM productos WHERE stock <= 0 AND deleted_at IS NULL ORDER BY nombre ASC LIMIT 5"
+	querySinStock := "SELECT uuid, codigo, nombre, precio_venta, stock, " + sqlDatosFarmaceuticos + " FROM productos WHERE stock <= 0 AND deleted_at IS NULL ORDER BY nombre ASC LIMIT 5"
 	rows, err = d.LocalDB.Query(querySinStock)
 	if err != nil {
 		return data, fmt.Errorf("error al obtener productos sin stock: %w", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var p Producto
-		if err := rows.Scan(&p.UUID, &p.Codigo, &p.Nombre, &p.PrecioVenta, &p.Stock); err != nil {
+		var p ProductoSinStock
+		if err := rows.Scan(append([]any{&p.UUID, &p.Codigo, &p.Nombre, &p.PrecioVenta, &p.Stock}, p.DatosFarmaceuticos.destinos()...)...); err != nil {
 			return data, err
 		}
 		data.ProductosSinStock = append(data.ProductosSinStock, p)
 	}
+	rows.Close()
+
+	// 6.a Equivalentes en stock de cada producto agotado, los más baratos primero.
+	for i := range data.ProductosSinStock {
+		p := &data.ProductosSinStock[i]
+		p.Sustitutos = make([]Producto, 0)
+		if p.PrincipioActivo == "" {
+			continue
+		}
+		if p.Sustitutos, err = d.consultarSustitutos(p.Producto, SustitutosDashboard); err != nil {
+			return data, err
+		}
+	}
+
+	// 6.b Lotes vencidos o que vencen dentro de la ventana de alerta, los más próximos primero.
+	data.LotesPorVencer, err = d.ObtenerLotesPorVencer(DiasAlertaVencimiento)
+	if err != nil {
+		return data, err
+	}
+	if len(data.LotesPorVencer) > 5 {
+		data.LotesPorVencer = data.LotesPorVencer[:5]
+	}
+
+	// 6.c Productos en su punto de reorden, antes de agotarse, los de menos días cubiertos primero.
+	data.PorReabastecer, err = d.ObtenerProductosPorReabastecer()
+	if err != nil {
+		return data, err
+	}
+	if len(data.PorReabastecer) > 5 {
+		data.PorReabastecer = data.PorReabastecer[:5]
+	}
 
 	// 7. Obtener el Top Vendedor del día.
 	queryTopVendedor := `
 		SELECT v.nombre, SUM(f.total) as total_vendido
 		FROM facturas f
 		JOIN vendedors v ON v.uuid = f.vendedor_uuid
-		WHERE f.fecha_emision BETWEEN ? AND ?
+		WHERE f.fecha_emision BETWEEN ? AND ? AND COALESCE(f.estado, '') != 'ANULADA'
 		GROUP BY v.nombre
 		ORDER BY total_vendido DESC
 		LIMIT 1`
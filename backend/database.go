@@ -7,6 +7,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,6 +32,7 @@ type OperacionStock struct {
 	StockResultante int       `json:"StockResultante"`
 	VendedorUUID    string    `json:"VendedorUUID"`
 	FacturaUUID     *string   `json:"FacturaUUID"`
+	LoteUUID        string    `json:"LoteUUID"` // vacío: stock sin lote
 	Timestamp       time.Time `json:"Timestamp" ts_type:"string"`
 	Sincronizado    bool      `json:"Sincronizado"`
 }
@@ -46,6 +49,7 @@ type Claims struct {
 type AjusteStockRequest struct {
 	ProductoUUID string `json:"ProductoUUID"`
 	NuevoStock   int    `json:"NuevoStock"`
+	VendedorUUID string `json:"VendedorUUID"` // a quien se atribuye el ajuste
 }
 
 type LoginResponse struct {
@@ -71,20 +75,24 @@ type Vendedor struct {
 	Contrasena string     `json:"Contrasena"`
 	MFASecret  string     `json:"-"`
 	MFAEnabled bool       `json:"MFAEnabled"`
+	Rol        string     `json:"Rol"` // VENDEDOR, SUPERVISOR o ADMIN
 }
 
 type Cliente struct {
-	CreatedAt time.Time  `json:"CreatedAt" ts_type:"string"`
-	UpdatedAt time.Time  `json:"UpdatedAt" ts_type:"string"`
-	DeletedAt *time.Time `json:"DeletedAt" ts_type:"string"`
-	UUID      string     `json:"UUID"`
-	Nombre    string     `json:"Nombre"`
-	Apellido  string     `json:"Apellido"`
-	TipoID    string     `json:"TipoID"`
-	NumeroID  string     `json:"NumeroID"`
-	Telefono  string     `json:"Telefono"`
-	Email     string     `json:"Email"`
-	Direccion string     `json:"Direccion"`
+	CreatedAt     time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt     time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt     *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID          string     `json:"UUID"`
+	Nombre        string     `json:"Nombre"`
+	Apellido      string     `json:"Apellido"`
+	TipoID        string     `json:"TipoID"`
+	NumeroID      string     `json:"NumeroID"`
+	Telefono      string     `json:"Telefono"`
+	Email         string     `json:"Email"`
+	Direccion     string     `json:"Direccion"`
+	LimiteCredito float64    `json:"LimiteCredito"` // 0: el cliente no compra a crédito
+	Saldo         float64    `json:"Saldo"`         // calculado desde las facturas a crédito, no se guarda
+	Puntos        int        `json:"Puntos"`        // suma del libro de movimientos de puntos, no se guarda
 }
 
 type Producto struct {
@@ -96,65 +104,539 @@ type Producto struct {
 	Codigo      string     `json:"Codigo"`
 	PrecioVenta float64    `json:"PrecioVenta"`
 	Stock       int        `json:"Stock"`
+	// Código del impuesto (IVA_19, IVA_5, EXENTO, EXCLUIDO) que aplica al producto.
+	ImpuestoCodigo string `json:"ImpuestoCodigo"`
+	// Categoría libre del producto; las reglas de puntos pueden excluirla o darle otra tasa.
+	Categoria string `json:"Categoria"`
+	// Costo promedio ponderado de la unidad mínima, recalculado con cada compra.
+	CostoPromedio float64 `json:"CostoPromedio"`
+	// Presentaciones adicionales a la unidad mínima, en la que se lleva Stock.
+	Presentaciones []PresentacionProducto `json:"Presentaciones"`
+	// Medicamento de control (FNE): exige fórmula médica para venderse y lleva libro de movimientos.
+	Controlado bool `json:"Controlado"`
+	// Venta bajo fórmula médica: lo vendido debe estar prescrito en una fórmula con cantidad pendiente.
+	RequiereFormula bool `json:"RequiereFormula"`
+	DatosFarmaceuticos
+}
+
+// DatosFarmaceuticos son los datos maestros del medicamento. Al actualizar un producto, un campo vacío
+// conserva el valor actual.
+type DatosFarmaceuticos struct {
+	PrincipioActivo   string `json:"PrincipioActivo"`
+	Concentracion     string `json:"Concentracion"`     // ej. 500 mg, 5 mg/mL
+	FormaFarmaceutica string `json:"FormaFarmaceutica"` // tableta, jarabe, solución inyectable...
+	Laboratorio       string `json:"Laboratorio"`
+	RegistroInvima    string `json:"RegistroInvima"`
+	VencimientoInvima string `json:"VencimientoInvima"` // AAAA-MM-DD
+	CodigoATC         string `json:"CodigoATC"`
+}
+
+// CategoriaProducto es un nodo del árbol de categorías; CategoriaPadre vacío indica una categoría raíz.
+type CategoriaProducto struct {
+	CreatedAt      time.Time           `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt      time.Time           `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt      *time.Time          `json:"DeletedAt" ts_type:"string"`
+	UUID           string              `json:"UUID"`
+	Nombre         string              `json:"Nombre"`
+	CategoriaPadre string              `json:"CategoriaPadre"`
+	Subcategorias  []CategoriaProducto `json:"Subcategorias"`
+}
+
+// PresentacionProducto es una forma de vender o comprar el producto (caja, blíster...) con su
+// propio precio y código de barras. Factor es el número de unidades mínimas que contiene.
+type PresentacionProducto struct {
+	CreatedAt    time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt    time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt    *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID         string     `json:"UUID"`
+	ProductoUUID string     `json:"ProductoUUID"`
+	Producto     *Producto  `json:"Producto,omitempty"`
+	Nombre       string     `json:"Nombre"`
+	Factor       int        `json:"Factor"`
+	PrecioVenta  float64    `json:"PrecioVenta"`
+	CodigoBarras string     `json:"CodigoBarras"`
+	Disponible   int        `json:"Disponible"` // presentaciones completas con el stock actual, no se guarda
+}
+
+// CodigoBarrasProducto es un código de barras adicional del producto, por ejemplo el EAN de otro
+// distribuidor. Sin PresentacionUUID corresponde a la unidad mínima.
+type CodigoBarrasProducto struct {
+	CreatedAt        time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt        time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt        *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID             string     `json:"UUID"`
+	Codigo           string     `json:"Codigo"`
+	ProductoUUID     string     `json:"ProductoUUID"`
+	PresentacionUUID string     `json:"PresentacionUUID"`
+	Presentacion     string     `json:"Presentacion"` // nombre de la presentación, no se guarda
+	Descripcion      string     `json:"Descripcion"`
+}
+
+// CodigoGS1 son los identificadores de aplicación leídos de un código GS1 (DataMatrix o GS1-128).
+type CodigoGS1 struct {
+	GTIN        string `json:"GTIN"`        // (01)
+	Lote        string `json:"Lote"`        // (10)
+	Vencimiento string `json:"Vencimiento"` // (17) AAAA-MM-DD
+	Serial      string `json:"Serial"`      // (21)
+}
+
+// CodigoEscaneado es un código leído en el POS resuelto a la presentación del producto y, si es un
+// código GS1 con lote, al lote del inventario.
+type CodigoEscaneado struct {
+	Codigo       string               `json:"Codigo"`
+	Presentacion PresentacionProducto `json:"Presentacion"` // incluye el producto
+	GS1          *CodigoGS1           `json:"GS1,omitempty"`
+	Lote         *Lote                `json:"Lote,omitempty"`
+	Advertencias []string             `json:"Advertencias"`
+}
+
+// Impuesto es una tarifa configurable que se asigna a los productos.
+type Impuesto struct {
+	CreatedAt time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID      string     `json:"UUID"`
+	Codigo    string     `json:"Codigo"`
+	Nombre    string     `json:"Nombre"`
+	Tarifa    float64    `json:"Tarifa"` // Porcentaje, ej. 19 para IVA 19%
 }
 
 type ProductoAjusteRequest struct {
-	UUID         string  `json:"UUID"`
-	Nombre       string  `json:"Nombre"`
-	PrecioVenta  float64 `json:"PrecioVenta"`
-	StockDeseado int     `json:"Stock"`
-	VendedorUUID string  `json:"VendedorUUID,omitempty"`
+	UUID            string  `json:"UUID"`
+	Nombre          string  `json:"Nombre"`
+	PrecioVenta     float64 `json:"PrecioVenta"`
+	StockDeseado    int     `json:"Stock"`
+	VendedorUUID    string  `json:"VendedorUUID,omitempty"`
+	ImpuestoCodigo  string  `json:"ImpuestoCodigo"`
+	Categoria       string  `json:"Categoria"`
+	Controlado      *bool   `json:"Controlado,omitempty"` // nil conserva el valor actual
+	RequiereFormula *bool   `json:"RequiereFormula,omitempty"`
+	DatosFarmaceuticos
 }
 
 type NuevoProducto struct {
-	UUID         string  `json:"UUID"`
-	VendedorUUID string  `json:"VendedorUUID"`
-	Nombre       string  `json:"Nombre"`
-	Codigo       string  `json:"Codigo"`
-	PrecioVenta  float64 `json:"PrecioVenta"`
-	Stock        int     `json:"Stock"`
+	UUID            string  `json:"UUID"`
+	VendedorUUID    string  `json:"VendedorUUID"`
+	Nombre          string  `json:"Nombre"`
+	Codigo          string  `json:"Codigo"`
+	PrecioVenta     float64 `json:"PrecioVenta"`
+	Stock           int     `json:"Stock"`
+	ImpuestoCodigo  string  `json:"ImpuestoCodigo"`
+	Categoria       string  `json:"Categoria"`
+	Controlado      bool    `json:"Controlado"`
+	RequiereFormula bool    `json:"RequiereFormula"`
+	DatosFarmaceuticos
 }
 
 type Factura struct {
-	CreatedAt     time.Time        `json:"CreatedAt" ts_type:"string"`
-	UpdatedAt     time.Time        `json:"UpdatedAt" ts_type:"string"`
-	DeletedAt     *time.Time       `json:"DeletedAt" ts_type:"string"`
-	UUID          string           `json:"UUID"`
-	NumeroFactura string           `json:"NumeroFactura"`
-	FechaEmision  time.Time        `json:"FechaEmision"  ts_type:"string"`
-	VendedorUUID  string           `json:"VendedorUUID"`
-	Vendedor      Vendedor         `json:"Vendedor"`
-	ClienteUUID   string           `json:"ClienteUUID"`
-	Cliente       Cliente          `json:"Cliente"`
-	Subtotal      float64          `json:"Subtotal"`
-	IVA           float64          `json:"IVA"`
-	Total         float64          `json:"Total"`
-	Estado        string           `json:"Estado"`
-	MetodoPago    string           `json:"MetodoPago"`
-	Detalles      []DetalleFactura `json:"Detalles"`
+	CreatedAt              time.Time         `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt              time.Time         `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt              *time.Time        `json:"DeletedAt" ts_type:"string"`
+	UUID                   string            `json:"UUID"`
+	NumeroFactura          string            `json:"NumeroFactura"`
+	FechaEmision           time.Time         `json:"FechaEmision"  ts_type:"string"`
+	VendedorUUID           string            `json:"VendedorUUID"`
+	Vendedor               Vendedor          `json:"Vendedor"`
+	ClienteUUID            string            `json:"ClienteUUID"`
+	Cliente                Cliente           `json:"Cliente"`
+	ValorBruto             float64           `json:"ValorBruto"`
+	Descuento              float64           `json:"Descuento"`
+	DescuentoFactura       float64           `json:"DescuentoFactura"`
+	MotivoDescuento        string            `json:"MotivoDescuento"`
+	DescuentoAutorizadoPor string            `json:"DescuentoAutorizadoPor"`
+	Subtotal               float64           `json:"Subtotal"`
+	IVA                    float64           `json:"IVA"`
+	Total                  float64           `json:"Total"`
+	Estado                 string            `json:"Estado"`
+	MetodoPago             string            `json:"MetodoPago"`
+	SesionCajaUUID         string            `json:"SesionCajaUUID"`
+	MotivoAnulacion        string            `json:"MotivoAnulacion"`
+	FechaAnulacion         *time.Time        `json:"FechaAnulacion" ts_type:"string"`
+	AnuladaPorUUID         string            `json:"AnuladaPorUUID"`
+	SesionCajaAnulacion    string            `json:"SesionCajaAnulacion"` // caja de la que salió el reembolso de la anulación
+	CUFE                   string            `json:"CUFE"`
+	FormulaUUID            string            `json:"FormulaUUID"`
+	Formula                *Formula          `json:"Formula,omitempty"` // fórmula dispensada, para el recibo
+	PuntosGanados          int               `json:"PuntosGanados"`
+	PuntosRedimidos        int               `json:"PuntosRedimidos"`
+	SaldoPuntos            int               `json:"SaldoPuntos"` // saldo actual del cliente, para el recibo
+	Rentabilidad           Rentabilidad      `json:"Rentabilidad"`
+	Detalles               []DetalleFactura  `json:"Detalles"`
+	Impuestos              []FacturaImpuesto `json:"Impuestos"`
+	Pagos                  []PagoFactura     `json:"Pagos"`
+	NotasCredito           []NotaCredito     `json:"NotasCredito"`
 }
 
 type DetalleFactura struct {
-	CreatedAt      time.Time  `json:"CreatedAt" ts_type:"string"`
-	UpdatedAt      time.Time  `json:"UpdatedAt" ts_type:"string"`
-	DeletedAt      *time.Time `json:"DeletedAt" ts_type:"string"`
-	UUID           string     `json:"UUID"`
-	FacturaUUID    string     `json:"FacturaUUID"`
-	ProductoUUID   string     `json:"ProductoUUID"`
-	Producto       Producto   `json:"Producto"`
-	Cantidad       int        `json:"Cantidad"`
-	PrecioUnitario float64    `json:"PrecioUnitario"`
-	PrecioTotal    float64    `json:"PrecioTotal"`
+	CreatedAt        time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt        time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt        *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID             string     `json:"UUID"`
+	FacturaUUID      string     `json:"FacturaUUID"`
+	ProductoUUID     string     `json:"ProductoUUID"`
+	Producto         Producto   `json:"Producto"`
+	Cantidad         int        `json:"Cantidad"`
+	CantidadDevuelta int        `json:"CantidadDevuelta"`
+	PrecioUnitario   float64    `json:"PrecioUnitario"`
+	ValorBruto       float64    `json:"ValorBruto"`
+	Descuento        float64    `json:"Descuento"`
+	MotivoDescuento  string     `json:"MotivoDescuento"`
+	PrecioTotal      float64    `json:"PrecioTotal"`
+	ImpuestoCodigo   string     `json:"ImpuestoCodigo"`
+	BaseImpuesto     float64    `json:"BaseImpuesto"`
+	TarifaImpuesto   float64    `json:"TarifaImpuesto"`
+	ValorImpuesto    float64    `json:"ValorImpuesto"`
+	// Cantidad y precios están en la presentación vendida; FactorConversion la lleva a unidades mínimas.
+	PresentacionUUID string `json:"PresentacionUUID"`
+	Presentacion     string `json:"Presentacion"`
+	FactorConversion int    `json:"FactorConversion"`
+	// Costo de la presentación vendida al momento de la venta (costo promedio por el factor).
+	CostoUnitario float64 `json:"CostoUnitario"`
+}
+
+// Cotizacion es una oferta de precios para un cliente, válida hasta FechaVencimiento.
+// Al convertirse en venta guarda la factura generada en FacturaUUID.
+type Cotizacion struct {
+	CreatedAt        time.Time           `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt        time.Time           `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt        *time.Time          `json:"DeletedAt" ts_type:"string"`
+	UUID             string              `json:"UUID"`
+	NumeroCotizacion string              `json:"NumeroCotizacion"`
+	FechaEmision     time.Time           `json:"FechaEmision" ts_type:"string"`
+	FechaVencimiento time.Time           `json:"FechaVencimiento" ts_type:"string"`
+	ClienteUUID      string              `json:"ClienteUUID"`
+	Cliente          Cliente             `json:"Cliente"`
+	VendedorUUID     string              `json:"VendedorUUID"`
+	Vendedor         Vendedor            `json:"Vendedor"`
+	Subtotal         float64             `json:"Subtotal"`
+	IVA              float64             `json:"IVA"`
+	Total            float64             `json:"Total"`
+	Estado           string              `json:"Estado"`
+	Observaciones    string              `json:"Observaciones"`
+	FacturaUUID      string              `json:"FacturaUUID"`
+	Detalles         []DetalleCotizacion `json:"Detalles"`
+}
+
+type DetalleCotizacion struct {
+	CreatedAt      time.Time `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt      time.Time `json:"UpdatedAt" ts_type:"string"`
+	UUID           string    `json:"UUID"`
+	CotizacionUUID string    `json:"CotizacionUUID"`
+	ProductoUUID   string    `json:"ProductoUUID"`
+	Producto       Producto  `json:"Producto"`
+	Cantidad       int       `json:"Cantidad"`
+	PrecioUnitario float64   `json:"PrecioUnitario"`
+	PrecioTotal    float64   `json:"PrecioTotal"`
+	ImpuestoCodigo string    `json:"ImpuestoCodigo"`
+	TarifaImpuesto float64   `json:"TarifaImpuesto"`
+	BaseImpuesto   float64   `json:"BaseImpuesto"`
+	ValorImpuesto  float64   `json:"ValorImpuesto"`
+	// Cantidad y precios están en la presentación cotizada; FactorConversion la lleva a unidades mínimas.
+	PresentacionUUID string `json:"PresentacionUUID"`
+	Presentacion     string `json:"Presentacion"`
+	FactorConversion int    `json:"FactorConversion"`
+}
+
+// SesionCaja es un turno de caja de un vendedor, desde la apertura con su base hasta el arqueo de cierre.
+type SesionCaja struct {
+	CreatedAt     time.Time    `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt     time.Time    `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt     *time.Time   `json:"DeletedAt" ts_type:"string"`
+	UUID          string       `json:"UUID"`
+	VendedorUUID  string       `json:"VendedorUUID"`
+	Vendedor      Vendedor     `json:"Vendedor"`
+	FechaApertura time.Time    `json:"FechaApertura" ts_type:"string"`
+	MontoApertura float64      `json:"MontoApertura"`
+	FechaCierre   *time.Time   `json:"FechaCierre" ts_type:"string"`
+	Estado        string       `json:"Estado"`
+	TotalEsperado float64      `json:"TotalEsperado"`
+	TotalContado  float64      `json:"TotalContado"`
+	Diferencia    float64      `json:"Diferencia"`
+	Observaciones string       `json:"Observaciones"`
+	NumeroVentas  int64        `json:"NumeroVentas"`
+	Conteos       []ConteoCaja `json:"Conteos"`
+}
+
+// ConteoCaja es el arqueo de un método de pago al cierre: lo esperado según las ventas y lo contado.
+type ConteoCaja struct {
+	CreatedAt      time.Time `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt      time.Time `json:"UpdatedAt" ts_type:"string"`
+	UUID           string    `json:"UUID"`
+	SesionCajaUUID string    `json:"SesionCajaUUID"`
+	MetodoPago     string    `json:"MetodoPago"`
+	Esperado       float64   `json:"Esperado"`
+	Contado        float64   `json:"Contado"`
+	Diferencia     float64   `json:"Diferencia"`
+}
+
+// RangoFacturacion es un bloque de números de factura reservado en el remoto para una terminal.
+type RangoFacturacion struct {
+	CreatedAt   time.Time `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt   time.Time `json:"UpdatedAt" ts_type:"string"`
+	UUID        string    `json:"UUID"`
+	TerminalID  string    `json:"TerminalID"`
+	Prefijo     string    `json:"Prefijo"`
+	Desde       int64     `json:"Desde"`
+	Hasta       int64     `json:"Hasta"`
+	UltimoUsado int64     `json:"UltimoUsado"`
+	Estado      string    `json:"Estado"`
+}
+
+// EstadoNumeracion resume la numeración de la terminal: último número emitido y números disponibles.
+type EstadoNumeracion struct {
+	TerminalID    string             `json:"TerminalID"`
+	Prefijo       string             `json:"Prefijo"`
+	UltimaFactura string             `json:"UltimaFactura"`
+	Disponibles   int64              `json:"Disponibles"`
+	Umbral        int64              `json:"Umbral"`
+	PorAgotarse   bool               `json:"PorAgotarse"`
+	Rangos        []RangoFacturacion `json:"Rangos"`
+}
+
+// CambioPrecio registra la venta de un producto a un precio distinto al de lista, autorizada por un supervisor.
+type CambioPrecio struct {
+	CreatedAt           time.Time `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt           time.Time `json:"UpdatedAt" ts_type:"string"`
+	UUID                string    `json:"UUID"`
+	FacturaUUID         string    `json:"FacturaUUID"`
+	NumeroFactura       string    `json:"NumeroFactura"`
+	DetalleFacturaUUID  string    `json:"DetalleFacturaUUID"`
+	ProductoUUID        string    `json:"ProductoUUID"`
+	ProductoNombre      string    `json:"ProductoNombre"`
+	Cantidad            int       `json:"Cantidad"`
+	PrecioOriginal      float64   `json:"PrecioOriginal"`
+	PrecioNuevo         float64   `json:"PrecioNuevo"`
+	VendedorUUID        string    `json:"VendedorUUID"`
+	VendedorNombre      string    `json:"VendedorNombre"`
+	AutorizadoPorUUID   string    `json:"AutorizadoPorUUID"`
+	AutorizadoPorNombre string    `json:"AutorizadoPorNombre"`
+	Motivo              string    `json:"Motivo"`
+}
+
+// ResumenCambiosPrecioDia agrupa los cambios de precio de un día. DiferenciaTotal es negativa si se vendió por debajo de lista.
+type ResumenCambiosPrecioDia struct {
+	Fecha           string         `json:"Fecha"`
+	Cantidad        int            `json:"Cantidad"`
+	DiferenciaTotal float64        `json:"DiferenciaTotal"`
+	Cambios         []CambioPrecio `json:"Cambios"`
+}
+
+// Rentabilidad resume la utilidad bruta de un conjunto de ventas. Ventas es la base sin IVA, neta de
+// descuentos y devoluciones; Margen es la utilidad sobre las ventas y Markup la utilidad sobre el costo (en %).
+type Rentabilidad struct {
+	Ventas   float64 `json:"Ventas"`
+	Costo    float64 `json:"Costo"`
+	Utilidad float64 `json:"Utilidad"`
+	Margen   float64 `json:"Margen"`
+	Markup   float64 `json:"Markup"`
+}
+
+// ResumenRentabilidad es la rentabilidad de un día, producto o vendedor dentro del reporte.
+type ResumenRentabilidad struct {
+	Clave    string `json:"Clave"` // fecha AAAA-MM-DD o UUID del producto/vendedor
+	Nombre   string `json:"Nombre"`
+	Cantidad int    `json:"Cantidad"` // unidades mínimas vendidas netas de devoluciones
+	Rentabilidad
+}
+
+// ReporteRentabilidad agrupa la utilidad bruta de las ventas de un rango de fechas.
+type ReporteRentabilidad struct {
+	FechaInicio string                `json:"FechaInicio"`
+	FechaFin    string                `json:"FechaFin"`
+	Totales     Rentabilidad          `json:"Totales"`
+	PorDia      []ResumenRentabilidad `json:"PorDia"`
+	PorProducto []ResumenRentabilidad `json:"PorProducto"`
+	PorVendedor []ResumenRentabilidad `json:"PorVendedor"`
+}
+
+// Abono es un pago de un cliente a su cuenta, repartido entre una o varias facturas a crédito.
+type Abono struct {
+	CreatedAt      time.Time      `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt      time.Time      `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt      *time.Time     `json:"DeletedAt" ts_type:"string"`
+	UUID           string         `json:"UUID"`
+	NumeroAbono    string         `json:"NumeroAbono"`
+	ClienteUUID    string         `json:"ClienteUUID"`
+	VendedorUUID   string         `json:"VendedorUUID"`
+	SesionCajaUUID string         `json:"SesionCajaUUID"`
+	Fecha          time.Time      `json:"Fecha" ts_type:"string"`
+	Monto          float64        `json:"Monto"`
+	MetodoPago     string         `json:"MetodoPago"`
+	Referencia     string         `json:"Referencia"`
+	Observaciones  string         `json:"Observaciones"`
+	Facturas       []AbonoFactura `json:"Facturas"`
+}
+
+// AbonoFactura es la parte de un abono aplicada a una factura.
+type AbonoFactura struct {
+	CreatedAt     time.Time `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt     time.Time `json:"UpdatedAt" ts_type:"string"`
+	UUID          string    `json:"UUID"`
+	AbonoUUID     string    `json:"AbonoUUID"`
+	FacturaUUID   string    `json:"FacturaUUID"`
+	NumeroFactura string    `json:"NumeroFactura"`
+	Monto         float64   `json:"Monto"`
+}
+
+// FacturaPendiente es una factura a crédito con saldo por cobrar.
+type FacturaPendiente struct {
+	FacturaUUID   string    `json:"FacturaUUID"`
+	NumeroFactura string    `json:"NumeroFactura"`
+	FechaEmision  time.Time `json:"FechaEmision" ts_type:"string"`
+	ValorCredito  float64   `json:"ValorCredito"`
+	Abonado       float64   `json:"Abonado"`
+	Saldo         float64   `json:"Saldo"`
+	Dias          int       `json:"Dias"` // días desde la emisión
+}
+
+// CarteraCliente es el saldo de un cliente repartido por antigüedad de sus facturas.
+type CarteraCliente struct {
+	ClienteUUID   string  `json:"ClienteUUID"`
+	Nombre        string  `json:"Nombre"`
+	NumeroID      string  `json:"NumeroID"`
+	LimiteCredito float64 `json:"LimiteCredito"`
+	De0a30        float64 `json:"De0a30"`
+	De31a60       float64 `json:"De31a60"`
+	De61a90       float64 `json:"De61a90"`
+	Mas90         float64 `json:"Mas90"`
+	Total         float64 `json:"Total"`
+}
+
+// ReporteCartera es el informe de cartera por edades con los totales de cada rango.
+type ReporteCartera struct {
+	FechaCorte time.Time        `json:"FechaCorte" ts_type:"string"`
+	Clientes   []CarteraCliente `json:"Clientes"`
+	Totales    CarteraCliente   `json:"Totales"`
+}
+
+// MovimientoCuenta es una línea del estado de cuenta: un cargo (venta a crédito) o un abono.
+type MovimientoCuenta struct {
+	Fecha      time.Time `json:"Fecha" ts_type:"string"`
+	Tipo       string    `json:"Tipo"` // FACTURA, ABONO o NOTA_CREDITO
+	Documento  string    `json:"Documento"`
+	Referencia string    `json:"Referencia"`
+	Cargo      float64   `json:"Cargo"`
+	Abono      float64   `json:"Abono"`
+	Saldo      float64   `json:"Saldo"`
+}
+
+// EstadoCuentaCliente es el extracto de un cliente entre dos fechas, con saldo inicial y final.
+type EstadoCuentaCliente struct {
+	Cliente            Cliente            `json:"Cliente"`
+	FechaInicio        string             `json:"FechaInicio"`
+	FechaFin           string             `json:"FechaFin"`
+	SaldoInicial       float64            `json:"SaldoInicial"`
+	Movimientos        []MovimientoCuenta `json:"Movimientos"`
+	SaldoFinal         float64            `json:"SaldoFinal"`
+	FacturasPendientes []FacturaPendiente `json:"FacturasPendientes"`
+	CupoDisponible     float64            `json:"CupoDisponible"`
+}
+
+// FacturaElectronica guarda el XML UBL 2.1 generado para una factura, su CUFE y el resultado del envío a la DIAN.
+type FacturaElectronica struct {
+	CreatedAt     time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt     time.Time  `json:"UpdatedAt" ts_type:"string"`
+	UUID          string     `json:"UUID"`
+	FacturaUUID   string     `json:"FacturaUUID"`
+	NumeroFactura string     `json:"NumeroFactura"`
+	CUFE          string     `json:"CUFE"`
+	QR            string     `json:"QR"`
+	XML           string     `json:"XML"`
+	Ambiente      string     `json:"Ambiente"`
+	Estado        string     `json:"Estado"` // GENERADA, ACEPTADA o RECHAZADA
+	MensajeDIAN   string     `json:"MensajeDIAN"`
+	FechaEnvio    *time.Time `json:"FechaEnvio" ts_type:"string"`
+}
+
+// VentaEnEspera es un borrador de venta guardado en la terminal para atender a otro cliente.
+type VentaEnEspera struct {
+	CreatedAt     time.Time    `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt     time.Time    `json:"UpdatedAt" ts_type:"string"`
+	UUID          string       `json:"UUID"`
+	VendedorUUID  string       `json:"VendedorUUID"`
+	ClienteUUID   string       `json:"ClienteUUID"`
+	Cliente       Cliente      `json:"Cliente"`
+	Descripcion   string       `json:"Descripcion"`
+	Venta         VentaRequest `json:"Venta"`
+	TotalEstimado float64      `json:"TotalEstimado"`
+	NumeroItems   int          `json:"NumeroItems"`
+}
+
+// VentaRetomada es la venta en espera lista para el carrito, con precios actualizados y
+// las advertencias encontradas al revalidarla.
+type VentaRetomada struct {
+	Venta        VentaRequest `json:"Venta"`
+	Advertencias []string     `json:"Advertencias"`
+}
+
+// PagoFactura es cada uno de los medios de pago con los que se pagó una factura.
+type PagoFactura struct {
+	CreatedAt        time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt        time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt        *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID             string     `json:"UUID"`
+	FacturaUUID      string     `json:"FacturaUUID"`
+	MetodoPago       string     `json:"MetodoPago"`
+	Monto            float64    `json:"Monto"`
+	Referencia       string     `json:"Referencia"`
+	EfectivoRecibido float64    `json:"EfectivoRecibido"`
+	Cambio           float64    `json:"Cambio"`
+}
+
+// FacturaImpuesto es el resumen de base e impuesto de una factura para una tarifa.
+type FacturaImpuesto struct {
+	CreatedAt      time.Time `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt      time.Time `json:"UpdatedAt" ts_type:"string"`
+	UUID           string    `json:"UUID"`
+	FacturaUUID    string    `json:"FacturaUUID"`
+	ImpuestoCodigo string    `json:"ImpuestoCodigo"`
+	Tarifa         float64   `json:"Tarifa"`
+	Base           float64   `json:"Base"`
+	Valor          float64   `json:"Valor"`
+}
+
+type NotaCredito struct {
+	CreatedAt       time.Time            `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt       time.Time            `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt       *time.Time           `json:"DeletedAt" ts_type:"string"`
+	UUID            string               `json:"UUID"`
+	NumeroNota      string               `json:"NumeroNota"`
+	FacturaUUID     string               `json:"FacturaUUID"`
+	FechaEmision    time.Time            `json:"FechaEmision" ts_type:"string"`
+	VendedorUUID    string               `json:"VendedorUUID"`
+	ClienteUUID     string               `json:"ClienteUUID"`
+	Motivo          string               `json:"Motivo"`
+	SesionCajaUUID  string               `json:"SesionCajaUUID"`
+	Subtotal        float64              `json:"Subtotal"`
+	IVA             float64              `json:"IVA"`
+	Total           float64              `json:"Total"`
+	AplicadoCartera float64              `json:"AplicadoCartera"` // parte descontada del saldo a crédito de la factura
+	Detalles        []DetalleNotaCredito `json:"Detalles"`
+}
+
+type DetalleNotaCredito struct {
+	CreatedAt          time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt          time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt          *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID               string     `json:"UUID"`
+	NotaCreditoUUID    string     `json:"NotaCreditoUUID"`
+	DetalleFacturaUUID string     `json:"DetalleFacturaUUID"`
+	ProductoUUID       string     `json:"ProductoUUID"`
+	Producto           Producto   `json:"Producto"`
+	Cantidad           int        `json:"Cantidad"`
+	PrecioUnitario     float64    `json:"PrecioUnitario"`
+	PrecioTotal        float64    `json:"PrecioTotal"`
 }
 
 type Proveedor struct {
-	CreatedAt time.Time  `json:"CreatedAt" ts_type:"string"`
-	UpdatedAt time.Time  `json:"UpdatedAt" ts_type:"string"`
-	DeletedAt *time.Time `json:"DeletedAt" ts_type:"string"`
-	UUID      string     `json:"uuid"`
-	Nombre    string     `json:"Nombre"`
-	Telefono  string     `json:"Telefono"`
-	Email     string     `json:"Email"`
+	CreatedAt   time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt   time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt   *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID        string     `json:"uuid"`
+	Nombre      string     `json:"Nombre"`
+	Telefono    string     `json:"Telefono"`
+	Email       string     `json:"Email"`
+	DiasEntrega int        `json:"DiasEntrega"` // tiempo de entrega de sus pedidos
 }
 
 type Compra struct {
@@ -165,6 +647,8 @@ type Compra struct {
 	Fecha         time.Time       `json:"Fecha" ts_type:"string"`
 	ProveedorUUID string          `json:"proveedor_uuid"`
 	Proveedor     Proveedor       `json:"proveedor"`
+	VendedorUUID  string          `json:"VendedorUUID"`
+	Vendedor      Vendedor        `json:"Vendedor"`
 	FacturaNumero string          `json:"FacturaNumero"`
 	Total         float64         `json:"Total"`
 	Detalles      []DetalleCompra `json:"Detalles"`
@@ -175,24 +659,301 @@ type DetalleCompra struct {
 	UpdatedAt            time.Time  `json:"UpdatedAt" ts_type:"string"`
 	DeletedAt            *time.Time `json:"DeletedAt" ts_type:"string"`
 	UUID                 string     `json:"UUID"`
-	CompraUUID           uint       `json:"coCompraUUIDmpra_uuid"`
+	CompraUUID           string     `json:"CompraUUID"`
 	ProductoUUID         string     `json:"ProductoUUID"`
 	Producto             Producto   `json:"Producto"`
 	Cantidad             int        `json:"Cantidad"`
 	PrecioCompraUnitario float64    `json:"PrecioCompraUnitario"`
+	PresentacionUUID     string     `json:"PresentacionUUID"`
+	FactorConversion     int        `json:"FactorConversion"`
+	LoteUUID             string     `json:"LoteUUID"`
+	NumeroLote           string     `json:"NumeroLote"`
+	FechaVencimiento     string     `json:"FechaVencimiento"` // AAAA-MM-DD
+}
+
+// Lote agrupa unidades de un producto con el mismo número de lote y fecha de vencimiento. Stock se
+// deriva de las operaciones de stock del lote; DiasParaVencer es negativo si ya venció. ValorCosto
+// valora el stock al costo promedio del producto.
+type Lote struct {
+	CreatedAt        time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt        time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt        *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID             string     `json:"UUID"`
+	ProductoUUID     string     `json:"ProductoUUID"`
+	ProductoNombre   string     `json:"ProductoNombre"`
+	NumeroLote       string     `json:"NumeroLote"`
+	FechaVencimiento string     `json:"FechaVencimiento"` // AAAA-MM-DD
+	Estado           string     `json:"Estado"`           // VIGENTE, CUARENTENA o DESTRUIDO
+	Observacion      string     `json:"Observacion"`
+	Stock            int        `json:"Stock"`
+	DiasParaVencer   int        `json:"DiasParaVencer"`
+	Vencido          bool       `json:"Vencido"`
+	CostoUnitario    float64    `json:"CostoUnitario"`
+	ValorCosto       float64    `json:"ValorCosto"`
+}
+
+// RetiroLoteRequest saca de la venta un lote vencido o dañado, pasándolo a CUARENTENA o DESTRUIDO.
+type RetiroLoteRequest struct {
+	LoteUUID     string `json:"LoteUUID"`
+	Estado       string `json:"Estado"`
+	VendedorUUID string `json:"VendedorUUID"`
+	Observacion  string `json:"Observacion"` // motivo o número de acta de destrucción
 }
 
 type VentaRequest struct {
-	ClienteUUID  string          `json:"ClienteUUID"`
-	VendedorUUID string          `json:"VendedorUUID"`
-	Productos    []ProductoVenta `json:"Productos"`
-	MetodoPago   string          `json:"MetodoPago"`
+	ClienteUUID     string                  `json:"ClienteUUID"`
+	VendedorUUID    string                  `json:"VendedorUUID"`
+	Productos       []ProductoVenta         `json:"Productos"`
+	MetodoPago      string                  `json:"MetodoPago"`
+	Pagos           []PagoVenta             `json:"Pagos"`
+	DescuentoTipo   string                  `json:"DescuentoTipo"` // PORCENTAJE o VALOR
+	Descuento       float64                 `json:"Descuento"`
+	MotivoDescuento string                  `json:"MotivoDescuento"`
+	Autorizacion    *AutorizacionSupervisor `json:"Autorizacion,omitempty"`
+	// Puntos del cliente redimidos como descuento global; para pagar con puntos se usa un pago "puntos".
+	PuntosDescuento int `json:"PuntosDescuento"`
+	// Fórmula médica de la venta: FormulaUUID para seguir dispensando una fórmula ya registrada o
+	// Formula para registrar una nueva. Cubre los productos que requieren fórmula.
+	FormulaUUID string          `json:"FormulaUUID"`
+	Formula     *FormulaRequest `json:"Formula,omitempty"`
 }
 
 type ProductoVenta struct {
+	ProductoUUID    string  `json:"ProductoUUID"`
+	Cantidad        int     `json:"Cantidad"`
+	PrecioUnitario  float64 `json:"PrecioUnitario"`
+	DescuentoTipo   string  `json:"DescuentoTipo"` // PORCENTAJE o VALOR
+	Descuento       float64 `json:"Descuento"`
+	MotivoDescuento string  `json:"MotivoDescuento"`
+	// Solo se usa si PrecioUnitario difiere del precio de lista (requiere autorización de supervisor).
+	MotivoCambioPrecio string `json:"MotivoCambioPrecio"`
+	// Vacío para vender en la unidad mínima; Cantidad y PrecioUnitario están en esta presentación.
+	PresentacionUUID string `json:"PresentacionUUID"`
+	// Vacío para despachar por FEFO (primero el lote que vence antes).
+	LoteUUID string `json:"LoteUUID"`
+	// Obligatoria para medicamentos de control.
+	Formula *FormulaMedica `json:"Formula,omitempty"`
+}
+
+// FormulaMedica son los datos de la prescripción que exige la dispensación de un medicamento de control.
+type FormulaMedica struct {
+	Prescriptor         string `json:"Prescriptor"`
+	RegistroPrescriptor string `json:"RegistroPrescriptor"` // registro profesional del médico
+	PacienteID          string `json:"PacienteID"`
+	NumeroFormula       string `json:"NumeroFormula"`
+}
+
+// Formula es una fórmula médica registrada en la farmacia. Puede dispensarse en varias ventas: Estado
+// es PENDIENTE, PARCIAL o COMPLETA según lo dispensado en facturas no anuladas.
+type Formula struct {
+	CreatedAt      time.Time     `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt      time.Time     `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt      *time.Time    `json:"DeletedAt" ts_type:"string"`
+	UUID           string        `json:"UUID"`
+	NumeroFormula  string        `json:"NumeroFormula"`
+	FechaFormula   string        `json:"FechaFormula"` // AAAA-MM-DD
+	Medico         string        `json:"Medico"`
+	RegistroMedico string        `json:"RegistroMedico"`
+	PacienteID     string        `json:"PacienteID"`
+	PacienteNombre string        `json:"PacienteNombre"`
+	ClienteUUID    string        `json:"ClienteUUID"`
+	Estado         string        `json:"Estado"` // calculado, no se guarda
+	Items          []ItemFormula `json:"Items"`
+}
+
+// ItemFormula es un producto prescrito en una fórmula. Las cantidades van en unidades mínimas.
+type ItemFormula struct {
+	UUID               string `json:"UUID"`
+	FormulaUUID        string `json:"FormulaUUID"`
+	ProductoUUID       string `json:"ProductoUUID"`
+	Codigo             string `json:"Codigo"`
+	Nombre             string `json:"Nombre"`
+	CantidadPrescrita  int    `json:"CantidadPrescrita"`
+	CantidadDispensada int    `json:"CantidadDispensada"`
+	CantidadPendiente  int    `json:"CantidadPendiente"`
+}
+
+// FormulaRequest registra una fórmula nueva al momento de la venta.
+type FormulaRequest struct {
+	NumeroFormula  string               `json:"NumeroFormula"`
+	FechaFormula   string               `json:"FechaFormula"` // AAAA-MM-DD
+	Medico         string               `json:"Medico"`
+	RegistroMedico string               `json:"RegistroMedico"`
+	PacienteID     string               `json:"PacienteID"`
+	PacienteNombre string               `json:"PacienteNombre"`
+	Items          []ItemFormulaRequest `json:"Items"`
+}
+
+type ItemFormulaRequest struct {
+	ProductoUUID      string `json:"ProductoUUID"`
+	CantidadPrescrita int    `json:"CantidadPrescrita"` // unidades mínimas
+}
+
+// MovimientoControlado es un asiento del libro de medicamentos de control. Cantidad va con signo como en
+// operacion_stocks y Saldo es el stock del producto después del movimiento.
+type MovimientoControlado struct {
+	UUID               string    `json:"UUID"`
+	ProductoUUID       string    `json:"ProductoUUID"`
+	OperacionStockUUID string    `json:"OperacionStockUUID"`
+	TipoOperacion      string    `json:"TipoOperacion"`
+	Cantidad           int       `json:"Cantidad"`
+	Saldo              int       `json:"Saldo"`
+	LoteUUID           string    `json:"LoteUUID"`
+	NumeroLote         string    `json:"NumeroLote"`
+	FacturaUUID        string    `json:"FacturaUUID"`
+	NumeroFactura      string    `json:"NumeroFactura"`
+	VendedorUUID       string    `json:"VendedorUUID"`
+	Fecha              time.Time `json:"Fecha" ts_type:"string"`
+	FormulaMedica
+}
+
+// ResumenControlado es el movimiento de un medicamento de control en el mes del reporte.
+type ResumenControlado struct {
+	ProductoUUID  string                 `json:"ProductoUUID"`
+	Codigo        string                 `json:"Codigo"`
+	Nombre        string                 `json:"Nombre"`
+	SaldoAnterior int                    `json:"SaldoAnterior"`
+	Entradas      int                    `json:"Entradas"`
+	Salidas       int                    `json:"Salidas"`
+	SaldoFinal    int                    `json:"SaldoFinal"`
+	Movimientos   []MovimientoControlado `json:"Movimientos"`
+}
+
+// ReporteControlados es el informe mensual de medicamentos de control que se presenta al FNE.
+type ReporteControlados struct {
+	Anio      int                 `json:"Anio"`
+	Mes       int                 `json:"Mes"`
+	Productos []ResumenControlado `json:"Productos"`
+}
+
+type AperturaCajaRequest struct {
+	VendedorUUID  string  `json:"VendedorUUID"`
+	MontoApertura float64 `json:"MontoApertura"`
+	Observaciones string  `json:"Observaciones"`
+}
+
+type CierreCajaRequest struct {
+	SesionCajaUUID string              `json:"SesionCajaUUID"`
+	VendedorUUID   string              `json:"VendedorUUID"`
+	Conteos        []ConteoCajaRequest `json:"Conteos"`
+	Observaciones  string              `json:"Observaciones"`
+}
+
+type ConteoCajaRequest struct {
+	MetodoPago string  `json:"MetodoPago"`
+	Contado    float64 `json:"Contado"`
+}
+
+// PagoVenta es un medio de pago dentro de una venta. Si la venta no trae pagos se
+// asume un único pago por el total con VentaRequest.MetodoPago.
+type PagoVenta struct {
+	MetodoPago       string  `json:"MetodoPago"`
+	Monto            float64 `json:"Monto"`
+	Referencia       string  `json:"Referencia"`
+	EfectivoRecibido float64 `json:"EfectivoRecibido"`
+}
+
+// AutorizacionSupervisor son las credenciales con las que un supervisor aprueba una operación restringida.
+type AutorizacionSupervisor struct {
+	Email      string `json:"Email"`
+	Contrasena string `json:"Contrasena"`
+}
+
+// CambioRolRequest asigna un rol a un vendedor; Autorizacion debe ser de un ADMIN.
+type CambioRolRequest struct {
+	VendedorUUID string                  `json:"VendedorUUID"`
+	Rol          string                  `json:"Rol"`
+	Autorizacion *AutorizacionSupervisor `json:"Autorizacion"`
+}
+
+type DevolucionRequest struct {
+	FacturaUUID  string           `json:"FacturaUUID"`
+	VendedorUUID string           `json:"VendedorUUID"`
+	Motivo       string           `json:"Motivo"`
+	Items        []ItemDevolucion `json:"Items"`
+}
+
+// AbonoRequest registra un pago a la cuenta de un cliente. Sin facturas indicadas, el abono
+// se aplica a las facturas pendientes más antiguas.
+// ReglaPuntos define cuántos pesos de compra equivalen a un punto. La regla sin categoría aplica a
+// todos los productos; una regla por categoría la reemplaza y con MontoPorPunto en 0 la excluye.
+type ReglaPuntos struct {
+	CreatedAt     time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt     time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt     *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID          string     `json:"UUID"`
+	Codigo        string     `json:"Codigo"`
+	Nombre        string     `json:"Nombre"`
+	Categoria     string     `json:"Categoria"`
+	MontoPorPunto float64    `json:"MontoPorPunto"`
+}
+
+// MovimientoPuntos es una entrada del libro de puntos de un cliente, con signo como operacion_stocks.
+type MovimientoPuntos struct {
+	UUID            string    `json:"UUID"`
+	ClienteUUID     string    `json:"ClienteUUID"`
+	Tipo            string    `json:"Tipo"`
+	Puntos          int       `json:"Puntos"`
+	SaldoResultante int       `json:"SaldoResultante"`
+	FacturaUUID     *string   `json:"FacturaUUID"`
+	NumeroFactura   string    `json:"NumeroFactura"`
+	VendedorUUID    string    `json:"VendedorUUID"`
+	Descripcion     string    `json:"Descripcion"`
+	Timestamp       time.Time `json:"Timestamp" ts_type:"string"`
+	Sincronizado    bool      `json:"Sincronizado"`
+}
+
+type AbonoRequest struct {
+	ClienteUUID   string   `json:"ClienteUUID"`
+	VendedorUUID  string   `json:"VendedorUUID"`
+	Monto         float64  `json:"Monto"`
+	MetodoPago    string   `json:"MetodoPago"`
+	Referencia    string   `json:"Referencia"`
+	Observaciones string   `json:"Observaciones"`
+	FacturaUUIDs  []string `json:"FacturaUUIDs"`
+}
+
+type CotizacionRequest struct {
+	ClienteUUID   string               `json:"ClienteUUID"`
+	VendedorUUID  string               `json:"VendedorUUID"`
+	DiasVigencia  int                  `json:"DiasVigencia"`
+	Observaciones string               `json:"Observaciones"`
+	Productos     []ProductoCotizacion `json:"Productos"`
+}
+
+// ProductoCotizacion es una línea de la cotización. Si no trae precio se cotiza al precio de venta actual.
+type ProductoCotizacion struct {
 	ProductoUUID   string  `json:"ProductoUUID"`
 	Cantidad       int     `json:"Cantidad"`
 	PrecioUnitario float64 `json:"PrecioUnitario"`
+	// Vacío para cotizar en la unidad mínima; Cantidad y PrecioUnitario están en esta presentación.
+	PresentacionUUID string `json:"PresentacionUUID"`
+}
+
+// ConversionCotizacionRequest son los datos de cobro con los que una cotización se convierte en venta.
+type ConversionCotizacionRequest struct {
+	CotizacionUUID string      `json:"CotizacionUUID"`
+	VendedorUUID   string      `json:"VendedorUUID"`
+	MetodoPago     string      `json:"MetodoPago"`
+	Pagos          []PagoVenta `json:"Pagos"`
+	// Requerida cuando el precio cotizado difiere del precio de lista actual.
+	Autorizacion *AutorizacionSupervisor `json:"Autorizacion,omitempty"`
+	// Fórmulas de los medicamentos de control cotizados, por UUID de producto.
+	Formulas map[string]FormulaMedica `json:"Formulas,omitempty"`
+	// Fórmula médica de la venta, como en VentaRequest.
+	FormulaUUID string          `json:"FormulaUUID"`
+	Formula     *FormulaRequest `json:"Formula,omitempty"`
+}
+
+// ConversionCotizacionResultado es la factura generada junto con las diferencias de precio encontradas.
+type ConversionCotizacionResultado struct {
+	Factura      Factura  `json:"Factura"`
+	Advertencias []string `json:"Advertencias"`
+}
+
+type ItemDevolucion struct {
+	DetalleFacturaUUID string `json:"DetalleFacturaUUID"`
+	Cantidad           int    `json:"Cantidad"`
 }
 
 type LoginRequest struct {
@@ -202,7 +963,9 @@ type LoginRequest struct {
 
 type CompraRequest struct {
 	ProveedorUUID string               `json:"ProveedorUUID"`
+	VendedorUUID  string               `json:"VendedorUUID"`
 	FacturaNumero string               `json:"FacturaNumero"`
+	PedidoUUID    string               `json:"PedidoUUID"` // pedido al proveedor que se recibe con la compra
 	Productos     []ProductoCompraInfo `json:"Productos"`
 }
 
@@ -210,6 +973,169 @@ type ProductoCompraInfo struct {
 	ProductoUUID         string  `json:"ProductoUUID"`
 	Cantidad             int     `json:"Cantidad"`
 	PrecioCompraUnitario float64 `json:"PrecioCompraUnitario"`
+	PresentacionUUID     string  `json:"PresentacionUUID"` // vacío: unidad mínima
+	NumeroLote           string  `json:"NumeroLote"`       // vacío: sin control de lote
+	FechaVencimiento     string  `json:"FechaVencimiento"` // AAAA-MM-DD, obligatoria con lote
+}
+
+// NivelReorden son los niveles de reabastecimiento de un producto en unidades mínimas, con su stock,
+// lo que viene en camino y la venta diaria con que se calculan los niveles automáticos.
+type NivelReorden struct {
+	UUID             string  `json:"UUID"` // vacío: el producto no tiene niveles guardados y se calculan
+	ProductoUUID     string  `json:"ProductoUUID"`
+	ProductoCodigo   string  `json:"ProductoCodigo"`
+	ProductoNombre   string  `json:"ProductoNombre"`
+	Automatico       bool    `json:"Automatico"`
+	StockMinimo      int     `json:"StockMinimo"` // punto de reorden
+	StockMaximo      int     `json:"StockMaximo"`
+	ProveedorUUID    string  `json:"ProveedorUUID"` // preferido, o el de la última compra
+	ProveedorNombre  string  `json:"ProveedorNombre"`
+	DiasEntrega      int     `json:"DiasEntrega"`
+	Stock            int     `json:"Stock"`
+	EnCamino         int     `json:"EnCamino"`         // unidades en pedidos pendientes
+	VentaDiaria      float64 `json:"VentaDiaria"`      // promedio del historial de ventas
+	CantidadSugerida int     `json:"CantidadSugerida"` // para llegar al máximo (o a una unidad si está agotado sin ventas), cero si no hay que pedir
+	CostoUnitario    float64 `json:"CostoUnitario"`
+}
+
+// NivelReordenRequest configura el reabastecimiento de un producto. Con Automatico los niveles se
+// calculan con la velocidad de venta y se ignoran StockMinimo y StockMaximo.
+type NivelReordenRequest struct {
+	ProductoUUID  string `json:"ProductoUUID"`
+	Automatico    bool   `json:"Automatico"`
+	StockMinimo   int    `json:"StockMinimo"`
+	StockMaximo   int    `json:"StockMaximo"`
+	ProveedorUUID string `json:"ProveedorUUID"` // vacío: el de la última compra
+}
+
+// SugerenciaCompra es el pedido sugerido a un proveedor con los productos que llegaron a su punto de reorden.
+type SugerenciaCompra struct {
+	Proveedor Proveedor      `json:"Proveedor"` // vacío: productos que nunca se han comprado
+	Items     []NivelReorden `json:"Items"`
+	Total     float64        `json:"Total"` // al costo promedio
+}
+
+// PedidoProveedor es un pedido enviado a un proveedor. Mientras está PENDIENTE sus unidades cuentan
+// como mercancía en camino.
+type PedidoProveedor struct {
+	CreatedAt     time.Time             `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt     time.Time             `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt     *time.Time            `json:"DeletedAt" ts_type:"string"`
+	UUID          string                `json:"UUID"`
+	ProveedorUUID string                `json:"ProveedorUUID"`
+	Proveedor     Proveedor             `json:"Proveedor"`
+	VendedorUUID  string                `json:"VendedorUUID"`
+	Fecha         time.Time             `json:"Fecha" ts_type:"string"`
+	FechaEsperada string                `json:"FechaEsperada"` // AAAA-MM-DD, según el tiempo de entrega del proveedor
+	Estado        string                `json:"Estado"`        // PENDIENTE, RECIBIDO o ANULADO
+	CompraUUID    string                `json:"CompraUUID"`
+	Observacion   string                `json:"Observacion"`
+	Total         float64               `json:"Total"` // al costo del pedido, no se guarda
+	Items         []ItemPedidoProveedor `json:"Items"`
+}
+
+// ItemPedidoProveedor es un producto pedido, en unidades mínimas, con el costo promedio al pedirlo.
+type ItemPedidoProveedor struct {
+	UUID           string  `json:"UUID"`
+	PedidoUUID     string  `json:"PedidoUUID"`
+	ProductoUUID   string  `json:"ProductoUUID"`
+	ProductoCodigo string  `json:"ProductoCodigo"`
+	ProductoNombre string  `json:"ProductoNombre"`
+	Cantidad       int     `json:"Cantidad"`
+	CostoUnitario  float64 `json:"CostoUnitario"`
+}
+
+type PedidoProveedorRequest struct {
+	ProveedorUUID string                    `json:"ProveedorUUID"`
+	VendedorUUID  string                    `json:"VendedorUUID"`
+	Observacion   string                    `json:"Observacion"`
+	Items         []ItemPedidoProveedorInfo `json:"Items"`
+}
+
+type ItemPedidoProveedorInfo struct {
+	ProductoUUID string `json:"ProductoUUID"`
+	Cantidad     int    `json:"Cantidad"` // unidades mínimas
+}
+
+// ConteoInventario es una sesión de toma física de inventario. Al abrirla se fija el stock esperado de
+// cada producto y lote; al aprobarla las diferencias con lo contado se ajustan en el inventario.
+type ConteoInventario struct {
+	CreatedAt     time.Time  `json:"CreatedAt" ts_type:"string"`
+	UpdatedAt     time.Time  `json:"UpdatedAt" ts_type:"string"`
+	DeletedAt     *time.Time `json:"DeletedAt" ts_type:"string"`
+	UUID          string     `json:"UUID"`
+	Descripcion   string     `json:"Descripcion"`
+	Categoria     string     `json:"Categoria"` // vacío: todos los productos
+	Estado        string     `json:"Estado"`    // ABIERTO, APROBADO o CANCELADO
+	VendedorUUID  string     `json:"VendedorUUID"`
+	AprobadoPor   string     `json:"AprobadoPor"`
+	FechaApertura time.Time  `json:"FechaApertura" ts_type:"string"`
+	FechaCierre   *time.Time `json:"FechaCierre" ts_type:"string"`
+	Observacion   string     `json:"Observacion"`
+}
+
+// ItemConteo es un producto o lote del conteo con el stock esperado al abrir la sesión y lo contado.
+type ItemConteo struct {
+	ProductoUUID     string  `json:"ProductoUUID"`
+	ProductoCodigo   string  `json:"ProductoCodigo"`
+	ProductoNombre   string  `json:"ProductoNombre"`
+	LoteUUID         string  `json:"LoteUUID"` // vacío: stock sin lote
+	NumeroLote       string  `json:"NumeroLote"`
+	FechaVencimiento string  `json:"FechaVencimiento"`
+	StockEsperado    int     `json:"StockEsperado"`
+	CantidadContada  int     `json:"CantidadContada"`
+	Registros        int     `json:"Registros"`  // sin registros el ítem no se ha contado y no se ajusta
+	Diferencia       int     `json:"Diferencia"` // contado menos esperado
+	CostoUnitario    float64 `json:"CostoUnitario"`
+	ValorDiferencia  float64 `json:"ValorDiferencia"`
+}
+
+// ReporteConteo son las diferencias de un conteo en unidades y al costo.
+type ReporteConteo struct {
+	Conteo            ConteoInventario `json:"Conteo"`
+	Items             []ItemConteo     `json:"Items"`
+	ItemsSinContar    int              `json:"ItemsSinContar"`
+	UnidadesSobrantes int              `json:"UnidadesSobrantes"`
+	UnidadesFaltantes int              `json:"UnidadesFaltantes"`
+	ValorSobrante     float64          `json:"ValorSobrante"`
+	ValorFaltante     float64          `json:"ValorFaltante"`
+	ValorNeto         float64          `json:"ValorNeto"`
+}
+
+// RegistroConteo es una cantidad contada desde un equipo. Se corrige anulándolo y registrando otra.
+type RegistroConteo struct {
+	CreatedAt    time.Time `json:"CreatedAt" ts_type:"string"`
+	UUID         string    `json:"UUID"`
+	ConteoUUID   string    `json:"ConteoUUID"`
+	ProductoUUID string    `json:"ProductoUUID"`
+	LoteUUID     string    `json:"LoteUUID"`
+	Cantidad     int       `json:"Cantidad"`
+	VendedorUUID string    `json:"VendedorUUID"`
+	Dispositivo  string    `json:"Dispositivo"`
+}
+
+type ConteoInventarioRequest struct {
+	Descripcion  string `json:"Descripcion"`
+	Categoria    string `json:"Categoria"` // limita el conteo a la categoría y sus subcategorías
+	VendedorUUID string `json:"VendedorUUID"`
+	Observacion  string `json:"Observacion"`
+}
+
+type RegistroConteoRequest struct {
+	ConteoUUID   string `json:"ConteoUUID"`
+	ProductoUUID string `json:"ProductoUUID"`
+	LoteUUID     string `json:"LoteUUID"` // vacío: stock sin lote
+	Cantidad     int    `json:"Cantidad"` // unidades mínimas
+	VendedorUUID string `json:"VendedorUUID"`
+	Dispositivo  string `json:"Dispositivo"`
+}
+
+// AprobacionConteoRequest aprueba un conteo con la autorización de un supervisor, a cuyo nombre quedan
+// los ajustes.
+type AprobacionConteoRequest struct {
+	ConteoUUID   string                  `json:"ConteoUUID"`
+	Observacion  string                  `json:"Observacion"`
+	Autorizacion *AutorizacionSupervisor `json:"Autorizacion,omitempty"`
 }
 
 type PaginatedResult struct {
@@ -234,6 +1160,21 @@ type Db struct {
 	Log       *logrus.Logger
 	syncMutex sync.Mutex
 	jwtKey    []byte
+	// Porcentaje máximo de descuento permitido sin autorización de un supervisor.
+	descuentoMaximo float64
+	// Cédula del vendedor que queda como ADMIN al registrarse, para instalaciones nuevas.
+	adminCedula string
+	// Identificación de la terminal y configuración de su numeración de facturas.
+	terminalID     string
+	prefijoFactura string
+	tamanoRango    int64
+	umbralRango    int64
+	rangoMutex     sync.Mutex
+	// Datos del facturador electrónico y canal de envío a la DIAN.
+	configDIAN   ConfiguracionDIAN
+	enviadorDIAN EnviadorDIAN
+	// Valor en pesos de un punto de fidelización al redimirlo.
+	valorPunto float64
 }
 
 var (
@@ -314,6 +1255,31 @@ func (d *Db) initDB() {
 	d.jwtKey = []byte(secret)
 	d.Log.Info("Clave secreta JWT cargada exitosamente.")
 
+	d.adminCedula = strings.TrimSpace(os.Getenv("ADMIN_CEDULA"))
+
+	d.descuentoMaximo = DescuentoMaximoPorDefecto
+	if v := os.Getenv("DESCUENTO_MAXIMO"); v != "" {
+		maximo, err := strconv.ParseFloat(v, 64)
+		if err != nil || maximo < 0 || maximo > 100 {
+			d.Log.Warnf("DESCUENTO_MAXIMO inválido (%s), se usará %.0f%%", v, DescuentoMaximoPorDefecto)
+		} else {
+			d.descuentoMaximo = maximo
+		}
+	}
+
+	d.valorPunto = ValorPuntoPorDefecto
+	if v := os.Getenv("PUNTOS_VALOR_PUNTO"); v != "" {
+		valor, err := strconv.ParseFloat(v, 64)
+		if err != nil || valor <= 0 {
+			d.Log.Warnf("PUNTOS_VALOR_PUNTO inválido (%s), se usará %.0f", v, ValorPuntoPorDefecto)
+		} else {
+			d.valorPunto = valor
+		}
+	}
+
+	d.cargarConfiguracionNumeracion()
+	d.cargarConfiguracionDIAN()
+
 	remoteDSN := os.Getenv("DATABASE_URL")
 	if remoteDSN != "" {
 		d.RemoteDB, err = d.NewRemoteDB(remoteDSN)
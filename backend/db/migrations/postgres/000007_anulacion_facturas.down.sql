@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS public.idx_facturas_estado;
+
+ALTER TABLE public.facturas DROP CONSTRAINT IF EXISTS fk_facturas_anulada_por;
+
+ALTER TABLE public.facturas
+DROP COLUMN IF EXISTS anulada_por_uuid,
+DROP COLUMN IF EXISTS fecha_anulacion,
+DROP COLUMN IF EXISTS motivo_anulacion;
@@ -0,0 +1,8 @@
+-- Soporte para anulación de facturas
+ALTER TABLE public.facturas ADD COLUMN IF NOT EXISTS motivo_anulacion text;
+ALTER TABLE public.facturas ADD COLUMN IF NOT EXISTS fecha_anulacion timestamp with time zone;
+ALTER TABLE public.facturas ADD COLUMN IF NOT EXISTS anulada_por_uuid uuid;
+
+ALTER TABLE public.facturas ADD CONSTRAINT fk_facturas_anulada_por FOREIGN KEY (anulada_por_uuid) REFERENCES public.vendedors (uuid);
+
+CREATE INDEX IF NOT EXISTS idx_facturas_estado ON public.facturas USING btree (estado);
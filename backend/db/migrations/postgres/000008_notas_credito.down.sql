@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.detalle_notas_credito;
+
+DROP TABLE IF EXISTS public.notas_credito;
@@ -0,0 +1,42 @@
+-- Devoluciones de clientes y notas crédito
+CREATE TABLE IF NOT EXISTS public.notas_credito (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    numero_nota text not null,
+    factura_uuid uuid not null,
+    fecha_emision timestamp with time zone null,
+    vendedor_uuid uuid null,
+    cliente_uuid uuid null,
+    motivo text null,
+    subtotal numeric null,
+    iva numeric null,
+    total numeric null,
+    constraint notas_credito_pkey primary key (uuid),
+    constraint uni_notas_credito_numero_nota unique (numero_nota),
+    constraint fk_notas_credito_factura foreign KEY (factura_uuid) references facturas (uuid) on update CASCADE,
+    constraint fk_notas_credito_vendedor foreign KEY (vendedor_uuid) references vendedors (uuid),
+    constraint fk_notas_credito_cliente foreign KEY (cliente_uuid) references clientes (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS public.detalle_notas_credito (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    nota_credito_uuid uuid not null,
+    detalle_factura_uuid uuid not null,
+    producto_uuid uuid not null,
+    cantidad bigint null,
+    precio_unitario numeric null,
+    precio_total numeric null,
+    constraint detalle_notas_credito_pkey primary key (uuid),
+    constraint fk_detalle_notas_credito_nota foreign KEY (nota_credito_uuid) references notas_credito (uuid) on update CASCADE on delete CASCADE,
+    constraint fk_detalle_notas_credito_detalle foreign KEY (detalle_factura_uuid) references detalle_facturas (uuid),
+    constraint fk_detalle_notas_credito_producto foreign KEY (producto_uuid) references productos (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notas_credito_factura_uuid ON public.notas_credito USING btree (factura_uuid);
+
+CREATE INDEX IF NOT EXISTS idx_detalle_notas_credito_detalle_factura_uuid ON public.detalle_notas_credito USING btree (detalle_factura_uuid);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS public.factura_impuestos;
+
+ALTER TABLE public.detalle_facturas
+DROP COLUMN IF EXISTS valor_impuesto,
+DROP COLUMN IF EXISTS tarifa_impuesto,
+DROP COLUMN IF EXISTS base_impuesto,
+DROP COLUMN IF EXISTS impuesto_codigo;
+
+ALTER TABLE public.productos DROP COLUMN IF EXISTS impuesto_codigo;
+
+DROP TABLE IF EXISTS public.impuestos;
@@ -0,0 +1,45 @@
+-- Motor de impuestos por producto
+CREATE TABLE IF NOT EXISTS public.impuestos (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    codigo text not null,
+    nombre text null,
+    tarifa numeric not null default 0,
+    constraint impuestos_pkey primary key (uuid),
+    constraint uni_impuestos_codigo unique (codigo)
+);
+
+INSERT INTO public.impuestos (created_at, updated_at, uuid, codigo, nombre, tarifa)
+VALUES
+    (now(), now(), '0b6f3e5a-2f1c-4d7e-9a51-1c0e9f2b7a19', 'IVA_19', 'IVA 19%', 19),
+    (now(), now(), '5c2d8a41-7e3b-4f06-8d2a-6b9e0c1f4a05', 'IVA_5', 'IVA 5%', 5),
+    (now(), now(), '9e4a7b10-3c5d-4e8f-a612-7d0b2c9e8f00', 'EXENTO', 'Exento', 0),
+    (now(), now(), 'd1f0c6e2-8a4b-4c3d-b7e5-2f9a1e0d3c00', 'EXCLUIDO', 'Excluido', 0)
+ON CONFLICT (codigo) DO NOTHING;
+
+ALTER TABLE public.productos ADD COLUMN IF NOT EXISTS impuesto_codigo text not null default 'EXCLUIDO';
+
+ALTER TABLE public.detalle_facturas
+ADD COLUMN IF NOT EXISTS impuesto_codigo text,
+ADD COLUMN IF NOT EXISTS base_impuesto numeric not null default 0,
+ADD COLUMN IF NOT EXISTS tarifa_impuesto numeric not null default 0,
+ADD COLUMN IF NOT EXISTS valor_impuesto numeric not null default 0;
+
+-- Las ventas anteriores no tenían impuesto: su base es el total de la línea.
+UPDATE public.detalle_facturas SET base_impuesto = precio_total WHERE base_impuesto = 0;
+
+CREATE TABLE IF NOT EXISTS public.factura_impuestos (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    uuid uuid not null,
+    factura_uuid uuid not null,
+    impuesto_codigo text null,
+    tarifa numeric not null,
+    base numeric not null,
+    valor numeric not null,
+    constraint factura_impuestos_pkey primary key (uuid),
+    constraint factura_impuestos_factura_tarifa_key unique (factura_uuid, tarifa),
+    constraint fk_factura_impuestos_factura foreign KEY (factura_uuid) references facturas (uuid) on update CASCADE on delete CASCADE
+);
@@ -0,0 +1,15 @@
+ALTER TABLE public.detalle_facturas
+DROP COLUMN IF EXISTS motivo_descuento,
+DROP COLUMN IF EXISTS descuento,
+DROP COLUMN IF EXISTS valor_bruto;
+
+ALTER TABLE public.facturas DROP CONSTRAINT IF EXISTS fk_facturas_descuento_autorizado_por;
+
+ALTER TABLE public.facturas
+DROP COLUMN IF EXISTS descuento_autorizado_por,
+DROP COLUMN IF EXISTS motivo_descuento,
+DROP COLUMN IF EXISTS descuento_factura,
+DROP COLUMN IF EXISTS descuento,
+DROP COLUMN IF EXISTS valor_bruto;
+
+ALTER TABLE public.vendedors DROP COLUMN IF EXISTS rol;
@@ -0,0 +1,25 @@
+-- Descuentos por línea y por factura, y rol de vendedor para autorizaciones
+ALTER TABLE public.vendedors ADD COLUMN IF NOT EXISTS rol text not null default 'VENDEDOR';
+
+-- El vendedor más antiguo queda como ADMIN para que alguien pueda asignar los demás roles.
+UPDATE public.vendedors SET rol = 'ADMIN'
+WHERE uuid = (SELECT uuid FROM public.vendedors WHERE deleted_at IS NULL ORDER BY created_at ASC LIMIT 1)
+AND NOT EXISTS (SELECT 1 FROM public.vendedors WHERE rol = 'ADMIN');
+
+ALTER TABLE public.facturas
+ADD COLUMN IF NOT EXISTS valor_bruto numeric not null default 0,
+ADD COLUMN IF NOT EXISTS descuento numeric not null default 0,
+ADD COLUMN IF NOT EXISTS descuento_factura numeric not null default 0,
+ADD COLUMN IF NOT EXISTS motivo_descuento text,
+ADD COLUMN IF NOT EXISTS descuento_autorizado_por uuid;
+
+ALTER TABLE public.facturas ADD CONSTRAINT fk_facturas_descuento_autorizado_por FOREIGN KEY (descuento_autorizado_por) REFERENCES public.vendedors (uuid);
+
+ALTER TABLE public.detalle_facturas
+ADD COLUMN IF NOT EXISTS valor_bruto numeric not null default 0,
+ADD COLUMN IF NOT EXISTS descuento numeric not null default 0,
+ADD COLUMN IF NOT EXISTS motivo_descuento text;
+
+-- Las ventas anteriores no tenían descuentos: el bruto es igual al neto.
+UPDATE public.facturas SET valor_bruto = total WHERE valor_bruto = 0;
+UPDATE public.detalle_facturas SET valor_bruto = precio_total WHERE valor_bruto = 0;
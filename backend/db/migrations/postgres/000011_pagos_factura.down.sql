@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.idx_pagos_factura_factura_uuid;
+
+DROP TABLE IF EXISTS public.pagos_factura;
@@ -0,0 +1,24 @@
+-- Pagos combinados: una fila por cada medio de pago usado en la factura
+CREATE TABLE IF NOT EXISTS public.pagos_factura (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    factura_uuid uuid not null,
+    metodo_pago text not null,
+    monto numeric not null,
+    referencia text null,
+    efectivo_recibido numeric not null default 0,
+    cambio numeric not null default 0,
+    constraint pagos_factura_pkey primary key (uuid),
+    constraint fk_pagos_factura_factura foreign KEY (factura_uuid) references facturas (uuid) on update CASCADE on delete CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_pagos_factura_factura_uuid ON public.pagos_factura USING btree (factura_uuid);
+
+-- Las facturas anteriores quedan con un único pago por el total. Se reutiliza el UUID de la
+-- factura para que el registro sea el mismo en la base local y en la remota.
+INSERT INTO public.pagos_factura (created_at, updated_at, uuid, factura_uuid, metodo_pago, monto)
+SELECT created_at, COALESCE(updated_at, created_at), uuid, uuid, COALESCE(metodo_pago, 'efectivo'), COALESCE(total, 0)
+FROM public.facturas
+ON CONFLICT (uuid) DO NOTHING;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS public.idx_facturas_sesion_caja_uuid;
+
+ALTER TABLE public.notas_credito DROP COLUMN IF EXISTS sesion_caja_uuid;
+ALTER TABLE public.facturas DROP COLUMN IF EXISTS sesion_caja_uuid;
+
+DROP TABLE IF EXISTS public.conteos_caja;
+
+DROP INDEX IF EXISTS public.idx_sesiones_caja_vendedor_estado;
+
+DROP TABLE IF EXISTS public.sesiones_caja;
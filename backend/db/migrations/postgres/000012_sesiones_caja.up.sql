@@ -0,0 +1,40 @@
+-- Sesiones de caja (apertura / cierre) con arqueo ciego por método de pago
+CREATE TABLE IF NOT EXISTS public.sesiones_caja (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    vendedor_uuid uuid not null,
+    fecha_apertura timestamp with time zone not null,
+    monto_apertura numeric not null default 0,
+    fecha_cierre timestamp with time zone null,
+    estado text not null default 'ABIERTA',
+    total_esperado numeric not null default 0,
+    total_contado numeric not null default 0,
+    diferencia numeric not null default 0,
+    observaciones text null,
+    constraint sesiones_caja_pkey primary key (uuid),
+    constraint fk_sesiones_caja_vendedor foreign KEY (vendedor_uuid) references vendedors (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sesiones_caja_vendedor_estado ON public.sesiones_caja USING btree (vendedor_uuid, estado);
+
+CREATE TABLE IF NOT EXISTS public.conteos_caja (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    uuid uuid not null,
+    sesion_caja_uuid uuid not null,
+    metodo_pago text not null,
+    esperado numeric not null default 0,
+    contado numeric not null default 0,
+    diferencia numeric not null default 0,
+    constraint conteos_caja_pkey primary key (uuid),
+    constraint conteos_caja_sesion_metodo_key unique (sesion_caja_uuid, metodo_pago),
+    constraint fk_conteos_caja_sesion foreign KEY (sesion_caja_uuid) references sesiones_caja (uuid) on update CASCADE on delete CASCADE
+);
+
+-- Sin llave foránea: las facturas y notas de otras terminales pueden llegar antes que su sesión.
+ALTER TABLE public.facturas ADD COLUMN IF NOT EXISTS sesion_caja_uuid uuid;
+ALTER TABLE public.notas_credito ADD COLUMN IF NOT EXISTS sesion_caja_uuid uuid;
+
+CREATE INDEX IF NOT EXISTS idx_facturas_sesion_caja_uuid ON public.facturas USING btree (sesion_caja_uuid);
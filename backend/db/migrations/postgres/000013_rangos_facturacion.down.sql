@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS public.idx_rangos_facturacion_terminal_id;
+
+DROP TABLE IF EXISTS public.rangos_facturacion;
+
+DROP TABLE IF EXISTS public.series_facturacion;
+
+DROP TABLE IF EXISTS public.terminales;
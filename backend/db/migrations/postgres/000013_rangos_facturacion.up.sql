@@ -0,0 +1,31 @@
+-- Numeración de facturas por terminal: cada terminal tiene su prefijo y recibe rangos
+-- disjuntos de la serie de ese prefijo, de modo que puede facturar sin conexión sin colisiones.
+CREATE TABLE IF NOT EXISTS public.terminales (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    terminal_id text not null,
+    prefijo text not null default 'FAC-',
+    tamano_rango integer not null default 500,
+    constraint terminales_pkey primary key (terminal_id)
+);
+
+CREATE TABLE IF NOT EXISTS public.series_facturacion (
+    updated_at timestamp with time zone null,
+    prefijo text not null,
+    siguiente_numero bigint not null default 1000,
+    constraint series_facturacion_pkey primary key (prefijo)
+);
+
+CREATE TABLE IF NOT EXISTS public.rangos_facturacion (
+    created_at timestamp with time zone null,
+    uuid uuid not null,
+    terminal_id text not null,
+    prefijo text not null,
+    desde bigint not null,
+    hasta bigint not null,
+    constraint rangos_facturacion_pkey primary key (uuid),
+    constraint uni_rangos_facturacion_prefijo_desde unique (prefijo, desde),
+    constraint fk_rangos_facturacion_terminal foreign KEY (terminal_id) references terminales (terminal_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rangos_facturacion_terminal_id ON public.rangos_facturacion USING btree (terminal_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.idx_facturas_electronicas_cufe;
+
+DROP TABLE IF EXISTS public.facturas_electronicas;
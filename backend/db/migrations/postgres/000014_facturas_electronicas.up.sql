@@ -0,0 +1,20 @@
+-- Factura electrónica DIAN (UBL 2.1): XML generado, CUFE y resultado del envío.
+-- Sin llave foránea: el documento puede llegar antes que la factura de otra terminal.
+CREATE TABLE IF NOT EXISTS public.facturas_electronicas (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    uuid uuid not null,
+    factura_uuid uuid not null,
+    numero_factura text not null,
+    cufe text not null,
+    qr text null,
+    xml text not null,
+    ambiente text not null,
+    estado text not null default 'GENERADA',
+    mensaje_dian text null,
+    fecha_envio timestamp with time zone null,
+    constraint facturas_electronicas_pkey primary key (uuid),
+    constraint uni_facturas_electronicas_factura_uuid unique (factura_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_facturas_electronicas_cufe ON public.facturas_electronicas USING btree (cufe);
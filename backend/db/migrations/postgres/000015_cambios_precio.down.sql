@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS public.idx_cambios_precio_created_at;
+
+DROP INDEX IF EXISTS public.idx_cambios_precio_factura_uuid;
+
+DROP TABLE IF EXISTS public.cambios_precio;
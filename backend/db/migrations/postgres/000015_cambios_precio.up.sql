@@ -0,0 +1,22 @@
+-- Precios de venta distintos al de lista, autorizados por un supervisor
+CREATE TABLE IF NOT EXISTS public.cambios_precio (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    uuid uuid not null,
+    factura_uuid uuid not null,
+    detalle_factura_uuid uuid not null,
+    producto_uuid uuid not null,
+    cantidad integer not null,
+    precio_original numeric not null,
+    precio_nuevo numeric not null,
+    vendedor_uuid uuid not null,
+    autorizado_por_uuid uuid not null,
+    motivo text not null,
+    constraint cambios_precio_pkey primary key (uuid),
+    constraint fk_cambios_precio_factura foreign KEY (factura_uuid) references facturas (uuid) on update CASCADE on delete CASCADE,
+    constraint fk_cambios_precio_producto foreign KEY (producto_uuid) references productos (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cambios_precio_factura_uuid ON public.cambios_precio USING btree (factura_uuid);
+
+CREATE INDEX IF NOT EXISTS idx_cambios_precio_created_at ON public.cambios_precio USING btree (created_at);
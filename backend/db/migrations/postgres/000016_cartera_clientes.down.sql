@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS public.idx_abono_facturas_factura_uuid;
+
+DROP TABLE IF EXISTS public.abono_facturas;
+
+DROP INDEX IF EXISTS public.idx_abonos_updated_at;
+
+DROP INDEX IF EXISTS public.idx_abonos_cliente_uuid;
+
+DROP TABLE IF EXISTS public.abonos;
+
+ALTER TABLE public.notas_credito DROP COLUMN IF EXISTS aplicado_cartera;
+
+ALTER TABLE public.clientes DROP COLUMN IF EXISTS limite_credito;
@@ -0,0 +1,43 @@
+-- Ventas a crédito: cupo por cliente y abonos aplicados a las facturas pendientes
+ALTER TABLE public.clientes ADD COLUMN IF NOT EXISTS limite_credito numeric not null default 0;
+
+-- Parte de la nota crédito que se descontó del saldo pendiente de la factura en lugar de reembolsarse.
+ALTER TABLE public.notas_credito ADD COLUMN IF NOT EXISTS aplicado_cartera numeric not null default 0;
+
+CREATE TABLE IF NOT EXISTS public.abonos (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    numero_abono text not null,
+    cliente_uuid uuid not null,
+    vendedor_uuid uuid not null,
+    sesion_caja_uuid uuid null,
+    fecha timestamp with time zone not null,
+    monto numeric not null,
+    metodo_pago text not null,
+    referencia text null,
+    observaciones text null,
+    constraint abonos_pkey primary key (uuid),
+    constraint abonos_numero_abono_key unique (numero_abono),
+    constraint fk_abonos_cliente foreign KEY (cliente_uuid) references clientes (uuid),
+    constraint fk_abonos_vendedor foreign KEY (vendedor_uuid) references vendedors (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_abonos_cliente_uuid ON public.abonos USING btree (cliente_uuid);
+
+CREATE INDEX IF NOT EXISTS idx_abonos_updated_at ON public.abonos USING btree (updated_at);
+
+CREATE TABLE IF NOT EXISTS public.abono_facturas (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    uuid uuid not null,
+    abono_uuid uuid not null,
+    factura_uuid uuid not null,
+    monto numeric not null,
+    constraint abono_facturas_pkey primary key (uuid),
+    constraint fk_abono_facturas_abono foreign KEY (abono_uuid) references abonos (uuid) on update CASCADE on delete CASCADE,
+    constraint fk_abono_facturas_factura foreign KEY (factura_uuid) references facturas (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_abono_facturas_factura_uuid ON public.abono_facturas USING btree (factura_uuid);
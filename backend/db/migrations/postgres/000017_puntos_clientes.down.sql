@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS public.idx_movimientos_puntos_timestamp;
+
+DROP INDEX IF EXISTS public.idx_movimientos_puntos_cliente_uuid;
+
+DROP TABLE IF EXISTS public.movimientos_puntos;
+
+DROP TABLE IF EXISTS public.reglas_puntos;
+
+ALTER TABLE public.productos DROP COLUMN IF EXISTS categoria;
@@ -0,0 +1,41 @@
+-- Programa de puntos: categoría de producto para las reglas de acumulación y libro de movimientos por cliente
+ALTER TABLE public.productos ADD COLUMN IF NOT EXISTS categoria text null;
+
+-- Una regla sin categoría es la general; monto_por_punto = 0 excluye la categoría de la acumulación.
+CREATE TABLE IF NOT EXISTS public.reglas_puntos (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    codigo text not null,
+    nombre text null,
+    categoria text null,
+    monto_por_punto numeric not null default 0,
+    constraint reglas_puntos_pkey primary key (uuid),
+    constraint uni_reglas_puntos_codigo unique (codigo)
+);
+
+INSERT INTO public.reglas_puntos (created_at, updated_at, uuid, codigo, nombre, categoria, monto_por_punto)
+VALUES
+    (now(), now(), '7f3c2a10-5b8e-4d61-9c0a-3e1f6b2d8a47', 'GENERAL', '1 punto por cada $1.000', null, 1000)
+ON CONFLICT (codigo) DO NOTHING;
+
+-- El saldo de puntos de un cliente es siempre SUM(puntos) de sus movimientos.
+CREATE TABLE IF NOT EXISTS public.movimientos_puntos (
+    uuid uuid not null,
+    cliente_uuid uuid not null,
+    tipo text not null,
+    puntos bigint not null,
+    saldo_resultante bigint not null,
+    factura_uuid uuid null,
+    vendedor_uuid uuid null,
+    descripcion text null,
+    timestamp timestamp with time zone not null,
+    constraint movimientos_puntos_pkey primary key (uuid),
+    constraint fk_movimientos_puntos_cliente foreign KEY (cliente_uuid) references clientes (uuid),
+    constraint fk_movimientos_puntos_factura foreign KEY (factura_uuid) references facturas (uuid) on update CASCADE on delete set null
+);
+
+CREATE INDEX IF NOT EXISTS idx_movimientos_puntos_cliente_uuid ON public.movimientos_puntos USING btree (cliente_uuid);
+
+CREATE INDEX IF NOT EXISTS idx_movimientos_puntos_timestamp ON public.movimientos_puntos USING btree (timestamp);
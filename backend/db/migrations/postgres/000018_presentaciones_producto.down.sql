@@ -0,0 +1,15 @@
+ALTER TABLE public.detalle_compras
+DROP COLUMN IF EXISTS factor_conversion,
+DROP COLUMN IF EXISTS presentacion_uuid;
+
+ALTER TABLE public.detalle_facturas
+DROP COLUMN IF EXISTS factor_conversion,
+DROP COLUMN IF EXISTS presentacion,
+DROP COLUMN IF EXISTS presentacion_uuid;
+
+ALTER TABLE public.detalle_facturas
+ADD CONSTRAINT detalle_facturas_factura_producto_key UNIQUE (factura_uuid, producto_uuid);
+
+DROP INDEX IF EXISTS public.idx_presentaciones_producto_producto_uuid;
+
+DROP TABLE IF EXISTS public.presentaciones_producto;
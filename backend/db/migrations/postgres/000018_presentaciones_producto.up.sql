@@ -0,0 +1,31 @@
+-- Presentaciones de venta y compra (caja, blíster, unidad). El stock se lleva en la unidad mínima
+-- del producto y cada presentación indica cuántas unidades mínimas contiene.
+CREATE TABLE IF NOT EXISTS public.presentaciones_producto (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    producto_uuid uuid not null,
+    nombre text not null,
+    factor bigint not null default 1,
+    precio_venta numeric not null default 0,
+    codigo_barras text null,
+    constraint presentaciones_producto_pkey primary key (uuid),
+    constraint uni_presentaciones_producto_codigo_barras unique (codigo_barras),
+    constraint fk_presentaciones_producto_producto foreign KEY (producto_uuid) references productos (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_presentaciones_producto_producto_uuid ON public.presentaciones_producto USING btree (producto_uuid);
+
+-- Una factura puede traer el mismo producto en varias presentaciones (caja y blíster).
+ALTER TABLE public.detalle_facturas
+DROP CONSTRAINT IF EXISTS detalle_facturas_factura_producto_key;
+
+ALTER TABLE public.detalle_facturas
+ADD COLUMN IF NOT EXISTS presentacion_uuid uuid null,
+ADD COLUMN IF NOT EXISTS presentacion text null,
+ADD COLUMN IF NOT EXISTS factor_conversion bigint not null default 1;
+
+ALTER TABLE public.detalle_compras
+ADD COLUMN IF NOT EXISTS presentacion_uuid uuid null,
+ADD COLUMN IF NOT EXISTS factor_conversion bigint not null default 1;
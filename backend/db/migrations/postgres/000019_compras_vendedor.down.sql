@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.idx_detalle_compras_compra_uuid;
+
+ALTER TABLE public.compras DROP COLUMN IF EXISTS vendedor_uuid;
@@ -0,0 +1,5 @@
+-- Vendedor que registró la compra; las operaciones de stock de la compra quedan a su nombre.
+ALTER TABLE public.compras
+ADD COLUMN IF NOT EXISTS vendedor_uuid uuid null;
+
+CREATE INDEX IF NOT EXISTS idx_detalle_compras_compra_uuid ON public.detalle_compras USING btree (compra_uuid);
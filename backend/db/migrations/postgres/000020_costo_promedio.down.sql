@@ -0,0 +1,3 @@
+ALTER TABLE public.detalle_facturas DROP COLUMN IF EXISTS costo_unitario;
+
+ALTER TABLE public.productos DROP COLUMN IF EXISTS costo_promedio;
@@ -0,0 +1,7 @@
+-- Costo promedio ponderado por unidad mínima, actualizado en cada compra.
+ALTER TABLE public.productos
+ADD COLUMN IF NOT EXISTS costo_promedio numeric not null default 0;
+
+-- Costo de la presentación vendida al momento de la venta, para calcular la utilidad bruta.
+ALTER TABLE public.detalle_facturas
+ADD COLUMN IF NOT EXISTS costo_unitario numeric not null default 0;
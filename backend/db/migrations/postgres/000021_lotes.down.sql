@@ -0,0 +1,9 @@
+ALTER TABLE public.detalle_compras DROP COLUMN IF EXISTS lote_uuid;
+
+DROP INDEX IF EXISTS public.idx_operacion_stocks_lote_uuid;
+
+ALTER TABLE public.operacion_stocks DROP COLUMN IF EXISTS lote_uuid;
+
+DROP INDEX IF EXISTS public.idx_lotes_fecha_vencimiento;
+
+DROP TABLE IF EXISTS public.lotes;
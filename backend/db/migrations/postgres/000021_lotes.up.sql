@@ -0,0 +1,24 @@
+-- Lotes de cada producto con su fecha de vencimiento. El stock de un lote es la suma
+-- de las operaciones de stock que lo referencian.
+CREATE TABLE IF NOT EXISTS public.lotes (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    producto_uuid uuid not null,
+    numero_lote text not null,
+    fecha_vencimiento date not null,
+    constraint lotes_pkey primary key (uuid),
+    constraint uni_lotes_producto_numero unique (producto_uuid, numero_lote),
+    constraint fk_lotes_producto foreign KEY (producto_uuid) references productos (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lotes_fecha_vencimiento ON public.lotes USING btree (fecha_vencimiento);
+
+ALTER TABLE public.operacion_stocks
+ADD COLUMN IF NOT EXISTS lote_uuid uuid null;
+
+CREATE INDEX IF NOT EXISTS idx_operacion_stocks_lote_uuid ON public.operacion_stocks USING btree (lote_uuid);
+
+ALTER TABLE public.detalle_compras
+ADD COLUMN IF NOT EXISTS lote_uuid uuid null;
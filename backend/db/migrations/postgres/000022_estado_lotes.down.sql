@@ -0,0 +1,3 @@
+ALTER TABLE public.lotes
+DROP COLUMN IF EXISTS observacion,
+DROP COLUMN IF EXISTS estado;
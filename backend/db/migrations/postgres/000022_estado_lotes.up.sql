@@ -0,0 +1,5 @@
+-- Estado del lote: VIGENTE, CUARENTENA o DESTRUIDO. Los lotes retirados no se venden y sus
+-- existencias salen del inventario con una operación de stock.
+ALTER TABLE public.lotes
+ADD COLUMN IF NOT EXISTS estado text not null default 'VIGENTE',
+ADD COLUMN IF NOT EXISTS observacion text;
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS trg_libro_controlados_inmutable ON public.libro_controlados;
+
+DROP FUNCTION IF EXISTS public.libro_controlados_inmutable ();
+
+DROP INDEX IF EXISTS public.idx_libro_controlados_producto_fecha;
+
+DROP TABLE IF EXISTS public.libro_controlados;
+
+ALTER TABLE public.productos DROP COLUMN IF EXISTS controlado;
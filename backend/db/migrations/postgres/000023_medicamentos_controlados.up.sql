@@ -0,0 +1,40 @@
+-- Medicamentos de control (FNE): los productos marcados llevan un libro de movimientos con saldo
+ALTER TABLE public.productos ADD COLUMN IF NOT EXISTS controlado boolean not null default false;
+
+-- Libro oficial de medicamentos de control. Cada operación de stock de un producto controlado deja un
+-- asiento con el saldo resultante; las dispensaciones guardan los datos de la fórmula médica.
+CREATE TABLE IF NOT EXISTS public.libro_controlados (
+    uuid uuid not null,
+    producto_uuid uuid not null,
+    operacion_stock_uuid uuid null,
+    tipo_operacion text not null,
+    cantidad bigint not null,
+    saldo bigint not null,
+    lote_uuid uuid null,
+    factura_uuid uuid null,
+    vendedor_uuid uuid null,
+    prescriptor text null,
+    registro_prescriptor text null,
+    paciente_id text null,
+    numero_formula text null,
+    fecha timestamp with time zone not null,
+    constraint libro_controlados_pkey primary key (uuid),
+    constraint uni_libro_controlados_operacion unique (operacion_stock_uuid),
+    constraint fk_libro_controlados_producto foreign KEY (producto_uuid) references productos (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_libro_controlados_producto_fecha ON public.libro_controlados USING btree (producto_uuid, fecha);
+
+-- El libro es de solo inserción.
+CREATE OR REPLACE FUNCTION public.libro_controlados_inmutable () RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'el libro de controlados es de solo inserción';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_libro_controlados_inmutable ON public.libro_controlados;
+
+CREATE TRIGGER trg_libro_controlados_inmutable BEFORE
+UPDATE
+OR DELETE ON public.libro_controlados FOR EACH ROW
+EXECUTE FUNCTION public.libro_controlados_inmutable ();
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS public.idx_facturas_formula_uuid;
+
+ALTER TABLE public.facturas DROP COLUMN IF EXISTS formula_uuid;
+
+DROP TABLE IF EXISTS public.items_formula;
+
+DROP INDEX IF EXISTS public.idx_formulas_medicas_paciente_id;
+
+DROP TABLE IF EXISTS public.formulas_medicas;
+
+ALTER TABLE public.productos DROP COLUMN IF EXISTS requiere_formula;
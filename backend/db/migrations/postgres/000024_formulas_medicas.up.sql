@@ -0,0 +1,42 @@
+-- Productos de venta bajo fórmula médica (no necesariamente de control)
+ALTER TABLE public.productos ADD COLUMN IF NOT EXISTS requiere_formula boolean not null default false;
+
+-- Fórmulas médicas presentadas en la farmacia. Una fórmula puede dispensarse en varias ventas; lo
+-- dispensado de cada ítem se calcula con los detalles de las facturas no anuladas que la referencian.
+CREATE TABLE IF NOT EXISTS public.formulas_medicas (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    numero_formula text not null,
+    fecha_formula date not null,
+    medico text not null,
+    registro_medico text not null,
+    paciente_id text not null,
+    paciente_nombre text null,
+    cliente_uuid uuid null,
+    constraint formulas_medicas_pkey primary key (uuid),
+    constraint uni_formulas_medicas_registro_numero unique (registro_medico, numero_formula)
+);
+
+CREATE INDEX IF NOT EXISTS idx_formulas_medicas_paciente_id ON public.formulas_medicas USING btree (paciente_id);
+
+-- Productos prescritos en cada fórmula, en unidades mínimas.
+CREATE TABLE IF NOT EXISTS public.items_formula (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    formula_uuid uuid not null,
+    producto_uuid uuid not null,
+    cantidad_prescrita bigint not null,
+    constraint items_formula_pkey primary key (uuid),
+    constraint uni_items_formula_producto unique (formula_uuid, producto_uuid),
+    constraint fk_items_formula_formula foreign KEY (formula_uuid) references formulas_medicas (uuid),
+    constraint fk_items_formula_producto foreign KEY (producto_uuid) references productos (uuid)
+);
+
+ALTER TABLE public.facturas
+ADD COLUMN IF NOT EXISTS formula_uuid uuid null;
+
+CREATE INDEX IF NOT EXISTS idx_facturas_formula_uuid ON public.facturas USING btree (formula_uuid);
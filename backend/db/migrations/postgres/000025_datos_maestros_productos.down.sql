@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS public.categorias_producto;
+
+DROP INDEX IF EXISTS public.idx_productos_principio_activo;
+
+ALTER TABLE public.productos
+DROP COLUMN IF EXISTS principio_activo,
+DROP COLUMN IF EXISTS concentracion,
+DROP COLUMN IF EXISTS forma_farmaceutica,
+DROP COLUMN IF EXISTS laboratorio,
+DROP COLUMN IF EXISTS registro_invima,
+DROP COLUMN IF EXISTS vencimiento_invima,
+DROP COLUMN IF EXISTS codigo_atc;
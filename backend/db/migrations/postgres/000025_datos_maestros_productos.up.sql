@@ -0,0 +1,24 @@
+-- Datos maestros farmacéuticos del producto. El registro sanitario INVIMA lleva su fecha de vencimiento
+-- y el código ATC la clasificación anatómica, terapéutica y química de la OMS.
+ALTER TABLE public.productos
+ADD COLUMN IF NOT EXISTS principio_activo text null,
+ADD COLUMN IF NOT EXISTS concentracion text null,
+ADD COLUMN IF NOT EXISTS forma_farmaceutica text null,
+ADD COLUMN IF NOT EXISTS laboratorio text null,
+ADD COLUMN IF NOT EXISTS registro_invima text null,
+ADD COLUMN IF NOT EXISTS vencimiento_invima date null,
+ADD COLUMN IF NOT EXISTS codigo_atc text null;
+
+CREATE INDEX IF NOT EXISTS idx_productos_principio_activo ON public.productos USING btree (principio_activo);
+
+-- Árbol de categorías. Los productos y las reglas de puntos siguen referenciando la categoría por nombre.
+CREATE TABLE IF NOT EXISTS public.categorias_producto (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    nombre text not null,
+    categoria_padre text null,
+    constraint categorias_producto_pkey primary key (uuid),
+    constraint uni_categorias_producto_nombre unique (nombre)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.idx_codigos_barras_producto_producto;
+
+DROP TABLE IF EXISTS public.codigos_barras_producto;
@@ -0,0 +1,17 @@
+-- Códigos de barras adicionales de un producto (EAN de cada distribuidor, GTIN de los códigos GS1).
+-- Con presentacion_uuid el código corresponde a esa presentación; si no, a la unidad mínima.
+CREATE TABLE IF NOT EXISTS public.codigos_barras_producto (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    codigo text not null,
+    producto_uuid uuid not null,
+    presentacion_uuid uuid null,
+    descripcion text null,
+    constraint codigos_barras_producto_pkey primary key (uuid),
+    constraint uni_codigos_barras_producto_codigo unique (codigo),
+    constraint fk_codigos_barras_producto_producto foreign KEY (producto_uuid) references productos (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_codigos_barras_producto_producto ON public.codigos_barras_producto USING btree (producto_uuid);
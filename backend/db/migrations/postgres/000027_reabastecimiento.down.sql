@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS public.idx_items_pedido_proveedor_producto;
+
+DROP TABLE IF EXISTS public.items_pedido_proveedor;
+
+DROP INDEX IF EXISTS public.idx_pedidos_proveedor_estado;
+
+DROP TABLE IF EXISTS public.pedidos_proveedor;
+
+DROP TABLE IF EXISTS public.niveles_reorden;
+
+ALTER TABLE public.proveedors DROP COLUMN IF EXISTS dias_entrega;
@@ -0,0 +1,60 @@
+-- Tiempo de entrega del proveedor en días, desde el pedido hasta la recepción de la mercancía.
+ALTER TABLE public.proveedors ADD COLUMN IF NOT EXISTS dias_entrega bigint not null default 0;
+
+-- Niveles de reabastecimiento por producto, en unidades mínimas. Con automatico los niveles se calculan
+-- con la velocidad de venta y los valores guardados no se usan. Sin proveedor_uuid se sugiere al
+-- proveedor de la última compra del producto.
+CREATE TABLE IF NOT EXISTS public.niveles_reorden (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    producto_uuid uuid not null,
+    automatico boolean not null default false,
+    stock_minimo bigint not null default 0,
+    stock_maximo bigint not null default 0,
+    proveedor_uuid uuid null,
+    constraint niveles_reorden_pkey primary key (uuid),
+    constraint uni_niveles_reorden_producto unique (producto_uuid),
+    constraint fk_niveles_reorden_producto foreign KEY (producto_uuid) references productos (uuid),
+    constraint fk_niveles_reorden_proveedor foreign KEY (proveedor_uuid) references proveedors (uuid)
+);
+
+-- Pedidos enviados a proveedores. Mientras están PENDIENTE sus unidades cuentan como mercancía en
+-- camino; pasan a RECIBIDO con la compra que los ingresa o a ANULADO.
+CREATE TABLE IF NOT EXISTS public.pedidos_proveedor (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    proveedor_uuid uuid not null,
+    vendedor_uuid uuid not null,
+    fecha timestamp with time zone not null,
+    fecha_esperada date not null,
+    estado text not null default 'PENDIENTE',
+    compra_uuid uuid null,
+    observacion text null,
+    constraint pedidos_proveedor_pkey primary key (uuid),
+    constraint fk_pedidos_proveedor_proveedor foreign KEY (proveedor_uuid) references proveedors (uuid),
+    constraint fk_pedidos_proveedor_vendedor foreign KEY (vendedor_uuid) references vendedors (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pedidos_proveedor_estado ON public.pedidos_proveedor USING btree (estado);
+
+-- Productos de cada pedido, en unidades mínimas.
+CREATE TABLE IF NOT EXISTS public.items_pedido_proveedor (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    pedido_uuid uuid not null,
+    producto_uuid uuid not null,
+    cantidad bigint not null,
+    costo_unitario numeric not null default 0,
+    constraint items_pedido_proveedor_pkey primary key (uuid),
+    constraint uni_items_pedido_proveedor_producto unique (pedido_uuid, producto_uuid),
+    constraint fk_items_pedido_proveedor_pedido foreign KEY (pedido_uuid) references pedidos_proveedor (uuid),
+    constraint fk_items_pedido_proveedor_producto foreign KEY (producto_uuid) references productos (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_items_pedido_proveedor_producto ON public.items_pedido_proveedor USING btree (producto_uuid);
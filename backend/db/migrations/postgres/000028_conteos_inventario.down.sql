@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS public.idx_registros_conteo_inventario_conteo;
+
+DROP TABLE IF EXISTS public.registros_conteo_inventario;
+
+DROP TABLE IF EXISTS public.items_conteo_inventario;
+
+DROP INDEX IF EXISTS public.idx_conteos_inventario_estado;
+
+DROP TABLE IF EXISTS public.conteos_inventario;
@@ -0,0 +1,58 @@
+-- Sesiones de toma física de inventario. Al abrir la sesión se fija el stock esperado de cada producto
+-- y lote; al aprobarla las diferencias con lo contado se ajustan con operaciones de stock.
+CREATE TABLE IF NOT EXISTS public.conteos_inventario (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    descripcion text not null,
+    categoria text null,
+    estado text not null default 'ABIERTO',
+    vendedor_uuid uuid not null,
+    aprobado_por uuid null,
+    fecha_apertura timestamp with time zone not null,
+    fecha_cierre timestamp with time zone null,
+    observacion text null,
+    constraint conteos_inventario_pkey primary key (uuid),
+    constraint fk_conteos_inventario_vendedor foreign KEY (vendedor_uuid) references vendedors (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conteos_inventario_estado ON public.conteos_inventario USING btree (estado);
+
+-- Stock esperado al abrir el conteo, en unidades mínimas. lote_uuid vacío es el stock sin lote.
+CREATE TABLE IF NOT EXISTS public.items_conteo_inventario (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    conteo_uuid uuid not null,
+    producto_uuid uuid not null,
+    lote_uuid text not null default '',
+    stock_esperado bigint not null,
+    costo_unitario numeric not null default 0,
+    constraint items_conteo_inventario_pkey primary key (uuid),
+    constraint uni_items_conteo_inventario_producto_lote unique (conteo_uuid, producto_uuid, lote_uuid),
+    constraint fk_items_conteo_inventario_conteo foreign KEY (conteo_uuid) references conteos_inventario (uuid),
+    constraint fk_items_conteo_inventario_producto foreign KEY (producto_uuid) references productos (uuid)
+);
+
+-- Cantidades contadas. Cada equipo agrega sus propios registros y lo contado de un producto o lote es
+-- la suma de los registros no anulados (deleted_at), así varios equipos cuentan a la vez sin pisarse.
+CREATE TABLE IF NOT EXISTS public.registros_conteo_inventario (
+    created_at timestamp with time zone null,
+    updated_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    uuid uuid not null,
+    conteo_uuid uuid not null,
+    producto_uuid uuid not null,
+    lote_uuid text not null default '',
+    cantidad bigint not null,
+    vendedor_uuid uuid not null,
+    dispositivo text null,
+    constraint registros_conteo_inventario_pkey primary key (uuid),
+    constraint fk_registros_conteo_inventario_conteo foreign KEY (conteo_uuid) references conteos_inventario (uuid),
+    constraint fk_registros_conteo_inventario_producto foreign KEY (producto_uuid) references productos (uuid),
+    constraint fk_registros_conteo_inventario_vendedor foreign KEY (vendedor_uuid) references vendedors (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_registros_conteo_inventario_conteo ON public.registros_conteo_inventario USING btree (conteo_uuid, producto_uuid);
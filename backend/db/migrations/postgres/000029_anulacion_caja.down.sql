@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.idx_facturas_sesion_caja_anulacion_uuid;
+
+ALTER TABLE public.facturas DROP COLUMN IF EXISTS sesion_caja_anulacion_uuid;
@@ -0,0 +1,5 @@
+-- Caja de la que sale el reembolso de una factura anulada. Puede ser distinta a la de la venta si se
+-- anula en otra sesión o por otro vendedor. Sin llave foránea, como sesion_caja_uuid.
+ALTER TABLE public.facturas ADD COLUMN IF NOT EXISTS sesion_caja_anulacion_uuid uuid;
+
+CREATE INDEX IF NOT EXISTS idx_facturas_sesion_caja_anulacion_uuid ON public.facturas USING btree (sesion_caja_anulacion_uuid);
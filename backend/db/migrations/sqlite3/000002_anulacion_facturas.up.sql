@@ -0,0 +1,8 @@
+-- Soporte para anulación de facturas
+ALTER TABLE facturas ADD COLUMN motivo_anulacion TEXT;
+
+ALTER TABLE facturas ADD COLUMN fecha_anulacion DATETIME;
+
+ALTER TABLE facturas ADD COLUMN anulada_por_uuid TEXT REFERENCES vendedors (uuid);
+
+CREATE INDEX IF NOT EXISTS idx_facturas_estado ON facturas (estado);
@@ -0,0 +1,42 @@
+-- Devoluciones de clientes y notas crédito
+CREATE TABLE
+    IF NOT EXISTS notas_credito (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        numero_nota TEXT UNIQUE NOT NULL,
+        factura_uuid TEXT NOT NULL,
+        fecha_emision DATETIME,
+        vendedor_uuid TEXT,
+        cliente_uuid TEXT,
+        motivo TEXT,
+        subtotal REAL,
+        iva REAL,
+        total REAL,
+        sincronizado BOOLEAN NOT NULL DEFAULT 0,
+        FOREIGN KEY (factura_uuid) REFERENCES facturas (uuid),
+        FOREIGN KEY (vendedor_uuid) REFERENCES vendedors (uuid),
+        FOREIGN KEY (cliente_uuid) REFERENCES clientes (uuid)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS detalle_notas_credito (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        nota_credito_uuid TEXT NOT NULL,
+        detalle_factura_uuid TEXT NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        cantidad INTEGER,
+        precio_unitario REAL,
+        precio_total REAL,
+        FOREIGN KEY (nota_credito_uuid) REFERENCES notas_credito (uuid),
+        FOREIGN KEY (detalle_factura_uuid) REFERENCES detalle_facturas (uuid),
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_notas_credito_factura_uuid ON notas_credito (factura_uuid);
+
+CREATE INDEX IF NOT EXISTS idx_detalle_notas_credito_detalle_factura_uuid ON detalle_notas_credito (detalle_factura_uuid);
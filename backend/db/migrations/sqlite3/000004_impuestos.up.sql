@@ -0,0 +1,46 @@
+-- Motor de impuestos por producto
+CREATE TABLE
+    IF NOT EXISTS impuestos (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        codigo TEXT UNIQUE NOT NULL,
+        nombre TEXT,
+        tarifa REAL NOT NULL DEFAULT 0
+    );
+
+INSERT
+OR IGNORE INTO impuestos (created_at, updated_at, uuid, codigo, nombre, tarifa)
+VALUES
+    (CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, '0b6f3e5a-2f1c-4d7e-9a51-1c0e9f2b7a19', 'IVA_19', 'IVA 19%', 19),
+    (CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, '5c2d8a41-7e3b-4f06-8d2a-6b9e0c1f4a05', 'IVA_5', 'IVA 5%', 5),
+    (CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, '9e4a7b10-3c5d-4e8f-a612-7d0b2c9e8f00', 'EXENTO', 'Exento', 0),
+    (CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 'd1f0c6e2-8a4b-4c3d-b7e5-2f9a1e0d3c00', 'EXCLUIDO', 'Excluido', 0);
+
+ALTER TABLE productos ADD COLUMN impuesto_codigo TEXT NOT NULL DEFAULT 'EXCLUIDO';
+
+ALTER TABLE detalle_facturas ADD COLUMN impuesto_codigo TEXT;
+
+ALTER TABLE detalle_facturas ADD COLUMN base_impuesto REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE detalle_facturas ADD COLUMN tarifa_impuesto REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE detalle_facturas ADD COLUMN valor_impuesto REAL NOT NULL DEFAULT 0;
+
+-- Las ventas anteriores no tenían impuesto: su base es el total de la línea.
+UPDATE detalle_facturas SET base_impuesto = precio_total WHERE base_impuesto = 0;
+
+CREATE TABLE
+    IF NOT EXISTS factura_impuestos (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        factura_uuid TEXT NOT NULL,
+        impuesto_codigo TEXT,
+        tarifa REAL NOT NULL,
+        base REAL NOT NULL,
+        valor REAL NOT NULL,
+        FOREIGN KEY (factura_uuid) REFERENCES facturas (uuid),
+        UNIQUE (factura_uuid, tarifa)
+    );
@@ -0,0 +1,27 @@
+-- Descuentos por línea y por factura, y rol de vendedor para autorizaciones
+ALTER TABLE vendedors ADD COLUMN rol TEXT NOT NULL DEFAULT 'VENDEDOR';
+
+-- El vendedor más antiguo queda como ADMIN para que alguien pueda asignar los demás roles.
+UPDATE vendedors SET rol = 'ADMIN'
+WHERE uuid = (SELECT uuid FROM vendedors WHERE deleted_at IS NULL ORDER BY created_at ASC LIMIT 1);
+
+ALTER TABLE facturas ADD COLUMN valor_bruto REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE facturas ADD COLUMN descuento REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE facturas ADD COLUMN descuento_factura REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE facturas ADD COLUMN motivo_descuento TEXT;
+
+ALTER TABLE facturas ADD COLUMN descuento_autorizado_por TEXT REFERENCES vendedors (uuid);
+
+ALTER TABLE detalle_facturas ADD COLUMN valor_bruto REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE detalle_facturas ADD COLUMN descuento REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE detalle_facturas ADD COLUMN motivo_descuento TEXT;
+
+-- Las ventas anteriores no tenían descuentos: el bruto es igual al neto.
+UPDATE facturas SET valor_bruto = total WHERE valor_bruto = 0;
+
+UPDATE detalle_facturas SET valor_bruto = precio_total WHERE valor_bruto = 0;
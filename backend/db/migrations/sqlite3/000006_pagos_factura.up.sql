@@ -0,0 +1,31 @@
+-- Pagos combinados: una fila por cada medio de pago usado en la factura
+CREATE TABLE
+    IF NOT EXISTS pagos_factura (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        factura_uuid TEXT NOT NULL,
+        metodo_pago TEXT NOT NULL,
+        monto REAL NOT NULL,
+        referencia TEXT,
+        efectivo_recibido REAL NOT NULL DEFAULT 0,
+        cambio REAL NOT NULL DEFAULT 0,
+        FOREIGN KEY (factura_uuid) REFERENCES facturas (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_pagos_factura_factura_uuid ON pagos_factura (factura_uuid);
+
+-- Las facturas anteriores quedan con un único pago por el total. Se reutiliza el UUID de la
+-- factura para que el registro sea el mismo en la base local y en la remota.
+INSERT
+OR IGNORE INTO pagos_factura (created_at, updated_at, uuid, factura_uuid, metodo_pago, monto)
+SELECT
+    created_at,
+    COALESCE(updated_at, created_at),
+    uuid,
+    uuid,
+    COALESCE(metodo_pago, 'efectivo'),
+    COALESCE(total, 0)
+FROM
+    facturas;
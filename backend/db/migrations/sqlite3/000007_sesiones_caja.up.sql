@@ -0,0 +1,42 @@
+-- Sesiones de caja (apertura / cierre) con arqueo ciego por método de pago
+CREATE TABLE
+    IF NOT EXISTS sesiones_caja (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        vendedor_uuid TEXT NOT NULL,
+        fecha_apertura DATETIME NOT NULL,
+        monto_apertura REAL NOT NULL DEFAULT 0,
+        fecha_cierre DATETIME,
+        estado TEXT NOT NULL DEFAULT 'ABIERTA',
+        total_esperado REAL NOT NULL DEFAULT 0,
+        total_contado REAL NOT NULL DEFAULT 0,
+        diferencia REAL NOT NULL DEFAULT 0,
+        observaciones TEXT,
+        sincronizado BOOLEAN NOT NULL DEFAULT 0,
+        FOREIGN KEY (vendedor_uuid) REFERENCES vendedors (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_sesiones_caja_vendedor_estado ON sesiones_caja (vendedor_uuid, estado);
+
+CREATE TABLE
+    IF NOT EXISTS conteos_caja (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        sesion_caja_uuid TEXT NOT NULL,
+        metodo_pago TEXT NOT NULL,
+        esperado REAL NOT NULL DEFAULT 0,
+        contado REAL NOT NULL DEFAULT 0,
+        diferencia REAL NOT NULL DEFAULT 0,
+        FOREIGN KEY (sesion_caja_uuid) REFERENCES sesiones_caja (uuid),
+        UNIQUE (sesion_caja_uuid, metodo_pago)
+    );
+
+-- Sin llave foránea: las facturas y notas de otras terminales pueden llegar antes que su sesión.
+ALTER TABLE facturas ADD COLUMN sesion_caja_uuid TEXT;
+
+ALTER TABLE notas_credito ADD COLUMN sesion_caja_uuid TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_facturas_sesion_caja_uuid ON facturas (sesion_caja_uuid);
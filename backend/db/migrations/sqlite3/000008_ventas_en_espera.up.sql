@@ -0,0 +1,17 @@
+-- Ventas en espera: borradores de venta que sobreviven al reinicio de la aplicación.
+-- Son propias de cada terminal, por eso no se sincronizan con el remoto ni reservan stock.
+CREATE TABLE
+    IF NOT EXISTS ventas_en_espera (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        vendedor_uuid TEXT NOT NULL,
+        cliente_uuid TEXT,
+        descripcion TEXT,
+        venta TEXT NOT NULL,
+        total_estimado REAL NOT NULL DEFAULT 0,
+        numero_items INTEGER NOT NULL DEFAULT 0,
+        FOREIGN KEY (vendedor_uuid) REFERENCES vendedors (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_ventas_en_espera_vendedor_uuid ON ventas_en_espera (vendedor_uuid);
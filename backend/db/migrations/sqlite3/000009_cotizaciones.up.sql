@@ -0,0 +1,42 @@
+-- Cotizaciones con numeración propia (COT-), convertibles en factura
+CREATE TABLE
+    IF NOT EXISTS cotizaciones (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        numero_cotizacion TEXT UNIQUE NOT NULL,
+        fecha_emision DATETIME NOT NULL,
+        fecha_vencimiento DATETIME NOT NULL,
+        cliente_uuid TEXT NOT NULL,
+        vendedor_uuid TEXT NOT NULL,
+        subtotal REAL NOT NULL DEFAULT 0,
+        iva REAL NOT NULL DEFAULT 0,
+        total REAL NOT NULL DEFAULT 0,
+        estado TEXT NOT NULL DEFAULT 'VIGENTE',
+        observaciones TEXT,
+        -- Sin llave foránea: la resincronización completa borra y recarga las facturas.
+        factura_uuid TEXT,
+        FOREIGN KEY (cliente_uuid) REFERENCES clientes (uuid),
+        FOREIGN KEY (vendedor_uuid) REFERENCES vendedors (uuid)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS detalle_cotizaciones (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        cotizacion_uuid TEXT NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        cantidad INTEGER NOT NULL,
+        precio_unitario REAL NOT NULL,
+        precio_total REAL NOT NULL,
+        impuesto_codigo TEXT,
+        tarifa_impuesto REAL NOT NULL DEFAULT 0,
+        base_impuesto REAL NOT NULL DEFAULT 0,
+        valor_impuesto REAL NOT NULL DEFAULT 0,
+        FOREIGN KEY (cotizacion_uuid) REFERENCES cotizaciones (uuid) ON DELETE CASCADE,
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_detalle_cotizaciones_cotizacion_uuid ON detalle_cotizaciones (cotizacion_uuid);
@@ -0,0 +1,16 @@
+-- Rangos de numeración asignados a esta terminal desde el remoto.
+-- Cada factura consume el siguiente número del rango activo más antiguo.
+CREATE TABLE
+    IF NOT EXISTS rangos_facturacion (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        terminal_id TEXT NOT NULL,
+        prefijo TEXT NOT NULL,
+        desde INTEGER NOT NULL,
+        hasta INTEGER NOT NULL,
+        ultimo_usado INTEGER NOT NULL DEFAULT 0,
+        estado TEXT NOT NULL DEFAULT 'ACTIVO'
+    );
+
+CREATE INDEX IF NOT EXISTS idx_rangos_facturacion_terminal_estado ON rangos_facturacion (terminal_id, estado);
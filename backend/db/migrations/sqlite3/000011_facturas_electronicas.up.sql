@@ -0,0 +1,19 @@
+-- Factura electrónica DIAN (UBL 2.1): XML generado, CUFE y resultado del envío
+CREATE TABLE
+    IF NOT EXISTS facturas_electronicas (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        factura_uuid TEXT UNIQUE NOT NULL,
+        numero_factura TEXT NOT NULL,
+        cufe TEXT NOT NULL,
+        qr TEXT,
+        xml TEXT NOT NULL,
+        ambiente TEXT NOT NULL,
+        estado TEXT NOT NULL DEFAULT 'GENERADA',
+        mensaje_dian TEXT,
+        fecha_envio DATETIME,
+        FOREIGN KEY (factura_uuid) REFERENCES facturas (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_facturas_electronicas_cufe ON facturas_electronicas (cufe);
@@ -0,0 +1,23 @@
+-- Precios de venta distintos al de lista, autorizados por un supervisor
+CREATE TABLE
+    IF NOT EXISTS cambios_precio (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        factura_uuid TEXT NOT NULL,
+        detalle_factura_uuid TEXT NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        cantidad INTEGER NOT NULL,
+        precio_original REAL NOT NULL,
+        precio_nuevo REAL NOT NULL,
+        vendedor_uuid TEXT NOT NULL,
+        autorizado_por_uuid TEXT NOT NULL,
+        motivo TEXT NOT NULL,
+        FOREIGN KEY (factura_uuid) REFERENCES facturas (uuid),
+        FOREIGN KEY (detalle_factura_uuid) REFERENCES detalle_facturas (uuid),
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_cambios_precio_factura_uuid ON cambios_precio (factura_uuid);
+
+CREATE INDEX IF NOT EXISTS idx_cambios_precio_created_at ON cambios_precio (created_at);
@@ -0,0 +1,43 @@
+-- Ventas a crédito: cupo por cliente y abonos aplicados a las facturas pendientes
+ALTER TABLE clientes ADD COLUMN limite_credito REAL NOT NULL DEFAULT 0;
+
+-- Parte de la nota crédito que se descontó del saldo pendiente de la factura en lugar de reembolsarse.
+ALTER TABLE notas_credito ADD COLUMN aplicado_cartera REAL NOT NULL DEFAULT 0;
+
+CREATE TABLE
+    IF NOT EXISTS abonos (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        numero_abono TEXT UNIQUE NOT NULL,
+        cliente_uuid TEXT NOT NULL,
+        vendedor_uuid TEXT NOT NULL,
+        sesion_caja_uuid TEXT,
+        fecha DATETIME NOT NULL,
+        monto REAL NOT NULL,
+        metodo_pago TEXT NOT NULL,
+        referencia TEXT,
+        observaciones TEXT,
+        sincronizado BOOLEAN NOT NULL DEFAULT 0,
+        FOREIGN KEY (cliente_uuid) REFERENCES clientes (uuid),
+        FOREIGN KEY (vendedor_uuid) REFERENCES vendedors (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_abonos_cliente_uuid ON abonos (cliente_uuid);
+
+CREATE INDEX IF NOT EXISTS idx_abonos_sesion_caja_uuid ON abonos (sesion_caja_uuid);
+
+CREATE TABLE
+    IF NOT EXISTS abono_facturas (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        abono_uuid TEXT NOT NULL,
+        factura_uuid TEXT NOT NULL,
+        monto REAL NOT NULL,
+        FOREIGN KEY (abono_uuid) REFERENCES abonos (uuid),
+        FOREIGN KEY (factura_uuid) REFERENCES facturas (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_abono_facturas_factura_uuid ON abono_facturas (factura_uuid);
@@ -0,0 +1,40 @@
+-- Programa de puntos: categoría de producto para las reglas de acumulación y libro de movimientos por cliente
+ALTER TABLE productos ADD COLUMN categoria TEXT;
+
+-- Una regla sin categoría es la general; monto_por_punto = 0 excluye la categoría de la acumulación.
+CREATE TABLE
+    IF NOT EXISTS reglas_puntos (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        codigo TEXT UNIQUE NOT NULL,
+        nombre TEXT,
+        categoria TEXT,
+        monto_por_punto REAL NOT NULL DEFAULT 0
+    );
+
+INSERT
+OR IGNORE INTO reglas_puntos (created_at, updated_at, uuid, codigo, nombre, categoria, monto_por_punto)
+VALUES
+    (CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, '7f3c2a10-5b8e-4d61-9c0a-3e1f6b2d8a47', 'GENERAL', '1 punto por cada $1.000', NULL, 1000);
+
+-- El saldo de puntos de un cliente es siempre SUM(puntos) de sus movimientos.
+CREATE TABLE
+    IF NOT EXISTS movimientos_puntos (
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        cliente_uuid TEXT NOT NULL,
+        tipo TEXT NOT NULL,
+        puntos INTEGER NOT NULL,
+        saldo_resultante INTEGER NOT NULL,
+        factura_uuid TEXT,
+        vendedor_uuid TEXT,
+        descripcion TEXT,
+        timestamp DATETIME NOT NULL,
+        sincronizado BOOLEAN NOT NULL DEFAULT 0,
+        FOREIGN KEY (cliente_uuid) REFERENCES clientes (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_movimientos_puntos_cliente_uuid ON movimientos_puntos (cliente_uuid);
+
+CREATE INDEX IF NOT EXISTS idx_movimientos_puntos_factura_uuid ON movimientos_puntos (factura_uuid);
@@ -0,0 +1,79 @@
+-- Presentaciones de venta y compra (caja, blíster, unidad). El stock se lleva en la unidad mínima
+-- del producto y cada presentación indica cuántas unidades mínimas contiene.
+CREATE TABLE
+    IF NOT EXISTS presentaciones_producto (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        nombre TEXT NOT NULL,
+        factor INTEGER NOT NULL DEFAULT 1,
+        precio_venta REAL NOT NULL DEFAULT 0,
+        codigo_barras TEXT UNIQUE,
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_presentaciones_producto_producto_uuid ON presentaciones_producto (producto_uuid);
+
+-- La cantidad de cada línea queda en la presentación vendida; factor_conversion la lleva a unidades mínimas.
+-- Una factura puede traer el mismo producto en varias presentaciones (caja y blíster), así que la tabla
+-- se reconstruye sin UNIQUE (factura_uuid, producto_uuid): SQLite no permite quitar una restricción con
+-- ALTER TABLE. Las filas vuelven a insertarse en la tabla nueva para que las llaves foráneas diferidas de
+-- notas crédito y cambios de precio queden satisfechas al confirmar la migración.
+PRAGMA defer_foreign_keys = ON;
+
+CREATE TABLE
+    detalle_facturas_respaldo AS
+SELECT
+    *
+FROM
+    detalle_facturas;
+
+DROP TABLE detalle_facturas;
+
+CREATE TABLE
+    detalle_facturas (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        factura_uuid TEXT NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        cantidad INTEGER,
+        precio_unitario REAL,
+        precio_total REAL,
+        impuesto_codigo TEXT,
+        base_impuesto REAL NOT NULL DEFAULT 0,
+        tarifa_impuesto REAL NOT NULL DEFAULT 0,
+        valor_impuesto REAL NOT NULL DEFAULT 0,
+        valor_bruto REAL NOT NULL DEFAULT 0,
+        descuento REAL NOT NULL DEFAULT 0,
+        motivo_descuento TEXT,
+        presentacion_uuid TEXT,
+        presentacion TEXT,
+        factor_conversion INTEGER NOT NULL DEFAULT 1,
+        FOREIGN KEY (factura_uuid) REFERENCES facturas (uuid),
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+INSERT INTO
+    detalle_facturas (
+        created_at, updated_at, deleted_at, uuid, factura_uuid, producto_uuid, cantidad, precio_unitario,
+        precio_total, impuesto_codigo, base_impuesto, tarifa_impuesto, valor_impuesto, valor_bruto, descuento,
+        motivo_descuento
+    )
+SELECT
+    created_at, updated_at, deleted_at, uuid, factura_uuid, producto_uuid, cantidad, precio_unitario,
+    precio_total, impuesto_codigo, base_impuesto, tarifa_impuesto, valor_impuesto, valor_bruto, descuento,
+    motivo_descuento
+FROM
+    detalle_facturas_respaldo;
+
+DROP TABLE detalle_facturas_respaldo;
+
+CREATE INDEX IF NOT EXISTS idx_detalle_facturas_factura_uuid ON detalle_facturas (factura_uuid);
+
+ALTER TABLE detalle_compra ADD COLUMN presentacion_uuid TEXT;
+
+ALTER TABLE detalle_compra ADD COLUMN factor_conversion INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,9 @@
+-- El esquema local nombraba la tabla en singular; el código y el remoto usan detalle_compras.
+ALTER TABLE detalle_compra RENAME TO detalle_compras;
+
+-- Vendedor que registró la compra; las operaciones de stock de la compra quedan a su nombre.
+ALTER TABLE compras ADD COLUMN vendedor_uuid TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_compras_fecha ON compras (fecha);
+
+CREATE INDEX IF NOT EXISTS idx_detalle_compras_compra_uuid ON detalle_compras (compra_uuid);
@@ -0,0 +1,5 @@
+-- Costo promedio ponderado por unidad mínima, actualizado en cada compra.
+ALTER TABLE productos ADD COLUMN costo_promedio REAL NOT NULL DEFAULT 0;
+
+-- Costo de la presentación vendida al momento de la venta, para calcular la utilidad bruta.
+ALTER TABLE detalle_facturas ADD COLUMN costo_unitario REAL NOT NULL DEFAULT 0;
@@ -0,0 +1,22 @@
+-- Lotes de cada producto con su fecha de vencimiento (AAAA-MM-DD). El stock de un lote es la suma
+-- de las operaciones de stock que lo referencian.
+CREATE TABLE
+    IF NOT EXISTS lotes (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        numero_lote TEXT NOT NULL,
+        fecha_vencimiento DATE NOT NULL,
+        UNIQUE (producto_uuid, numero_lote),
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_lotes_fecha_vencimiento ON lotes (fecha_vencimiento);
+
+ALTER TABLE operacion_stocks ADD COLUMN lote_uuid TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_operacion_stocks_lote_uuid ON operacion_stocks (lote_uuid);
+
+ALTER TABLE detalle_compras ADD COLUMN lote_uuid TEXT;
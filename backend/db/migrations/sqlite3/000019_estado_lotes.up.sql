@@ -0,0 +1,4 @@
+-- Estado del lote: VIGENTE, CUARENTENA o DESTRUIDO. Los lotes retirados no se venden y sus
+-- existencias salen del inventario con una operación de stock.
+ALTER TABLE lotes ADD COLUMN estado TEXT NOT NULL DEFAULT 'VIGENTE';
+ALTER TABLE lotes ADD COLUMN observacion TEXT;
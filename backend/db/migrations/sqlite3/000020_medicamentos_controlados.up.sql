@@ -0,0 +1,53 @@
+-- Medicamentos de control (FNE): los productos marcados llevan un libro de movimientos con saldo
+ALTER TABLE productos ADD COLUMN controlado BOOLEAN NOT NULL DEFAULT 0;
+
+-- Libro oficial de medicamentos de control. Cada operación de stock de un producto controlado deja un
+-- asiento con el saldo resultante; las dispensaciones guardan los datos de la fórmula médica.
+CREATE TABLE
+    IF NOT EXISTS libro_controlados (
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        operacion_stock_uuid TEXT UNIQUE,
+        tipo_operacion TEXT NOT NULL,
+        cantidad INTEGER NOT NULL,
+        saldo INTEGER NOT NULL,
+        lote_uuid TEXT,
+        factura_uuid TEXT,
+        vendedor_uuid TEXT,
+        prescriptor TEXT,
+        registro_prescriptor TEXT,
+        paciente_id TEXT,
+        numero_formula TEXT,
+        fecha DATETIME NOT NULL,
+        sincronizado BOOLEAN NOT NULL DEFAULT 0,
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_libro_controlados_producto_fecha ON libro_controlados (producto_uuid, fecha);
+
+-- El libro es de solo inserción: únicamente se permite marcar un asiento como sincronizado.
+CREATE TRIGGER IF NOT EXISTS trg_libro_controlados_no_update BEFORE
+UPDATE OF uuid,
+producto_uuid,
+operacion_stock_uuid,
+tipo_operacion,
+cantidad,
+saldo,
+lote_uuid,
+factura_uuid,
+vendedor_uuid,
+prescriptor,
+registro_prescriptor,
+paciente_id,
+numero_formula,
+fecha ON libro_controlados BEGIN
+SELECT
+    RAISE (ABORT, 'el libro de controlados no admite modificaciones');
+
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_libro_controlados_no_delete BEFORE DELETE ON libro_controlados BEGIN
+SELECT
+    RAISE (ABORT, 'el libro de controlados no admite borrados');
+
+END;
@@ -0,0 +1,41 @@
+-- Productos de venta bajo fórmula médica (no necesariamente de control)
+ALTER TABLE productos ADD COLUMN requiere_formula BOOLEAN NOT NULL DEFAULT 0;
+
+-- Fórmulas médicas presentadas en la farmacia. Una fórmula puede dispensarse en varias ventas; lo
+-- dispensado de cada ítem se calcula con los detalles de las facturas no anuladas que la referencian.
+CREATE TABLE
+    IF NOT EXISTS formulas_medicas (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        numero_formula TEXT NOT NULL,
+        fecha_formula DATE NOT NULL,
+        medico TEXT NOT NULL,
+        registro_medico TEXT NOT NULL,
+        paciente_id TEXT NOT NULL,
+        paciente_nombre TEXT,
+        cliente_uuid TEXT,
+        UNIQUE (registro_medico, numero_formula)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_formulas_medicas_paciente_id ON formulas_medicas (paciente_id);
+
+-- Productos prescritos en cada fórmula, en unidades mínimas.
+CREATE TABLE
+    IF NOT EXISTS items_formula (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        formula_uuid TEXT NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        cantidad_prescrita INTEGER NOT NULL,
+        UNIQUE (formula_uuid, producto_uuid),
+        FOREIGN KEY (formula_uuid) REFERENCES formulas_medicas (uuid),
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+ALTER TABLE facturas ADD COLUMN formula_uuid TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_facturas_formula_uuid ON facturas (formula_uuid);
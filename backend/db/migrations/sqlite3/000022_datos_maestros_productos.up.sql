@@ -0,0 +1,28 @@
+-- Datos maestros farmacéuticos del producto. El registro sanitario INVIMA lleva su fecha de vencimiento
+-- (AAAA-MM-DD) y el código ATC la clasificación anatómica, terapéutica y química de la OMS.
+ALTER TABLE productos ADD COLUMN principio_activo TEXT;
+
+ALTER TABLE productos ADD COLUMN concentracion TEXT;
+
+ALTER TABLE productos ADD COLUMN forma_farmaceutica TEXT;
+
+ALTER TABLE productos ADD COLUMN laboratorio TEXT;
+
+ALTER TABLE productos ADD COLUMN registro_invima TEXT;
+
+ALTER TABLE productos ADD COLUMN vencimiento_invima DATE;
+
+ALTER TABLE productos ADD COLUMN codigo_atc TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_productos_principio_activo ON productos (principio_activo);
+
+-- Árbol de categorías. Los productos y las reglas de puntos siguen referenciando la categoría por nombre.
+CREATE TABLE
+    IF NOT EXISTS categorias_producto (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        nombre TEXT UNIQUE NOT NULL,
+        categoria_padre TEXT
+    );
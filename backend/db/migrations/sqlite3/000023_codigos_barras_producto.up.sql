@@ -0,0 +1,16 @@
+-- Códigos de barras adicionales de un producto (EAN de cada distribuidor, GTIN de los códigos GS1).
+-- Con presentacion_uuid el código corresponde a esa presentación; si no, a la unidad mínima.
+CREATE TABLE
+    IF NOT EXISTS codigos_barras_producto (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        codigo TEXT UNIQUE NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        presentacion_uuid TEXT,
+        descripcion TEXT,
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_codigos_barras_producto_producto ON codigos_barras_producto (producto_uuid);
@@ -0,0 +1,59 @@
+-- Tiempo de entrega del proveedor en días, desde el pedido hasta la recepción de la mercancía.
+ALTER TABLE proveedors ADD COLUMN dias_entrega INTEGER NOT NULL DEFAULT 0;
+
+-- Niveles de reabastecimiento por producto, en unidades mínimas. Con automatico los niveles se calculan
+-- con la velocidad de venta y los valores guardados no se usan. Sin proveedor_uuid se sugiere al
+-- proveedor de la última compra del producto.
+CREATE TABLE
+    IF NOT EXISTS niveles_reorden (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        producto_uuid TEXT UNIQUE NOT NULL,
+        automatico BOOLEAN NOT NULL DEFAULT 0,
+        stock_minimo INTEGER NOT NULL DEFAULT 0,
+        stock_maximo INTEGER NOT NULL DEFAULT 0,
+        proveedor_uuid TEXT,
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid),
+        FOREIGN KEY (proveedor_uuid) REFERENCES proveedors (uuid)
+    );
+
+-- Pedidos enviados a proveedores. Mientras están PENDIENTE sus unidades cuentan como mercancía en
+-- camino; pasan a RECIBIDO con la compra que los ingresa o a ANULADO.
+CREATE TABLE
+    IF NOT EXISTS pedidos_proveedor (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        proveedor_uuid TEXT NOT NULL,
+        vendedor_uuid TEXT NOT NULL,
+        fecha DATETIME NOT NULL,
+        fecha_esperada DATE NOT NULL,
+        estado TEXT NOT NULL DEFAULT 'PENDIENTE',
+        compra_uuid TEXT,
+        observacion TEXT,
+        FOREIGN KEY (proveedor_uuid) REFERENCES proveedors (uuid),
+        FOREIGN KEY (vendedor_uuid) REFERENCES vendedors (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_pedidos_proveedor_estado ON pedidos_proveedor (estado);
+
+-- Productos de cada pedido, en unidades mínimas.
+CREATE TABLE
+    IF NOT EXISTS items_pedido_proveedor (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        pedido_uuid TEXT NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        cantidad INTEGER NOT NULL,
+        costo_unitario REAL NOT NULL DEFAULT 0,
+        UNIQUE (pedido_uuid, producto_uuid),
+        FOREIGN KEY (pedido_uuid) REFERENCES pedidos_proveedor (uuid),
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_items_pedido_proveedor_producto ON items_pedido_proveedor (producto_uuid);
@@ -0,0 +1,58 @@
+-- Sesiones de toma física de inventario. Al abrir la sesión se fija el stock esperado de cada producto
+-- y lote; al aprobarla las diferencias con lo contado se ajustan con operaciones de stock.
+CREATE TABLE
+    IF NOT EXISTS conteos_inventario (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        descripcion TEXT NOT NULL,
+        categoria TEXT,
+        estado TEXT NOT NULL DEFAULT 'ABIERTO',
+        vendedor_uuid TEXT NOT NULL,
+        aprobado_por TEXT,
+        fecha_apertura DATETIME NOT NULL,
+        fecha_cierre DATETIME,
+        observacion TEXT,
+        FOREIGN KEY (vendedor_uuid) REFERENCES vendedors (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_conteos_inventario_estado ON conteos_inventario (estado);
+
+-- Stock esperado al abrir el conteo, en unidades mínimas. lote_uuid vacío es el stock sin lote.
+CREATE TABLE
+    IF NOT EXISTS items_conteo_inventario (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        conteo_uuid TEXT NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        lote_uuid TEXT NOT NULL DEFAULT '',
+        stock_esperado INTEGER NOT NULL,
+        costo_unitario REAL NOT NULL DEFAULT 0,
+        UNIQUE (conteo_uuid, producto_uuid, lote_uuid),
+        FOREIGN KEY (conteo_uuid) REFERENCES conteos_inventario (uuid),
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid)
+    );
+
+-- Cantidades contadas. Cada equipo agrega sus propios registros y lo contado de un producto o lote es
+-- la suma de los registros no anulados (deleted_at), así varios equipos cuentan a la vez sin pisarse.
+CREATE TABLE
+    IF NOT EXISTS registros_conteo_inventario (
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        deleted_at DATETIME,
+        uuid TEXT UNIQUE PRIMARY KEY NOT NULL,
+        conteo_uuid TEXT NOT NULL,
+        producto_uuid TEXT NOT NULL,
+        lote_uuid TEXT NOT NULL DEFAULT '',
+        cantidad INTEGER NOT NULL,
+        vendedor_uuid TEXT NOT NULL,
+        dispositivo TEXT,
+        FOREIGN KEY (conteo_uuid) REFERENCES conteos_inventario (uuid),
+        FOREIGN KEY (producto_uuid) REFERENCES productos (uuid),
+        FOREIGN KEY (vendedor_uuid) REFERENCES vendedors (uuid)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_registros_conteo_inventario_conteo ON registros_conteo_inventario (conteo_uuid, producto_uuid);
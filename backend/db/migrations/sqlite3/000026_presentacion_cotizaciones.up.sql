@@ -0,0 +1,6 @@
+-- Las líneas de la cotización quedan en la presentación cotizada; factor_conversion la lleva a unidades mínimas.
+ALTER TABLE detalle_cotizaciones ADD COLUMN presentacion_uuid TEXT;
+
+ALTER TABLE detalle_cotizaciones ADD COLUMN presentacion TEXT;
+
+ALTER TABLE detalle_cotizaciones ADD COLUMN factor_conversion INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+-- Caja de la que sale el reembolso de una factura anulada. Puede ser distinta a la de la venta si se
+-- anula en otra sesión o por otro vendedor.
+ALTER TABLE facturas ADD COLUMN sesion_caja_anulacion_uuid TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_facturas_sesion_caja_anulacion_uuid ON facturas (sesion_caja_anulacion_uuid);
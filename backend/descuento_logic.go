@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tipos de descuento aceptados en ProductoVenta y VentaRequest.
+const (
+	DescuentoPorcentaje = "PORCENTAJE"
+	DescuentoValor      = "VALOR"
+)
+
+// DescuentoMaximoPorDefecto se usa cuando la variable DESCUENTO_MAXIMO no está configurada.
+const DescuentoMaximoPorDefecto = 10.0
+
+// ObtenerDescuentoMaximo devuelve el porcentaje máximo de descuento que un vendedor puede
+// aplicar sin autorización de un supervisor.
+func (d *Db) ObtenerDescuentoMaximo() float64 {
+	return d.descuentoMaximo
+}
+
+// calcularDescuento convierte un descuento (porcentaje o valor fijo) en pesos sobre el valor bruto.
+func calcularDescuento(tipo string, valor, bruto float64) (float64, error) {
+	if valor == 0 {
+		return 0, nil
+	}
+	if valor < 0 {
+		return 0, fmt.Errorf("el descuento no puede ser negativo")
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(tipo)) {
+	case DescuentoPorcentaje:
+		if valor > 100 {
+			return 0, fmt.Errorf("el descuento porcentual no puede superar el 100%%")
+		}
+		return redondearMoneda(bruto * valor / 100), nil
+	case DescuentoValor:
+		if valor > bruto {
+			return 0, fmt.Errorf("el descuento (%.2f) supera el valor de la venta (%.2f)", valor, bruto)
+		}
+		return redondearMoneda(valor), nil
+	default:
+		return 0, fmt.Errorf("tipo de descuento inválido '%s': use %s o %s", tipo, DescuentoPorcentaje, DescuentoValor)
+	}
+}
+
+// porcentajeDescuento expresa un descuento como porcentaje del valor bruto.
+func porcentajeDescuento(descuento, bruto float64) float64 {
+	if bruto <= 0 {
+		return 0
+	}
+	return descuento / bruto * 100
+}
+
+// prorratearDescuento reparte un descuento de factura entre las líneas en proporción a su valor neto,
+// de modo que la base gravable de cada línea refleje el descuento. La última línea absorbe el redondeo.
+func prorratearDescuento(detalles []DetalleFactura, descuento float64) {
+	if descuento <= 0 || len(detalles) == 0 {
+		return
+	}
+	var totalNeto float64
+	for _, det := range detalles {
+		totalNeto += det.PrecioTotal
+	}
+	if totalNeto <= 0 {
+		return
+	}
+
+	restante := descuento
+	for i := range detalles {
+		parte := redondearMoneda(descuento * detalles[i].PrecioTotal / totalNeto)
+		if i == len(detalles)-1 {
+			parte = redondearMoneda(restante)
+		}
+		restante -= parte
+		detalles[i].Descuento = redondearMoneda(detalles[i].Descuento + parte)
+		detalles[i].PrecioTotal = redondearMoneda(detalles[i].PrecioTotal - parte)
+	}
+}
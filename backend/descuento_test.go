@@ -0,0 +1,108 @@
+package backend
+
+import "testing"
+
+func TestCalcularDescuento(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		tipo      string
+		valor     float64
+		bruto     float64
+		descuento float64
+		error     bool
+	}{
+		{nombre: "porcentaje", tipo: DescuentoPorcentaje, valor: 10, bruto: 25000, descuento: 2500},
+		{nombre: "porcentaje redondeado a centavos", tipo: DescuentoPorcentaje, valor: 33.33, bruto: 1000, descuento: 333.3},
+		{nombre: "tipo en minúscula y con espacios", tipo: " porcentaje ", valor: 5, bruto: 8000, descuento: 400},
+		{nombre: "porcentaje total", tipo: DescuentoPorcentaje, valor: 100, bruto: 4500, descuento: 4500},
+		{nombre: "valor fijo", tipo: DescuentoValor, valor: 3000, bruto: 20000, descuento: 3000},
+		{nombre: "valor igual al bruto", tipo: DescuentoValor, valor: 20000, bruto: 20000, descuento: 20000},
+		{nombre: "sin descuento no valida el tipo", tipo: "", valor: 0, bruto: 20000, descuento: 0},
+		{nombre: "negativo", tipo: DescuentoValor, valor: -1, bruto: 20000, error: true},
+		{nombre: "porcentaje mayor a 100", tipo: DescuentoPorcentaje, valor: 100.5, bruto: 20000, error: true},
+		{nombre: "valor mayor al bruto", tipo: DescuentoValor, valor: 20000.01, bruto: 20000, error: true},
+		{nombre: "tipo desconocido", tipo: "CUPON", valor: 10, bruto: 20000, error: true},
+	}
+	for _, c := range casos {
+		descuento, err := calcularDescuento(c.tipo, c.valor, c.bruto)
+		if c.error {
+			if err == nil {
+				t.Errorf("%s: se esperaba error y se obtuvo %.2f", c.nombre, descuento)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error inesperado: %v", c.nombre, err)
+			continue
+		}
+		if descuento != c.descuento {
+			t.Errorf("%s: descuento = %.2f, se esperaba %.2f", c.nombre, descuento, c.descuento)
+		}
+	}
+}
+
+func TestProrratearDescuento(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		netos      []float64
+		previos    []float64 // descuento de línea ya aplicado
+		descuento  float64
+		descuentos []float64
+		netosFinal []float64
+	}{
+		{
+			nombre:     "proporcional al neto",
+			netos:      []float64{10000, 20000, 30000},
+			previos:    []float64{0, 0, 0},
+			descuento:  6000,
+			descuentos: []float64{1000, 2000, 3000},
+			netosFinal: []float64{9000, 18000, 27000},
+		},
+		{
+			nombre:     "la última línea absorbe el redondeo",
+			netos:      []float64{100, 100, 100},
+			previos:    []float64{0, 0, 0},
+			descuento:  100,
+			descuentos: []float64{33.33, 33.33, 33.34},
+			netosFinal: []float64{66.67, 66.67, 66.66},
+		},
+		{
+			nombre:     "se suma al descuento de línea",
+			netos:      []float64{4500, 5500},
+			previos:    []float64{500, 0},
+			descuento:  1000,
+			descuentos: []float64{950, 550},
+			netosFinal: []float64{4050, 4950},
+		},
+		{
+			nombre:     "sin descuento no cambia las líneas",
+			netos:      []float64{1000, 2000},
+			previos:    []float64{0, 0},
+			descuento:  0,
+			descuentos: []float64{0, 0},
+			netosFinal: []float64{1000, 2000},
+		},
+		{
+			nombre:     "líneas en cero",
+			netos:      []float64{0, 0},
+			previos:    []float64{0, 0},
+			descuento:  500,
+			descuentos: []float64{0, 0},
+			netosFinal: []float64{0, 0},
+		},
+	}
+	for _, c := range casos {
+		detalles := make([]DetalleFactura, len(c.netos))
+		for i := range c.netos {
+			detalles[i] = DetalleFactura{PrecioTotal: c.netos[i], Descuento: c.previos[i]}
+		}
+		prorratearDescuento(detalles, c.descuento)
+
+		for i, det := range detalles {
+			if det.Descuento != c.descuentos[i] || det.PrecioTotal != c.netosFinal[i] {
+				t.Errorf("%s: línea %d con descuento %.2f y neto %.2f, se esperaba %.2f y %.2f",
+					c.nombre, i, det.Descuento, det.PrecioTotal, c.descuentos[i], c.netosFinal[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// RegistrarDevolucion procesa una devolución parcial o total de una factura.
+// Genera una nota crédito con numeración propia (NC-) y regresa las unidades al inventario
+// con operaciones DEVOLUCION_CLIENTE asociadas a la factura original.
+// Si la factura es a crédito, la nota reduce primero su saldo pendiente.
+func (d *Db) RegistrarDevolucion(req DevolucionRequest) (NotaCredito, error) {
+	if req.FacturaUUID == "" || req.VendedorUUID == "" {
+		return NotaCredito{}, fmt.Errorf("se requiere la factura y el vendedor que registra la devolución")
+	}
+	if len(req.Items) == 0 {
+		return NotaCredito{}, fmt.Errorf("la devolución no contiene productos")
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return NotaCredito{}, fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[RegistrarDevolucion] rollback: %v", rErr)
+		}
+	}()
+
+	// 1️⃣ Validar la factura
+	var estado sql.NullString
+	var clienteUUID string
+	err = tx.QueryRow(`SELECT estado, cliente_uuid FROM facturas WHERE uuid = ?`, req.FacturaUUID).Scan(&estado, &clienteUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return NotaCredito{}, fmt.Errorf("factura [%s] no encontrada", req.FacturaUUID)
+		}
+		return NotaCredito{}, fmt.Errorf("error consultando factura: %w", err)
+	}
+	if strings.EqualFold(estado.String, "ANULADA") {
+		return NotaCredito{}, fmt.Errorf("no se puede registrar una devolución sobre una factura anulada")
+	}
+
+	// El reembolso sale de la caja abierta de quien registra la devolución, si la tiene
+	sesionCajaUUID, err := sesionCajaAbierta(tx, req.VendedorUUID)
+	if err != nil {
+		return NotaCredito{}, err
+	}
+
+	numeroNota, err := generarConsecutivo(tx, "notas_credito", "numero_nota", d.prefijoDocumentoTerminal("NC-"))
+	if err != nil {
+		return NotaCredito{}, fmt.Errorf("error al generar número de nota crédito: %w", err)
+	}
+
+	now := time.Now()
+	nota := NotaCredito{
+		UUID:           uuid.New().String(),
+		NumeroNota:     numeroNota,
+		FacturaUUID:    req.FacturaUUID,
+		FechaEmision:   now,
+		VendedorUUID:   req.VendedorUUID,
+		ClienteUUID:    clienteUUID,
+		SesionCajaUUID: sesionCajaUUID,
+		Motivo:         strings.TrimSpace(req.Motivo),
+	}
+
+	// 2️⃣ Validar cantidades contra lo vendido y lo ya devuelto
+	stmtDet, err := tx.Prepare(`
+		SELECT d.producto_uuid, d.cantidad, d.precio_total, d.tarifa_impuesto, d.factor_conversion,
+			(SELECT COALESCE(SUM(dn.cantidad), 0) FROM detalle_notas_credito dn WHERE dn.detalle_factura_uuid = d.uuid)
+		FROM detalle_facturas d
+		WHERE d.uuid = ? AND d.factura_uuid = ?`)
+	if err != nil {
+		return NotaCredito{}, fmt.Errorf("error preparando consulta de detalles: %w", err)
+	}
+	defer stmtDet.Close()
+
+	solicitado := make(map[string]int, len(req.Items))
+	for _, item := range req.Items {
+		if item.Cantidad <= 0 {
+			return NotaCredito{}, fmt.Errorf("la cantidad a devolver debe ser mayor que cero")
+		}
+		solicitado[item.DetalleFacturaUUID] += item.Cantidad
+	}
+
+	// Las cantidades se devuelven en la presentación vendida; el stock regresa en unidades mínimas
+	factores := make(map[string]int, len(solicitado))
+	var subtotal, iva float64
+	for detalleUUID, cantidad := range solicitado {
+		var productoUUID string
+		var vendida, devuelta, factor int
+		var totalLinea, tarifa float64
+		if err := stmtDet.QueryRow(detalleUUID, req.FacturaUUID).Scan(&productoUUID, &vendida, &totalLinea, &tarifa, &factor, &devuelta); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NotaCredito{}, fmt.Errorf("el detalle [%s] no pertenece a la factura", detalleUUID)
+			}
+			return NotaCredito{}, fmt.Errorf("error consultando detalle [%s]: %w", detalleUUID, err)
+		}
+		if cantidad > vendida-devuelta {
+			return NotaCredito{}, fmt.Errorf("cantidad a devolver (%d) supera lo disponible para devolución (%d) en el producto [%s]",
+				cantidad, vendida-devuelta, productoUUID)
+		}
+		if factor < 1 {
+			factor = 1
+		}
+		factores[detalleUUID] = factor
+
+		// Se reembolsa el valor neto pagado (después de descuentos), proporcional a las unidades
+		// devueltas, y se reversa el impuesto con la misma tarifa con la que se vendió
+		precioUnitario := redondearMoneda(totalLinea / float64(vendida))
+		precioTotal := redondearMoneda(totalLinea * float64(cantidad) / float64(vendida))
+		base, valorImpuesto := calcularImpuestoIncluido(precioTotal, tarifa)
+		subtotal += base
+		iva += valorImpuesto
+		nota.Detalles = append(nota.Detalles, DetalleNotaCredito{
+			UUID:               uuid.New().String(),
+			NotaCreditoUUID:    nota.UUID,
+			DetalleFacturaUUID: detalleUUID,
+			ProductoUUID:       productoUUID,
+			Cantidad:           cantidad,
+			PrecioUnitario:     precioUnitario,
+			PrecioTotal:        precioTotal,
+		})
+	}
+
+	nota.Subtotal = redondearMoneda(subtotal)
+	nota.IVA = redondearMoneda(iva)
+	nota.Total = nota.Subtotal + nota.IVA
+
+	// 2.b En una venta a crédito lo devuelto se descuenta primero del saldo pendiente; solo el excedente se reembolsa
+	var saldoCubierto bool
+	if strings.EqualFold(estado.String, EstadoFacturaPendiente) {
+		saldo, err := saldoFactura(tx, req.FacturaUUID)
+		if err != nil {
+			return NotaCredito{}, err
+		}
+		nota.AplicadoCartera = redondearMoneda(math.Max(math.Min(saldo, nota.Total), 0))
+		saldoCubierto = saldo-nota.AplicadoCartera <= 0.005
+	}
+
+	// 3️⃣ Insertar nota crédito y sus detalles
+	_, err = tx.Exec(`
+		INSERT INTO notas_credito (
+			uuid, numero_nota, factura_uuid, fecha_emision, vendedor_uuid, cliente_uuid,
+			motivo, subtotal, iva, total, aplicado_cartera, sesion_caja_uuid, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		nota.UUID, nota.NumeroNota, nota.FacturaUUID, nota.FechaEmision, nota.VendedorUUID, nota.ClienteUUID,
+		nota.Motivo, nota.Subtotal, nota.IVA, nota.Total, nota.AplicadoCartera, nullableString(nota.SesionCajaUUID), now, now)
+	if err != nil {
+		return NotaCredito{}, fmt.Errorf("error insertando nota crédito: %w", err)
+	}
+	if saldoCubierto {
+		if _, err := tx.Exec(`UPDATE facturas SET estado = ?, updated_at = ? WHERE uuid = ?`, EstadoFacturaPagada, now, req.FacturaUUID); err != nil {
+			return NotaCredito{}, fmt.Errorf("error actualizando estado de la factura: %w", err)
+		}
+	}
+
+	// 3.b Retirar los puntos acumulados en proporción a lo devuelto
+	if err := d.reversarPuntosDevolucion(tx, req.FacturaUUID, req.VendedorUUID, nota.NumeroNota); err != nil {
+		return NotaCredito{}, err
+	}
+
+	stmtIns, err := tx.Prepare(`
+		INSERT INTO detalle_notas_credito (
+			uuid, nota_credito_uuid, detalle_factura_uuid, producto_uuid, cantidad, precio_unitario, precio_total,
+			created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return NotaCredito{}, fmt.Errorf("error preparando statement detalle_notas_credito: %w", err)
+	}
+	defer stmtIns.Close()
+
+	for _, det := range nota.Detalles {
+		if _, err := stmtIns.Exec(det.UUID, nota.UUID, det.DetalleFacturaUUID, det.ProductoUUID,
+			det.Cantidad, det.PrecioUnitario, det.PrecioTotal, now, now); err != nil {
+			return NotaCredito{}, fmt.Errorf("error insertando detalle de nota crédito %s: %w", det.ProductoUUID, err)
+		}
+
+		// 4️⃣ Regresar stock a los lotes de los que salió
+		if err := d.reintegrarStockLotes(
+			tx,
+			det.ProductoUUID,
+			"DEVOLUCION_CLIENTE",
+			det.Cantidad*factores[det.DetalleFacturaUUID],
+			req.VendedorUUID,
+			req.FacturaUUID,
+		); err != nil {
+			return NotaCredito{}, fmt.Errorf("error registrando operación de stock por devolución [%s]: %w", det.ProductoUUID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NotaCredito{}, fmt.Errorf("error confirmando transacción de devolución: %w", err)
+	}
+	d.Log.Infof("[DEVOLUCION] Nota crédito %s generada para factura %s por %.2f", nota.NumeroNota, nota.FacturaUUID, nota.Total)
+
+	go func() {
+		if err := d.syncNotaCreditoToRemote(nota.UUID); err != nil {
+			d.Log.Errorf("[SYNC] Error sincronizando nota crédito %s: %v", nota.UUID, err)
+		}
+	}()
+
+	return d.ObtenerDetalleNotaCredito(nota.UUID)
+}
+
+// ObtenerDetalleNotaCredito devuelve una nota crédito con sus detalles y productos.
+func (d *Db) ObtenerDetalleNotaCredito(notaUUID string) (NotaCredito, error) {
+	var nota NotaCredito
+	err := d.LocalDB.QueryRow(`
+		SELECT uuid, numero_nota, factura_uuid, fecha_emision, vendedor_uuid, cliente_uuid,
+			COALESCE(motivo, ''), subtotal, iva, total, aplicado_cartera, COALESCE(sesion_caja_uuid, ''), created_at, updated_at
+		FROM notas_credito
+		WHERE uuid = ?`, notaUUID).Scan(
+		&nota.UUID, &nota.NumeroNota, &nota.FacturaUUID, &nota.FechaEmision, &nota.VendedorUUID, &nota.ClienteUUID,
+		&nota.Motivo, &nota.Subtotal, &nota.IVA, &nota.Total, &nota.AplicadoCartera, &nota.SesionCajaUUID, &nota.CreatedAt, &nota.UpdatedAt)
+	if err != nil {
+		return NotaCredito{}, fmt.Errorf("error al obtener nota crédito %s: %w", notaUUID, err)
+	}
+
+	nota.Detalles, err = d.obtenerDetallesNotaCredito(notaUUID)
+	if err != nil {
+		return nota, err
+	}
+	return nota, nil
+}
+
+// obtenerNotasCreditoFactura lista las notas crédito (con detalles) emitidas contra una factura.
+func (d *Db) obtenerNotasCreditoFactura(facturaUUID string) ([]NotaCredito, error) {
+	notas := make([]NotaCredito, 0)
+	rows, err := d.LocalDB.Query(`
+		SELECT uuid, numero_nota, factura_uuid, fecha_emision, vendedor_uuid, cliente_uuid,
+			COALESCE(motivo, ''), subtotal, iva, total, created_at, updated_at
+		FROM notas_credito
+		WHERE factura_uuid = ?
+		ORDER BY fecha_emision ASC`, facturaUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando notas crédito: %w", err)
+	}
+	for rows.Next() {
+		var n NotaCredito
+		if err := rows.Scan(&n.UUID, &n.NumeroNota, &n.FacturaUUID, &n.FechaEmision, &n.VendedorUUID, &n.ClienteUUID,
+			&n.Motivo, &n.Subtotal, &n.IVA, &n.Total, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error escaneando nota crédito: %w", err)
+		}
+		notas = append(notas, n)
+	}
+	rows.Close()
+
+	for i := range notas {
+		notas[i].Detalles, err = d.obtenerDetallesNotaCredito(notas[i].UUID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return notas, nil
+}
+
+func (d *Db) obtenerDetallesNotaCredito(notaUUID string) ([]DetalleNotaCredito, error) {
+	detalles := make([]DetalleNotaCredito, 0)
+	rows, err := d.LocalDB.Query(`
+		SELECT dn.uuid, dn.nota_credito_uuid, dn.detalle_factura_uuid, dn.cantidad, dn.precio_unitario, dn.precio_total,
+			p.uuid, p.codigo, p.nombre
+		FROM detalle_notas_credito dn
+		JOIN productos p ON p.uuid = dn.producto_uuid
+		WHERE dn.nota_credito_uuid = ?`, notaUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando detalles de nota crédito: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var det DetalleNotaCredito
+		if err := rows.Scan(&det.UUID, &det.NotaCreditoUUID, &det.DetalleFacturaUUID, &det.Cantidad, &det.PrecioUnitario, &det.PrecioTotal,
+			&det.Producto.UUID, &det.Producto.Codigo, &det.Producto.Nombre); err != nil {
+			return nil, fmt.Errorf("error escaneando detalle de nota crédito: %w", err)
+		}
+		det.ProductoUUID = det.Producto.UUID
+		detalles = append(detalles, det)
+	}
+	return detalles, rows.Err()
+}
+
+// syncNotaCreditoToRemote sube una nota crédito y sus detalles al remoto. Las operaciones de
+// stock de la devolución viajan con el resto de operaciones pendientes. Sin conexión la nota queda
+// con sincronizado = 0 y la sube SincronizarNotasCreditoHaciaRemoto.
+func (d *Db) syncNotaCreditoToRemote(notaUUID string) error {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return nil
+	}
+	ctx := d.ctx
+	runtime.EventsEmit(d.ctx, "sync:start", notaUUID)
+
+	nota, err := d.ObtenerDetalleNotaCredito(notaUUID)
+	if err != nil {
+		return fmt.Errorf("[LOCAL] - nota crédito no encontrada localmente: %w", err)
+	}
+
+	// La factura debe existir en el remoto antes de la nota crédito.
+	if err := d.syncVentaToRemote(nota.FacturaUUID); err != nil {
+		return fmt.Errorf("error sincronizando factura de la nota crédito: %w", err)
+	}
+
+	rtx, err := d.RemoteDB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("[REMOTO] - error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := rtx.Rollback(ctx); rErr != nil && !errors.Is(rErr, pgx.ErrTxClosed) {
+			d.Log.Errorf("[REMOTO] - Error durante [syncNotaCreditoToRemote] rollback %v", rErr)
+		}
+	}()
+
+	_, err = rtx.Exec(ctx, `
+		INSERT INTO notas_credito (uuid, numero_nota, factura_uuid, fecha_emision, vendedor_uuid, cliente_uuid,
+			motivo, subtotal, iva, total, aplicado_cartera, sesion_caja_uuid, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (uuid) DO NOTHING`,
+		nota.UUID, nota.NumeroNota, nota.FacturaUUID, nota.FechaEmision, nota.VendedorUUID, nota.ClienteUUID,
+		nota.Motivo, nota.Subtotal, nota.IVA, nota.Total, nota.AplicadoCartera, nullableString(nota.SesionCajaUUID), nota.CreatedAt, nota.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("[REMOTO] - error insertando nota crédito: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, det := range nota.Detalles {
+		batch.Queue(`
+			INSERT INTO detalle_notas_credito (uuid, nota_credito_uuid, detalle_factura_uuid, producto_uuid, cantidad,
+				precio_unitario, precio_total, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (uuid) DO NOTHING`,
+			det.UUID, det.NotaCreditoUUID, det.DetalleFacturaUUID, det.ProductoUUID, det.Cantidad,
+			det.PrecioUnitario, det.PrecioTotal, nota.CreatedAt, nota.UpdatedAt)
+	}
+	if err := rtx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("[REMOTO] - Error ejecutando batch de detalle_notas_credito: %w", err)
+	}
+
+	if err := rtx.Commit(ctx); err != nil {
+		return fmt.Errorf("Error confirmando transacción remota: %w", err)
+	}
+
+	if _, err := d.LocalDB.ExecContext(ctx, `UPDATE notas_credito SET sincronizado = 1 WHERE uuid = ?`, nota.UUID); err != nil {
+		return fmt.Errorf("[LOCAL] - error marcando nota crédito sincronizada: %w", err)
+	}
+	d.Log.Infof("[LOCAL -> REMOTO] - Nota crédito %s sincronizada correctamente.", nota.UUID)
+	runtime.EventsEmit(d.ctx, "sync:finish", notaUUID)
+	go d.SincronizarOperacionesStockHaciaRemoto()
+	go d.SincronizarMovimientosPuntosHaciaRemoto()
+	go d.SincronizarLibroControladosHaciaRemoto()
+	return nil
+}
+
+// SincronizarNotasCreditoHaciaRemoto sube las notas crédito que quedaron pendientes por falta de conexión.
+func (d *Db) SincronizarNotasCreditoHaciaRemoto() {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] Base de datos remota no disponible, omitiendo sincronización de notas crédito.")
+		return
+	}
+	pendientes, err := d.uuidsPendientes(`SELECT uuid FROM notas_credito WHERE sincronizado = 0 ORDER BY created_at ASC`)
+	if err != nil {
+		d.Log.Errorf("[SYNC NOTAS] Error leyendo notas crédito pendientes: %v", err)
+		return
+	}
+	for _, notaUUID := range pendientes {
+		if err := d.syncNotaCreditoToRemote(notaUUID); err != nil {
+			d.Log.Errorf("[SYNC NOTAS] Error sincronizando nota crédito %s: %v", notaUUID, err)
+		}
+	}
+}
@@ -0,0 +1,972 @@
+package backend
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Códigos de tributo de la DIAN (tabla 13.2.6.1 del anexo técnico) que intervienen en el CUFE.
+const (
+	TributoIVA = "01"
+	TributoICA = "03"
+	TributoINC = "04"
+)
+
+// Tipos de ambiente de la DIAN.
+const (
+	AmbienteProduccion = "1"
+	AmbientePruebas    = "2"
+)
+
+// Plazo con el que se reporta a la DIAN el vencimiento de las ventas a crédito.
+const plazoCreditoDias = 30
+
+// Documento del adquiriente cuando la venta es a consumidor final.
+const ConsumidorFinalID = "222222222222"
+
+// Las fechas y horas de los documentos electrónicos se expresan en hora legal colombiana.
+var zonaHorariaColombia = time.FixedZone("COT", -5*60*60)
+
+// ConfiguracionDIAN reúne los datos del facturador electrónico: emisor, software y resolución de numeración.
+type ConfiguracionDIAN struct {
+	NIT                   string
+	DV                    string
+	RazonSocial           string
+	NombreComercial       string
+	TipoPersona           string // 1 = jurídica, 2 = natural
+	ResponsabilidadFiscal string
+	Direccion             string
+	CodigoMunicipio       string
+	Ciudad                string
+	CodigoDepartamento    string
+	Departamento          string
+	Telefono              string
+	Email                 string
+	SoftwareID            string
+	SoftwarePIN           string
+	ClaveTecnica          string
+	Ambiente              string
+	NumeroResolucion      string
+	PrefijoResolucion     string
+	ResolucionDesde       string // AAAA-MM-DD
+	ResolucionHasta       string // AAAA-MM-DD
+	RangoDesde            int64
+	RangoHasta            int64
+}
+
+// validar comprueba que la configuración tenga lo mínimo para calcular el CUFE y armar el XML.
+func (c ConfiguracionDIAN) validar() error {
+	var faltantes []string
+	if c.NIT == "" {
+		faltantes = append(faltantes, "DIAN_NIT")
+	}
+	if c.RazonSocial == "" {
+		faltantes = append(faltantes, "DIAN_RAZON_SOCIAL")
+	}
+	if c.ClaveTecnica == "" {
+		faltantes = append(faltantes, "DIAN_CLAVE_TECNICA")
+	}
+	if c.SoftwareID == "" {
+		faltantes = append(faltantes, "DIAN_SOFTWARE_ID")
+	}
+	if c.NumeroResolucion == "" {
+		faltantes = append(faltantes, "DIAN_RESOLUCION")
+	}
+	if c.RangoHasta == 0 {
+		faltantes = append(faltantes, "DIAN_RANGO_HASTA")
+	}
+	if len(faltantes) > 0 {
+		return fmt.Errorf("facturación electrónica sin configurar: faltan %s", strings.Join(faltantes, ", "))
+	}
+	if c.Ambiente != AmbienteProduccion && c.Ambiente != AmbientePruebas {
+		return fmt.Errorf("ambiente DIAN inválido '%s': use %s (producción) o %s (pruebas)", c.Ambiente, AmbienteProduccion, AmbientePruebas)
+	}
+	if c.RangoDesde > c.RangoHasta {
+		return fmt.Errorf("rango de la resolución inválido: DIAN_RANGO_DESDE (%d) es mayor que DIAN_RANGO_HASTA (%d)", c.RangoDesde, c.RangoHasta)
+	}
+	return nil
+}
+
+// validarNumero comprueba que el número de la factura, sin separadores, lleve el prefijo de la
+// resolución seguido de un consecutivo dentro del rango autorizado.
+func (c ConfiguracionDIAN) validarNumero(numeroFactura string) error {
+	numFac := numeroDocumentoDIAN(numeroFactura)
+	if !strings.HasPrefix(numFac, c.PrefijoResolucion) {
+		return fmt.Errorf("la factura %s no usa el prefijo %s de la resolución %s", numeroFactura, c.PrefijoResolucion, c.NumeroResolucion)
+	}
+	consecutivo, err := strconv.ParseInt(strings.TrimPrefix(numFac, c.PrefijoResolucion), 10, 64)
+	if err != nil {
+		return fmt.Errorf("la factura %s no tiene un consecutivo numérico después del prefijo %s", numeroFactura, c.PrefijoResolucion)
+	}
+	if consecutivo < c.RangoDesde || consecutivo > c.RangoHasta {
+		return fmt.Errorf("la factura %s está fuera del rango autorizado por la resolución %s (%d a %d)",
+			numeroFactura, c.NumeroResolucion, c.RangoDesde, c.RangoHasta)
+	}
+	return nil
+}
+
+// DatosCUFE son los campos que la DIAN concatena, en este orden, para calcular el CUFE.
+type DatosCUFE struct {
+	NumFac       string
+	FechaEmision time.Time
+	ValFac       float64 // Total antes de impuestos (LineExtensionAmount)
+	ValIVA       float64
+	ValINC       float64
+	ValICA       float64
+	ValTot       float64 // Total a pagar (PayableAmount)
+	NitOFE       string
+	NumAdq       string
+	ClaveTecnica string
+	Ambiente     string
+}
+
+// Cadena devuelve el texto sobre el que se calcula el hash, útil para comparar con la
+// validación previa de la DIAN cuando un CUFE es rechazado.
+func (c DatosCUFE) Cadena() string {
+	fecha, hora := fechaHoraDIAN(c.FechaEmision)
+	return c.NumFac + fecha + hora + formatoValorDIAN(c.ValFac) +
+		TributoIVA + formatoValorDIAN(c.ValIVA) +
+		TributoINC + formatoValorDIAN(c.ValINC) +
+		TributoICA + formatoValorDIAN(c.ValICA) +
+		formatoValorDIAN(c.ValTot) + c.NitOFE + c.NumAdq + c.ClaveTecnica + c.Ambiente
+}
+
+// CalcularCUFE devuelve el Código Único de Factura Electrónica: SHA-384 en hexadecimal de la cadena.
+func CalcularCUFE(c DatosCUFE) string {
+	suma := sha512.Sum384([]byte(c.Cadena()))
+	return hex.EncodeToString(suma[:])
+}
+
+// calcularCodigoSeguridadSoftware: SHA-384 del identificador del software, su PIN y el número del documento.
+func calcularCodigoSeguridadSoftware(softwareID, pin, numFac string) string {
+	suma := sha512.Sum384([]byte(softwareID + pin + numFac))
+	return hex.EncodeToString(suma[:])
+}
+
+// DigitoVerificacion calcula el dígito de verificación de un NIT con los pesos primos de la DIAN.
+func DigitoVerificacion(nit string) (string, error) {
+	pesos := []int{3, 7, 13, 17, 19, 23, 29, 37, 41, 43, 47, 53, 59, 67, 71}
+	nit = strings.TrimSpace(nit)
+	if nit == "" || len(nit) > len(pesos) {
+		return "", fmt.Errorf("NIT inválido '%s'", nit)
+	}
+	suma := 0
+	for i := 0; i < len(nit); i++ {
+		c := nit[len(nit)-1-i]
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("NIT inválido '%s': solo se admiten dígitos", nit)
+		}
+		suma += int(c-'0') * pesos[i]
+	}
+	residuo := suma % 11
+	if residuo > 1 {
+		return strconv.Itoa(11 - residuo), nil
+	}
+	return strconv.Itoa(residuo), nil
+}
+
+// numeroDocumentoDIAN quita los separadores del número interno: la DIAN solo admite prefijo alfanumérico y consecutivo.
+func numeroDocumentoDIAN(numeroFactura string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(numeroFactura)
+}
+
+func formatoValorDIAN(v float64) string {
+	return strconv.FormatFloat(redondearMoneda(v), 'f', 2, 64)
+}
+
+func fechaHoraDIAN(t time.Time) (fecha, hora string) {
+	t = t.In(zonaHorariaColombia)
+	return t.Format("2006-01-02"), t.Format("15:04:05-07:00")
+}
+
+// tributoDIAN indica el tributo de una categoría de impuesto de la tabla impuestos.
+// Los bienes excluidos no causan impuesto y no se reportan en los TaxTotal.
+func tributoDIAN(codigo string, tarifa float64) (string, bool) {
+	codigo = strings.ToUpper(strings.TrimSpace(codigo))
+	switch {
+	case codigo == ImpuestoPorDefecto, codigo == "" && tarifa == 0:
+		return "", false
+	case strings.HasPrefix(codigo, "INC"):
+		return TributoINC, true
+	default:
+		return TributoIVA, true
+	}
+}
+
+func nombreTributoDIAN(tributo string) string {
+	switch tributo {
+	case TributoINC:
+		return "INC"
+	case TributoICA:
+		return "ICA"
+	default:
+		return "IVA"
+	}
+}
+
+// tipoDocumentoDIAN traduce el tipo de identificación del cliente a la tabla 13.2.1 del anexo técnico.
+func tipoDocumentoDIAN(tipo string) string {
+	switch strings.ToUpper(strings.TrimSpace(tipo)) {
+	case "RC":
+		return "11"
+	case "TI":
+		return "12"
+	case "TE":
+		return "21"
+	case "CE":
+		return "22"
+	case "NIT":
+		return "31"
+	case "PASAPORTE", "PA", "PP":
+		return "41"
+	case "DIE":
+		return "42"
+	case "PEP":
+		return "47"
+	default:
+		return "13"
+	}
+}
+
+// medioPagoDIAN traduce el método de pago del POS a la tabla 13.3.4.2 del anexo técnico.
+func medioPagoDIAN(metodo string) string {
+	m := strings.ToLower(strings.TrimSpace(metodo))
+	switch {
+	case m == MetodoPagoEfectivo:
+		return "10"
+	case strings.Contains(m, "debito") || strings.Contains(m, "débito"):
+		return "49"
+	case strings.Contains(m, "credito") || strings.Contains(m, "crédito") || strings.Contains(m, "tarjeta"):
+		return "48"
+	case strings.Contains(m, "transferencia") || strings.Contains(m, "nequi") || strings.Contains(m, "daviplata"):
+		return "47"
+	default:
+		return "ZZZ"
+	}
+}
+
+// urlConsultaDIAN es la dirección del catálogo de la DIAN que se codifica en el QR de la factura.
+func urlConsultaDIAN(ambiente, cufe string) string {
+	if ambiente == AmbienteProduccion {
+		return "https://catalogo-vpfe.dian.gov.co/document/searchqr?documentkey=" + cufe
+	}
+	return "https://catalogo-vpfe-hab.dian.gov.co/document/searchqr?documentkey=" + cufe
+}
+
+// adquirienteDIAN es la identificación del cliente tal como se reporta a la DIAN.
+type adquirienteDIAN struct {
+	Nombre        string
+	TipoDocumento string
+	Numero        string
+	DV            string
+	TipoPersona   string
+	Cliente       Cliente
+}
+
+func nuevoAdquirienteDIAN(c Cliente) adquirienteDIAN {
+	numero := strings.TrimSpace(c.NumeroID)
+	if numero == "" || numero == ConsumidorFinalID {
+		return adquirienteDIAN{Nombre: "Consumidor final", TipoDocumento: "13", Numero: ConsumidorFinalID, TipoPersona: "2", Cliente: c}
+	}
+
+	a := adquirienteDIAN{
+		Nombre:        strings.TrimSpace(c.Nombre + " " + c.Apellido),
+		TipoDocumento: tipoDocumentoDIAN(c.TipoID),
+		Numero:        numero,
+		TipoPersona:   "2",
+		Cliente:       c,
+	}
+	if a.TipoDocumento == "31" {
+		a.TipoPersona = "1"
+		// El NIT puede venir con el dígito de verificación: 900123456-7
+		if i := strings.LastIndex(numero, "-"); i > 0 {
+			a.Numero, a.DV = strings.TrimSpace(numero[:i]), strings.TrimSpace(numero[i+1:])
+		}
+		a.Numero = strings.ReplaceAll(a.Numero, ".", "")
+		if a.DV == "" {
+			a.DV, _ = DigitoVerificacion(a.Numero)
+		}
+	}
+	return a
+}
+
+// totalTributo acumula la base y el impuesto de un tributo a una tarifa.
+type totalTributo struct {
+	Tributo string
+	Tarifa  float64
+	Base    float64
+	Valor   float64
+}
+
+// agruparTributos consolida los impuestos de los detalles por tributo y tarifa, en orden estable.
+func agruparTributos(detalles []DetalleFactura) []totalTributo {
+	type llave struct {
+		tributo string
+		tarifa  float64
+	}
+	porLlave := make(map[llave]*totalTributo)
+	for _, det := range detalles {
+		tributo, gravado := tributoDIAN(det.ImpuestoCodigo, det.TarifaImpuesto)
+		if !gravado {
+			continue
+		}
+		k := llave{tributo, det.TarifaImpuesto}
+		t, ok := porLlave[k]
+		if !ok {
+			t = &totalTributo{Tributo: tributo, Tarifa: det.TarifaImpuesto}
+			porLlave[k] = t
+		}
+		t.Base += det.BaseImpuesto
+		t.Valor += det.ValorImpuesto
+	}
+
+	totales := make([]totalTributo, 0, len(porLlave))
+	for _, t := range porLlave {
+		t.Base = redondearMoneda(t.Base)
+		t.Valor = redondearMoneda(t.Valor)
+		totales = append(totales, *t)
+	}
+	sort.Slice(totales, func(i, j int) bool {
+		if totales[i].Tributo != totales[j].Tributo {
+			return totales[i].Tributo < totales[j].Tributo
+		}
+		return totales[i].Tarifa > totales[j].Tarifa
+	})
+	return totales
+}
+
+// DocumentoDIAN es el resultado de armar la factura electrónica: el XML sin firmar y su CUFE.
+type DocumentoDIAN struct {
+	NumeroDocumento string
+	CUFE            string
+	CadenaCUFE      string
+	QR              string
+	XML             []byte
+}
+
+// GenerarXMLFacturaDIAN arma el XML UBL 2.1 de una factura según el anexo técnico de la DIAN y calcula su CUFE.
+// No usa base de datos ni red: la factura debe venir con Detalles, Cliente y Vendedor cargados.
+// La firma XAdES se agrega al enviar, por eso se deja una segunda UBLExtension vacía para ella.
+func GenerarXMLFacturaDIAN(f Factura, cfg ConfiguracionDIAN) (DocumentoDIAN, error) {
+	if err := cfg.validar(); err != nil {
+		return DocumentoDIAN{}, err
+	}
+	if len(f.Detalles) == 0 {
+		return DocumentoDIAN{}, errors.New("la factura no tiene detalles")
+	}
+	if strings.EqualFold(f.Estado, "ANULADA") {
+		return DocumentoDIAN{}, fmt.Errorf("la factura %s está anulada", f.NumeroFactura)
+	}
+	if err := cfg.validarNumero(f.NumeroFactura); err != nil {
+		return DocumentoDIAN{}, err
+	}
+
+	numFac := numeroDocumentoDIAN(f.NumeroFactura)
+	adquiriente := nuevoAdquirienteDIAN(f.Cliente)
+	dvEmisor := cfg.DV
+	if dvEmisor == "" {
+		var err error
+		if dvEmisor, err = DigitoVerificacion(cfg.NIT); err != nil {
+			return DocumentoDIAN{}, err
+		}
+	}
+
+	// Los precios del POS incluyen impuesto: la base de cada línea ya viene separada en BaseImpuesto.
+	var valFac, valTot float64
+	for _, det := range f.Detalles {
+		valFac += det.BaseImpuesto
+		valTot += det.BaseImpuesto + det.ValorImpuesto
+	}
+	valFac = redondearMoneda(valFac)
+	valTot = redondearMoneda(valTot)
+
+	tributos := agruparTributos(f.Detalles)
+	var valIVA, valINC, valICA, baseGravable float64
+	for _, t := range tributos {
+		switch t.Tributo {
+		case TributoIVA:
+			valIVA += t.Valor
+		case TributoINC:
+			valINC += t.Valor
+		case TributoICA:
+			valICA += t.Valor
+		}
+		baseGravable += t.Base
+	}
+
+	datos := DatosCUFE{
+		NumFac:       numFac,
+		FechaEmision: f.FechaEmision,
+		ValFac:       valFac,
+		ValIVA:       valIVA,
+		ValINC:       valINC,
+		ValICA:       valICA,
+		ValTot:       valTot,
+		NitOFE:       cfg.NIT,
+		NumAdq:       adquiriente.Numero,
+		ClaveTecnica: cfg.ClaveTecnica,
+		Ambiente:     cfg.Ambiente,
+	}
+	cufe := CalcularCUFE(datos)
+	fecha, hora := fechaHoraDIAN(f.FechaEmision)
+
+	qr := strings.Join([]string{
+		"NumFac: " + numFac,
+		"FecFac: " + fecha,
+		"HorFac: " + hora,
+		"NitFac: " + cfg.NIT,
+		"DocAdq: " + adquiriente.Numero,
+		"ValFac: " + formatoValorDIAN(valFac),
+		"ValIva: " + formatoValorDIAN(valIVA),
+		"ValOtroIm: " + formatoValorDIAN(valINC+valICA),
+		"ValTolFac: " + formatoValorDIAN(valTot),
+		"CUFE: " + cufe,
+		"QRCode: " + urlConsultaDIAN(cfg.Ambiente, cufe),
+	}, "\n")
+
+	inv := invoiceUBL{
+		Xmlns:      "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2",
+		XmlnsCac:   "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2",
+		XmlnsCbc:   "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2",
+		XmlnsDs:    "http://www.w3.org/2000/09/xmldsig#",
+		XmlnsExt:   "urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2",
+		XmlnsSts:   "dian:gov:co:facturaelectronica:Structures-2-1",
+		XmlnsXades: "http://uri.etsi.org/01903/v1.3.2#",
+		UBLExtensions: ublExtensionsUBL{Extension: []ublExtensionUBL{
+			{Content: extensionContentUBL{DianExtensions: &dianExtensionsUBL{
+				InvoiceControl: invoiceControlUBL{
+					InvoiceAuthorization: cfg.NumeroResolucion,
+					AuthorizationPeriod:  periodoUBL{StartDate: cfg.ResolucionDesde, EndDate: cfg.ResolucionHasta},
+					AuthorizedInvoices: facturasAutorizadasUBL{
+						Prefix: cfg.PrefijoResolucion,
+						From:   cfg.RangoDesde,
+						To:     cfg.RangoHasta,
+					},
+				},
+				InvoiceSource: fuenteUBL{IdentificationCode: codigoListaUBL{
+					ListAgencyID:   "6",
+					ListAgencyName: "United Nations Economic Commission for Europe",
+					ListSchemeURI:  "urn:oasis:names:specification:ubl:codelist:gc:CountryIdentificationCode-2.1",
+					Valor:          "CO",
+				}},
+				SoftwareProvider: proveedorSoftwareUBL{
+					ProviderID: identificadorDIAN(cfg.NIT, dvEmisor, "31"),
+					SoftwareID: identificadorUBL{SchemeAgencyID: "195", SchemeAgencyName: agenciaDIAN, Valor: cfg.SoftwareID},
+				},
+				SoftwareSecurityCode: identificadorUBL{
+					SchemeAgencyID:   "195",
+					SchemeAgencyName: agenciaDIAN,
+					Valor:            calcularCodigoSeguridadSoftware(cfg.SoftwareID, cfg.SoftwarePIN, numFac),
+				},
+				AuthorizationProvider: proveedorAutorizacionUBL{
+					AuthorizationProviderID: identificadorDIAN("800197268", "4", "31"),
+				},
+				QRCode: qr,
+			}}},
+			{}, // Firma XAdES
+		}},
+		UBLVersionID:         "UBL 2.1",
+		CustomizationID:      "10",
+		ProfileID:            "DIAN 2.1: Factura Electrónica de Venta",
+		ProfileExecutionID:   cfg.Ambiente,
+		ID:                   numFac,
+		UUID:                 identificadorUBL{SchemeID: cfg.Ambiente, SchemeName: "CUFE-SHA384", Valor: cufe},
+		IssueDate:            fecha,
+		IssueTime:            hora,
+		InvoiceTypeCode:      "01",
+		DocumentCurrencyCode: "COP",
+		LineCountNumeric:     len(f.Detalles),
+		AccountingSupplierParty: partyUBL{
+			AdditionalAccountID: cfg.TipoPersona,
+			Party:               emisorUBL(cfg, dvEmisor),
+		},
+		AccountingCustomerParty: partyUBL{
+			AdditionalAccountID: adquiriente.TipoPersona,
+			Party:               adquirienteUBL(adquiriente),
+		},
+		PaymentMeans: mediosPagoUBL(f),
+		TaxTotal:     totalesImpuestoUBL(tributos),
+		LegalMonetaryTotal: totalMonetarioUBL{
+			LineExtensionAmount: montoCOP(valFac),
+			TaxExclusiveAmount:  montoCOP(baseGravable),
+			TaxInclusiveAmount:  montoCOP(valTot),
+			PayableAmount:       montoCOP(valTot),
+		},
+	}
+	if f.Vendedor.Nombre != "" {
+		inv.Note = append(inv.Note, "Vendedor: "+strings.TrimSpace(f.Vendedor.Nombre+" "+f.Vendedor.Apellido))
+	}
+
+	for i, det := range f.Detalles {
+		inv.InvoiceLine = append(inv.InvoiceLine, lineaUBL(i+1, det))
+	}
+
+	cuerpo, err := xml.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return DocumentoDIAN{}, fmt.Errorf("error generando XML de la factura %s: %w", f.NumeroFactura, err)
+	}
+
+	return DocumentoDIAN{
+		NumeroDocumento: numFac,
+		CUFE:            cufe,
+		CadenaCUFE:      datos.Cadena(),
+		QR:              qr,
+		XML:             append([]byte(xml.Header), cuerpo...),
+	}, nil
+}
+
+const agenciaDIAN = "CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)"
+
+func identificadorDIAN(numero, dv, tipoDocumento string) identificadorUBL {
+	return identificadorUBL{
+		SchemeAgencyID:   "195",
+		SchemeAgencyName: agenciaDIAN,
+		SchemeID:         dv,
+		SchemeName:       tipoDocumento,
+		Valor:            numero,
+	}
+}
+
+func montoCOP(v float64) montoUBL {
+	return montoUBL{Moneda: "COP", Valor: formatoValorDIAN(v)}
+}
+
+func emisorUBL(cfg ConfiguracionDIAN, dv string) partyDetalleUBL {
+	direccion := &direccionUBL{
+		ID:                   cfg.CodigoMunicipio,
+		CityName:             cfg.Ciudad,
+		CountrySubentity:     cfg.Departamento,
+		CountrySubentityCode: cfg.CodigoDepartamento,
+		AddressLine:          lineaDireccionUBL{Line: cfg.Direccion},
+		Country:              paisColombiaUBL(),
+	}
+	nombre := cfg.NombreComercial
+	if nombre == "" {
+		nombre = cfg.RazonSocial
+	}
+	p := partyDetalleUBL{
+		PartyName: []nombreUBL{{Name: nombre}},
+		PartyTaxScheme: partyTaxSchemeUBL{
+			RegistrationName: cfg.RazonSocial,
+			CompanyID:        identificadorDIAN(cfg.NIT, dv, "31"),
+			TaxLevelCode:     codigoListaUBL{ListName: "48", Valor: cfg.ResponsabilidadFiscal},
+			TaxScheme:        esquemaTributoUBL{ID: TributoIVA, Name: "IVA"},
+		},
+		PartyLegalEntity: partyLegalEntityUBL{
+			RegistrationName:            cfg.RazonSocial,
+			CompanyID:                   identificadorDIAN(cfg.NIT, dv, "31"),
+			CorporateRegistrationScheme: &registroMercantilUBL{ID: cfg.PrefijoResolucion},
+		},
+	}
+	if cfg.Direccion != "" {
+		p.PhysicalLocation = &ubicacionUBL{Address: *direccion}
+		p.PartyTaxScheme.RegistrationAddress = direccion
+	}
+	if cfg.Telefono != "" || cfg.Email != "" {
+		p.Contact = &contactoUBL{Telephone: cfg.Telefono, ElectronicMail: cfg.Email}
+	}
+	return p
+}
+
+func adquirienteUBL(a adquirienteDIAN) partyDetalleUBL {
+	id := identificadorDIAN(a.Numero, a.DV, a.TipoDocumento)
+	p := partyDetalleUBL{
+		PartyIdentification: &identificacionUBL{ID: id},
+		PartyName:           []nombreUBL{{Name: a.Nombre}},
+		PartyTaxScheme: partyTaxSchemeUBL{
+			RegistrationName: a.Nombre,
+			CompanyID:        id,
+			TaxLevelCode:     codigoListaUBL{ListName: "48", Valor: "R-99-PN"},
+			TaxScheme:        esquemaTributoUBL{ID: "ZZ", Name: "No aplica"},
+		},
+		PartyLegalEntity: partyLegalEntityUBL{
+			RegistrationName: a.Nombre,
+			CompanyID:        id,
+		},
+	}
+	if dir := strings.TrimSpace(a.Cliente.Direccion); dir != "" && a.Numero != ConsumidorFinalID {
+		p.PhysicalLocation = &ubicacionUBL{Address: direccionUBL{
+			AddressLine: lineaDireccionUBL{Line: dir},
+			Country:     paisColombiaUBL(),
+		}}
+	}
+	if a.Numero != ConsumidorFinalID && (a.Cliente.Telefono != "" || a.Cliente.Email != "") {
+		p.Contact = &contactoUBL{Telephone: a.Cliente.Telefono, ElectronicMail: a.Cliente.Email}
+	}
+	return p
+}
+
+func paisColombiaUBL() paisUBL {
+	return paisUBL{IdentificationCode: "CO", Name: nombrePaisUBL{LanguageID: "es", Valor: "Colombia"}}
+}
+
+// mediosPagoUBL reporta un PaymentMeans por cada medio distinto. La parte vendida a crédito se
+// reporta con forma de pago 2 y vencimiento a plazoCreditoDias; el resto es de contado.
+func mediosPagoUBL(f Factura) []mediosPagoUBLItem {
+	vistos := make(map[string]bool)
+	var medios []mediosPagoUBLItem
+	agregar := func(metodo string) {
+		item := mediosPagoUBLItem{ID: "1", PaymentMeansCode: medioPagoDIAN(metodo)}
+		if strings.EqualFold(strings.TrimSpace(metodo), MetodoPagoCredito) {
+			item = mediosPagoUBLItem{ID: "2", PaymentMeansCode: "ZZZ",
+				PaymentDueDate: f.FechaEmision.In(zonaHorariaColombia).AddDate(0, 0, plazoCreditoDias).Format("2006-01-02")}
+		}
+		if vistos[item.ID+item.PaymentMeansCode] {
+			return
+		}
+		vistos[item.ID+item.PaymentMeansCode] = true
+		medios = append(medios, item)
+	}
+	for _, p := range f.Pagos {
+		agregar(p.MetodoPago)
+	}
+	if len(medios) == 0 {
+		agregar(f.MetodoPago)
+	}
+	return medios
+}
+
+func totalesImpuestoUBL(tributos []totalTributo) []taxTotalUBL {
+	var totales []taxTotalUBL
+	for _, t := range tributos {
+		if n := len(totales); n > 0 && totales[n-1].tributo == t.Tributo {
+			totales[n-1].valor += t.Valor
+			totales[n-1].TaxAmount = montoCOP(totales[n-1].valor)
+			totales[n-1].TaxSubtotal = append(totales[n-1].TaxSubtotal, subtotalImpuestoUBL(t))
+			continue
+		}
+		totales = append(totales, taxTotalUBL{
+			tributo:     t.Tributo,
+			valor:       t.Valor,
+			TaxAmount:   montoCOP(t.Valor),
+			TaxSubtotal: []taxSubtotalUBL{subtotalImpuestoUBL(t)},
+		})
+	}
+	return totales
+}
+
+func subtotalImpuestoUBL(t totalTributo) taxSubtotalUBL {
+	return taxSubtotalUBL{
+		TaxableAmount: montoCOP(t.Base),
+		TaxAmount:     montoCOP(t.Valor),
+		TaxCategory: categoriaImpuestoUBL{
+			Percent:   formatoValorDIAN(t.Tarifa),
+			TaxScheme: esquemaTributoUBL{ID: t.Tributo, Name: nombreTributoDIAN(t.Tributo)},
+		},
+	}
+}
+
+// lineaUBL expresa el detalle sin impuestos: el valor bruto se lleva a base dividiendo por
+// (1 + tarifa) y el descuento es la diferencia con BaseImpuesto, para que la línea cuadre.
+func lineaUBL(n int, det DetalleFactura) invoiceLineUBL {
+	factor := 1.0
+	if det.TarifaImpuesto > 0 {
+		factor = 1 + det.TarifaImpuesto/100
+	}
+	bruto := det.ValorBruto
+	if bruto == 0 {
+		bruto = det.PrecioUnitario * float64(det.Cantidad)
+	}
+	brutoBase := redondearMoneda(bruto / factor)
+	descuentoBase := redondearMoneda(brutoBase - det.BaseImpuesto)
+	precioBase := brutoBase
+	if det.Cantidad > 0 {
+		precioBase = brutoBase / float64(det.Cantidad)
+	}
+
+	linea := invoiceLineUBL{
+		ID:                  n,
+		InvoicedQuantity:    cantidadUBL{UnitCode: "94", Valor: strconv.Itoa(det.Cantidad)},
+		LineExtensionAmount: montoCOP(det.BaseImpuesto),
+		Item: itemUBL{
+			Description:               nombreConPresentacion(det.Producto.Nombre, det.Presentacion),
+			SellersItemIdentification: &identificacionItemUBL{ID: det.Producto.Codigo},
+		},
+		Price: precioUBL{
+			PriceAmount:  montoCOP(precioBase),
+			BaseQuantity: cantidadUBL{UnitCode: "94", Valor: "1"},
+		},
+	}
+	if det.Descuento > 0 && descuentoBase > 0 {
+		motivo := det.MotivoDescuento
+		if motivo == "" {
+			motivo = "Descuento"
+		}
+		linea.AllowanceCharge = append(linea.AllowanceCharge, cargoDescuentoUBL{
+			ID:                      1,
+			ChargeIndicator:         false,
+			AllowanceChargeReason:   motivo,
+			MultiplierFactorNumeric: formatoValorDIAN(porcentajeDescuento(descuentoBase, brutoBase)),
+			Amount:                  montoCOP(descuentoBase),
+			BaseAmount:              montoCOP(brutoBase),
+		})
+	}
+	if tributo, gravado := tributoDIAN(det.ImpuestoCodigo, det.TarifaImpuesto); gravado {
+		linea.TaxTotal = totalesImpuestoUBL([]totalTributo{{
+			Tributo: tributo,
+			Tarifa:  det.TarifaImpuesto,
+			Base:    det.BaseImpuesto,
+			Valor:   det.ValorImpuesto,
+		}})
+	}
+	return linea
+}
+
+// --- Estructuras UBL 2.1 ---
+// encoding/xml no maneja prefijos de espacio de nombres, por eso los nombres de elemento
+// llevan el prefijo literal y las declaraciones xmlns van como atributos de Invoice.
+
+type invoiceUBL struct {
+	XMLName                 xml.Name            `xml:"Invoice"`
+	Xmlns                   string              `xml:"xmlns,attr"`
+	XmlnsCac                string              `xml:"xmlns:cac,attr"`
+	XmlnsCbc                string              `xml:"xmlns:cbc,attr"`
+	XmlnsDs                 string              `xml:"xmlns:ds,attr"`
+	XmlnsExt                string              `xml:"xmlns:ext,attr"`
+	XmlnsSts                string              `xml:"xmlns:sts,attr"`
+	XmlnsXades              string              `xml:"xmlns:xades,attr"`
+	UBLExtensions           ublExtensionsUBL    `xml:"ext:UBLExtensions"`
+	UBLVersionID            string              `xml:"cbc:UBLVersionID"`
+	CustomizationID         string              `xml:"cbc:CustomizationID"`
+	ProfileID               string              `xml:"cbc:ProfileID"`
+	ProfileExecutionID      string              `xml:"cbc:ProfileExecutionID"`
+	ID                      string              `xml:"cbc:ID"`
+	UUID                    identificadorUBL    `xml:"cbc:UUID"`
+	IssueDate               string              `xml:"cbc:IssueDate"`
+	IssueTime               string              `xml:"cbc:IssueTime"`
+	InvoiceTypeCode         string              `xml:"cbc:InvoiceTypeCode"`
+	Note                    []string            `xml:"cbc:Note,omitempty"`
+	DocumentCurrencyCode    string              `xml:"cbc:DocumentCurrencyCode"`
+	LineCountNumeric        int                 `xml:"cbc:LineCountNumeric"`
+	AccountingSupplierParty partyUBL            `xml:"cac:AccountingSupplierParty"`
+	AccountingCustomerParty partyUBL            `xml:"cac:AccountingCustomerParty"`
+	PaymentMeans            []mediosPagoUBLItem `xml:"cac:PaymentMeans"`
+	TaxTotal                []taxTotalUBL       `xml:"cac:TaxTotal"`
+	LegalMonetaryTotal      totalMonetarioUBL   `xml:"cac:LegalMonetaryTotal"`
+	InvoiceLine             []invoiceLineUBL    `xml:"cac:InvoiceLine"`
+}
+
+type ublExtensionsUBL struct {
+	Extension []ublExtensionUBL `xml:"ext:UBLExtension"`
+}
+
+type ublExtensionUBL struct {
+	Content extensionContentUBL `xml:"ext:ExtensionContent"`
+}
+
+type extensionContentUBL struct {
+	DianExtensions *dianExtensionsUBL `xml:"sts:DianExtensions,omitempty"`
+}
+
+type dianExtensionsUBL struct {
+	InvoiceControl        invoiceControlUBL        `xml:"sts:InvoiceControl"`
+	InvoiceSource         fuenteUBL                `xml:"sts:InvoiceSource"`
+	SoftwareProvider      proveedorSoftwareUBL     `xml:"sts:SoftwareProvider"`
+	SoftwareSecurityCode  identificadorUBL         `xml:"sts:SoftwareSecurityCode"`
+	AuthorizationProvider proveedorAutorizacionUBL `xml:"sts:AuthorizationProvider"`
+	QRCode                string                   `xml:"sts:QRCode"`
+}
+
+type invoiceControlUBL struct {
+	InvoiceAuthorization string                 `xml:"sts:InvoiceAuthorization"`
+	AuthorizationPeriod  periodoUBL             `xml:"sts:AuthorizationPeriod"`
+	AuthorizedInvoices   facturasAutorizadasUBL `xml:"sts:AuthorizedInvoices"`
+}
+
+type periodoUBL struct {
+	StartDate string `xml:"cbc:StartDate"`
+	EndDate   string `xml:"cbc:EndDate"`
+}
+
+type facturasAutorizadasUBL struct {
+	Prefix string `xml:"sts:Prefix,omitempty"`
+	From   int64  `xml:"sts:From"`
+	To     int64  `xml:"sts:To"`
+}
+
+type fuenteUBL struct {
+	IdentificationCode codigoListaUBL `xml:"cbc:IdentificationCode"`
+}
+
+type proveedorSoftwareUBL struct {
+	ProviderID identificadorUBL `xml:"sts:ProviderID"`
+	SoftwareID identificadorUBL `xml:"sts:SoftwareID"`
+}
+
+type proveedorAutorizacionUBL struct {
+	AuthorizationProviderID identificadorUBL `xml:"sts:AuthorizationProviderID"`
+}
+
+type identificadorUBL struct {
+	SchemeAgencyID   string `xml:"schemeAgencyID,attr,omitempty"`
+	SchemeAgencyName string `xml:"schemeAgencyName,attr,omitempty"`
+	SchemeID         string `xml:"schemeID,attr,omitempty"`
+	SchemeName       string `xml:"schemeName,attr,omitempty"`
+	Valor            string `xml:",chardata"`
+}
+
+type codigoListaUBL struct {
+	ListAgencyID   string `xml:"listAgencyID,attr,omitempty"`
+	ListAgencyName string `xml:"listAgencyName,attr,omitempty"`
+	ListSchemeURI  string `xml:"listSchemeURI,attr,omitempty"`
+	ListName       string `xml:"listName,attr,omitempty"`
+	Valor          string `xml:",chardata"`
+}
+
+type montoUBL struct {
+	Moneda string `xml:"currencyID,attr"`
+	Valor  string `xml:",chardata"`
+}
+
+type cantidadUBL struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Valor    string `xml:",chardata"`
+}
+
+type partyUBL struct {
+	AdditionalAccountID string          `xml:"cbc:AdditionalAccountID"`
+	Party               partyDetalleUBL `xml:"cac:Party"`
+}
+
+type partyDetalleUBL struct {
+	PartyIdentification *identificacionUBL  `xml:"cac:PartyIdentification,omitempty"`
+	PartyName           []nombreUBL         `xml:"cac:PartyName"`
+	PhysicalLocation    *ubicacionUBL       `xml:"cac:PhysicalLocation,omitempty"`
+	PartyTaxScheme      partyTaxSchemeUBL   `xml:"cac:PartyTaxScheme"`
+	PartyLegalEntity    partyLegalEntityUBL `xml:"cac:PartyLegalEntity"`
+	Contact             *contactoUBL        `xml:"cac:Contact,omitempty"`
+}
+
+type identificacionUBL struct {
+	ID identificadorUBL `xml:"cbc:ID"`
+}
+
+type nombreUBL struct {
+	Name string `xml:"cbc:Name"`
+}
+
+type ubicacionUBL struct {
+	Address direccionUBL `xml:"cac:Address"`
+}
+
+type direccionUBL struct {
+	ID                   string            `xml:"cbc:ID,omitempty"`
+	CityName             string            `xml:"cbc:CityName,omitempty"`
+	CountrySubentity     string            `xml:"cbc:CountrySubentity,omitempty"`
+	CountrySubentityCode string            `xml:"cbc:CountrySubentityCode,omitempty"`
+	AddressLine          lineaDireccionUBL `xml:"cac:AddressLine"`
+	Country              paisUBL           `xml:"cac:Country"`
+}
+
+type lineaDireccionUBL struct {
+	Line string `xml:"cbc:Line"`
+}
+
+type paisUBL struct {
+	IdentificationCode string        `xml:"cbc:IdentificationCode"`
+	Name               nombrePaisUBL `xml:"cbc:Name"`
+}
+
+type nombrePaisUBL struct {
+	LanguageID string `xml:"languageID,attr"`
+	Valor      string `xml:",chardata"`
+}
+
+type partyTaxSchemeUBL struct {
+	RegistrationName    string            `xml:"cbc:RegistrationName"`
+	CompanyID           identificadorUBL  `xml:"cbc:CompanyID"`
+	TaxLevelCode        codigoListaUBL    `xml:"cbc:TaxLevelCode"`
+	RegistrationAddress *direccionUBL     `xml:"cac:RegistrationAddress,omitempty"`
+	TaxScheme           esquemaTributoUBL `xml:"cac:TaxScheme"`
+}
+
+type partyLegalEntityUBL struct {
+	RegistrationName            string                `xml:"cbc:RegistrationName"`
+	CompanyID                   identificadorUBL      `xml:"cbc:CompanyID"`
+	CorporateRegistrationScheme *registroMercantilUBL `xml:"cac:CorporateRegistrationScheme,omitempty"`
+}
+
+type registroMercantilUBL struct {
+	ID string `xml:"cbc:ID,omitempty"`
+}
+
+type contactoUBL struct {
+	Telephone      string `xml:"cbc:Telephone,omitempty"`
+	ElectronicMail string `xml:"cbc:ElectronicMail,omitempty"`
+}
+
+type esquemaTributoUBL struct {
+	ID   string `xml:"cbc:ID"`
+	Name string `xml:"cbc:Name"`
+}
+
+type mediosPagoUBLItem struct {
+	ID               string `xml:"cbc:ID"`
+	PaymentMeansCode string `xml:"cbc:PaymentMeansCode"`
+	PaymentDueDate   string `xml:"cbc:PaymentDueDate,omitempty"`
+}
+
+type taxTotalUBL struct {
+	tributo     string
+	valor       float64
+	TaxAmount   montoUBL         `xml:"cbc:TaxAmount"`
+	TaxSubtotal []taxSubtotalUBL `xml:"cac:TaxSubtotal"`
+}
+
+type taxSubtotalUBL struct {
+	TaxableAmount montoUBL             `xml:"cbc:TaxableAmount"`
+	TaxAmount     montoUBL             `xml:"cbc:TaxAmount"`
+	TaxCategory   categoriaImpuestoUBL `xml:"cac:TaxCategory"`
+}
+
+type categoriaImpuestoUBL struct {
+	Percent   string            `xml:"cbc:Percent"`
+	TaxScheme esquemaTributoUBL `xml:"cac:TaxScheme"`
+}
+
+type totalMonetarioUBL struct {
+	LineExtensionAmount montoUBL `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount  montoUBL `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount  montoUBL `xml:"cbc:TaxInclusiveAmount"`
+	PayableAmount       montoUBL `xml:"cbc:PayableAmount"`
+}
+
+type invoiceLineUBL struct {
+	ID                  int                 `xml:"cbc:ID"`
+	InvoicedQuantity    cantidadUBL         `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount montoUBL            `xml:"cbc:LineExtensionAmount"`
+	AllowanceCharge     []cargoDescuentoUBL `xml:"cac:AllowanceCharge"`
+	TaxTotal            []taxTotalUBL       `xml:"cac:TaxTotal"`
+	Item                itemUBL             `xml:"cac:Item"`
+	Price               precioUBL           `xml:"cac:Price"`
+}
+
+type cargoDescuentoUBL struct {
+	ID                      int      `xml:"cbc:ID"`
+	ChargeIndicator         bool     `xml:"cbc:ChargeIndicator"`
+	AllowanceChargeReason   string   `xml:"cbc:AllowanceChargeReason"`
+	MultiplierFactorNumeric string   `xml:"cbc:MultiplierFactorNumeric"`
+	Amount                  montoUBL `xml:"cbc:Amount"`
+	BaseAmount              montoUBL `xml:"cbc:BaseAmount"`
+}
+
+type itemUBL struct {
+	Description               string                 `xml:"cbc:Description"`
+	SellersItemIdentification *identificacionItemUBL `xml:"cac:SellersItemIdentification,omitempty"`
+}
+
+type identificacionItemUBL struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type precioUBL struct {
+	PriceAmount  montoUBL    `xml:"cbc:PriceAmount"`
+	BaseQuantity cantidadUBL `xml:"cbc:BaseQuantity"`
+}
@@ -0,0 +1,252 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Estados de una factura electrónica.
+const (
+	FacturaElectronicaGenerada  = "GENERADA"
+	FacturaElectronicaAceptada  = "ACEPTADA"
+	FacturaElectronicaRechazada = "RECHAZADA"
+	// FacturaElectronicaRevision marca un documento que no se debe enviar hasta que un administrador
+	// resuelva el caso, por ejemplo un número de factura repetido después de calcular el CUFE.
+	FacturaElectronicaRevision = "REVISION_MANUAL"
+)
+
+// RespuestaDIAN es el resultado de la validación de un documento electrónico.
+type RespuestaDIAN struct {
+	Aceptada bool
+	Mensaje  string
+}
+
+// EnviadorDIAN entrega una factura electrónica a la DIAN, directamente o a través de un
+// proveedor tecnológico, que es quien firma el XML y consume el servicio web.
+type EnviadorDIAN interface {
+	Enviar(ctx context.Context, fe FacturaElectronica) (RespuestaDIAN, error)
+}
+
+// EnviadorDIANLocal simula la recepción de la DIAN sin conexión: verifica que el XML contenga
+// el CUFE registrado y acepta el documento. Se usa mientras no haya un proveedor configurado.
+type EnviadorDIANLocal struct{}
+
+func (EnviadorDIANLocal) Enviar(_ context.Context, fe FacturaElectronica) (RespuestaDIAN, error) {
+	if fe.CUFE == "" || !strings.Contains(fe.XML, fe.CUFE) {
+		return RespuestaDIAN{Aceptada: false, Mensaje: "El CUFE del documento no coincide con el registrado"}, nil
+	}
+	return RespuestaDIAN{Aceptada: true, Mensaje: "Documento validado localmente (simulación, no enviado a la DIAN)"}, nil
+}
+
+// cargarConfiguracionDIAN lee del .env los datos del emisor, del software y de la resolución de facturación.
+func (d *Db) cargarConfiguracionDIAN() {
+	env := func(variable, porDefecto string) string {
+		if v := strings.TrimSpace(os.Getenv(variable)); v != "" {
+			return v
+		}
+		return porDefecto
+	}
+
+	d.configDIAN = ConfiguracionDIAN{
+		NIT:                   env("DIAN_NIT", ""),
+		DV:                    env("DIAN_DV", ""),
+		RazonSocial:           env("DIAN_RAZON_SOCIAL", ""),
+		NombreComercial:       env("DIAN_NOMBRE_COMERCIAL", ""),
+		TipoPersona:           env("DIAN_TIPO_PERSONA", "1"),
+		ResponsabilidadFiscal: env("DIAN_RESPONSABILIDAD_FISCAL", "R-99-PN"),
+		Direccion:             env("DIAN_DIRECCION", ""),
+		CodigoMunicipio:       env("DIAN_CODIGO_MUNICIPIO", ""),
+		Ciudad:                env("DIAN_CIUDAD", ""),
+		CodigoDepartamento:    env("DIAN_CODIGO_DEPARTAMENTO", ""),
+		Departamento:          env("DIAN_DEPARTAMENTO", ""),
+		Telefono:              env("DIAN_TELEFONO", ""),
+		Email:                 env("DIAN_EMAIL", ""),
+		SoftwareID:            env("DIAN_SOFTWARE_ID", ""),
+		SoftwarePIN:           env("DIAN_SOFTWARE_PIN", ""),
+		ClaveTecnica:          env("DIAN_CLAVE_TECNICA", ""),
+		Ambiente:              env("DIAN_AMBIENTE", AmbientePruebas),
+		NumeroResolucion:      env("DIAN_RESOLUCION", ""),
+		PrefijoResolucion:     env("DIAN_RESOLUCION_PREFIJO", ""),
+		ResolucionDesde:       env("DIAN_RESOLUCION_DESDE", ""),
+		ResolucionHasta:       env("DIAN_RESOLUCION_HASTA", ""),
+		RangoDesde:            leerEnteroPositivo(d, "DIAN_RANGO_DESDE", 1),
+		RangoHasta:            leerEnteroPositivo(d, "DIAN_RANGO_HASTA", 0),
+	}
+	d.enviadorDIAN = EnviadorDIANLocal{}
+
+	if err := d.configDIAN.validar(); err != nil {
+		d.Log.Warnf("[DIAN] %v", err)
+		return
+	}
+	d.Log.Infof("[DIAN] Facturación electrónica configurada para NIT %s (ambiente %s)", d.configDIAN.NIT, d.configDIAN.Ambiente)
+}
+
+// GenerarFacturaElectronica arma el XML UBL 2.1 de una factura, calcula su CUFE y los guarda.
+// Una factura ya aceptada por la DIAN no se vuelve a generar, ni una marcada para revisión manual.
+func (d *Db) GenerarFacturaElectronica(facturaUUID string) (FacturaElectronica, error) {
+	existente, err := d.ObtenerFacturaElectronica(facturaUUID)
+	if err == nil && existente.Estado == FacturaElectronicaAceptada {
+		return existente, nil
+	}
+	if err == nil && existente.Estado == FacturaElectronicaRevision {
+		return FacturaElectronica{}, fmt.Errorf("la factura %s requiere revisión manual: %s", existente.NumeroFactura, existente.MensajeDIAN)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return FacturaElectronica{}, err
+	}
+
+	factura, err := d.ObtenerDetalleFactura(facturaUUID)
+	if err != nil {
+		return FacturaElectronica{}, fmt.Errorf("error obteniendo factura %s: %w", facturaUUID, err)
+	}
+	// El detalle de la factura solo trae nombre e identificación; el XML necesita el cliente completo.
+	err = d.LocalDB.QueryRowContext(d.ctx, `
+		SELECT COALESCE(tipo_id, ''), COALESCE(telefono, ''), COALESCE(email, ''), COALESCE(direccion, '')
+		FROM clientes WHERE uuid = ?`, factura.ClienteUUID).Scan(
+		&factura.Cliente.TipoID, &factura.Cliente.Telefono, &factura.Cliente.Email, &factura.Cliente.Direccion)
+	if err != nil {
+		return FacturaElectronica{}, fmt.Errorf("error obteniendo cliente de la factura %s: %w", factura.NumeroFactura, err)
+	}
+
+	doc, err := GenerarXMLFacturaDIAN(factura, d.configDIAN)
+	if err != nil {
+		return FacturaElectronica{}, err
+	}
+
+	now := time.Now()
+	fe := FacturaElectronica{
+		UUID:          existente.UUID,
+		FacturaUUID:   facturaUUID,
+		NumeroFactura: factura.NumeroFactura,
+		CUFE:          doc.CUFE,
+		QR:            doc.QR,
+		XML:           string(doc.XML),
+		Ambiente:      d.configDIAN.Ambiente,
+		Estado:        FacturaElectronicaGenerada,
+		CreatedAt:     existente.CreatedAt,
+		UpdatedAt:     now,
+	}
+	if fe.UUID == "" {
+		fe.UUID = uuid.New().String()
+		fe.CreatedAt = now
+	}
+
+	_, err = d.LocalDB.ExecContext(d.ctx, `
+		INSERT INTO facturas_electronicas (uuid, factura_uuid, numero_factura, cufe, qr, xml, ambiente, estado, mensaje_dian, fecha_envio, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL, ?, ?)
+		ON CONFLICT(factura_uuid) DO UPDATE SET
+			numero_factura = excluded.numero_factura, cufe = excluded.cufe, qr = excluded.qr, xml = excluded.xml,
+			ambiente = excluded.ambiente, estado = excluded.estado, mensaje_dian = NULL, fecha_envio = NULL,
+			updated_at = excluded.updated_at`,
+		fe.UUID, fe.FacturaUUID, fe.NumeroFactura, fe.CUFE, fe.QR, fe.XML, fe.Ambiente, fe.Estado, fe.CreatedAt, fe.UpdatedAt)
+	if err != nil {
+		return FacturaElectronica{}, fmt.Errorf("error guardando factura electrónica %s: %w", factura.NumeroFactura, err)
+	}
+	d.Log.Infof("[DIAN] Factura electrónica %s generada, CUFE %s", doc.NumeroDocumento, doc.CUFE)
+
+	go d.syncFacturaElectronicaToRemote(facturaUUID)
+	return fe, nil
+}
+
+// EnviarFacturaElectronica genera (si hace falta) y envía la factura electrónica a la DIAN,
+// registrando la respuesta. Los errores de comunicación dejan el documento en GENERADA para reintentar.
+func (d *Db) EnviarFacturaElectronica(facturaUUID string) (FacturaElectronica, error) {
+	fe, err := d.ObtenerFacturaElectronica(facturaUUID)
+	if err == nil && fe.Estado == FacturaElectronicaRevision {
+		return FacturaElectronica{}, fmt.Errorf("la factura %s requiere revisión manual: %s", fe.NumeroFactura, fe.MensajeDIAN)
+	}
+	if err != nil || fe.Estado == FacturaElectronicaRechazada {
+		if fe, err = d.GenerarFacturaElectronica(facturaUUID); err != nil {
+			return FacturaElectronica{}, err
+		}
+	}
+	if fe.Estado == FacturaElectronicaAceptada {
+		return fe, nil
+	}
+
+	resp, err := d.enviadorDIAN.Enviar(d.ctx, fe)
+	if err != nil {
+		return FacturaElectronica{}, fmt.Errorf("error enviando la factura %s a la DIAN: %w", fe.NumeroFactura, err)
+	}
+
+	now := time.Now()
+	fe.Estado = FacturaElectronicaRechazada
+	if resp.Aceptada {
+		fe.Estado = FacturaElectronicaAceptada
+	}
+	fe.MensajeDIAN = resp.Mensaje
+	fe.FechaEnvio = &now
+	fe.UpdatedAt = now
+
+	if _, err := d.LocalDB.ExecContext(d.ctx,
+		`UPDATE facturas_electronicas SET estado = ?, mensaje_dian = ?, fecha_envio = ?, updated_at = ? WHERE uuid = ?`,
+		fe.Estado, fe.MensajeDIAN, now, now, fe.UUID); err != nil {
+		return FacturaElectronica{}, fmt.Errorf("error registrando respuesta de la DIAN: %w", err)
+	}
+	d.Log.Infof("[DIAN] Factura %s %s: %s", fe.NumeroFactura, fe.Estado, fe.MensajeDIAN)
+
+	go d.syncFacturaElectronicaToRemote(facturaUUID)
+	return fe, nil
+}
+
+// ObtenerFacturaElectronica devuelve el XML, el CUFE y el estado de envío de una factura.
+func (d *Db) ObtenerFacturaElectronica(facturaUUID string) (FacturaElectronica, error) {
+	var fe FacturaElectronica
+	err := d.LocalDB.QueryRowContext(d.ctx, `
+		SELECT uuid, factura_uuid, numero_factura, cufe, COALESCE(qr, ''), xml, ambiente, estado,
+			COALESCE(mensaje_dian, ''), fecha_envio, created_at, updated_at
+		FROM facturas_electronicas WHERE factura_uuid = ?`, facturaUUID).Scan(
+		&fe.UUID, &fe.FacturaUUID, &fe.NumeroFactura, &fe.CUFE, &fe.QR, &fe.XML, &fe.Ambiente, &fe.Estado,
+		&fe.MensajeDIAN, &fe.FechaEnvio, &fe.CreatedAt, &fe.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return FacturaElectronica{}, err
+		}
+		return FacturaElectronica{}, fmt.Errorf("error obteniendo factura electrónica: %w", err)
+	}
+	return fe, nil
+}
+
+// marcarRevisionManual deja la factura electrónica en REVISION_MANUAL con el motivo en el mensaje,
+// para que no se envíe a la DIAN hasta que un administrador resuelva el caso.
+func (d *Db) marcarRevisionManual(facturaUUID, motivo string) error {
+	if _, err := d.LocalDB.ExecContext(d.ctx,
+		`UPDATE facturas_electronicas SET estado = ?, mensaje_dian = ?, updated_at = ? WHERE factura_uuid = ?`,
+		FacturaElectronicaRevision, motivo, time.Now(), facturaUUID); err != nil {
+		return fmt.Errorf("error marcando factura electrónica para revisión manual: %w", err)
+	}
+	return nil
+}
+
+// DescargarXMLFacturaElectronica guarda el XML de la factura en la ruta que elija el usuario.
+// Devuelve la ruta del archivo, o vacío si se canceló el diálogo.
+func (d *Db) DescargarXMLFacturaElectronica(facturaUUID string) (string, error) {
+	fe, err := d.ObtenerFacturaElectronica(facturaUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		fe, err = d.GenerarFacturaElectronica(facturaUUID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ruta, err := runtime.SaveFileDialog(d.ctx, runtime.SaveDialogOptions{
+		Title:           "Guardar factura electrónica",
+		DefaultFilename: numeroDocumentoDIAN(fe.NumeroFactura) + ".xml",
+		Filters:         []runtime.FileFilter{{DisplayName: "XML (*.xml)", Pattern: "*.xml"}},
+	})
+	if err != nil || ruta == "" {
+		return "", err
+	}
+	if err := os.WriteFile(ruta, []byte(fe.XML), 0644); err != nil {
+		return "", fmt.Errorf("error guardando el XML en %s: %w", ruta, err)
+	}
+	return ruta, nil
+}
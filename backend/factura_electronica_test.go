@@ -0,0 +1,240 @@
+package backend
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Ejemplo de cálculo del CUFE del anexo técnico de factura electrónica de venta de la DIAN.
+func TestCalcularCUFEAnexoTecnico(t *testing.T) {
+	datos := DatosCUFE{
+		NumFac:       "323200000129",
+		FechaEmision: time.Date(2019, 1, 16, 10, 53, 10, 0, zonaHorariaColombia),
+		ValFac:       1500000,
+		ValIVA:       285000,
+		ValINC:       0,
+		ValICA:       0,
+		ValTot:       1785000,
+		NitOFE:       "700085371",
+		NumAdq:       "800199436",
+		ClaveTecnica: "693ff6f2a553c3646a063436fd4dd9ded0311471",
+		Ambiente:     AmbienteProduccion,
+	}
+
+	cadena := "3232000001292019-01-1610:53:10-05:001500000.0001285000.00040.00030.001785000.00" +
+		"700085371800199436693ff6f2a553c3646a063436fd4dd9ded03114711"
+	if got := datos.Cadena(); got != cadena {
+		t.Fatalf("cadena del CUFE\n got: %s\nwant: %s", got, cadena)
+	}
+
+	cufe := "8bb918b19ba22a694f1da11c643b5e9de39adf60311cf179179e9b33381030bcd4c3c3f156c506ed5908f9276f5bd9b4"
+	if got := CalcularCUFE(datos); got != cufe {
+		t.Fatalf("CUFE\n got: %s\nwant: %s", got, cufe)
+	}
+}
+
+func TestDigitoVerificacion(t *testing.T) {
+	casos := []struct {
+		nit string
+		dv  string
+	}{
+		{"800197268", "4"}, // DIAN
+		{"899999034", "1"},
+		{"860002964", "4"},
+		{"900373115", "3"},
+		{"900000009", "0"}, // residuo 0
+		{"900000002", "1"}, // residuo 1
+		{" 800197268 ", "4"},
+	}
+	for _, c := range casos {
+		dv, err := DigitoVerificacion(c.nit)
+		if err != nil {
+			t.Errorf("DigitoVerificacion(%q): error inesperado: %v", c.nit, err)
+			continue
+		}
+		if dv != c.dv {
+			t.Errorf("DigitoVerificacion(%q) = %s, se esperaba %s", c.nit, dv, c.dv)
+		}
+	}
+
+	for _, nit := range []string{"", "90037311A", "900.373.115", "1234567890123456"} {
+		if _, err := DigitoVerificacion(nit); err == nil {
+			t.Errorf("DigitoVerificacion(%q): se esperaba error", nit)
+		}
+	}
+}
+
+func configuracionDIANPrueba() ConfiguracionDIAN {
+	return ConfiguracionDIAN{
+		NIT:                   "900373115",
+		RazonSocial:           "Droguería de Prueba S.A.S.",
+		TipoPersona:           "1",
+		ResponsabilidadFiscal: "O-13",
+		Direccion:             "Calle 10 # 20-30",
+		CodigoMunicipio:       "11001",
+		Ciudad:                "Bogotá, D.C.",
+		CodigoDepartamento:    "11",
+		Departamento:          "Bogotá",
+		SoftwareID:            "56f2ae4e-9812-4fad-9255-08fcfcd5ccb0",
+		SoftwarePIN:           "12345",
+		ClaveTecnica:          "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c",
+		Ambiente:              AmbientePruebas,
+		NumeroResolucion:      "18760000001",
+		PrefijoResolucion:     "SETP",
+		ResolucionDesde:       "2019-01-19",
+		ResolucionHasta:       "2030-01-19",
+		RangoDesde:            990000000,
+		RangoHasta:            995000000,
+	}
+}
+
+// detallePrueba arma una línea con el precio con impuesto incluido, como la guarda RegistrarVenta.
+func detallePrueba(codigo, impuesto string, tarifa, total float64) DetalleFactura {
+	base, valor := calcularImpuestoIncluido(total, tarifa)
+	return DetalleFactura{
+		Producto:       Producto{Codigo: codigo, Nombre: "Producto " + codigo},
+		Cantidad:       1,
+		PrecioUnitario: total,
+		ValorBruto:     total,
+		PrecioTotal:    total,
+		ImpuestoCodigo: impuesto,
+		TarifaImpuesto: tarifa,
+		BaseImpuesto:   base,
+		ValorImpuesto:  valor,
+	}
+}
+
+func facturaMixtaPrueba() Factura {
+	return Factura{
+		NumeroFactura: "SETP-990000101",
+		FechaEmision:  time.Date(2026, 3, 2, 9, 15, 0, 0, zonaHorariaColombia),
+		MetodoPago:    MetodoPagoEfectivo,
+		Cliente:       Cliente{Nombre: "Ana", Apellido: "Gómez", TipoID: "CC", NumeroID: "1020304050"},
+		Detalles: []DetalleFactura{
+			detallePrueba("A1", "IVA_19", 19, 11900),
+			detallePrueba("A2", "IVA_5", 5, 10500),
+			detallePrueba("A3", "INC_8", 8, 10800),
+			detallePrueba("A4", ImpuestoPorDefecto, 0, 5000),
+		},
+	}
+}
+
+func TestGenerarXMLFacturaDIANTotalesImpuestosMixtos(t *testing.T) {
+	cfg := configuracionDIANPrueba()
+	doc, err := GenerarXMLFacturaDIAN(facturaMixtaPrueba(), cfg)
+	if err != nil {
+		t.Fatalf("GenerarXMLFacturaDIAN: %v", err)
+	}
+
+	var inv struct {
+		ID       string `xml:"ID"`
+		UUID     string `xml:"UUID"`
+		TaxTotal []struct {
+			TaxAmount   string `xml:"TaxAmount"`
+			TaxSubtotal []struct {
+				TaxableAmount string `xml:"TaxableAmount"`
+				TaxAmount     string `xml:"TaxAmount"`
+				Percent       string `xml:"TaxCategory>Percent"`
+				Tributo       string `xml:"TaxCategory>TaxScheme>ID"`
+			} `xml:"TaxSubtotal"`
+		} `xml:"TaxTotal"`
+		Totales struct {
+			LineExtensionAmount string `xml:"LineExtensionAmount"`
+			TaxExclusiveAmount  string `xml:"TaxExclusiveAmount"`
+			TaxInclusiveAmount  string `xml:"TaxInclusiveAmount"`
+			PayableAmount       string `xml:"PayableAmount"`
+		} `xml:"LegalMonetaryTotal"`
+	}
+	if err := xml.Unmarshal(doc.XML, &inv); err != nil {
+		t.Fatalf("el XML generado no es válido: %v", err)
+	}
+
+	if inv.ID != "SETP990000101" || doc.NumeroDocumento != "SETP990000101" {
+		t.Errorf("número del documento = %s / %s, se esperaba SETP990000101", inv.ID, doc.NumeroDocumento)
+	}
+
+	// El producto excluido no se reporta en los TaxTotal; IVA agrupa sus dos tarifas.
+	if len(inv.TaxTotal) != 2 {
+		t.Fatalf("TaxTotal: %d grupos, se esperaban 2 (IVA e INC)", len(inv.TaxTotal))
+	}
+	iva, inc := inv.TaxTotal[0], inv.TaxTotal[1]
+	if iva.TaxAmount != "2400.00" || len(iva.TaxSubtotal) != 2 {
+		t.Errorf("IVA = %s en %d tarifas, se esperaba 2400.00 en 2", iva.TaxAmount, len(iva.TaxSubtotal))
+	}
+	for i, want := range []struct{ tributo, tarifa, base, valor string }{
+		{TributoIVA, "19.00", "10000.00", "1900.00"},
+		{TributoIVA, "5.00", "10000.00", "500.00"},
+	} {
+		if i >= len(iva.TaxSubtotal) {
+			break
+		}
+		got := iva.TaxSubtotal[i]
+		if got.Tributo != want.tributo || got.Percent != want.tarifa || got.TaxableAmount != want.base || got.TaxAmount != want.valor {
+			t.Errorf("subtotal IVA %d = %+v, se esperaba %+v", i, got, want)
+		}
+	}
+	if inc.TaxAmount != "800.00" || len(inc.TaxSubtotal) != 1 || inc.TaxSubtotal[0].Tributo != TributoINC {
+		t.Errorf("INC = %s %+v, se esperaba 800.00 con tributo %s", inc.TaxAmount, inc.TaxSubtotal, TributoINC)
+	}
+
+	totales := inv.Totales
+	if totales.LineExtensionAmount != "35000.00" || totales.TaxExclusiveAmount != "30000.00" ||
+		totales.TaxInclusiveAmount != "38200.00" || totales.PayableAmount != "38200.00" {
+		t.Errorf("LegalMonetaryTotal = %+v", totales)
+	}
+
+	// El CUFE del XML se calcula con los mismos totales, con ICA en cero.
+	cufe := CalcularCUFE(DatosCUFE{
+		NumFac:       "SETP990000101",
+		FechaEmision: time.Date(2026, 3, 2, 9, 15, 0, 0, zonaHorariaColombia),
+		ValFac:       35000,
+		ValIVA:       2400,
+		ValINC:       800,
+		ValICA:       0,
+		ValTot:       38200,
+		NitOFE:       cfg.NIT,
+		NumAdq:       "1020304050",
+		ClaveTecnica: cfg.ClaveTecnica,
+		Ambiente:     cfg.Ambiente,
+	})
+	if inv.UUID != cufe || doc.CUFE != cufe {
+		t.Errorf("CUFE del XML = %s, se esperaba %s", inv.UUID, cufe)
+	}
+	if !strings.Contains(doc.CadenaCUFE, "01"+"2400.00"+"04"+"800.00"+"03"+"0.00") {
+		t.Errorf("la cadena del CUFE no trae los tributos esperados: %s", doc.CadenaCUFE)
+	}
+}
+
+func TestGenerarXMLFacturaDIANValidaNumeroResolucion(t *testing.T) {
+	cfg := configuracionDIANPrueba()
+	casos := []struct {
+		numero string
+		valido bool
+	}{
+		{"SETP-990000000", true},
+		{"SETP-995000000", true},
+		{"SETP-989999999", false}, // antes del rango autorizado
+		{"SETP-995000001", false}, // después del rango autorizado
+		{"FAC-990000101", false},  // otro prefijo
+		{"SETP-99000A101", false},
+	}
+	for _, c := range casos {
+		f := facturaMixtaPrueba()
+		f.NumeroFactura = c.numero
+		_, err := GenerarXMLFacturaDIAN(f, cfg)
+		if c.valido && err != nil {
+			t.Errorf("%s: error inesperado: %v", c.numero, err)
+		}
+		if !c.valido && err == nil {
+			t.Errorf("%s: se esperaba error por estar fuera de la resolución", c.numero)
+		}
+	}
+
+	sinRango := cfg
+	sinRango.RangoHasta = 0
+	if _, err := GenerarXMLFacturaDIAN(facturaMixtaPrueba(), sinRango); err == nil {
+		t.Error("se esperaba error sin DIAN_RANGO_HASTA configurado")
+	}
+}
@@ -0,0 +1,305 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Estados de una fórmula médica según lo dispensado de sus ítems.
+const (
+	FormulaPendiente = "PENDIENTE"
+	FormulaParcial   = "PARCIAL"
+	FormulaCompleta  = "COMPLETA"
+)
+
+// espacioUUIDFormulas es el espacio de nombres de los uuid derivados de fórmulas y sus ítems.
+var espacioUUIDFormulas = uuid.MustParse("42d40fe8-dfba-4aca-acba-5f855c0571a0")
+
+// uuidFormula deriva el uuid de una fórmula del registro del médico y su número, y uuidItemFormula el de
+// cada ítem de la fórmula y el producto. Si dos terminales registran la misma fórmula sin conexión, la
+// sincronización encuentra el mismo registro en vez de chocar con los UNIQUE de ambas bases.
+func uuidFormula(registroMedico, numeroFormula string) string {
+	return uuid.NewSHA1(espacioUUIDFormulas, []byte(registroMedico+"|"+numeroFormula)).String()
+}
+
+func uuidItemFormula(formulaUUID, productoUUID string) string {
+	return uuid.NewSHA1(espacioUUIDFormulas, []byte(formulaUUID+"|"+productoUUID)).String()
+}
+
+// sqlFormulas lista fórmulas médicas (alias fm).
+const sqlFormulas = `
+	SELECT fm.uuid, fm.numero_formula, strftime('%Y-%m-%d', fm.fecha_formula), fm.medico, fm.registro_medico,
+		fm.paciente_id, COALESCE(fm.paciente_nombre, ''), COALESCE(fm.cliente_uuid, ''), fm.created_at, fm.updated_at
+	FROM formulas_medicas fm`
+
+// sqlItemsFormula lista los ítems de una fórmula con lo dispensado en unidades mínimas: las líneas de
+// facturas no anuladas que referencian la fórmula, menos lo devuelto con notas crédito.
+const sqlItemsFormula = `
+	SELECT i.uuid, i.formula_uuid, i.producto_uuid, p.codigo, p.nombre, i.cantidad_prescrita,
+		COALESCE((
+			SELECT SUM((df.cantidad - (SELECT COALESCE(SUM(dn.cantidad), 0) FROM detalle_notas_credito dn WHERE dn.detalle_factura_uuid = df.uuid)) * df.factor_conversion)
+			FROM detalle_facturas df
+			JOIN facturas f ON f.uuid = df.factura_uuid
+			WHERE f.formula_uuid = i.formula_uuid AND df.producto_uuid = i.producto_uuid AND f.estado <> 'ANULADA'
+		), 0)
+	FROM items_formula i
+	JOIN productos p ON p.uuid = i.producto_uuid
+	WHERE i.formula_uuid = ? AND i.deleted_at IS NULL
+	ORDER BY p.nombre ASC`
+
+type consultaFormulas interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// ObtenerFormula devuelve una fórmula con lo prescrito, lo dispensado y lo pendiente de cada producto.
+func (d *Db) ObtenerFormula(formulaUUID string) (Formula, error) {
+	return cargarFormula(d.LocalDB, formulaUUID)
+}
+
+// ObtenerFormulasPendientes busca por identificación del paciente o número de fórmula las fórmulas
+// que aún tienen productos por dispensar, para completar la entrega cuando el paciente regresa.
+func (d *Db) ObtenerFormulasPendientes(busqueda string) ([]Formula, error) {
+	busqueda = strings.TrimSpace(busqueda)
+	if busqueda == "" {
+		return nil, fmt.Errorf("se requiere la identificación del paciente o el número de fórmula")
+	}
+	rows, err := d.LocalDB.Query(sqlFormulas+`
+		WHERE fm.deleted_at IS NULL AND (fm.paciente_id = ? OR fm.numero_formula = ?)
+		ORDER BY fm.fecha_formula DESC`, busqueda, busqueda)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando fórmulas: %w", err)
+	}
+	var formulas []Formula
+	for rows.Next() {
+		f, err := escanearFormula(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		formulas = append(formulas, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pendientes := make([]Formula, 0, len(formulas))
+	for _, f := range formulas {
+		if f.Items, err = itemsFormula(d.LocalDB, f.UUID); err != nil {
+			return nil, err
+		}
+		f.Estado = estadoFormula(f.Items)
+		if f.Estado != FormulaCompleta {
+			pendientes = append(pendientes, f)
+		}
+	}
+	return pendientes, nil
+}
+
+func cargarFormula(q consultaFormulas, formulaUUID string) (Formula, error) {
+	f, err := escanearFormula(q.QueryRow(sqlFormulas+` WHERE fm.uuid = ? AND fm.deleted_at IS NULL`, formulaUUID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Formula{}, fmt.Errorf("fórmula médica [%s] no encontrada", formulaUUID)
+	}
+	if err != nil {
+		return Formula{}, err
+	}
+	if f.Items, err = itemsFormula(q, f.UUID); err != nil {
+		return Formula{}, err
+	}
+	f.Estado = estadoFormula(f.Items)
+	return f, nil
+}
+
+func escanearFormula(row interface{ Scan(dest ...any) error }) (Formula, error) {
+	var f Formula
+	err := row.Scan(&f.UUID, &f.NumeroFormula, &f.FechaFormula, &f.Medico, &f.RegistroMedico,
+		&f.PacienteID, &f.PacienteNombre, &f.ClienteUUID, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Formula{}, fmt.Errorf("error leyendo fórmula médica: %w", err)
+	}
+	return f, err
+}
+
+func itemsFormula(q consultaFormulas, formulaUUID string) ([]ItemFormula, error) {
+	rows, err := q.Query(sqlItemsFormula, formulaUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando ítems de la fórmula: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]ItemFormula, 0)
+	for rows.Next() {
+		var it ItemFormula
+		if err := rows.Scan(&it.UUID, &it.FormulaUUID, &it.ProductoUUID, &it.Codigo, &it.Nombre, &it.CantidadPrescrita, &it.CantidadDispensada); err != nil {
+			return nil, fmt.Errorf("error leyendo ítem de la fórmula: %w", err)
+		}
+		if it.CantidadPendiente = it.CantidadPrescrita - it.CantidadDispensada; it.CantidadPendiente < 0 {
+			it.CantidadPendiente = 0
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+func estadoFormula(items []ItemFormula) string {
+	var dispensado, pendiente int
+	for _, it := range items {
+		dispensado += it.CantidadDispensada
+		pendiente += it.CantidadPendiente
+	}
+	switch {
+	case pendiente == 0:
+		return FormulaCompleta
+	case dispensado > 0:
+		return FormulaParcial
+	default:
+		return FormulaPendiente
+	}
+}
+
+// resolverFormulaVenta carga la fórmula ya registrada que indica la venta o registra la nueva. Devuelve
+// nil si la venta no trae fórmula.
+func resolverFormulaVenta(tx *sql.Tx, req VentaRequest, now time.Time) (*Formula, error) {
+	switch {
+	case req.FormulaUUID != "" && req.Formula != nil:
+		return nil, fmt.Errorf("indique una fórmula registrada o una fórmula nueva, no ambas")
+	case req.FormulaUUID != "":
+		f, err := cargarFormula(tx, req.FormulaUUID)
+		if err != nil {
+			return nil, err
+		}
+		if f.Estado == FormulaCompleta {
+			return nil, fmt.Errorf("la fórmula %s ya fue dispensada completamente", f.NumeroFormula)
+		}
+		return &f, nil
+	case req.Formula != nil:
+		return registrarFormula(tx, *req.Formula, req.ClienteUUID, now)
+	}
+	return nil, nil
+}
+
+// registrarFormula valida y guarda una fórmula nueva con sus ítems. Una misma fórmula (registro del
+// médico y número) solo se registra una vez; para seguir dispensándola se usa su UUID.
+func registrarFormula(tx *sql.Tx, req FormulaRequest, clienteUUID string, now time.Time) (*Formula, error) {
+	f := Formula{
+		NumeroFormula:  strings.ToUpper(strings.TrimSpace(req.NumeroFormula)),
+		FechaFormula:   strings.TrimSpace(req.FechaFormula),
+		Medico:         strings.TrimSpace(req.Medico),
+		RegistroMedico: strings.TrimSpace(req.RegistroMedico),
+		PacienteID:     strings.TrimSpace(req.PacienteID),
+		PacienteNombre: strings.TrimSpace(req.PacienteNombre),
+		ClienteUUID:    clienteUUID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	var faltantes []string
+	if f.NumeroFormula == "" {
+		faltantes = append(faltantes, "número de fórmula")
+	}
+	if f.FechaFormula == "" {
+		faltantes = append(faltantes, "fecha")
+	}
+	if f.Medico == "" {
+		faltantes = append(faltantes, "médico")
+	}
+	if f.RegistroMedico == "" {
+		faltantes = append(faltantes, "registro médico")
+	}
+	if f.PacienteID == "" {
+		faltantes = append(faltantes, "identificación del paciente")
+	}
+	if len(faltantes) > 0 {
+		return nil, fmt.Errorf("la fórmula médica está incompleta: falta %s", strings.Join(faltantes, ", "))
+	}
+	fecha, err := time.ParseInLocation("2006-01-02", f.FechaFormula, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("fecha de fórmula inválida %q: %w", f.FechaFormula, err)
+	}
+	if fecha.After(now) {
+		return nil, fmt.Errorf("la fecha de la fórmula %s no puede ser futura", f.NumeroFormula)
+	}
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("la fórmula %s no tiene productos prescritos", f.NumeroFormula)
+	}
+
+	f.UUID = uuidFormula(f.RegistroMedico, f.NumeroFormula)
+	var existente string
+	err = tx.QueryRow(`SELECT uuid FROM formulas_medicas WHERE registro_medico = ? AND numero_formula = ?`,
+		f.RegistroMedico, f.NumeroFormula).Scan(&existente)
+	if err == nil {
+		return nil, fmt.Errorf("la fórmula %s ya está registrada: continúe su dispensación desde las fórmulas pendientes", f.NumeroFormula)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("error consultando fórmula %s: %w", f.NumeroFormula, err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO formulas_medicas (
+			uuid, numero_formula, fecha_formula, medico, registro_medico, paciente_id, paciente_nombre, cliente_uuid, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		f.UUID, f.NumeroFormula, f.FechaFormula, f.Medico, f.RegistroMedico, f.PacienteID,
+		nullableString(f.PacienteNombre), nullableString(f.ClienteUUID), now, now); err != nil {
+		return nil, fmt.Errorf("error registrando fórmula %s: %w", f.NumeroFormula, err)
+	}
+
+	prescritos := make(map[string]bool, len(req.Items))
+	for _, it := range req.Items {
+		if it.CantidadPrescrita <= 0 {
+			return nil, fmt.Errorf("la cantidad prescrita del producto [%s] debe ser mayor que cero", it.ProductoUUID)
+		}
+		if prescritos[it.ProductoUUID] {
+			return nil, fmt.Errorf("el producto [%s] está repetido en la fórmula %s", it.ProductoUUID, f.NumeroFormula)
+		}
+		prescritos[it.ProductoUUID] = true
+		if _, err := tx.Exec(`
+			INSERT INTO items_formula (uuid, formula_uuid, producto_uuid, cantidad_prescrita, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			uuidItemFormula(f.UUID, it.ProductoUUID), f.UUID, it.ProductoUUID, it.CantidadPrescrita, now, now); err != nil {
+			return nil, fmt.Errorf("error registrando el producto [%s] de la fórmula: %w", it.ProductoUUID, err)
+		}
+	}
+
+	registrada, err := cargarFormula(tx, f.UUID)
+	if err != nil {
+		return nil, err
+	}
+	return &registrada, nil
+}
+
+// dispensarFormula descuenta de lo pendiente de la fórmula las unidades vendidas de un producto bajo
+// fórmula. Falla si el producto no está prescrito o si se entrega más de lo pendiente.
+func dispensarFormula(formula *Formula, productoUUID string, unidades int, nombre string) error {
+	if formula == nil {
+		return fmt.Errorf("%s se vende bajo fórmula médica: registre la fórmula de la venta", nombre)
+	}
+	for i := range formula.Items {
+		it := &formula.Items[i]
+		if it.ProductoUUID != productoUUID {
+			continue
+		}
+		if unidades > it.CantidadPendiente {
+			return fmt.Errorf("la fórmula %s tiene %d unidades pendientes de %s y se intentan dispensar %d",
+				formula.NumeroFormula, it.CantidadPendiente, nombre, unidades)
+		}
+		it.CantidadPendiente -= unidades
+		return nil
+	}
+	return fmt.Errorf("%s no está prescrito en la fórmula %s", nombre, formula.NumeroFormula)
+}
+
+// formulaMedica son los datos de la fórmula que se asientan en el libro de medicamentos de control.
+func (f *Formula) formulaMedica() *FormulaMedica {
+	return &FormulaMedica{
+		Prescriptor:         f.Medico,
+		RegistroPrescriptor: f.RegistroMedico,
+		PacienteID:          f.PacienteID,
+		NumeroFormula:       f.NumeroFormula,
+	}
+}
@@ -0,0 +1,178 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// separadorGS1 es el carácter FNC1 (GS, ASCII 29) que cierra los campos de longitud variable.
+const separadorGS1 = "\x1d"
+
+// longitudesAI son los identificadores de aplicación GS1 que se reconocen. Un valor positivo es una
+// longitud fija; uno negativo, la longitud máxima de un campo variable.
+var longitudesAI = map[string]int{
+	"00": 18, "01": 14, "02": 14, "11": 6, "12": 6, "13": 6, "15": 6, "16": 6, "17": 6, "20": 2,
+	"10": -20, "21": -20, "22": -20, "30": -8, "37": -8, "240": -30, "241": -30,
+	"710": -20, "711": -20, "712": -20, "713": -20, "714": -20, // números nacionales de reembolso en salud
+}
+
+// esCodigoGS1 reconoce un código GS1 con identificadores de aplicación: con identificador de
+// simbología (]d2 DataMatrix, ]C1 GS1-128, ]Q3 QR), en formato legible con paréntesis o un GTIN
+// (01) seguido de más datos. Un EAN o GTIN suelto se trata como código de barras simple.
+// Sin prefijo ni FNC1 el GTIN debe tener dígito de control válido y el resto leerse como campos
+// GS1: un código interno largo que empieza por 01 no es GS1.
+func esCodigoGS1(codigo string) bool {
+	switch {
+	case strings.HasPrefix(codigo, "]d2"), strings.HasPrefix(codigo, "]C1"), strings.HasPrefix(codigo, "]Q3"):
+		return true
+	case strings.HasPrefix(codigo, "(01)"), strings.Contains(codigo, separadorGS1):
+		return true
+	}
+	if !strings.HasPrefix(codigo, "01") || len(codigo) <= 16 {
+		return false
+	}
+	campos, err := camposGS1(codigo)
+	return err == nil && gtinValido(campos["01"])
+}
+
+// parsearGS1 extrae GTIN, lote, vencimiento y serial de un código GS1. Los demás identificadores
+// reconocidos se leen para avanzar pero no se devuelven.
+func parsearGS1(codigo string) (CodigoGS1, error) {
+	campos, err := camposGS1(codigo)
+	if err != nil {
+		return CodigoGS1{}, err
+	}
+
+	gs1 := CodigoGS1{GTIN: campos["01"], Lote: strings.ToUpper(campos["10"]), Serial: campos["21"]}
+	if gs1.GTIN == "" {
+		return CodigoGS1{}, fmt.Errorf("el código GS1 no trae GTIN (01)")
+	}
+	if !gtinValido(gs1.GTIN) {
+		return CodigoGS1{}, fmt.Errorf("GTIN inválido %s: el dígito de control no coincide", gs1.GTIN)
+	}
+	if v, ok := campos["17"]; ok {
+		if gs1.Vencimiento, err = fechaGS1(v); err != nil {
+			return CodigoGS1{}, err
+		}
+	}
+	return gs1, nil
+}
+
+// camposGS1 separa el código en identificador de aplicación -> valor, tanto en formato legible
+// "(01)...(17)..." como en el formato crudo que entrega el lector, con FNC1 tras los campos variables.
+func camposGS1(codigo string) (map[string]string, error) {
+	codigo = strings.TrimSpace(codigo)
+	if strings.HasPrefix(codigo, "]") && len(codigo) >= 3 {
+		codigo = codigo[3:]
+	}
+	codigo = strings.TrimPrefix(codigo, separadorGS1)
+
+	campos := make(map[string]string)
+	if strings.HasPrefix(codigo, "(") {
+		for _, parte := range strings.Split(codigo[1:], "(") {
+			ai, valor, ok := strings.Cut(parte, ")")
+			if !ok {
+				return nil, fmt.Errorf("código GS1 mal formado cerca de %q", parte)
+			}
+			if _, conocido := longitudesAI[ai]; !conocido {
+				return nil, fmt.Errorf("identificador de aplicación GS1 desconocido (%s)", ai)
+			}
+			campos[ai] = strings.TrimSpace(valor)
+		}
+		return campos, nil
+	}
+
+	for codigo != "" {
+		if len(codigo) < 2 {
+			return nil, fmt.Errorf("código GS1 incompleto al final: %q", codigo)
+		}
+		ai := codigo[:2]
+		longitud, ok := longitudesAI[ai]
+		if !ok && len(codigo) >= 3 {
+			ai = codigo[:3]
+			longitud, ok = longitudesAI[ai]
+		}
+		if !ok {
+			return nil, fmt.Errorf("identificador de aplicación GS1 desconocido al inicio de %q", codigo)
+		}
+		codigo = codigo[len(ai):]
+
+		var valor string
+		if longitud > 0 {
+			if len(codigo) < longitud {
+				return nil, fmt.Errorf("el campo GS1 (%s) debe tener %d caracteres", ai, longitud)
+			}
+			valor, codigo = codigo[:longitud], codigo[longitud:]
+		} else {
+			fin := strings.Index(codigo, separadorGS1)
+			if fin < 0 {
+				fin = len(codigo)
+			}
+			valor, codigo = codigo[:fin], codigo[fin:]
+			if len(valor) > -longitud {
+				return nil, fmt.Errorf("el campo GS1 (%s) supera %d caracteres", ai, -longitud)
+			}
+		}
+		campos[ai] = valor
+		codigo = strings.TrimPrefix(codigo, separadorGS1)
+	}
+	return campos, nil
+}
+
+// gtinValido verifica el dígito de control (módulo 10) de un GTIN-8, 12, 13 o 14.
+func gtinValido(gtin string) bool {
+	switch len(gtin) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+	suma := 0
+	for i := len(gtin) - 2; i >= 0; i-- {
+		digito, err := strconv.Atoi(gtin[i : i+1])
+		if err != nil {
+			return false
+		}
+		// De derecha a izquierda, sin el dígito de control, los pesos alternan 3 y 1
+		if (len(gtin)-2-i)%2 == 0 {
+			digito *= 3
+		}
+		suma += digito
+	}
+	control, err := strconv.Atoi(gtin[len(gtin)-1:])
+	return err == nil && (10-suma%10)%10 == control
+}
+
+// fechaGS1 convierte una fecha AAMMDD a AAAA-MM-DD. Día 00 significa el último día del mes.
+func fechaGS1(valor string) (string, error) {
+	if len(valor) != 6 {
+		return "", fmt.Errorf("fecha GS1 inválida %q: se espera AAMMDD", valor)
+	}
+	anio, errA := strconv.Atoi(valor[0:2])
+	mes, errM := strconv.Atoi(valor[2:4])
+	dia, errD := strconv.Atoi(valor[4:6])
+	if errA != nil || errM != nil || errD != nil || mes < 1 || mes > 12 {
+		return "", fmt.Errorf("fecha GS1 inválida %q", valor)
+	}
+	if dia == 0 {
+		return time.Date(2000+anio, time.Month(mes)+1, 0, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), nil
+	}
+	fecha := time.Date(2000+anio, time.Month(mes), dia, 0, 0, 0, 0, time.UTC)
+	if fecha.Day() != dia {
+		return "", fmt.Errorf("fecha GS1 inválida %q", valor)
+	}
+	return fecha.Format("2006-01-02"), nil
+}
+
+// variantesGTIN devuelve el código y su equivalente con o sin el cero inicial: el GTIN-14 de un código
+// GS1 es el mismo EAN-13 impreso en el empaque.
+func variantesGTIN(codigo string) []string {
+	variantes := []string{codigo}
+	if len(codigo) == 14 && strings.HasPrefix(codigo, "0") {
+		variantes = append(variantes, codigo[1:])
+	} else if len(codigo) == 13 {
+		variantes = append(variantes, "0"+codigo)
+	}
+	return variantes
+}
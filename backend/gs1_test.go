@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCamposGS1(t *testing.T) {
+	casos := []struct {
+		nombre string
+		codigo string
+		campos map[string]string
+		error  bool
+	}{
+		{
+			nombre: "crudo con FNC1 tras el lote",
+			codigo: "0107501031311309" + "17250600" + "10LOTE-A1" + separadorGS1 + "21SER9",
+			campos: map[string]string{"01": "07501031311309", "17": "250600", "10": "LOTE-A1", "21": "SER9"},
+		},
+		{
+			nombre: "identificador de simbología y FNC1 inicial",
+			codigo: "]d2" + separadorGS1 + "010750103131130910ABC" + separadorGS1 + "17261231",
+			campos: map[string]string{"01": "07501031311309", "10": "ABC", "17": "261231"},
+		},
+		{
+			nombre: "formato legible con paréntesis",
+			codigo: "(01)07501031311309(17)250600(10)LOTE1",
+			campos: map[string]string{"01": "07501031311309", "17": "250600", "10": "LOTE1"},
+		},
+		{
+			nombre: "identificador de tres dígitos",
+			codigo: "0107501031311309710INVIMA123" + separadorGS1 + "10L1",
+			campos: map[string]string{"01": "07501031311309", "710": "INVIMA123", "10": "L1"},
+		},
+		{
+			nombre: "identificador de tres dígitos entre paréntesis",
+			codigo: "(01)07501031311309(240)REF-7",
+			campos: map[string]string{"01": "07501031311309", "240": "REF-7"},
+		},
+		{nombre: "identificador desconocido", codigo: "0107501031311309991234", error: true},
+		{nombre: "identificador desconocido entre paréntesis", codigo: "(01)07501031311309(99)1234", error: true},
+		{nombre: "campo fijo incompleto", codigo: "010750103131", error: true},
+		{nombre: "campo variable demasiado largo", codigo: "0107501031311309" + "10ABCDEFGHIJKLMNOPQRSTU", error: true},
+		{nombre: "paréntesis sin cerrar", codigo: "(01)07501031311309(17250600", error: true},
+	}
+	for _, c := range casos {
+		campos, err := camposGS1(c.codigo)
+		if c.error {
+			if err == nil {
+				t.Errorf("%s: se esperaba error y se obtuvo %v", c.nombre, campos)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error inesperado: %v", c.nombre, err)
+			continue
+		}
+		if !reflect.DeepEqual(campos, c.campos) {
+			t.Errorf("%s: campos = %v, se esperaba %v", c.nombre, campos, c.campos)
+		}
+	}
+}
+
+func TestGTINValido(t *testing.T) {
+	casos := []struct {
+		gtin   string
+		valido bool
+	}{
+		{"7501031311309", true},  // EAN-13
+		{"07501031311309", true}, // GTIN-14 del mismo EAN-13
+		{"96385074", true},       // EAN-8
+		{"036000291452", true},   // UPC-A
+		{"7501031311308", false}, // dígito de control errado
+		{"12345678901234", false},
+		{"75010313113O9", false}, // letra O en lugar de cero
+		{"750103131130", false},  // longitud que no es GTIN
+		{"", false},
+	}
+	for _, c := range casos {
+		if got := gtinValido(c.gtin); got != c.valido {
+			t.Errorf("gtinValido(%q) = %v, se esperaba %v", c.gtin, got, c.valido)
+		}
+	}
+}
+
+func TestFechaGS1(t *testing.T) {
+	casos := []struct {
+		valor string
+		fecha string
+		error bool
+	}{
+		{valor: "251231", fecha: "2025-12-31"},
+		{valor: "250600", fecha: "2025-06-30"}, // día 00: último día del mes
+		{valor: "240200", fecha: "2024-02-29"}, // día 00 en febrero bisiesto
+		{valor: "251200", fecha: "2025-12-31"}, // día 00 en diciembre
+		{valor: "250230", error: true},
+		{valor: "251301", error: true},
+		{valor: "250001", error: true},
+		{valor: "2506", error: true},
+		{valor: "25AB01", error: true},
+	}
+	for _, c := range casos {
+		fecha, err := fechaGS1(c.valor)
+		if c.error {
+			if err == nil {
+				t.Errorf("fechaGS1(%q): se esperaba error y se obtuvo %s", c.valor, fecha)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("fechaGS1(%q): error inesperado: %v", c.valor, err)
+			continue
+		}
+		if fecha != c.fecha {
+			t.Errorf("fechaGS1(%q) = %s, se esperaba %s", c.valor, fecha, c.fecha)
+		}
+	}
+}
+
+func TestEsCodigoGS1(t *testing.T) {
+	casos := []struct {
+		nombre string
+		codigo string
+		gs1    bool
+	}{
+		{"DataMatrix con identificador de simbología", "]d2" + "0107501031311309" + "17250600" + "10L1", true},
+		{"GS1-128 con identificador de simbología", "]C10107501031311309", true},
+		{"formato legible", "(01)07501031311309(10)L1", true},
+		{"con separador FNC1", "0107501031311309" + "10L1" + separadorGS1 + "21S1", true},
+		{"GTIN seguido de vencimiento y lote", "0107501031311309" + "17250600" + "10L1", true},
+		{"EAN-13 suelto", "7501031311309", false},
+		{"GTIN-14 suelto", "07501031311309", false},
+		{"código interno largo que empieza por 01", "0112345678901234567", false},
+		{"GTIN válido seguido de datos que no son GS1", "010750103131130999XYZ", false},
+		{"código corto", "0101", false},
+	}
+	for _, c := range casos {
+		if got := esCodigoGS1(c.codigo); got != c.gs1 {
+			t.Errorf("%s: esCodigoGS1(%q) = %v, se esperaba %v", c.nombre, c.codigo, got, c.gs1)
+		}
+	}
+}
@@ -279,12 +279,28 @@ func mapRowToStruct(rowMap map[string]string, modelName string) (interface{}, er
 			return nil, fmt.Errorf("los campos 'nombre' y 'codigo' no pueden estar vacíos")
 		}
 
+		// Datos farmacéuticos y categoría: columnas opcionales
+		datos := DatosFarmaceuticos{
+			PrincipioActivo:   rowMap["principio_activo"],
+			Concentracion:     rowMap["concentracion"],
+			FormaFarmaceutica: rowMap["forma_farmaceutica"],
+			Laboratorio:       rowMap["laboratorio"],
+			RegistroInvima:    rowMap["registro_invima"],
+			VencimientoInvima: rowMap["vencimiento_invima"],
+			CodigoATC:         rowMap["codigo_atc"],
+		}
+		if err := datos.normalizar(); err != nil {
+			return nil, fmt.Errorf("código '%s': %w", codigo, err)
+		}
+
 		// CAMBIO: Devolver el struct por valor, no como puntero.
 		return Producto{
-			Nombre:      nombre,
-			Codigo:      codigo,
-			PrecioVenta: precio,
-			Stock:       stock,
+			Nombre:             nombre,
+			Codigo:             codigo,
+			PrecioVenta:        precio,
+			Stock:              stock,
+			Categoria:          normalizarCategoria(rowMap["categoria"]),
+			DatosFarmaceuticos: datos,
 		}, nil
 
 	case "Clientes":
@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImpuestoPorDefecto es la categoría asignada a los productos sin impuesto configurado.
+// La mayoría de medicamentos están excluidos de IVA.
+const ImpuestoPorDefecto = "EXCLUIDO"
+
+// ObtenerImpuestos devuelve la tabla de impuestos vigente.
+func (d *Db) ObtenerImpuestos() ([]Impuesto, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT uuid, codigo, COALESCE(nombre, ''), tarifa, created_at, updated_at
+		FROM impuestos
+		WHERE deleted_at IS NULL
+		ORDER BY tarifa DESC, codigo ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener impuestos: %w", err)
+	}
+	defer rows.Close()
+
+	impuestos := make([]Impuesto, 0)
+	for rows.Next() {
+		var i Impuesto
+		if err := rows.Scan(&i.UUID, &i.Codigo, &i.Nombre, &i.Tarifa, &i.CreatedAt, &i.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear impuesto: %w", err)
+		}
+		impuestos = append(impuestos, i)
+	}
+	return impuestos, rows.Err()
+}
+
+// GuardarImpuesto crea o actualiza (por código) una tarifa de impuesto.
+func (d *Db) GuardarImpuesto(imp Impuesto) (Impuesto, error) {
+	imp.Codigo = strings.ToUpper(strings.TrimSpace(imp.Codigo))
+	if imp.Codigo == "" {
+		return Impuesto{}, errors.New("el código del impuesto es obligatorio")
+	}
+	if imp.Tarifa < 0 || imp.Tarifa > 100 {
+		return Impuesto{}, fmt.Errorf("tarifa inválida %.2f: debe estar entre 0 y 100", imp.Tarifa)
+	}
+
+	now := time.Now()
+	var existente string
+	err := d.LocalDB.QueryRowContext(d.ctx, `SELECT uuid FROM impuestos WHERE codigo = ?`, imp.Codigo).Scan(&existente)
+	switch {
+	case err == nil:
+		imp.UUID = existente
+		_, err = d.LocalDB.ExecContext(d.ctx,
+			`UPDATE impuestos SET nombre = ?, tarifa = ?, deleted_at = NULL, updated_at = ? WHERE uuid = ?`,
+			imp.Nombre, imp.Tarifa, now, imp.UUID)
+	case errors.Is(err, sql.ErrNoRows):
+		imp.UUID = uuid.New().String()
+		imp.CreatedAt = now
+		_, err = d.LocalDB.ExecContext(d.ctx,
+			`INSERT INTO impuestos (uuid, codigo, nombre, tarifa, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			imp.UUID, imp.Codigo, imp.Nombre, imp.Tarifa, now, now)
+	}
+	if err != nil {
+		return Impuesto{}, fmt.Errorf("error al guardar impuesto %s: %w", imp.Codigo, err)
+	}
+	imp.UpdatedAt = now
+
+	go d.syncImpuestoToRemote(imp.UUID)
+	return imp, nil
+}
+
+// validarImpuestoCodigo normaliza el código recibido y verifica que exista en la tabla de impuestos.
+func validarImpuestoCodigo(tx *sql.Tx, codigo string) (string, error) {
+	codigo = strings.ToUpper(strings.TrimSpace(codigo))
+	if codigo == "" {
+		return ImpuestoPorDefecto, nil
+	}
+	var existe int
+	if err := tx.QueryRow(`SELECT COUNT(1) FROM impuestos WHERE codigo = ? AND deleted_at IS NULL`, codigo).Scan(&existe); err != nil {
+		return "", fmt.Errorf("error al validar impuesto: %w", err)
+	}
+	if existe == 0 {
+		return "", fmt.Errorf("el impuesto '%s' no existe", codigo)
+	}
+	return codigo, nil
+}
+
+// calcularImpuestoIncluido separa base e impuesto de un valor que ya incluye el impuesto,
+// ya que los precios de venta al público se manejan con IVA incluido.
+func calcularImpuestoIncluido(total, tarifa float64) (base, impuesto float64) {
+	if tarifa <= 0 {
+		return redondearMoneda(total), 0
+	}
+	base = redondearMoneda(total / (1 + tarifa/100))
+	return base, redondearMoneda(total - base)
+}
+
+// redondearMoneda redondea un valor a dos decimales.
+func redondearMoneda(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// agruparImpuestos consolida los detalles de una factura en una línea por tarifa.
+func agruparImpuestos(facturaUUID string, detalles []DetalleFactura, now time.Time) []FacturaImpuesto {
+	porTarifa := make(map[float64]*FacturaImpuesto)
+	for _, det := range detalles {
+		fi, ok := porTarifa[det.TarifaImpuesto]
+		if !ok {
+			fi = &FacturaImpuesto{
+				UUID:           uuid.New().String(),
+				FacturaUUID:    facturaUUID,
+				ImpuestoCodigo: det.ImpuestoCodigo,
+				Tarifa:         det.TarifaImpuesto,
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			}
+			porTarifa[det.TarifaImpuesto] = fi
+		} else if fi.ImpuestoCodigo != det.ImpuestoCodigo {
+			// Dos categorías con la misma tarifa (ej. EXENTO y EXCLUIDO) comparten renglón.
+			fi.ImpuestoCodigo = ""
+		}
+		fi.Base = redondearMoneda(fi.Base + det.BaseImpuesto)
+		fi.Valor = redondearMoneda(fi.Valor + det.ValorImpuesto)
+	}
+
+	resumen := make([]FacturaImpuesto, 0, len(porTarifa))
+	for _, fi := range porTarifa {
+		resumen = append(resumen, *fi)
+	}
+	sort.Slice(resumen, func(i, j int) bool { return resumen[i].Tarifa > resumen[j].Tarifa })
+	return resumen
+}
+
+// obtenerImpuestosFactura devuelve el desglose por tarifa de una factura.
+func (d *Db) obtenerImpuestosFactura(facturaUUID string) ([]FacturaImpuesto, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT uuid, factura_uuid, COALESCE(impuesto_codigo, ''), tarifa, base, valor, created_at, updated_at
+		FROM factura_impuestos
+		WHERE factura_uuid = ?
+		ORDER BY tarifa DESC`, facturaUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener impuestos de la factura: %w", err)
+	}
+	defer rows.Close()
+
+	impuestos := make([]FacturaImpuesto, 0)
+	for rows.Next() {
+		var fi FacturaImpuesto
+		if err := rows.Scan(&fi.UUID, &fi.FacturaUUID, &fi.ImpuestoCodigo, &fi.Tarifa, &fi.Base, &fi.Valor, &fi.CreatedAt, &fi.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear impuesto de factura: %w", err)
+		}
+		impuestos = append(impuestos, fi)
+	}
+	return impuestos, rows.Err()
+}
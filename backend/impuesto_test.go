@@ -0,0 +1,31 @@
+package backend
+
+import "testing"
+
+func TestCalcularImpuestoIncluido(t *testing.T) {
+	casos := []struct {
+		total    float64
+		tarifa   float64
+		base     float64
+		impuesto float64
+	}{
+		{total: 11900, tarifa: 19, base: 10000, impuesto: 1900},
+		{total: 10500, tarifa: 5, base: 10000, impuesto: 500},
+		{total: 1000, tarifa: 19, base: 840.34, impuesto: 159.66},
+		{total: 99.99, tarifa: 19, base: 84.03, impuesto: 15.96},
+		{total: 5000, tarifa: 0, base: 5000, impuesto: 0}, // exento o excluido
+		{total: 0, tarifa: 19, base: 0, impuesto: 0},
+		// Neto de una línea de 11.900 con 1.190 de descuento prorrateado: el IVA sale del valor ya descontado
+		{total: 10710, tarifa: 19, base: 9000, impuesto: 1710},
+	}
+	for _, c := range casos {
+		base, impuesto := calcularImpuestoIncluido(c.total, c.tarifa)
+		if base != c.base || impuesto != c.impuesto {
+			t.Errorf("calcularImpuestoIncluido(%.2f, %.0f%%) = %.2f + %.2f, se esperaba %.2f + %.2f",
+				c.total, c.tarifa, base, impuesto, c.base, c.impuesto)
+		}
+		if redondearMoneda(base+impuesto) != c.total {
+			t.Errorf("calcularImpuestoIncluido(%.2f, %.0f%%): base más impuesto suman %.2f", c.total, c.tarifa, base+impuesto)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Estados de un lote. Solo los vigentes se despachan; los demás ya salieron del inventario.
+const (
+	LoteVigente    = "VIGENTE"
+	LoteCuarentena = "CUARENTENA"
+	LoteDestruido  = "DESTRUIDO"
+)
+
+// DiasAlertaVencimiento es la ventana de lotes próximos a vencer que muestra el dashboard.
+const DiasAlertaVencimiento = 30
+
+// espacioUUIDLotes es el espacio de nombres de los uuid derivados de lotes (ver uuidLote).
+var espacioUUIDLotes = uuid.MustParse("3162e2a8-284b-4388-92d2-aa9629633dc4")
+
+// uuidLote deriva el uuid de un lote de su producto y su número. Dos terminales que registran el mismo
+// lote sin conexión generan así el mismo registro, y la sincronización no choca con el UNIQUE
+// (producto_uuid, numero_lote) de ambas bases.
+func uuidLote(productoUUID, numeroLote string) string {
+	return uuid.NewSHA1(espacioUUIDLotes, []byte(productoUUID+"|"+numeroLote)).String()
+}
+
+// sqlLotes lista lotes con su stock derivado de operacion_stocks (alias l para lotes, p para productos).
+const sqlLotes = `
+	SELECT l.uuid, l.producto_uuid, p.nombre, l.numero_lote, strftime('%Y-%m-%d', l.fecha_vencimiento),
+		l.estado, COALESCE(l.observacion, ''),
+		COALESCE((SELECT SUM(o.cantidad_cambio) FROM operacion_stocks o WHERE o.lote_uuid = l.uuid), 0),
+		COALESCE(p.costo_promedio, 0), l.created_at, l.updated_at
+	FROM lotes l
+	JOIN productos p ON p.uuid = l.producto_uuid`
+
+// ObtenerLotesProducto lista los lotes vigentes de un producto en orden FEFO, con su stock.
+func (d *Db) ObtenerLotesProducto(productoUUID string) ([]Lote, error) {
+	return d.consultarLotes(sqlLotes+`
+		WHERE l.producto_uuid = ? AND l.deleted_at IS NULL
+		ORDER BY l.fecha_vencimiento ASC, l.created_at ASC`, productoUUID)
+}
+
+// ObtenerLotesPorVencer lista los lotes vigentes con existencias que vencen dentro de los próximos días,
+// incluidos los ya vencidos, con las unidades y su valor al costo para retirarlos a tiempo.
+func (d *Db) ObtenerLotesPorVencer(dias int) ([]Lote, error) {
+	if dias < 0 {
+		return nil, fmt.Errorf("el número de días no puede ser negativo")
+	}
+	limite := time.Now().AddDate(0, 0, dias).Format("2006-01-02")
+	lotes, err := d.consultarLotes(sqlLotes+`
+		WHERE l.deleted_at IS NULL AND l.estado = ? AND strftime('%Y-%m-%d', l.fecha_vencimiento) <= ?
+		ORDER BY l.fecha_vencimiento ASC, p.nombre ASC`, LoteVigente, limite)
+	if err != nil {
+		return nil, err
+	}
+	conStock := make([]Lote, 0, len(lotes))
+	for _, l := range lotes {
+		if l.Stock > 0 {
+			conStock = append(conStock, l)
+		}
+	}
+	return conStock, nil
+}
+
+// ObtenerMovimientosLote devuelve el historial de operaciones de stock de un lote.
+func (d *Db) ObtenerMovimientosLote(loteUUID string) ([]OperacionStock, error) {
+	rows, err := d.LocalDB.Query(`
+		SELECT uuid, producto_uuid, tipo_operacion, cantidad_cambio, COALESCE(stock_resultante, 0),
+			COALESCE(vendedor_uuid, ''), factura_uuid, COALESCE(lote_uuid, ''), timestamp, COALESCE(sincronizado, 0)
+		FROM operacion_stocks
+		WHERE lote_uuid = ?
+		ORDER BY timestamp ASC`, loteUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener movimientos del lote: %w", err)
+	}
+	defer rows.Close()
+
+	movimientos := make([]OperacionStock, 0)
+	for rows.Next() {
+		var op OperacionStock
+		if err := rows.Scan(&op.UUID, &op.ProductoUUID, &op.TipoOperacion, &op.CantidadCambio, &op.StockResultante,
+			&op.VendedorUUID, &op.FacturaUUID, &op.LoteUUID, &op.Timestamp, &op.Sincronizado); err != nil {
+			return nil, fmt.Errorf("error al escanear movimiento del lote: %w", err)
+		}
+		movimientos = append(movimientos, op)
+	}
+	return movimientos, rows.Err()
+}
+
+func (d *Db) consultarLotes(query string, args ...any) ([]Lote, error) {
+	rows, err := d.LocalDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener lotes: %w", err)
+	}
+	defer rows.Close()
+
+	hoy, _ := time.ParseInLocation("2006-01-02", time.Now().Format("2006-01-02"), time.Local)
+	lotes := make([]Lote, 0)
+	for rows.Next() {
+		var l Lote
+		if err := rows.Scan(&l.UUID, &l.ProductoUUID, &l.ProductoNombre, &l.NumeroLote, &l.FechaVencimiento,
+			&l.Estado, &l.Observacion, &l.Stock, &l.CostoUnitario, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear lote: %w", err)
+		}
+		if vence, err := time.ParseInLocation("2006-01-02", l.FechaVencimiento, time.Local); err == nil {
+			l.DiasParaVencer = int(vence.Sub(hoy).Hours() / 24)
+			l.Vencido = l.DiasParaVencer < 0
+		}
+		l.ValorCosto = redondearMoneda(float64(l.Stock) * l.CostoUnitario)
+		lotes = append(lotes, l)
+	}
+	return lotes, rows.Err()
+}
+
+// stockLoteLocal suma las operaciones de stock de un lote dentro de la transacción.
+func stockLoteLocal(tx *sql.Tx, loteUUID string) (int, error) {
+	var stock int
+	err := tx.QueryRow(`SELECT COALESCE(SUM(cantidad_cambio), 0) FROM operacion_stocks WHERE lote_uuid = ?`, loteUUID).Scan(&stock)
+	return stock, err
+}
+
+// resolverLoteCompra busca el lote de una línea de compra o lo crea. Una línea sin número ni fecha
+// no lleva control de lote y devuelve un Lote vacío.
+func resolverLoteCompra(tx *sql.Tx, productoUUID, numeroLote, fechaVencimiento string, now time.Time) (Lote, error) {
+	numeroLote = strings.ToUpper(strings.TrimSpace(numeroLote))
+	fechaVencimiento = strings.TrimSpace(fechaVencimiento)
+	if numeroLote == "" && fechaVencimiento == "" {
+		return Lote{}, nil
+	}
+	if numeroLote == "" || fechaVencimiento == "" {
+		return Lote{}, fmt.Errorf("el lote del producto [%s] requiere número y fecha de vencimiento", productoUUID)
+	}
+	if _, err := time.Parse("2006-01-02", fechaVencimiento); err != nil {
+		return Lote{}, fmt.Errorf("fecha de vencimiento inválida %q para el lote %s: %w", fechaVencimiento, numeroLote, err)
+	}
+
+	lote := Lote{ProductoUUID: productoUUID, NumeroLote: numeroLote}
+	err := tx.QueryRow(`
+		SELECT uuid, strftime('%Y-%m-%d', fecha_vencimiento) FROM lotes
+		WHERE producto_uuid = ? AND numero_lote = ? AND deleted_at IS NULL`, productoUUID, numeroLote).Scan(&lote.UUID, &lote.FechaVencimiento)
+	switch {
+	case err == nil:
+		if lote.FechaVencimiento != fechaVencimiento {
+			return Lote{}, fmt.Errorf("el lote %s ya está registrado con vencimiento %s, no %s", numeroLote, lote.FechaVencimiento, fechaVencimiento)
+		}
+		return lote, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return Lote{}, fmt.Errorf("error consultando lote %s: %w", numeroLote, err)
+	}
+
+	lote.UUID = uuidLote(productoUUID, numeroLote)
+	lote.FechaVencimiento = fechaVencimiento
+	lote.CreatedAt, lote.UpdatedAt = now, now
+	if _, err := tx.Exec(`
+		INSERT INTO lotes (uuid, producto_uuid, numero_lote, fecha_vencimiento, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		lote.UUID, lote.ProductoUUID, lote.NumeroLote, lote.FechaVencimiento, now, now); err != nil {
+		return Lote{}, fmt.Errorf("error creando lote %s: %w", numeroLote, err)
+	}
+	return lote, nil
+}
+
+// descontarStockFEFO retira unidades de un producto imputándolas a lotes. Con un lote explícito se usa
+// ese; si no, primero el stock sin lote (anterior al control de lotes) y luego los lotes que vencen antes.
+// Los lotes vencidos solo se consumen si permitirVencidos (ajustes y bajas, nunca ventas). unidades
+// siempre es positivo, también para ajustes que guardan la cantidad con signo. La fórmula acompaña
+// las ventas de medicamentos de control.
+func (d *Db) descontarStockFEFO(
+	tx *sql.Tx,
+	productoUUID string,
+	tipoOperacion string,
+	unidades int,
+	loteUUID string,
+	vendedorUUID string,
+	facturaUUID *string,
+	permitirVencidos bool,
+	formula *FormulaMedica,
+) error {
+	hoy := time.Now().Format("2006-01-02")
+
+	if loteUUID != "" {
+		var productoLote, numero, vence, estado string
+		err := tx.QueryRow(`
+			SELECT producto_uuid, numero_lote, strftime('%Y-%m-%d', fecha_vencimiento), estado FROM lotes
+			WHERE uuid = ? AND deleted_at IS NULL`, loteUUID).Scan(&productoLote, &numero, &vence, &estado)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("lote [%s] no encontrado", loteUUID)
+		}
+		if err != nil {
+			return fmt.Errorf("error consultando lote: %w", err)
+		}
+		if productoLote != productoUUID {
+			return fmt.Errorf("el lote %s no pertenece al producto [%s]", numero, productoUUID)
+		}
+		if estado != LoteVigente {
+			return fmt.Errorf("el lote %s está en %s y no se puede despachar", numero, estado)
+		}
+		if vence < hoy && !permitirVencidos {
+			return fmt.Errorf("el lote %s venció el %s y no se puede despachar", numero, vence)
+		}
+		cambio := unidades
+		if !esSalidaStock(tipoOperacion) {
+			cambio = -unidades
+		}
+		return d.crearOperacionStockLote(tx, productoUUID, tipoOperacion, cambio, vendedorUUID, facturaUUID, loteUUID, formula)
+	}
+
+	// Existencias por lote (vacío: sin lote) en orden de consumo
+	type existencia struct {
+		loteUUID string
+		stock    int
+	}
+	var existencias []existencia
+
+	var sinLote int
+	if err := tx.QueryRow(`SELECT COALESCE(SUM(cantidad_cambio), 0) FROM operacion_stocks WHERE producto_uuid = ? AND lote_uuid IS NULL`,
+		productoUUID).Scan(&sinLote); err != nil {
+		return fmt.Errorf("error consultando stock sin lote: %w", err)
+	}
+	if sinLote > 0 {
+		existencias = append(existencias, existencia{stock: sinLote})
+	}
+
+	query := `
+		SELECT l.uuid, SUM(o.cantidad_cambio)
+		FROM lotes l
+		JOIN operacion_stocks o ON o.lote_uuid = l.uuid
+		WHERE l.producto_uuid = ? AND l.deleted_at IS NULL AND l.estado = ?`
+	args := []any{productoUUID, LoteVigente}
+	if !permitirVencidos {
+		query += ` AND strftime('%Y-%m-%d', l.fecha_vencimiento) >= ?`
+		args = append(args, hoy)
+	}
+	query += `
+		GROUP BY l.uuid
+		HAVING SUM(o.cantidad_cambio) > 0
+		ORDER BY l.fecha_vencimiento ASC, l.created_at ASC`
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("error consultando lotes del producto: %w", err)
+	}
+	for rows.Next() {
+		var e existencia
+		if err := rows.Scan(&e.loteUUID, &e.stock); err != nil {
+			rows.Close()
+			return fmt.Errorf("error leyendo lote del producto: %w", err)
+		}
+		existencias = append(existencias, e)
+	}
+	rows.Close()
+
+	disponible := 0
+	for _, e := range existencias {
+		disponible += e.stock
+	}
+	if disponible < unidades {
+		if permitirVencidos {
+			return fmt.Errorf("stock insuficiente [%s] disponible %d solicitado %d", productoUUID, disponible, unidades)
+		}
+		return fmt.Errorf("stock insuficiente [%s] disponible sin vencer %d solicitado %d", productoUUID, disponible, unidades)
+	}
+
+	restante := unidades
+	for _, e := range existencias {
+		if restante == 0 {
+			break
+		}
+		tomar := e.stock
+		if tomar > restante {
+			tomar = restante
+		}
+		cambio := tomar
+		if !esSalidaStock(tipoOperacion) {
+			cambio = -tomar // ajustes: la cantidad va con signo
+		}
+		if err := d.crearOperacionStockLote(tx, productoUUID, tipoOperacion, cambio, vendedorUUID, facturaUUID, e.loteUUID, formula); err != nil {
+			return err
+		}
+		restante -= tomar
+	}
+	return nil
+}
+
+// reintegrarStockLotes devuelve al inventario unidades de una venta, a los mismos lotes de los que salieron
+// según las operaciones de la factura. Lo que no pueda imputarse a un lote regresa como stock sin lote.
+func (d *Db) reintegrarStockLotes(
+	tx *sql.Tx,
+	productoUUID string,
+	tipoOperacion string,
+	unidades int,
+	vendedorUUID string,
+	facturaUUID string,
+) error {
+	// Neto despachado por lote en la factura (ventas menos lo ya reintegrado)
+	rows, err := tx.Query(`
+		SELECT COALESCE(o.lote_uuid, ''), -SUM(o.cantidad_cambio)
+		FROM operacion_stocks o
+		LEFT JOIN lotes l ON l.uuid = o.lote_uuid
+		WHERE o.factura_uuid = ? AND o.producto_uuid = ?
+		GROUP BY COALESCE(o.lote_uuid, '')
+		HAVING SUM(o.cantidad_cambio) < 0
+		ORDER BY MAX(l.fecha_vencimiento) DESC`, facturaUUID, productoUUID)
+	if err != nil {
+		return fmt.Errorf("error consultando lotes despachados: %w", err)
+	}
+	despachado := make(map[string]int)
+	var orden []string
+	for rows.Next() {
+		var lote string
+		var cantidad int
+		if err := rows.Scan(&lote, &cantidad); err != nil {
+			rows.Close()
+			return fmt.Errorf("error leyendo lotes despachados: %w", err)
+		}
+		despachado[lote] = cantidad
+		orden = append(orden, lote)
+	}
+	rows.Close()
+
+	restante := unidades
+	for _, lote := range orden {
+		if restante == 0 {
+			break
+		}
+		tomar := despachado[lote]
+		if tomar > restante {
+			tomar = restante
+		}
+		if err := d.crearOperacionStockLote(tx, productoUUID, tipoOperacion, tomar, vendedorUUID, &facturaUUID, lote, nil); err != nil {
+			return err
+		}
+		restante -= tomar
+	}
+	if restante > 0 {
+		return d.crearOperacionStockLote(tx, productoUUID, tipoOperacion, restante, vendedorUUID, &facturaUUID, "", nil)
+	}
+	return nil
+}
+
+// RetirarLote pasa un lote vigente a CUARENTENA o DESTRUIDO. Sus existencias salen del inventario con una
+// operación de stock a nombre del vendedor, que queda como registro auditable del retiro.
+func (d *Db) RetirarLote(req RetiroLoteRequest) (Lote, error) {
+	var tipoOperacion string
+	switch req.Estado {
+	case LoteCuarentena:
+		tipoOperacion = "CUARENTENA"
+	case LoteDestruido:
+		tipoOperacion = "DESTRUCCION"
+	default:
+		return Lote{}, fmt.Errorf("estado de retiro inválido %q: debe ser %s o %s", req.Estado, LoteCuarentena, LoteDestruido)
+	}
+	if req.LoteUUID == "" || req.VendedorUUID == "" {
+		return Lote{}, errors.New("se requiere el lote y el vendedor que registra el retiro")
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return Lote{}, fmt.Errorf("error al iniciar transacción de retiro: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[LOCAL] - Error durante [RetirarLote] rollback %v", rErr)
+		}
+	}()
+
+	var productoUUID, numero, estado string
+	err = tx.QueryRow(`SELECT producto_uuid, numero_lote, estado FROM lotes WHERE uuid = ? AND deleted_at IS NULL`,
+		req.LoteUUID).Scan(&productoUUID, &numero, &estado)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Lote{}, fmt.Errorf("lote [%s] no encontrado", req.LoteUUID)
+	}
+	if err != nil {
+		return Lote{}, fmt.Errorf("error consultando lote: %w", err)
+	}
+	if estado != LoteVigente {
+		return Lote{}, fmt.Errorf("el lote %s ya fue retirado (%s)", numero, estado)
+	}
+
+	stock, err := stockLoteLocal(tx, req.LoteUUID)
+	if err != nil {
+		return Lote{}, fmt.Errorf("error obteniendo stock del lote: %w", err)
+	}
+	if stock > 0 {
+		if err := d.crearOperacionStockLote(tx, productoUUID, tipoOperacion, stock, req.VendedorUUID, nil, req.LoteUUID, nil); err != nil {
+			return Lote{}, err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE lotes SET estado = ?, observacion = ?, updated_at = ? WHERE uuid = ?`,
+		req.Estado, nullableString(strings.TrimSpace(req.Observacion)), time.Now(), req.LoteUUID); err != nil {
+		return Lote{}, fmt.Errorf("error actualizando estado del lote: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Lote{}, fmt.Errorf("error al confirmar retiro del lote: %w", err)
+	}
+	d.Log.Infof("[LOTE] Lote %s pasado a %s con %d unidades", numero, req.Estado, stock)
+
+	go func() {
+		// El lote debe existir en remoto antes que la operación que lo referencia
+		d.syncLoteToRemote(req.LoteUUID)
+		d.SincronizarOperacionesStockHaciaRemoto()
+	}()
+
+	lotes, err := d.consultarLotes(sqlLotes+` WHERE l.uuid = ?`, req.LoteUUID)
+	if err != nil {
+		return Lote{}, err
+	}
+	if len(lotes) == 0 {
+		return Lote{}, fmt.Errorf("lote [%s] no encontrado", req.LoteUUID)
+	}
+	return lotes[0], nil
+}
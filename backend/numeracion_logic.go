@@ -0,0 +1,390 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Estados de un rango de facturación local.
+const (
+	RangoActivo  = "ACTIVO"
+	RangoAgotado = "AGOTADO"
+)
+
+// Valores por defecto de la numeración cuando no están configurados en el .env.
+const (
+	PrefijoFacturaPorDefecto = "FAC-"
+	TamanoRangoPorDefecto    = 500
+	UmbralRangoPorDefecto    = 50
+	// NumeroFacturaInicial es el primer número de la serie cuando no hay una resolución DIAN configurada.
+	NumeroFacturaInicial = 1000
+)
+
+// cargarConfiguracionNumeracion lee la identificación de la terminal y el tamaño de sus rangos.
+// TERMINAL_ID debe ser único por equipo; si no se configura se usa el nombre del equipo.
+func (d *Db) cargarConfiguracionNumeracion() {
+	d.terminalID = strings.TrimSpace(os.Getenv("TERMINAL_ID"))
+	if d.terminalID == "" {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "TERMINAL-1"
+		}
+		d.terminalID = host
+		d.Log.Warnf("TERMINAL_ID no está configurada, se usará el nombre del equipo: %s", d.terminalID)
+	}
+
+	d.prefijoFactura = strings.TrimSpace(os.Getenv("FACTURA_PREFIJO"))
+	if d.prefijoFactura == "" {
+		d.prefijoFactura = PrefijoFacturaPorDefecto
+	}
+
+	d.tamanoRango = leerEnteroPositivo(d, "RANGO_FACTURACION", TamanoRangoPorDefecto)
+	d.umbralRango = leerEnteroPositivo(d, "RANGO_FACTURACION_UMBRAL", UmbralRangoPorDefecto)
+	d.Log.Infof("Numeración de facturas: terminal %s, prefijo %s, rangos de %d", d.terminalID, d.prefijoFactura, d.tamanoRango)
+}
+
+func leerEnteroPositivo(d *Db, variable string, porDefecto int64) int64 {
+	v := os.Getenv(variable)
+	if v == "" {
+		return porDefecto
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		d.Log.Warnf("%s inválido (%s), se usará %d", variable, v, porDefecto)
+		return porDefecto
+	}
+	return n
+}
+
+// generarNumeroFactura toma el siguiente número del rango activo de la terminal. No reserva rangos:
+// eso lo hace prepararRangoFacturacion antes de abrir la transacción de la venta.
+func (d *Db) generarNumeroFactura(tx *sql.Tx) (string, error) {
+	rango, err := rangoActivo(tx, d.terminalID)
+	if err != nil {
+		return "", err
+	}
+	if rango == nil {
+		return "", fmt.Errorf("la terminal %s no tiene un rango de facturación disponible", d.terminalID)
+	}
+
+	siguiente := rango.UltimoUsado + 1
+	if siguiente < rango.Desde {
+		siguiente = rango.Desde
+	}
+	estado := RangoActivo
+	if siguiente >= rango.Hasta {
+		estado = RangoAgotado
+	}
+	if _, err := tx.Exec(`UPDATE rangos_facturacion SET ultimo_usado = ?, estado = ?, updated_at = ? WHERE uuid = ?`,
+		siguiente, estado, time.Now(), rango.UUID); err != nil {
+		return "", fmt.Errorf("error actualizando rango de facturación: %w", err)
+	}
+
+	return fmt.Sprintf("%s%d", rango.Prefijo, siguiente), nil
+}
+
+// prefijoDocumentoTerminal arma el prefijo de los documentos que cada terminal numera por su cuenta,
+// como notas crédito y abonos (ej. "NC-CAJA1-"). El consecutivo local es por terminal, así que dos
+// terminales sin conexión no emiten el mismo número. Del TERMINAL_ID solo se toman letras y dígitos.
+func (d *Db) prefijoDocumentoTerminal(prefijo string) string {
+	terminal := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, d.terminalID)
+	return prefijo + terminal + "-"
+}
+
+// rangoActivo devuelve el rango activo más antiguo de la terminal, o nil si no tiene.
+func rangoActivo(tx *sql.Tx, terminalID string) (*RangoFacturacion, error) {
+	var r RangoFacturacion
+	err := tx.QueryRow(`
+		SELECT uuid, terminal_id, prefijo, desde, hasta, ultimo_usado, estado
+		FROM rangos_facturacion
+		WHERE terminal_id = ? AND estado = ?
+		ORDER BY desde ASC LIMIT 1`, terminalID, RangoActivo).Scan(
+		&r.UUID, &r.TerminalID, &r.Prefijo, &r.Desde, &r.Hasta, &r.UltimoUsado, &r.Estado)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error consultando rango de facturación activo: %w", err)
+	}
+	return &r, nil
+}
+
+func insertarRangoLocal(tx *sql.Tx, r RangoFacturacion) error {
+	_, err := tx.Exec(`
+		INSERT INTO rangos_facturacion (uuid, terminal_id, prefijo, desde, hasta, ultimo_usado, estado, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.UUID, r.TerminalID, r.Prefijo, r.Desde, r.Hasta, r.Desde-1, RangoActivo, r.CreatedAt, r.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error guardando rango de facturación local: %w", err)
+	}
+	return nil
+}
+
+// reservarRangoRemoto registra la terminal si no existe y le asigna el siguiente bloque de la
+// serie de su prefijo. La fila de la serie se bloquea para que dos terminales no reciban el mismo bloque.
+// Con una resolución DIAN configurada la serie arranca en su primer número y no pasa del último;
+// sin resolución arranca en NumeroFacturaInicial, como la numeración previa a los rangos.
+func (d *Db) reservarRangoRemoto(ctx context.Context, minimo int64) (RangoFacturacion, error) {
+	rtx, err := d.RemoteDB.Begin(ctx)
+	if err != nil {
+		return RangoFacturacion{}, fmt.Errorf("[REMOTO] - error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := rtx.Rollback(ctx); rErr != nil && !errors.Is(rErr, pgx.ErrTxClosed) {
+			d.Log.Errorf("[REMOTO] - Error durante [reservarRangoRemoto] rollback %v", rErr)
+		}
+	}()
+
+	now := time.Now()
+	if _, err := rtx.Exec(ctx, `
+		INSERT INTO terminales (terminal_id, prefijo, tamano_rango, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (terminal_id) DO NOTHING`, d.terminalID, d.prefijoFactura, d.tamanoRango, now); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("[REMOTO] - error registrando terminal: %w", err)
+	}
+
+	// El prefijo y el tamaño del rango se administran en el remoto; el .env solo da los valores iniciales.
+	var prefijo string
+	var tamano int64
+	if err := rtx.QueryRow(ctx, `SELECT prefijo, tamano_rango FROM terminales WHERE terminal_id = $1`, d.terminalID).Scan(&prefijo, &tamano); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("[REMOTO] - error consultando terminal: %w", err)
+	}
+
+	// Ante la DIAN el número va sin separadores: el prefijo FAC- corresponde a la resolución con prefijo FAC
+	resolucion := d.configDIAN
+	if resolucion.PrefijoResolucion != "" && resolucion.PrefijoResolucion != numeroDocumentoDIAN(prefijo) {
+		return RangoFacturacion{}, fmt.Errorf("el prefijo %s de la terminal no corresponde al de la resolución de facturación %s (%s)",
+			prefijo, resolucion.NumeroResolucion, resolucion.PrefijoResolucion)
+	}
+	inicio := resolucion.RangoDesde
+	if inicio <= 0 {
+		inicio = NumeroFacturaInicial
+	}
+
+	if _, err := rtx.Exec(ctx, `
+		INSERT INTO series_facturacion (prefijo, siguiente_numero, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (prefijo) DO NOTHING`, prefijo, inicio, now); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("[REMOTO] - error registrando serie %s: %w", prefijo, err)
+	}
+
+	var siguiente int64
+	if err := rtx.QueryRow(ctx, `SELECT siguiente_numero FROM series_facturacion WHERE prefijo = $1 FOR UPDATE`, prefijo).Scan(&siguiente); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("[REMOTO] - error bloqueando serie %s: %w", prefijo, err)
+	}
+
+	// Las facturas emitidas antes de las series no pueden quedar dentro del nuevo rango.
+	var maxEmitido int64
+	if err := rtx.QueryRow(ctx, `
+		SELECT COALESCE(MAX(CAST(SUBSTRING(numero_factura FROM '(\d+)$') AS BIGINT)), 0)
+		FROM facturas
+		WHERE numero_factura LIKE $1`, prefijo+"%").Scan(&maxEmitido); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("[REMOTO] - error consultando último número emitido: %w", err)
+	}
+
+	desde := siguiente
+	if maxEmitido+1 > desde {
+		desde = maxEmitido + 1
+	}
+	if prefijo == d.prefijoFactura && minimo > desde {
+		desde = minimo
+	}
+	if desde < inicio {
+		desde = inicio
+	}
+	hasta := desde + tamano - 1
+	if resolucion.RangoHasta > 0 {
+		if desde > resolucion.RangoHasta {
+			return RangoFacturacion{}, fmt.Errorf("la resolución de facturación %s se agotó (autoriza del %d al %d): solicite una nueva a la DIAN",
+				resolucion.NumeroResolucion, resolucion.RangoDesde, resolucion.RangoHasta)
+		}
+		if hasta > resolucion.RangoHasta {
+			hasta = resolucion.RangoHasta
+		}
+	}
+	rango := RangoFacturacion{
+		UUID:       uuid.New().String(),
+		TerminalID: d.terminalID,
+		Prefijo:    prefijo,
+		Desde:      desde,
+		Hasta:      hasta,
+		Estado:     RangoActivo,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	rango.UltimoUsado = rango.Desde - 1
+
+	if _, err := rtx.Exec(ctx, `UPDATE series_facturacion SET siguiente_numero = $1, updated_at = $2 WHERE prefijo = $3`,
+		rango.Hasta+1, now, prefijo); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("[REMOTO] - error avanzando serie %s: %w", prefijo, err)
+	}
+	if _, err := rtx.Exec(ctx, `
+		INSERT INTO rangos_facturacion (uuid, terminal_id, prefijo, desde, hasta, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		rango.UUID, rango.TerminalID, rango.Prefijo, rango.Desde, rango.Hasta, now); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("[REMOTO] - error registrando rango: %w", err)
+	}
+
+	if err := rtx.Commit(ctx); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("Error confirmando transacción remota: %w", err)
+	}
+
+	d.Log.Infof("[NUMERACION] Rango %s%d - %s%d asignado a la terminal %s", rango.Prefijo, rango.Desde, rango.Prefijo, rango.Hasta, rango.TerminalID)
+	return rango, nil
+}
+
+// asegurarRangoFacturacion reserva un rango nuevo cuando a la terminal le quedan menos números
+// que el umbral configurado, para que pueda seguir facturando si pierde la conexión.
+func (d *Db) asegurarRangoFacturacion() {
+	if !d.rangoMutex.TryLock() {
+		return
+	}
+	defer d.rangoMutex.Unlock()
+
+	disponibles, err := d.numerosDisponibles()
+	if err != nil {
+		d.Log.Errorf("[NUMERACION] %v", err)
+		return
+	}
+	if disponibles >= d.umbralRango || !d.isRemoteDBAvailable() {
+		return
+	}
+	if _, err := d.solicitarRango(); err != nil {
+		d.Log.Errorf("[NUMERACION] No se pudo reservar un nuevo rango de facturación: %v", err)
+	}
+}
+
+// prepararRangoFacturacion reserva un rango cuando la terminal se quedó sin números. Se llama antes de
+// abrir la transacción de la venta, para no mantener la escritura local abierta durante la reserva remota.
+func (d *Db) prepararRangoFacturacion() error {
+	d.rangoMutex.Lock()
+	defer d.rangoMutex.Unlock()
+
+	disponibles, err := d.numerosDisponibles()
+	if err != nil {
+		return err
+	}
+	if disponibles > 0 {
+		return nil
+	}
+	if !d.isRemoteDBAvailable() {
+		return fmt.Errorf("la terminal %s no tiene números de factura disponibles y no hay conexión para reservar un rango", d.terminalID)
+	}
+	_, err = d.solicitarRango()
+	return err
+}
+
+// SolicitarRangoFacturacion reserva manualmente un nuevo rango para la terminal.
+func (d *Db) SolicitarRangoFacturacion() (RangoFacturacion, error) {
+	if !d.isRemoteDBAvailable() {
+		return RangoFacturacion{}, fmt.Errorf("se requiere conexión con el servidor para solicitar un rango de facturación")
+	}
+	d.rangoMutex.Lock()
+	defer d.rangoMutex.Unlock()
+	return d.solicitarRango()
+}
+
+func (d *Db) solicitarRango() (RangoFacturacion, error) {
+	var maxLocal int64
+	if err := d.LocalDB.QueryRow(fmt.Sprintf(`
+		SELECT COALESCE(MAX(CAST(SUBSTR(numero_factura, %d) AS INTEGER)), 0)
+		FROM facturas
+		WHERE numero_factura LIKE ?`, len(d.prefijoFactura)+1), d.prefijoFactura+"%").Scan(&maxLocal); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("error consultando último número de factura local: %w", err)
+	}
+
+	rango, err := d.reservarRangoRemoto(d.ctx, maxLocal+1)
+	if err != nil {
+		return RangoFacturacion{}, err
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return RangoFacturacion{}, fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[solicitarRango] rollback: %v", rErr)
+		}
+	}()
+	if err := insertarRangoLocal(tx, rango); err != nil {
+		return RangoFacturacion{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return RangoFacturacion{}, fmt.Errorf("error confirmando rango de facturación: %w", err)
+	}
+	return rango, nil
+}
+
+// numerosDisponibles suma los números sin usar de los rangos activos de la terminal.
+func (d *Db) numerosDisponibles() (int64, error) {
+	var disponibles int64
+	err := d.LocalDB.QueryRow(`
+		SELECT COALESCE(SUM(hasta - MAX(ultimo_usado, desde - 1)), 0)
+		FROM rangos_facturacion
+		WHERE terminal_id = ? AND estado = ?`, d.terminalID, RangoActivo).Scan(&disponibles)
+	if err != nil {
+		return 0, fmt.Errorf("error calculando números de factura disponibles: %w", err)
+	}
+	return disponibles, nil
+}
+
+// ObtenerEstadoNumeracion expone la numeración de la terminal para detectar a tiempo
+// cuándo se está agotando su rango.
+func (d *Db) ObtenerEstadoNumeracion() (EstadoNumeracion, error) {
+	estado := EstadoNumeracion{
+		TerminalID: d.terminalID,
+		Prefijo:    d.prefijoFactura,
+		Umbral:     d.umbralRango,
+		Rangos:     make([]RangoFacturacion, 0),
+	}
+
+	rows, err := d.LocalDB.Query(`
+		SELECT uuid, terminal_id, prefijo, desde, hasta, ultimo_usado, estado, created_at, updated_at
+		FROM rangos_facturacion
+		WHERE terminal_id = ?
+		ORDER BY desde DESC`, d.terminalID)
+	if err != nil {
+		return EstadoNumeracion{}, fmt.Errorf("error consultando rangos de facturación: %w", err)
+	}
+	defer rows.Close()
+
+	var ultimoUso time.Time
+	for rows.Next() {
+		var r RangoFacturacion
+		if err := rows.Scan(&r.UUID, &r.TerminalID, &r.Prefijo, &r.Desde, &r.Hasta, &r.UltimoUsado, &r.Estado, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return EstadoNumeracion{}, fmt.Errorf("error escaneando rango de facturación: %w", err)
+		}
+		if r.UltimoUsado >= r.Desde && r.UpdatedAt.After(ultimoUso) {
+			ultimoUso = r.UpdatedAt
+			estado.UltimaFactura = fmt.Sprintf("%s%d", r.Prefijo, r.UltimoUsado)
+			estado.Prefijo = r.Prefijo
+		}
+		estado.Rangos = append(estado.Rangos, r)
+	}
+	if err := rows.Err(); err != nil {
+		return EstadoNumeracion{}, err
+	}
+
+	estado.Disponibles, err = d.numerosDisponibles()
+	if err != nil {
+		return EstadoNumeracion{}, err
+	}
+	estado.PorAgotarse = estado.Disponibles < estado.Umbral
+	return estado, nil
+}
@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Métodos de pago con tratamiento especial. Se guardan en minúscula como los envía el POS.
+// El pago "credito" es la parte de la venta que el cliente queda debiendo y el pago "puntos"
+// la parte cubierta con puntos de fidelización del cliente.
+const (
+	MetodoPagoEfectivo = "efectivo"
+	MetodoPagoMixto    = "mixto"
+	MetodoPagoCredito  = "credito"
+	MetodoPagoPuntos   = "puntos"
+)
+
+// construirPagos arma los pagos de una venta y valida que su suma sea igual al total de la factura.
+// Devuelve además el método a registrar en facturas.metodo_pago ("mixto" si hay más de uno).
+// Una venta en cero (por ejemplo con descuento total) no lleva pagos.
+func construirPagos(facturaUUID string, req VentaRequest, total float64, now time.Time) ([]PagoFactura, string, error) {
+	solicitados := req.Pagos
+	if len(solicitados) == 0 {
+		metodo := strings.TrimSpace(req.MetodoPago)
+		if metodo == "" {
+			metodo = MetodoPagoEfectivo
+		}
+		if redondearMoneda(total) == 0 {
+			return []PagoFactura{}, metodo, nil
+		}
+		solicitados = []PagoVenta{{MetodoPago: metodo, Monto: total}}
+	}
+
+	pagos := make([]PagoFactura, 0, len(solicitados))
+	metodos := make(map[string]bool)
+	var suma float64
+	for _, p := range solicitados {
+		metodo := strings.TrimSpace(p.MetodoPago)
+		if metodo == "" {
+			return nil, "", fmt.Errorf("cada pago debe indicar su método de pago")
+		}
+		if p.Monto <= 0 {
+			return nil, "", fmt.Errorf("el monto del pago en %s debe ser mayor que cero", metodo)
+		}
+
+		pago := PagoFactura{
+			UUID:        uuid.New().String(),
+			FacturaUUID: facturaUUID,
+			MetodoPago:  metodo,
+			Monto:       redondearMoneda(p.Monto),
+			Referencia:  strings.TrimSpace(p.Referencia),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		// Solo el efectivo recibe más de lo que se cobra y genera cambio.
+		if strings.EqualFold(metodo, MetodoPagoEfectivo) {
+			pago.EfectivoRecibido = redondearMoneda(p.EfectivoRecibido)
+			if pago.EfectivoRecibido == 0 {
+				pago.EfectivoRecibido = pago.Monto
+			}
+			if pago.EfectivoRecibido < pago.Monto {
+				return nil, "", fmt.Errorf("el efectivo recibido (%.2f) es menor que el monto a pagar en efectivo (%.2f)", pago.EfectivoRecibido, pago.Monto)
+			}
+			pago.Cambio = redondearMoneda(pago.EfectivoRecibido - pago.Monto)
+		}
+
+		suma += pago.Monto
+		metodos[strings.ToLower(metodo)] = true
+		pagos = append(pagos, pago)
+	}
+
+	if math.Abs(redondearMoneda(suma)-redondearMoneda(total)) >= 0.01 {
+		return nil, "", fmt.Errorf("la suma de los pagos (%.2f) no coincide con el total de la factura (%.2f)", suma, total)
+	}
+
+	metodoResumen := pagos[0].MetodoPago
+	if len(metodos) > 1 {
+		metodoResumen = MetodoPagoMixto
+	}
+	return pagos, metodoResumen, nil
+}
+
+// obtenerPagosFactura devuelve los medios de pago registrados para una factura.
+func (d *Db) obtenerPagosFactura(facturaUUID string) ([]PagoFactura, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT uuid, factura_uuid, metodo_pago, monto, COALESCE(referencia, ''), efectivo_recibido, cambio, created_at, updated_at
+		FROM pagos_factura
+		WHERE factura_uuid = ? AND deleted_at IS NULL
+		ORDER BY created_at ASC`, facturaUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener pagos de la factura: %w", err)
+	}
+	defer rows.Close()
+
+	pagos := make([]PagoFactura, 0)
+	for rows.Next() {
+		var p PagoFactura
+		if err := rows.Scan(&p.UUID, &p.FacturaUUID, &p.MetodoPago, &p.Monto, &p.Referencia, &p.EfectivoRecibido, &p.Cambio, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear pago de factura: %w", err)
+		}
+		pagos = append(pagos, p)
+	}
+	return pagos, rows.Err()
+}
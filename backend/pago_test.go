@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConstruirPagos(t *testing.T) {
+	type pagoEsperado struct {
+		metodo   string
+		monto    float64
+		recibido float64
+		cambio   float64
+	}
+	casos := []struct {
+		nombre string
+		req    VentaRequest
+		total  float64
+		metodo string
+		pagos  []pagoEsperado
+		error  bool
+	}{
+		{
+			nombre: "sin pagos se cobra todo en efectivo",
+			req:    VentaRequest{},
+			total:  37500,
+			metodo: MetodoPagoEfectivo,
+			pagos:  []pagoEsperado{{MetodoPagoEfectivo, 37500, 37500, 0}},
+		},
+		{
+			nombre: "sin pagos con el método de la venta",
+			req:    VentaRequest{MetodoPago: " tarjeta "},
+			total:  37500,
+			metodo: "tarjeta",
+			pagos:  []pagoEsperado{{"tarjeta", 37500, 0, 0}},
+		},
+		{
+			nombre: "venta en cero sin pagos",
+			req:    VentaRequest{},
+			total:  0,
+			metodo: MetodoPagoEfectivo,
+			pagos:  []pagoEsperado{},
+		},
+		{
+			nombre: "efectivo con cambio",
+			req:    VentaRequest{Pagos: []PagoVenta{{MetodoPago: MetodoPagoEfectivo, Monto: 37500, EfectivoRecibido: 50000}}},
+			total:  37500,
+			metodo: MetodoPagoEfectivo,
+			pagos:  []pagoEsperado{{MetodoPagoEfectivo, 37500, 50000, 12500}},
+		},
+		{
+			nombre: "pago mixto",
+			req: VentaRequest{Pagos: []PagoVenta{
+				{MetodoPago: MetodoPagoEfectivo, Monto: 20000, EfectivoRecibido: 20000},
+				{MetodoPago: "tarjeta", Monto: 17500, Referencia: "1234"},
+			}},
+			total:  37500,
+			metodo: MetodoPagoMixto,
+			pagos:  []pagoEsperado{{MetodoPagoEfectivo, 20000, 20000, 0}, {"tarjeta", 17500, 0, 0}},
+		},
+		{
+			nombre: "parte a crédito",
+			req: VentaRequest{Pagos: []PagoVenta{
+				{MetodoPago: MetodoPagoCredito, Monto: 30000},
+				{MetodoPago: MetodoPagoEfectivo, Monto: 7500.004},
+			}},
+			total:  37500,
+			metodo: MetodoPagoMixto,
+			pagos:  []pagoEsperado{{MetodoPagoCredito, 30000, 0, 0}, {MetodoPagoEfectivo, 7500, 7500, 0}},
+		},
+		{
+			nombre: "la suma no coincide con el total",
+			req:    VentaRequest{Pagos: []PagoVenta{{MetodoPago: "tarjeta", Monto: 30000}}},
+			total:  37500,
+			error:  true,
+		},
+		{
+			nombre: "pago en cero",
+			req:    VentaRequest{Pagos: []PagoVenta{{MetodoPago: "tarjeta", Monto: 0}}},
+			total:  0,
+			error:  true,
+		},
+		{
+			nombre: "pago en una venta en cero",
+			req:    VentaRequest{Pagos: []PagoVenta{{MetodoPago: MetodoPagoEfectivo, Monto: 1000}}},
+			total:  0,
+			error:  true,
+		},
+		{
+			nombre: "pago sin método",
+			req:    VentaRequest{Pagos: []PagoVenta{{MetodoPago: " ", Monto: 37500}}},
+			total:  37500,
+			error:  true,
+		},
+		{
+			nombre: "efectivo recibido menor al monto",
+			req:    VentaRequest{Pagos: []PagoVenta{{MetodoPago: MetodoPagoEfectivo, Monto: 37500, EfectivoRecibido: 30000}}},
+			total:  37500,
+			error:  true,
+		},
+	}
+
+	now := time.Now()
+	for _, c := range casos {
+		pagos, metodo, err := construirPagos("factura-1", c.req, c.total, now)
+		if c.error {
+			if err == nil {
+				t.Errorf("%s: se esperaba error y se obtuvieron %d pagos", c.nombre, len(pagos))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error inesperado: %v", c.nombre, err)
+			continue
+		}
+		if metodo != c.metodo {
+			t.Errorf("%s: método = %s, se esperaba %s", c.nombre, metodo, c.metodo)
+		}
+		if len(pagos) != len(c.pagos) {
+			t.Errorf("%s: %d pagos, se esperaban %d", c.nombre, len(pagos), len(c.pagos))
+			continue
+		}
+		for i, p := range pagos {
+			e := c.pagos[i]
+			if p.MetodoPago != e.metodo || p.Monto != e.monto || p.EfectivoRecibido != e.recibido || p.Cambio != e.cambio {
+				t.Errorf("%s: pago %d = %s %.2f (recibido %.2f, cambio %.2f), se esperaba %s %.2f (recibido %.2f, cambio %.2f)",
+					c.nombre, i, p.MetodoPago, p.Monto, p.EfectivoRecibido, p.Cambio, e.metodo, e.monto, e.recibido, e.cambio)
+			}
+			if p.UUID == "" || p.FacturaUUID != "factura-1" {
+				t.Errorf("%s: pago %d sin UUID o con factura %q", c.nombre, i, p.FacturaUUID)
+			}
+		}
+	}
+}
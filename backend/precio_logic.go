@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// resolverPrecioVenta decide el precio unitario a facturar. Sin precio en la solicitud se usa el de lista;
+// un precio distinto solo se acepta con autorización de supervisor y un motivo, y queda registrado.
+func resolverPrecioVenta(item ProductoVenta, nombre string, precioLista float64, vendedorUUID, autorizadoPor string) (float64, *CambioPrecio, error) {
+	if item.PrecioUnitario < 0 {
+		return 0, nil, fmt.Errorf("el precio de [%s] no puede ser negativo", nombre)
+	}
+	if esPrecioDeLista(item.PrecioUnitario, precioLista) {
+		return precioLista, nil, nil
+	}
+
+	if autorizadoPor == "" {
+		return 0, nil, fmt.Errorf("el precio de [%s] (%.2f) difiere del precio de lista (%.2f) y requiere autorización de un supervisor",
+			nombre, item.PrecioUnitario, precioLista)
+	}
+	motivo := strings.TrimSpace(item.MotivoCambioPrecio)
+	if motivo == "" {
+		return 0, nil, fmt.Errorf("debe indicar el motivo del cambio de precio de [%s]", nombre)
+	}
+
+	precio := redondearMoneda(item.PrecioUnitario)
+	return precio, &CambioPrecio{
+		UUID:              uuid.New().String(),
+		ProductoUUID:      item.ProductoUUID,
+		ProductoNombre:    nombre,
+		Cantidad:          item.Cantidad,
+		PrecioOriginal:    precioLista,
+		PrecioNuevo:       precio,
+		VendedorUUID:      vendedorUUID,
+		AutorizadoPorUUID: autorizadoPor,
+		Motivo:            motivo,
+	}, nil
+}
+
+// esPrecioDeLista indica si un precio pedido corresponde al de lista: 0 significa precio de lista y las
+// diferencias menores a medio centavo son de redondeo.
+func esPrecioDeLista(precio, precioLista float64) bool {
+	return precio == 0 || math.Abs(precio-precioLista) < 0.005
+}
+
+// insertarCambiosPrecio registra dentro de la transacción de la venta los precios modificados.
+func insertarCambiosPrecio(tx *sql.Tx, cambios []CambioPrecio) error {
+	for _, c := range cambios {
+		if _, err := tx.Exec(`
+			INSERT INTO cambios_precio (uuid, factura_uuid, detalle_factura_uuid, producto_uuid, cantidad, precio_original, precio_nuevo,
+				vendedor_uuid, autorizado_por_uuid, motivo, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.UUID, c.FacturaUUID, c.DetalleFacturaUUID, c.ProductoUUID, c.Cantidad, c.PrecioOriginal, c.PrecioNuevo,
+			c.VendedorUUID, c.AutorizadoPorUUID, c.Motivo, c.CreatedAt, c.UpdatedAt); err != nil {
+			return fmt.Errorf("error registrando cambio de precio de %s: %w", c.ProductoNombre, err)
+		}
+	}
+	return nil
+}
+
+// obtenerCambiosPrecioFactura devuelve los cambios de precio de una factura, para sincronizarlos con ella.
+func (d *Db) obtenerCambiosPrecioFactura(facturaUUID string) ([]CambioPrecio, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT uuid, factura_uuid, detalle_factura_uuid, producto_uuid, cantidad, precio_original, precio_nuevo,
+			vendedor_uuid, autorizado_por_uuid, motivo, created_at, updated_at
+		FROM cambios_precio WHERE factura_uuid = ?`, facturaUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener cambios de precio de la factura: %w", err)
+	}
+	defer rows.Close()
+
+	cambios := make([]CambioPrecio, 0)
+	for rows.Next() {
+		var c CambioPrecio
+		if err := rows.Scan(&c.UUID, &c.FacturaUUID, &c.DetalleFacturaUUID, &c.ProductoUUID, &c.Cantidad, &c.PrecioOriginal, &c.PrecioNuevo,
+			&c.VendedorUUID, &c.AutorizadoPorUUID, &c.Motivo, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear cambio de precio: %w", err)
+		}
+		cambios = append(cambios, c)
+	}
+	return cambios, rows.Err()
+}
+
+// ObtenerReporteCambiosPrecio agrupa por día los cambios de precio autorizados entre dos fechas (AAAA-MM-DD).
+// Sin fechas se reporta el día actual. Las facturas anuladas se incluyen: el cambio de precio sí ocurrió.
+func (d *Db) ObtenerReporteCambiosPrecio(fechaInicio, fechaFin string) ([]ResumenCambiosPrecioDia, error) {
+	hoy := time.Now().Format("2006-01-02")
+	if fechaInicio == "" {
+		fechaInicio = hoy
+	}
+	if fechaFin == "" {
+		fechaFin = fechaInicio
+	}
+	inicio, err := time.ParseInLocation("2006-01-02", fechaInicio, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("formato de fecha inválido: %w", err)
+	}
+	fin, err := time.ParseInLocation("2006-01-02", fechaFin, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("formato de fecha inválido: %w", err)
+	}
+	if fin.Before(inicio) {
+		return nil, fmt.Errorf("la fecha final (%s) es anterior a la inicial (%s)", fechaFin, fechaInicio)
+	}
+	fin = fin.Add(24*time.Hour - time.Nanosecond)
+
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT c.uuid, c.factura_uuid, f.numero_factura, c.detalle_factura_uuid, c.producto_uuid, p.nombre, c.cantidad,
+			c.precio_original, c.precio_nuevo, c.vendedor_uuid, v.nombre || ' ' || v.apellido,
+			c.autorizado_por_uuid, s.nombre || ' ' || s.apellido, c.motivo, c.created_at, c.updated_at
+		FROM cambios_precio c
+		JOIN facturas f ON f.uuid = c.factura_uuid
+		JOIN productos p ON p.uuid = c.producto_uuid
+		JOIN vendedors v ON v.uuid = c.vendedor_uuid
+		JOIN vendedors s ON s.uuid = c.autorizado_por_uuid
+		WHERE c.created_at BETWEEN ? AND ?
+		ORDER BY c.created_at ASC`, inicio, fin)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener cambios de precio: %w", err)
+	}
+	defer rows.Close()
+
+	reporte := make([]ResumenCambiosPrecioDia, 0)
+	for rows.Next() {
+		var c CambioPrecio
+		if err := rows.Scan(&c.UUID, &c.FacturaUUID, &c.NumeroFactura, &c.DetalleFacturaUUID, &c.ProductoUUID, &c.ProductoNombre, &c.Cantidad,
+			&c.PrecioOriginal, &c.PrecioNuevo, &c.VendedorUUID, &c.VendedorNombre,
+			&c.AutorizadoPorUUID, &c.AutorizadoPorNombre, &c.Motivo, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear cambio de precio: %w", err)
+		}
+
+		fecha := c.CreatedAt.In(time.Local).Format("2006-01-02")
+		if n := len(reporte); n == 0 || reporte[n-1].Fecha != fecha {
+			reporte = append(reporte, ResumenCambiosPrecioDia{Fecha: fecha, Cambios: make([]CambioPrecio, 0)})
+		}
+		dia := &reporte[len(reporte)-1]
+		dia.Cantidad++
+		dia.DiferenciaTotal = redondearMoneda(dia.DiferenciaTotal + (c.PrecioNuevo-c.PrecioOriginal)*float64(c.Cantidad))
+		dia.Cambios = append(dia.Cambios, c)
+	}
+	return reporte, rows.Err()
+}
@@ -0,0 +1,228 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PresentacionUnidad es el nombre de la unidad mínima del producto, la que no tiene registro propio
+// en presentaciones_producto: se vende con el código y el precio del producto.
+const PresentacionUnidad = "UNIDAD"
+
+// ObtenerPresentacionesProducto lista las presentaciones vigentes de un producto con las
+// presentaciones completas que alcanza a cubrir el stock actual.
+func (d *Db) ObtenerPresentacionesProducto(productoUUID string) ([]PresentacionProducto, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT pp.uuid, pp.producto_uuid, pp.nombre, pp.factor, pp.precio_venta, COALESCE(pp.codigo_barras, ''),
+			pp.created_at, pp.updated_at, COALESCE(p.stock, 0)
+		FROM presentaciones_producto pp
+		JOIN productos p ON p.uuid = pp.producto_uuid
+		WHERE pp.producto_uuid = ? AND pp.deleted_at IS NULL
+		ORDER BY pp.factor ASC, pp.nombre ASC`, productoUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener presentaciones del producto: %w", err)
+	}
+	defer rows.Close()
+
+	presentaciones := make([]PresentacionProducto, 0)
+	for rows.Next() {
+		var pp PresentacionProducto
+		var stock int
+		if err := rows.Scan(&pp.UUID, &pp.ProductoUUID, &pp.Nombre, &pp.Factor, &pp.PrecioVenta, &pp.CodigoBarras,
+			&pp.CreatedAt, &pp.UpdatedAt, &stock); err != nil {
+			return nil, fmt.Errorf("error al escanear presentación: %w", err)
+		}
+		if pp.Factor > 0 {
+			pp.Disponible = stock / pp.Factor
+		}
+		presentaciones = append(presentaciones, pp)
+	}
+	return presentaciones, rows.Err()
+}
+
+// GuardarPresentacionProducto crea una presentación o actualiza la existente si trae UUID.
+func (d *Db) GuardarPresentacionProducto(pp PresentacionProducto) (PresentacionProducto, error) {
+	pp.Nombre = strings.ToUpper(strings.TrimSpace(pp.Nombre))
+	pp.CodigoBarras = strings.TrimSpace(pp.CodigoBarras)
+	if pp.ProductoUUID == "" || pp.Nombre == "" {
+		return PresentacionProducto{}, errors.New("se requiere el producto y el nombre de la presentación")
+	}
+	if pp.Factor < 1 {
+		return PresentacionProducto{}, fmt.Errorf("factor inválido %d: la presentación debe contener al menos una unidad", pp.Factor)
+	}
+	if pp.PrecioVenta < 0 {
+		return PresentacionProducto{}, fmt.Errorf("el precio de la presentación no puede ser negativo")
+	}
+
+	var productos int
+	if err := d.LocalDB.QueryRowContext(d.ctx, `SELECT COUNT(*) FROM productos WHERE uuid = ? AND deleted_at IS NULL`, pp.ProductoUUID).Scan(&productos); err != nil {
+		return PresentacionProducto{}, fmt.Errorf("error verificando producto: %w", err)
+	}
+	if productos == 0 {
+		return PresentacionProducto{}, fmt.Errorf("producto [%s] no encontrado", pp.ProductoUUID)
+	}
+
+	// El código de barras identifica una sola presentación, incluida la unidad mínima de cualquier producto
+	if pp.CodigoBarras != "" {
+		if err := d.validarCodigoBarrasLibre(pp.CodigoBarras, pp.UUID, ""); err != nil {
+			return PresentacionProducto{}, err
+		}
+	}
+
+	now := time.Now()
+	var err error
+	if pp.UUID == "" {
+		pp.UUID = uuid.New().String()
+		pp.CreatedAt = now
+		_, err = d.LocalDB.ExecContext(d.ctx, `
+			INSERT INTO presentaciones_producto (uuid, producto_uuid, nombre, factor, precio_venta, codigo_barras, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			pp.UUID, pp.ProductoUUID, pp.Nombre, pp.Factor, pp.PrecioVenta, nullableString(pp.CodigoBarras), now, now)
+	} else {
+		var res sql.Result
+		res, err = d.LocalDB.ExecContext(d.ctx, `
+			UPDATE presentaciones_producto SET nombre = ?, factor = ?, precio_venta = ?, codigo_barras = ?, deleted_at = NULL, updated_at = ?
+			WHERE uuid = ? AND producto_uuid = ?`,
+			pp.Nombre, pp.Factor, pp.PrecioVenta, nullableString(pp.CodigoBarras), now, pp.UUID, pp.ProductoUUID)
+		if err == nil {
+			if n, _ := res.RowsAffected(); n == 0 {
+				return PresentacionProducto{}, fmt.Errorf("presentación [%s] no encontrada", pp.UUID)
+			}
+		}
+	}
+	if err != nil {
+		return PresentacionProducto{}, fmt.Errorf("error al guardar presentación %s: %w", pp.Nombre, err)
+	}
+	pp.UpdatedAt = now
+
+	go d.syncPresentacionToRemote(pp.UUID)
+	return pp, nil
+}
+
+// EliminarPresentacionProducto realiza un borrado lógico de la presentación. Las facturas que la
+// usaron conservan su nombre y factor.
+func (d *Db) EliminarPresentacionProducto(presentacionUUID string) error {
+	now := time.Now()
+	if _, err := d.LocalDB.Exec(`UPDATE presentaciones_producto SET deleted_at = ?, updated_at = ? WHERE uuid = ?`, now, now, presentacionUUID); err != nil {
+		return fmt.Errorf("error al eliminar presentación: %w", err)
+	}
+	go d.syncPresentacionToRemote(presentacionUUID)
+	return nil
+}
+
+// BuscarPresentacionPorCodigo resuelve un código escaneado en el POS. Primero busca entre los códigos
+// de barras de las presentaciones, luego entre los códigos adicionales del producto; si no, el código
+// del producto corresponde a su unidad mínima. Un GTIN-14 con cero inicial equivale a su EAN-13.
+func (d *Db) BuscarPresentacionPorCodigo(codigo string) (PresentacionProducto, error) {
+	codigo = strings.TrimSpace(codigo)
+	if codigo == "" {
+		return PresentacionProducto{}, errors.New("se requiere un código")
+	}
+
+	for _, variante := range variantesGTIN(codigo) {
+		pp, err := d.presentacionPorCodigo(variante)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return PresentacionProducto{}, err
+		}
+
+		producto, err := d.ObtenerProductoPorUUID(pp.ProductoUUID)
+		if err != nil {
+			return PresentacionProducto{}, err
+		}
+		pp.Disponible = producto.Stock / pp.Factor
+		pp.Producto = &producto
+		return pp, nil
+	}
+	return PresentacionProducto{}, fmt.Errorf("no hay productos con el código %s", codigo)
+}
+
+// presentacionPorCodigo busca un código exacto. Devuelve sql.ErrNoRows si ningún producto lo usa.
+func (d *Db) presentacionPorCodigo(codigo string) (PresentacionProducto, error) {
+	var pp PresentacionProducto
+	err := d.LocalDB.QueryRow(`
+		SELECT uuid, producto_uuid, nombre, factor, precio_venta, COALESCE(codigo_barras, '')
+		FROM presentaciones_producto
+		WHERE codigo_barras = ? AND deleted_at IS NULL`, codigo).Scan(
+		&pp.UUID, &pp.ProductoUUID, &pp.Nombre, &pp.Factor, &pp.PrecioVenta, &pp.CodigoBarras)
+	if !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			return PresentacionProducto{}, fmt.Errorf("error buscando presentación por código: %w", err)
+		}
+		return pp, nil
+	}
+
+	// Un código adicional sin presentación es la unidad mínima; si su presentación fue eliminada no se
+	// vende como unidad, porque en el empaque vienen varias
+	err = d.LocalDB.QueryRow(`
+		SELECT cb.producto_uuid, COALESCE(pp.uuid, ''), COALESCE(pp.nombre, ?), COALESCE(pp.factor, 1),
+			COALESCE(pp.precio_venta, p.precio_venta), cb.codigo
+		FROM codigos_barras_producto cb
+		JOIN productos p ON p.uuid = cb.producto_uuid
+		LEFT JOIN presentaciones_producto pp ON pp.uuid = cb.presentacion_uuid AND pp.deleted_at IS NULL
+		WHERE cb.codigo = ? AND cb.deleted_at IS NULL AND p.deleted_at IS NULL
+			AND (cb.presentacion_uuid IS NULL OR pp.uuid IS NOT NULL)`, PresentacionUnidad, codigo).Scan(
+		&pp.ProductoUUID, &pp.UUID, &pp.Nombre, &pp.Factor, &pp.PrecioVenta, &pp.CodigoBarras)
+	if !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			return PresentacionProducto{}, fmt.Errorf("error buscando código de barras del producto: %w", err)
+		}
+		return pp, nil
+	}
+
+	err = d.LocalDB.QueryRow(`SELECT uuid, precio_venta, codigo FROM productos WHERE codigo = ? AND deleted_at IS NULL`, codigo).Scan(
+		&pp.ProductoUUID, &pp.PrecioVenta, &pp.CodigoBarras)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PresentacionProducto{}, err
+		}
+		return PresentacionProducto{}, fmt.Errorf("error buscando producto por código: %w", err)
+	}
+	pp.Nombre, pp.Factor = PresentacionUnidad, 1
+	return pp, nil
+}
+
+// resolverPresentacion devuelve la presentación de una línea de venta o compra. Sin presentación
+// se usa la unidad mínima con el precio del producto.
+func resolverPresentacion(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, productoUUID, presentacionUUID string, precioProducto float64) (PresentacionProducto, error) {
+	if presentacionUUID == "" {
+		return PresentacionProducto{ProductoUUID: productoUUID, Nombre: PresentacionUnidad, Factor: 1, PrecioVenta: precioProducto}, nil
+	}
+
+	var pp PresentacionProducto
+	err := q.QueryRow(`
+		SELECT uuid, producto_uuid, nombre, factor, precio_venta, COALESCE(codigo_barras, '')
+		FROM presentaciones_producto
+		WHERE uuid = ? AND deleted_at IS NULL`, presentacionUUID).Scan(
+		&pp.UUID, &pp.ProductoUUID, &pp.Nombre, &pp.Factor, &pp.PrecioVenta, &pp.CodigoBarras)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PresentacionProducto{}, fmt.Errorf("presentación [%s] no encontrada", presentacionUUID)
+	}
+	if err != nil {
+		return PresentacionProducto{}, fmt.Errorf("error consultando presentación: %w", err)
+	}
+	if pp.ProductoUUID != productoUUID {
+		return PresentacionProducto{}, fmt.Errorf("la presentación %s no pertenece al producto [%s]", pp.Nombre, productoUUID)
+	}
+	if pp.Factor < 1 {
+		pp.Factor = 1
+	}
+	return pp, nil
+}
+
+// nombreConPresentacion identifica la línea en mensajes y recibos ("Acetaminofén x CAJA").
+func nombreConPresentacion(nombre, presentacion string) string {
+	if presentacion == "" || presentacion == PresentacionUnidad {
+		return nombre
+	}
+	return nombre + " x " + presentacion
+}
@@ -4,12 +4,52 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// sqlDatosFarmaceuticos son las columnas de DatosFarmaceuticos en el orden de destinos().
+const sqlDatosFarmaceuticos = `COALESCE(principio_activo, ''), COALESCE(concentracion, ''), COALESCE(forma_farmaceutica, ''),
+	COALESCE(laboratorio, ''), COALESCE(registro_invima, ''), COALESCE(strftime('%Y-%m-%d', vencimiento_invima), ''), COALESCE(codigo_atc, '')`
+
+// codigoATC acepta cualquier nivel de la clasificación ATC, de la letra del grupo anatómico (A) a la
+// sustancia (A10BA02).
+var codigoATC = regexp.MustCompile(`^[A-Z](\d{2}([A-Z]([A-Z](\d{2})?)?)?)?$`)
+
+func (f *DatosFarmaceuticos) destinos() []any {
+	return []any{&f.PrincipioActivo, &f.Concentracion, &f.FormaFarmaceutica, &f.Laboratorio, &f.RegistroInvima, &f.VencimientoInvima, &f.CodigoATC}
+}
+
+// valores devuelve los datos en el orden de sqlDatosFarmaceuticos, con NULL para los vacíos.
+func (f *DatosFarmaceuticos) valores() []any {
+	return []any{nullableString(f.PrincipioActivo), nullableString(f.Concentracion), nullableString(f.FormaFarmaceutica),
+		nullableString(f.Laboratorio), nullableString(f.RegistroInvima), nullableString(f.VencimientoInvima), nullableString(f.CodigoATC)}
+}
+
+// normalizar limpia los datos maestros y valida la fecha de vencimiento del registro INVIMA y el código ATC.
+func (f *DatosFarmaceuticos) normalizar() error {
+	f.PrincipioActivo = strings.TrimSpace(f.PrincipioActivo)
+	f.Concentracion = strings.TrimSpace(f.Concentracion)
+	f.FormaFarmaceutica = strings.TrimSpace(f.FormaFarmaceutica)
+	f.Laboratorio = strings.TrimSpace(f.Laboratorio)
+	f.RegistroInvima = strings.ToUpper(strings.TrimSpace(f.RegistroInvima))
+	f.VencimientoInvima = strings.TrimSpace(f.VencimientoInvima)
+	f.CodigoATC = strings.ToUpper(strings.TrimSpace(f.CodigoATC))
+
+	if f.VencimientoInvima != "" {
+		if _, err := time.Parse("2006-01-02", f.VencimientoInvima); err != nil {
+			return fmt.Errorf("fecha de vencimiento del registro INVIMA inválida %q: %w", f.VencimientoInvima, err)
+		}
+	}
+	if f.CodigoATC != "" && !codigoATC.MatchString(f.CodigoATC) {
+		return fmt.Errorf("código ATC inválido %q: se espera un código como N02BE01", f.CodigoATC)
+	}
+	return nil
+}
+
 // CrearProducto inserta un nuevo producto en la base de datos local.
 func (d *Db) RegistrarProducto(nuevo NuevoProducto) (Producto, error) {
 	tx, err := d.LocalDB.Begin()
@@ -22,6 +62,19 @@ func (d *Db) RegistrarProducto(nuevo NuevoProducto) (Producto, error) {
 		}
 	}()
 
+	nuevo.ImpuestoCodigo, err = validarImpuestoCodigo(tx, nuevo.ImpuestoCodigo)
+	if err != nil {
+		return Producto{}, err
+	}
+	nuevo.Categoria = normalizarCategoria(nuevo.Categoria)
+	if err := nuevo.DatosFarmaceuticos.normalizar(); err != nil {
+		return Producto{}, err
+	}
+	categoriaCreada, err := asegurarCategoria(tx, nuevo.Categoria, time.Now())
+	if err != nil {
+		return Producto{}, err
+	}
+
 	// Verificar existencia
 	var existente struct {
 		UUID      string
@@ -37,8 +90,11 @@ func (d *Db) RegistrarProducto(nuevo NuevoProducto) (Producto, error) {
 	case err == nil && existente.DeletedAt.Valid:
 		// Restaurar producto
 		_, err = tx.Exec(`
-			UPDATE productos SET nombre=?, precio_venta=?, stock=0, deleted_at=NULL, updated_at=CURRENT_TIMESTAMP WHERE uuid=?`,
-			nuevo.Nombre, nuevo.PrecioVenta, existente.UUID)
+			UPDATE productos SET nombre=?, precio_venta=?, impuesto_codigo=?, categoria=?, controlado=?, requiere_formula=?,
+				principio_activo=?, concentracion=?, forma_farmaceutica=?, laboratorio=?, registro_invima=?, vencimiento_invima=?, codigo_atc=?,
+				stock=0, deleted_at=NULL, updated_at=CURRENT_TIMESTAMP WHERE uuid=?`,
+			append(append([]any{nuevo.Nombre, nuevo.PrecioVenta, nuevo.ImpuestoCodigo, nullableString(nuevo.Categoria), nuevo.Controlado, nuevo.RequiereFormula},
+				nuevo.DatosFarmaceuticos.valores()...), existente.UUID)...)
 		if err != nil {
 			return Producto{}, fmt.Errorf("error al restaurar producto: %w", err)
 		}
@@ -51,9 +107,11 @@ func (d *Db) RegistrarProducto(nuevo NuevoProducto) (Producto, error) {
 	case errors.Is(err, sql.ErrNoRows):
 		nuevo.UUID = uuid.New().String()
 		_, err = tx.Exec(`
-			INSERT INTO productos (uuid, nombre, codigo, precio_venta, stock, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
-			nuevo.UUID, nuevo.Nombre, nuevo.Codigo, nuevo.PrecioVenta, nuevo.Stock)
+			INSERT INTO productos (uuid, nombre, codigo, precio_venta, impuesto_codigo, categoria, controlado, requiere_formula, stock,
+				principio_activo, concentracion, forma_farmaceutica, laboratorio, registro_invima, vencimiento_invima, codigo_atc, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+			append([]any{nuevo.UUID, nuevo.Nombre, nuevo.Codigo, nuevo.PrecioVenta, nuevo.ImpuestoCodigo, nullableString(nuevo.Categoria), nuevo.Controlado, nuevo.RequiereFormula, nuevo.Stock},
+				nuevo.DatosFarmaceuticos.valores()...)...)
 		if err != nil {
 			return Producto{}, fmt.Errorf("error al registrar producto: %w", err)
 		}
@@ -68,15 +126,23 @@ func (d *Db) RegistrarProducto(nuevo NuevoProducto) (Producto, error) {
 		return Producto{}, fmt.Errorf("error al confirmar transacción: %w", err)
 	}
 
+	if categoriaCreada != "" {
+		go d.syncCategoriaToRemote(categoriaCreada)
+	}
 	go d.syncProductoToRemote(nuevo.UUID)
 	go d.SincronizarOperacionesStockHaciaRemoto()
 
 	return Producto{
-		UUID:        nuevo.UUID,
-		Nombre:      nuevo.Nombre,
-		Codigo:      nuevo.Codigo,
-		PrecioVenta: nuevo.PrecioVenta,
-		Stock:       nuevo.Stock,
+		UUID:               nuevo.UUID,
+		Nombre:             nuevo.Nombre,
+		Codigo:             nuevo.Codigo,
+		PrecioVenta:        nuevo.PrecioVenta,
+		Stock:              nuevo.Stock,
+		ImpuestoCodigo:     nuevo.ImpuestoCodigo,
+		Categoria:          nuevo.Categoria,
+		Controlado:         nuevo.Controlado,
+		RequiereFormula:    nuevo.RequiereFormula,
+		DatosFarmaceuticos: nuevo.DatosFarmaceuticos,
 	}, nil
 }
 
@@ -116,12 +182,32 @@ func (d *Db) ActualizarProducto(req ProductoAjusteRequest) (string, error) {
 		return "", fmt.Errorf("error leyendo stock real: %w", err)
 	}
 
-	// 2️⃣ Actualizar info del producto
+	// 2️⃣ Actualizar info del producto (sin código de impuesto, categoría, marca de control o dato
+	// farmacéutico se conserva el actual)
+	if req.ImpuestoCodigo != "" {
+		if req.ImpuestoCodigo, err = validarImpuestoCodigo(tx, req.ImpuestoCodigo); err != nil {
+			return "", err
+		}
+	}
+	if err := req.DatosFarmaceuticos.normalizar(); err != nil {
+		return "", err
+	}
+	categoriaCreada, err := asegurarCategoria(tx, normalizarCategoria(req.Categoria), time.Now())
+	if err != nil {
+		return "", err
+	}
 	_, err = tx.Exec(`
 		UPDATE productos 
-		SET nombre=?, precio_venta=?, updated_at=CURRENT_TIMESTAMP
+		SET nombre=?, precio_venta=?, impuesto_codigo=COALESCE(NULLIF(?, ''), impuesto_codigo),
+			categoria=COALESCE(NULLIF(?, ''), categoria), controlado=COALESCE(?, controlado),
+			requiere_formula=COALESCE(?, requiere_formula),
+			principio_activo=COALESCE(?, principio_activo), concentracion=COALESCE(?, concentracion),
+			forma_farmaceutica=COALESCE(?, forma_farmaceutica), laboratorio=COALESCE(?, laboratorio),
+			registro_invima=COALESCE(?, registro_invima), vencimiento_invima=COALESCE(?, vencimiento_invima),
+			codigo_atc=COALESCE(?, codigo_atc), updated_at=CURRENT_TIMESTAMP
 		WHERE uuid=?`,
-		req.Nombre, req.PrecioVenta, req.UUID)
+		append(append([]any{req.Nombre, req.PrecioVenta, req.ImpuestoCodigo, normalizarCategoria(req.Categoria), req.Controlado, req.RequiereFormula},
+			req.DatosFarmaceuticos.valores()...), req.UUID)...)
 	if err != nil {
 		return "", fmt.Errorf("error actualizando producto: %w", err)
 	}
@@ -133,7 +219,13 @@ func (d *Db) ActualizarProducto(req ProductoAjusteRequest) (string, error) {
 		if req.VendedorUUID != "" {
 			tipo = "AJUSTE_USUARIO"
 		}
-		if err := d.CrearOperacionStock(tx, req.UUID, tipo, cambio, req.VendedorUUID, nil); err != nil {
+		// Las bajas se imputan a los lotes por FEFO, incluidos los vencidos
+		if cambio < 0 {
+			err = d.descontarStockFEFO(tx, req.UUID, tipo, -cambio, "", req.VendedorUUID, nil, true, nil)
+		} else {
+			err = d.CrearOperacionStock(tx, req.UUID, tipo, cambio, req.VendedorUUID, nil)
+		}
+		if err != nil {
 			return "", err
 		}
 	}
@@ -144,6 +236,9 @@ func (d *Db) ActualizarProducto(req ProductoAjusteRequest) (string, error) {
 	}
 
 	// 5️⃣ Sincronización asincrónica
+	if categoriaCreada != "" {
+		go d.syncCategoriaToRemote(categoriaCreada)
+	}
 	go d.syncProductoToRemote(req.UUID)
 	go d.SincronizarOperacionesStockHaciaRemoto()
 
@@ -160,8 +255,11 @@ func (d *Db) ObtenerProductosPaginado(page, pageSize int, search, sortBy, sortOr
 
 	if search != "" {
 		searchTerm := "%" + strings.ToLower(search) + "%"
-		whereClause = " AND (LOWER(nombre) LIKE ? OR LOWER(codigo) LIKE ?)" // Espacio al inicio
-		args = append(args, searchTerm, searchTerm)
+		// También busca por datos farmacéuticos y por categoría, incluidas sus subcategorías
+		whereClause = ` AND (LOWER(nombre) LIKE ? OR LOWER(codigo) LIKE ? OR LOWER(principio_activo) LIKE ?
+			OR LOWER(laboratorio) LIKE ? OR LOWER(registro_invima) LIKE ? OR LOWER(codigo_atc) LIKE ?
+			OR LOWER(categoria) LIKE ? OR categoria IN (` + sqlSubarbolCategorias + `))` // Espacio al inicio
+		args = append(args, searchTerm, searchTerm, searchTerm, searchTerm, searchTerm, searchTerm, searchTerm, searchTerm)
 	}
 
 	countQuery := "SELECT COUNT(uuid) " + baseQuery + whereClause
@@ -169,7 +267,8 @@ func (d *Db) ObtenerProductosPaginado(page, pageSize int, search, sortBy, sortOr
 		return PaginatedResult{}, fmt.Errorf("error al contar productos: %w", err)
 	}
 
-	selectQuery := "SELECT uuid, codigo, nombre, precio_venta, stock " + baseQuery + whereClause
+	selectQuery := "SELECT uuid, codigo, nombre, precio_venta, stock, COALESCE(impuesto_codigo, ''), COALESCE(categoria, ''), costo_promedio, controlado, requiere_formula, " +
+		sqlDatosFarmaceuticos + " " + baseQuery + whereClause
 
 	if sortBy != "" {
 		order := "ASC"
@@ -177,7 +276,8 @@ func (d *Db) ObtenerProductosPaginado(page, pageSize int, search, sortBy, sortOr
 			order = "DESC"
 		}
 		// Validar columnas para evitar inyección SQL
-		allowedSortBy := map[string]string{"Nombre": "nombre", "Codigo": "codigo", "PrecioVenta": "precio_venta", "Stock": "stock"}
+		allowedSortBy := map[string]string{"Nombre": "nombre", "Codigo": "codigo", "PrecioVenta": "precio_venta", "Stock": "stock", "ImpuestoCodigo": "impuesto_codigo", "Categoria": "categoria", "CostoPromedio": "costo_promedio", "Controlado": "controlado", "RequiereFormula": "requiere_formula",
+			"PrincipioActivo": "principio_activo", "Laboratorio": "laboratorio", "FormaFarmaceutica": "forma_farmaceutica", "VencimientoInvima": "vencimiento_invima", "CodigoATC": "codigo_atc"}
 		if col, ok := allowedSortBy[sortBy]; ok {
 			selectQuery += fmt.Sprintf(" ORDER BY %s %s", col, order)
 		}
@@ -196,7 +296,8 @@ func (d *Db) ObtenerProductosPaginado(page, pageSize int, search, sortBy, sortOr
 
 	for rows.Next() {
 		var p Producto
-		if err := rows.Scan(&p.UUID, &p.Codigo, &p.Nombre, &p.PrecioVenta, &p.Stock); err != nil {
+		if err := rows.Scan(append([]any{&p.UUID, &p.Codigo, &p.Nombre, &p.PrecioVenta, &p.Stock, &p.ImpuestoCodigo, &p.Categoria, &p.CostoPromedio, &p.Controlado, &p.RequiereFormula},
+			p.DatosFarmaceuticos.destinos()...)...); err != nil {
 			return PaginatedResult{}, fmt.Errorf("error al escanear producto: %w", err)
 		}
 		productos = append(productos, p)
@@ -208,13 +309,20 @@ func (d *Db) ObtenerProductosPaginado(page, pageSize int, search, sortBy, sortOr
 // ObtenerProductoPorUUID busca un producto por su UUID.
 func (d *Db) ObtenerProductoPorUUID(uuid string) (Producto, error) {
 	var p Producto
-	query := "SELECT uuid, codigo, nombre, precio_venta, stock FROM productos WHERE uuid = ? AND deleted_at IS NULL"
+	query := "SELECT uuid, codigo, nombre, precio_venta, stock, COALESCE(impuesto_codigo, ''), COALESCE(categoria, ''), costo_promedio, controlado, requiere_formula, " +
+		sqlDatosFarmaceuticos + " FROM productos WHERE uuid = ? AND deleted_at IS NULL"
 
-	err := d.LocalDB.QueryRow(query, uuid).Scan(&p.UUID, &p.Codigo, &p.Nombre, &p.PrecioVenta, &p.Stock)
+	err := d.LocalDB.QueryRow(query, uuid).Scan(append([]any{&p.UUID, &p.Codigo, &p.Nombre, &p.PrecioVenta, &p.Stock, &p.ImpuestoCodigo, &p.Categoria, &p.CostoPromedio, &p.Controlado, &p.RequiereFormula},
+		p.DatosFarmaceuticos.destinos()...)...)
 	if err != nil {
 		return Producto{}, fmt.Errorf("error al buscar producto por UUID %s: %w", uuid, err)
 	}
 
+	p.Presentaciones, err = d.ObtenerPresentacionesProducto(p.UUID)
+	if err != nil {
+		return Producto{}, err
+	}
+
 	return p, nil
 }
 
@@ -269,85 +377,60 @@ func (d *Db) ObtenerHistorialStock(productoUUID string) ([]OperacionStock, error
 	return historial, nil
 }
 
+// ActualizarStockMasivo fija el stock de varios productos con ajustes a nombre del vendedor de cada
+// línea. Para una toma física se usan los conteos de inventario, que guardan lo esperado y lo contado.
 func (d *Db) ActualizarStockMasivo(ajustes []AjusteStockRequest) (string, error) {
 	if len(ajustes) == 0 {
 		return "No hay ajustes para procesar.", nil
 	}
+	for _, a := range ajustes {
+		if a.ProductoUUID == "" || a.VendedorUUID == "" {
+			return "", errors.New("cada ajuste requiere el producto y el vendedor que lo realiza")
+		}
+		if a.NuevoStock < 0 {
+			return "", fmt.Errorf("el stock del producto [%s] no puede ser negativo", a.ProductoUUID)
+		}
+	}
 	tx, err := d.LocalDB.BeginTx(d.ctx, nil)
 	if err != nil {
 		return "", fmt.Errorf("error al iniciar la transacción masiva: %w", err)
 	}
 	defer func() {
 		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
-			d.Log.Errorf("[LOCAL] - Error durante [ActualizarStockMasivo] rollback %v", err)
+			d.Log.Errorf("[LOCAL] - Error durante [ActualizarStockMasivo] rollback %v", rErr)
 		}
 	}()
-	// 1. Preparar IDs para la consulta en lote.
-	productoUUIDs := make([]string, 0, len(ajustes))
-	mapaAjustes := make(map[string]int, len(ajustes))
-	for _, a := range ajustes {
-		productoUUIDs = append(productoUUIDs, a.ProductoUUID)
-		mapaAjustes[a.ProductoUUID] = a.NuevoStock
-	}
-
-	// Implementación de consulta IN (...) para SQLite
-	args := make([]interface{}, len(productoUUIDs))
-	for i, p_uuid := range productoUUIDs {
-		args[i] = p_uuid
-	}
-	query := `SELECT producto_uuid, COALESCE(SUM(cantidad_cambio), 0)
-			  FROM operacion_stocks
-			  WHERE producto_uuid IN (?` + strings.Repeat(",?", len(productoUUIDs)-1) + `)
-			  GROUP BY producto_uuid`
-
-	// 2. Obtener stocks reales actuales en una sola consulta.
-	rows, err := tx.QueryContext(d.ctx, query, args...)
-	if err != nil {
-		return "", fmt.Errorf("error al obtener stocks reales en lote: %w", err)
-	}
-	defer rows.Close()
 
-	stocksReales := make(map[string]int)
-	for rows.Next() {
-		var uuid string
-		var stock int
-		if err := rows.Scan(&uuid, &stock); err != nil {
-			return "", err
+	ajustados := 0
+	for _, a := range ajustes {
+		stockActual, err := calcularStockRealLocal(tx, a.ProductoUUID)
+		if err != nil {
+			return "", fmt.Errorf("error obteniendo stock del producto UUID %s: %w", a.ProductoUUID, err)
 		}
-		stocksReales[uuid] = stock
-	}
-
-	// 3. Preparar la sentencia para la inserción en lote de ajustes.
-	stmt, err := tx.PrepareContext(d.ctx, `
-		INSERT INTO operacion_stocks (uuid, producto_uuid, tipo_operacion, cantidad_cambio, vendedor_uuid, timestamp) 
-		VALUES (?, ?, 'AJUSTE', ?, 1, ?)`)
-	if err != nil {
-		return "", fmt.Errorf("error al preparar la inserción de ajustes: %w", err)
-	}
-	defer stmt.Close()
-
-	// 4. Calcular cambios y ejecutar la inserción en lote.
-	for _, uuid := range productoUUIDs {
-		cantidadCambio := mapaAjustes[uuid] - stocksReales[uuid]
-		if cantidadCambio != 0 {
-			if _, err := stmt.ExecContext(d.ctx, uuid, uuid, cantidadCambio, time.Now()); err != nil {
-				return "", fmt.Errorf("error al insertar ajuste para producto UUID %s: %w", uuid, err)
-			}
+		cambio := a.NuevoStock - stockActual
+		switch {
+		case cambio > 0:
+			err = d.CrearOperacionStock(tx, a.ProductoUUID, "AJUSTE_POSITIVO", cambio, a.VendedorUUID, nil)
+		case cambio < 0:
+			// Las bajas se imputan a los lotes por FEFO, incluidos los vencidos
+			err = d.descontarStockFEFO(tx, a.ProductoUUID, "AJUSTE_NEGATIVO", -cambio, "", a.VendedorUUID, nil, true, nil)
+		default:
+			continue
 		}
-	}
-
-	// 5. ¡Uso de la función auxiliar en bucle para cada producto afectado!
-	for _, p_uuid := range productoUUIDs {
-		if err := RecalcularYActualizarStock(tx, p_uuid); err != nil {
-			return "", fmt.Errorf("error al recalcular stock en lote para producto UUID %s: %w", p_uuid, err)
+		if err != nil {
+			return "", fmt.Errorf("error al ajustar producto UUID %s: %w", a.ProductoUUID, err)
 		}
+		ajustados++
 	}
 
 	if err := tx.Commit(); err != nil {
 		return "", fmt.Errorf("error al confirmar la transacción masiva: %w", err)
 	}
 
-	go d.SincronizarOperacionesStockHaciaRemoto()
+	go func() {
+		d.SincronizarOperacionesStockHaciaRemoto()
+		d.SincronizarLibroControladosHaciaRemoto()
+	}()
 
-	return "Stock actualizado masivamente.", nil
+	return fmt.Sprintf("Stock actualizado en %d productos.", ajustados), nil
 }
@@ -0,0 +1,501 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Tipos de movimiento del libro de puntos. Los puntos se guardan con signo para que
+// SUM(puntos) sea siempre el saldo del cliente, igual que operacion_stocks con el stock.
+const (
+	MovimientoPuntosAcumulacion = "ACUMULACION"
+	MovimientoPuntosRedencion   = "REDENCION"
+	MovimientoPuntosReversion   = "REVERSION"
+)
+
+// ValorPuntoPorDefecto es el valor en pesos de un punto redimido cuando PUNTOS_VALOR_PUNTO no está configurado.
+const ValorPuntoPorDefecto = 10.0
+
+// sqlPuntosCliente suma el libro de puntos del cliente con alias c.
+const sqlPuntosCliente = `(SELECT COALESCE(SUM(mp.puntos), 0) FROM movimientos_puntos mp WHERE mp.cliente_uuid = c.uuid)`
+
+// ObtenerReglasPuntos devuelve las reglas de acumulación vigentes.
+func (d *Db) ObtenerReglasPuntos() ([]ReglaPuntos, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT uuid, codigo, COALESCE(nombre, ''), COALESCE(categoria, ''), monto_por_punto, created_at, updated_at
+		FROM reglas_puntos
+		WHERE deleted_at IS NULL
+		ORDER BY categoria IS NOT NULL, categoria ASC, codigo ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener reglas de puntos: %w", err)
+	}
+	defer rows.Close()
+
+	reglas := make([]ReglaPuntos, 0)
+	for rows.Next() {
+		var r ReglaPuntos
+		if err := rows.Scan(&r.UUID, &r.Codigo, &r.Nombre, &r.Categoria, &r.MontoPorPunto, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear regla de puntos: %w", err)
+		}
+		reglas = append(reglas, r)
+	}
+	return reglas, rows.Err()
+}
+
+// GuardarReglaPuntos crea o actualiza (por código) una regla de acumulación.
+func (d *Db) GuardarReglaPuntos(regla ReglaPuntos) (ReglaPuntos, error) {
+	regla.Codigo = strings.ToUpper(strings.TrimSpace(regla.Codigo))
+	regla.Categoria = normalizarCategoria(regla.Categoria)
+	if regla.Codigo == "" {
+		return ReglaPuntos{}, errors.New("el código de la regla de puntos es obligatorio")
+	}
+	if regla.MontoPorPunto < 0 {
+		return ReglaPuntos{}, fmt.Errorf("monto por punto inválido %.2f: use 0 para excluir la categoría", regla.MontoPorPunto)
+	}
+
+	now := time.Now()
+	var existente string
+	err := d.LocalDB.QueryRowContext(d.ctx, `SELECT uuid FROM reglas_puntos WHERE codigo = ?`, regla.Codigo).Scan(&existente)
+	switch {
+	case err == nil:
+		regla.UUID = existente
+		_, err = d.LocalDB.ExecContext(d.ctx,
+			`UPDATE reglas_puntos SET nombre = ?, categoria = ?, monto_por_punto = ?, deleted_at = NULL, updated_at = ? WHERE uuid = ?`,
+			regla.Nombre, nullableString(regla.Categoria), regla.MontoPorPunto, now, regla.UUID)
+	case errors.Is(err, sql.ErrNoRows):
+		regla.UUID = uuid.New().String()
+		regla.CreatedAt = now
+		_, err = d.LocalDB.ExecContext(d.ctx,
+			`INSERT INTO reglas_puntos (uuid, codigo, nombre, categoria, monto_por_punto, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			regla.UUID, regla.Codigo, regla.Nombre, nullableString(regla.Categoria), regla.MontoPorPunto, now, now)
+	}
+	if err != nil {
+		return ReglaPuntos{}, fmt.Errorf("error al guardar regla de puntos %s: %w", regla.Codigo, err)
+	}
+	regla.UpdatedAt = now
+
+	go d.syncReglaPuntosToRemote(regla.UUID)
+	return regla, nil
+}
+
+// ObtenerHistorialPuntosCliente devuelve el libro de puntos del cliente, del más reciente al más antiguo.
+func (d *Db) ObtenerHistorialPuntosCliente(clienteUUID string) ([]MovimientoPuntos, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, `
+		SELECT mp.uuid, mp.cliente_uuid, mp.tipo, mp.puntos, mp.saldo_resultante, mp.factura_uuid,
+			COALESCE(f.numero_factura, ''), COALESCE(mp.vendedor_uuid, ''), COALESCE(mp.descripcion, ''), mp.timestamp, mp.sincronizado
+		FROM movimientos_puntos mp
+		LEFT JOIN facturas f ON f.uuid = mp.factura_uuid
+		WHERE mp.cliente_uuid = ?
+		ORDER BY mp.timestamp DESC`, clienteUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener historial de puntos: %w", err)
+	}
+	defer rows.Close()
+
+	movimientos := make([]MovimientoPuntos, 0)
+	for rows.Next() {
+		var m MovimientoPuntos
+		var facturaUUID sql.NullString
+		if err := rows.Scan(&m.UUID, &m.ClienteUUID, &m.Tipo, &m.Puntos, &m.SaldoResultante, &facturaUUID,
+			&m.NumeroFactura, &m.VendedorUUID, &m.Descripcion, &m.Timestamp, &m.Sincronizado); err != nil {
+			return nil, fmt.Errorf("error al escanear movimiento de puntos: %w", err)
+		}
+		if facturaUUID.Valid {
+			m.FacturaUUID = &facturaUUID.String
+		}
+		movimientos = append(movimientos, m)
+	}
+	return movimientos, rows.Err()
+}
+
+// normalizarCategoria compara categorías sin distinguir mayúsculas ni espacios.
+func normalizarCategoria(categoria string) string {
+	return strings.ToUpper(strings.TrimSpace(categoria))
+}
+
+// saldoPuntosCliente se usa dentro de las transacciones de venta y fuera de ellas en las consultas.
+func saldoPuntosCliente(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, clienteUUID string) (int, error) {
+	var saldo int
+	if err := q.QueryRow(`SELECT COALESCE(SUM(puntos), 0) FROM movimientos_puntos WHERE cliente_uuid = ?`, clienteUUID).Scan(&saldo); err != nil {
+		return 0, fmt.Errorf("error calculando saldo de puntos del cliente: %w", err)
+	}
+	return saldo, nil
+}
+
+// crearMovimientoPuntos registra una entrada del libro con el saldo resultante del cliente.
+// Solo la redención exige saldo suficiente; una reversión puede dejar el saldo en negativo
+// si el cliente ya gastó los puntos que se le retiran.
+func (d *Db) crearMovimientoPuntos(
+	tx *sql.Tx,
+	clienteUUID string,
+	tipo string,
+	puntos int,
+	facturaUUID *string,
+	vendedorUUID string,
+	descripcion string,
+) error {
+	saldoPrevio, err := saldoPuntosCliente(tx, clienteUUID)
+	if err != nil {
+		return err
+	}
+	saldoResultante := saldoPrevio + puntos
+	if tipo == MovimientoPuntosRedencion && saldoResultante < 0 {
+		return fmt.Errorf("puntos insuficientes: el cliente tiene %d y se intentan redimir %d", saldoPrevio, -puntos)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO movimientos_puntos (
+			uuid, cliente_uuid, tipo, puntos, saldo_resultante, factura_uuid, vendedor_uuid, descripcion, timestamp, sincronizado
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		uuid.New().String(), clienteUUID, tipo, puntos, saldoResultante, facturaUUID,
+		nullableString(vendedorUUID), nullableString(descripcion), time.Now(), false)
+	if err != nil {
+		return fmt.Errorf("error registrando movimiento de puntos: %w", err)
+	}
+
+	d.Log.Debugf("[PUNTOS] %s -> saldo previo %d, %s %+d, nuevo %d", clienteUUID, saldoPrevio, tipo, puntos, saldoResultante)
+	return nil
+}
+
+// montoPuntos devuelve la parte de la venta pagada con puntos.
+func montoPuntos(pagos []PagoFactura) float64 {
+	var monto float64
+	for _, p := range pagos {
+		if strings.EqualFold(p.MetodoPago, MetodoPagoPuntos) {
+			monto += p.Monto
+		}
+	}
+	return redondearMoneda(monto)
+}
+
+// reglasPuntosVigentes devuelve el monto por punto de las categorías con regla propia y el de la
+// regla general. Sin regla general solo acumulan los productos de categorías con regla.
+func reglasPuntosVigentes(tx *sql.Tx) (map[string]float64, float64, error) {
+	rows, err := tx.Query(`SELECT COALESCE(categoria, ''), monto_por_punto FROM reglas_puntos WHERE deleted_at IS NULL`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error consultando reglas de puntos: %w", err)
+	}
+	defer rows.Close()
+
+	porCategoria := make(map[string]float64)
+	var general float64
+	for rows.Next() {
+		var categoria string
+		var monto float64
+		if err := rows.Scan(&categoria, &monto); err != nil {
+			return nil, 0, fmt.Errorf("error escaneando regla de puntos: %w", err)
+		}
+		if categoria = normalizarCategoria(categoria); categoria == "" {
+			general = monto
+		} else {
+			porCategoria[categoria] = monto
+		}
+	}
+	return porCategoria, general, rows.Err()
+}
+
+// calcularPuntosGanados aplica las reglas al neto de cada línea. El factor reduce la base en la
+// proporción de la venta pagada con puntos, que no vuelve a acumular.
+func calcularPuntosGanados(detalles []DetalleFactura, categorias map[string]string, porCategoria map[string]float64, general, factor float64) int {
+	var puntos float64
+	for _, det := range detalles {
+		monto := general
+		if m, ok := porCategoria[normalizarCategoria(categorias[det.ProductoUUID])]; ok {
+			monto = m
+		}
+		if monto <= 0 {
+			continue
+		}
+		puntos += det.PrecioTotal * factor / monto
+	}
+	return int(math.Floor(puntos + 1e-9))
+}
+
+// registrarPuntosVenta descuenta del libro los puntos redimidos en la venta (como descuento o como
+// medio de pago) y acumula los puntos ganados. El consumidor final no participa del programa.
+func (d *Db) registrarPuntosVenta(tx *sql.Tx, factura *Factura, detalles []DetalleFactura, categorias map[string]string, puntosDescuento int) error {
+	pagadoConPuntos := montoPuntos(factura.Pagos)
+
+	var numeroID string
+	if err := tx.QueryRow(`SELECT numero_id FROM clientes WHERE uuid = ?`, factura.ClienteUUID).Scan(&numeroID); err != nil {
+		return fmt.Errorf("error consultando cliente de la venta: %w", err)
+	}
+	if numeroID == ConsumidorFinalID {
+		if puntosDescuento > 0 || pagadoConPuntos > 0 {
+			return fmt.Errorf("el consumidor final no puede redimir puntos")
+		}
+		return nil
+	}
+
+	redimidos := puntosDescuento
+	if pagadoConPuntos > 0 {
+		puntos := pagadoConPuntos / d.valorPunto
+		if math.Abs(puntos-math.Round(puntos)) > 1e-6 {
+			return fmt.Errorf("el pago con puntos (%.2f) debe equivaler a un número entero de puntos de %.2f", pagadoConPuntos, d.valorPunto)
+		}
+		redimidos += int(math.Round(puntos))
+	}
+	if redimidos > 0 {
+		if err := d.crearMovimientoPuntos(tx, factura.ClienteUUID, MovimientoPuntosRedencion, -redimidos, &factura.UUID,
+			factura.VendedorUUID, "Redención en factura "+factura.NumeroFactura); err != nil {
+			return err
+		}
+		factura.PuntosRedimidos = redimidos
+	}
+
+	porCategoria, general, err := reglasPuntosVigentes(tx)
+	if err != nil {
+		return err
+	}
+	factor := 1.0
+	if factura.Total > 0 {
+		factor = math.Max(factura.Total-pagadoConPuntos, 0) / factura.Total
+	}
+	if ganados := calcularPuntosGanados(detalles, categorias, porCategoria, general, factor); ganados > 0 {
+		if err := d.crearMovimientoPuntos(tx, factura.ClienteUUID, MovimientoPuntosAcumulacion, ganados, &factura.UUID,
+			factura.VendedorUUID, "Compra factura "+factura.NumeroFactura); err != nil {
+			return err
+		}
+		factura.PuntosGanados = ganados
+	}
+	return nil
+}
+
+// reversarPuntosFactura deja en cero el efecto de la factura en el libro de puntos: devuelve lo
+// redimido y retira lo acumulado (neto de lo ya reversado por devoluciones). Se usa al anular.
+func (d *Db) reversarPuntosFactura(tx *sql.Tx, facturaUUID, vendedorUUID string) error {
+	var clienteUUID, numeroFactura string
+	var neto int
+	err := tx.QueryRow(`
+		SELECT mp.cliente_uuid, f.numero_factura, SUM(mp.puntos)
+		FROM movimientos_puntos mp
+		JOIN facturas f ON f.uuid = mp.factura_uuid
+		WHERE mp.factura_uuid = ?
+		GROUP BY mp.cliente_uuid, f.numero_factura`, facturaUUID).Scan(&clienteUUID, &numeroFactura, &neto)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error consultando puntos de la factura: %w", err)
+	}
+	if neto == 0 {
+		return nil
+	}
+	return d.crearMovimientoPuntos(tx, clienteUUID, MovimientoPuntosReversion, -neto, &facturaUUID, vendedorUUID,
+		"Anulación de la factura "+numeroFactura)
+}
+
+// reversarPuntosDevolucion retira los puntos acumulados en proporción a lo devuelto de la factura.
+// Debe llamarse después de insertar la nota crédito. Los puntos redimidos no se devuelven al
+// cliente en devoluciones parciales; solo la anulación los restituye.
+func (d *Db) reversarPuntosDevolucion(tx *sql.Tx, facturaUUID, vendedorUUID, numeroNota string) error {
+	var clienteUUID string
+	var totalFactura, totalDevuelto float64
+	var acumulados, reversados int
+	err := tx.QueryRow(`
+		SELECT f.cliente_uuid, f.total,
+			(SELECT COALESCE(SUM(nc.total), 0) FROM notas_credito nc WHERE nc.factura_uuid = f.uuid),
+			(SELECT COALESCE(SUM(mp.puntos), 0) FROM movimientos_puntos mp WHERE mp.factura_uuid = f.uuid AND mp.tipo = ?),
+			(SELECT COALESCE(SUM(mp.puntos), 0) FROM movimientos_puntos mp WHERE mp.factura_uuid = f.uuid AND mp.tipo = ?)
+		FROM facturas f WHERE f.uuid = ?`,
+		MovimientoPuntosAcumulacion, MovimientoPuntosReversion, facturaUUID).Scan(&clienteUUID, &totalFactura, &totalDevuelto, &acumulados, &reversados)
+	if err != nil {
+		return fmt.Errorf("error consultando puntos de la factura: %w", err)
+	}
+	if acumulados <= 0 || totalFactura <= 0 {
+		return nil
+	}
+
+	proporcion := math.Min(totalDevuelto/totalFactura, 1)
+	porReversar := int(math.Floor(float64(acumulados)*proporcion+1e-9)) + reversados
+	if porReversar <= 0 {
+		return nil
+	}
+	return d.crearMovimientoPuntos(tx, clienteUUID, MovimientoPuntosReversion, -porReversar, &facturaUUID, vendedorUUID,
+		"Devolución "+numeroNota)
+}
+
+// obtenerPuntosFactura completa los puntos ganados y redimidos en la factura y el saldo actual del cliente.
+func (d *Db) obtenerPuntosFactura(factura *Factura) error {
+	err := d.LocalDB.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN tipo = ? THEN puntos ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN tipo = ? THEN -puntos ELSE 0 END), 0)
+		FROM movimientos_puntos WHERE factura_uuid = ?`,
+		MovimientoPuntosAcumulacion, MovimientoPuntosRedencion, factura.UUID).Scan(&factura.PuntosGanados, &factura.PuntosRedimidos)
+	if err != nil {
+		return fmt.Errorf("error consultando puntos de la factura: %w", err)
+	}
+	factura.SaldoPuntos, err = saldoPuntosCliente(d.LocalDB, factura.ClienteUUID)
+	return err
+}
+
+// SincronizarMovimientosPuntosHaciaRemoto sube los movimientos de puntos locales pendientes.
+// Las facturas a las que pertenecen deben existir ya en el remoto.
+func (d *Db) SincronizarMovimientosPuntosHaciaRemoto() {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] Base de datos remota no disponible, omitiendo sincronización de puntos.")
+		return
+	}
+	ctx := d.ctx
+
+	rows, err := d.LocalDB.QueryContext(ctx, `
+		SELECT uuid, cliente_uuid, tipo, puntos, saldo_resultante, COALESCE(factura_uuid, ''),
+			COALESCE(vendedor_uuid, ''), COALESCE(descripcion, ''), timestamp
+		FROM movimientos_puntos
+		WHERE sincronizado = 0
+		ORDER BY timestamp ASC`)
+	if err != nil {
+		d.Log.Errorf("[SYNC PUNTOS] Error leyendo movimientos locales: %v", err)
+		return
+	}
+	var pendientes []MovimientoPuntos
+	var facturas []string
+	for rows.Next() {
+		var m MovimientoPuntos
+		var facturaUUID string
+		if err := rows.Scan(&m.UUID, &m.ClienteUUID, &m.Tipo, &m.Puntos, &m.SaldoResultante, &facturaUUID,
+			&m.VendedorUUID, &m.Descripcion, &m.Timestamp); err != nil {
+			d.Log.Warnf("[SYNC PUNTOS] Error leyendo movimiento de puntos, saltando: %v", err)
+			continue
+		}
+		facturas = append(facturas, facturaUUID)
+		pendientes = append(pendientes, m)
+	}
+	rows.Close()
+
+	if len(pendientes) == 0 {
+		return
+	}
+
+	rtx, err := d.RemoteDB.Begin(ctx)
+	if err != nil {
+		d.Log.Errorf("[SYNC PUNTOS] No se pudo iniciar transacción remota: %v", err)
+		return
+	}
+	defer func() {
+		if rErr := rtx.Rollback(ctx); rErr != nil && !errors.Is(rErr, pgx.ErrTxClosed) {
+			d.Log.Errorf("[REMOTO] - Error durante [SincronizarMovimientosPuntosHaciaRemoto] rollback %v", rErr)
+		}
+	}()
+
+	batch := &pgx.Batch{}
+	for i, m := range pendientes {
+		batch.Queue(`
+			INSERT INTO movimientos_puntos (uuid, cliente_uuid, tipo, puntos, saldo_resultante, factura_uuid, vendedor_uuid, descripcion, timestamp)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (uuid) DO NOTHING`,
+			m.UUID, m.ClienteUUID, m.Tipo, m.Puntos, m.SaldoResultante, nullableString(facturas[i]),
+			nullableString(m.VendedorUUID), nullableString(m.Descripcion), m.Timestamp)
+	}
+	if err := rtx.SendBatch(ctx, batch).Close(); err != nil {
+		d.Log.Errorf("[SYNC PUNTOS] Error ejecutando batch de movimientos_puntos: %v", err)
+		return
+	}
+	if err := rtx.Commit(ctx); err != nil {
+		d.Log.Errorf("[SYNC PUNTOS] Error al confirmar la transacción remota: %v", err)
+		return
+	}
+
+	ids := make([]any, len(pendientes))
+	placeholders := make([]string, len(pendientes))
+	for i, m := range pendientes {
+		ids[i] = m.UUID
+		placeholders[i] = "?"
+	}
+	localUpdate := fmt.Sprintf("UPDATE movimientos_puntos SET sincronizado = 1 WHERE uuid IN (%s)", strings.Join(placeholders, ","))
+	if _, err := d.LocalDB.ExecContext(ctx, localUpdate, ids...); err != nil {
+		d.Log.Errorf("[SYNC PUNTOS] Error actualizando flag de sincronización local: %v", err)
+	}
+	d.Log.Infof("[SYNC PUNTOS] %d movimientos de puntos sincronizados", len(pendientes))
+}
+
+// sincronizarMovimientosPuntosHaciaLocal descarga los movimientos de puntos registrados en otras terminales.
+func (d *Db) sincronizarMovimientosPuntosHaciaLocal() error {
+	if !d.isRemoteDBAvailable() {
+		return fmt.Errorf("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+	}
+	ctx := d.ctx
+
+	var lastSyncStr sql.NullString
+	if err := d.LocalDB.QueryRowContext(ctx, `SELECT MAX(timestamp) FROM movimientos_puntos WHERE sincronizado = 1`).Scan(&lastSyncStr); err != nil {
+		return fmt.Errorf("error obteniendo el último movimiento de puntos local: %w", err)
+	}
+	lastSyncTime := time.Unix(0, 0)
+	if lastSyncStr.Valid && lastSyncStr.String != "" {
+		if parsedTime, parseErr := parseFlexibleTime(lastSyncStr.String); parseErr == nil {
+			lastSyncTime = parsedTime
+		} else {
+			d.Log.Warnf("No se pudo parsear fecha de movimiento de puntos local '%s': %v. Realizando carga inicial completa.", lastSyncStr.String, parseErr)
+		}
+	}
+
+	rows, err := d.RemoteDB.Query(ctx, `
+		SELECT uuid, cliente_uuid, tipo, puntos, saldo_resultante, COALESCE(factura_uuid::text, ''),
+			COALESCE(vendedor_uuid::text, ''), COALESCE(descripcion, ''), timestamp
+		FROM movimientos_puntos
+		WHERE timestamp > $1
+		ORDER BY timestamp ASC`, lastSyncTime)
+	if err != nil {
+		return fmt.Errorf("error obteniendo movimientos de puntos remotos: %w", err)
+	}
+	var nuevos []MovimientoPuntos
+	var facturas []string
+	for rows.Next() {
+		var m MovimientoPuntos
+		var facturaUUID string
+		if err := rows.Scan(&m.UUID, &m.ClienteUUID, &m.Tipo, &m.Puntos, &m.SaldoResultante, &facturaUUID,
+			&m.VendedorUUID, &m.Descripcion, &m.Timestamp); err != nil {
+			d.Log.Warnf("Error al escanear movimiento de puntos remoto: %v", err)
+			continue
+		}
+		facturas = append(facturas, facturaUUID)
+		nuevos = append(nuevos, m)
+	}
+	rows.Close()
+
+	if len(nuevos) == 0 {
+		return nil
+	}
+
+	tx, err := d.LocalDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción local: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("rollback en sincronizarMovimientosPuntosHaciaLocal: %v", rErr)
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO movimientos_puntos (
+			uuid, cliente_uuid, tipo, puntos, saldo_resultante, factura_uuid, vendedor_uuid, descripcion, timestamp, sincronizado
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT(uuid) DO NOTHING`)
+	if err != nil {
+		return fmt.Errorf("error al preparar statement local: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, m := range nuevos {
+		if _, err := stmt.ExecContext(ctx, m.UUID, m.ClienteUUID, m.Tipo, m.Puntos, m.SaldoResultante,
+			nullableString(facturas[i]), nullableString(m.VendedorUUID), nullableString(m.Descripcion), m.Timestamp); err != nil {
+			return fmt.Errorf("error al insertar movimiento de puntos local (UUID: %s): %w", m.UUID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción local: %w", err)
+	}
+	d.Log.Infof("Sincronizados %d movimientos de puntos desde remoto.", len(nuevos))
+	return nil
+}
@@ -0,0 +1,72 @@
+package backend
+
+import "testing"
+
+func TestCalcularPuntosGanados(t *testing.T) {
+	categorias := map[string]string{"amoxicilina": " Antibioticos ", "clonazepam": "controlados", "acetaminofen": ""}
+	casos := []struct {
+		nombre       string
+		lineas       map[string]float64 // producto -> neto de la línea
+		porCategoria map[string]float64
+		general      float64
+		factor       float64
+		puntos       int
+	}{
+		{
+			nombre:  "regla general",
+			lineas:  map[string]float64{"acetaminofen": 25000},
+			general: 1000, factor: 1, puntos: 25,
+		},
+		{
+			nombre:  "las fracciones se truncan sobre el total",
+			lineas:  map[string]float64{"acetaminofen": 1500, "amoxicilina": 1499},
+			general: 1000, factor: 1, puntos: 2,
+		},
+		{
+			nombre:  "tolerancia al sumar fracciones",
+			lineas:  map[string]float64{"acetaminofen": 100, "amoxicilina": 200},
+			general: 300, factor: 1, puntos: 1,
+		},
+		{
+			nombre:       "la regla de la categoría reemplaza la general",
+			lineas:       map[string]float64{"amoxicilina": 10000, "acetaminofen": 10000},
+			porCategoria: map[string]float64{"ANTIBIOTICOS": 5000},
+			general:      1000, factor: 1, puntos: 12,
+		},
+		{
+			nombre:       "categoría excluida con monto cero",
+			lineas:       map[string]float64{"clonazepam": 50000, "acetaminofen": 10000},
+			porCategoria: map[string]float64{"CONTROLADOS": 0},
+			general:      1000, factor: 1, puntos: 10,
+		},
+		{
+			nombre:       "sin regla general solo acumulan las categorías con regla",
+			lineas:       map[string]float64{"amoxicilina": 10000, "acetaminofen": 10000},
+			porCategoria: map[string]float64{"ANTIBIOTICOS": 2000},
+			general:      0, factor: 1, puntos: 5,
+		},
+		{
+			nombre:  "la parte pagada con puntos no acumula",
+			lineas:  map[string]float64{"acetaminofen": 20000},
+			general: 1000, factor: 0.5, puntos: 10,
+		},
+		{
+			nombre:  "venta pagada toda con puntos",
+			lineas:  map[string]float64{"acetaminofen": 20000},
+			general: 1000, factor: 0, puntos: 0,
+		},
+		{
+			nombre:  "sin líneas",
+			general: 1000, factor: 1, puntos: 0,
+		},
+	}
+	for _, c := range casos {
+		detalles := make([]DetalleFactura, 0, len(c.lineas))
+		for producto, neto := range c.lineas {
+			detalles = append(detalles, DetalleFactura{ProductoUUID: producto, PrecioTotal: neto})
+		}
+		if got := calcularPuntosGanados(detalles, categorias, c.porCategoria, c.general, c.factor); got != c.puntos {
+			t.Errorf("%s: %d puntos, se esperaban %d", c.nombre, got, c.puntos)
+		}
+	}
+}
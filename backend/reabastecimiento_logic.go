@@ -0,0 +1,459 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Estados de un pedido a proveedor.
+const (
+	PedidoPendiente = "PENDIENTE"
+	PedidoRecibido  = "RECIBIDO"
+	PedidoAnulado   = "ANULADO"
+)
+
+const (
+	// DiasHistorialVentas es el periodo de ventas con que se calcula la venta diaria de un producto.
+	DiasHistorialVentas = 60
+	// DiasStockSeguridad es la cobertura adicional al tiempo de entrega que fija el punto de reorden.
+	DiasStockSeguridad = 7
+	// DiasCoberturaPedido son los días de venta que cubre un pedido por encima del punto de reorden.
+	DiasCoberturaPedido = 30
+)
+
+// sqlNivelesReorden lista productos con sus niveles, el proveedor que los surte, lo que está en camino
+// en pedidos pendientes y lo vendido en unidades mínimas desde una fecha (sin anuladas ni devoluciones).
+// Un producto sin niveles guardados se trata como automático.
+const sqlNivelesReorden = `
+	SELECT p.uuid, p.codigo, p.nombre, COALESCE(p.stock, 0), COALESCE(p.costo_promedio, 0),
+		COALESCE(n.uuid, ''), COALESCE(n.automatico, 1), COALESCE(n.stock_minimo, 0), COALESCE(n.stock_maximo, 0),
+		COALESCE(pr.uuid, ''), COALESCE(pr.nombre, ''), COALESCE(pr.dias_entrega, 0),
+		COALESCE((
+			SELECT SUM(i.cantidad)
+			FROM items_pedido_proveedor i
+			JOIN pedidos_proveedor pe ON pe.uuid = i.pedido_uuid
+			WHERE i.producto_uuid = p.uuid AND pe.estado = 'PENDIENTE' AND pe.deleted_at IS NULL
+		), 0),
+		COALESCE((
+			SELECT SUM((df.cantidad - (SELECT COALESCE(SUM(dn.cantidad), 0) FROM detalle_notas_credito dn WHERE dn.detalle_factura_uuid = df.uuid)) * df.factor_conversion)
+			FROM detalle_facturas df
+			JOIN facturas f ON f.uuid = df.factura_uuid
+			WHERE df.producto_uuid = p.uuid AND f.fecha_emision >= ? AND COALESCE(f.estado, '') != 'ANULADA'
+		), 0)
+	FROM productos p
+	LEFT JOIN niveles_reorden n ON n.producto_uuid = p.uuid AND n.deleted_at IS NULL
+	LEFT JOIN proveedors pr ON pr.uuid = COALESCE(n.proveedor_uuid, (
+		SELECT co.proveedor_uuid
+		FROM detalle_compras dc
+		JOIN compras co ON co.uuid = dc.compra_uuid
+		WHERE dc.producto_uuid = p.uuid AND co.deleted_at IS NULL
+		ORDER BY co.fecha DESC LIMIT 1
+	))
+	WHERE p.deleted_at IS NULL`
+
+// ObtenerNivelReorden devuelve los niveles de reabastecimiento de un producto con su situación actual.
+func (d *Db) ObtenerNivelReorden(productoUUID string) (NivelReorden, error) {
+	niveles, err := d.consultarNivelesReorden(sqlNivelesReorden+` AND p.uuid = ?`, productoUUID)
+	if err != nil {
+		return NivelReorden{}, err
+	}
+	if len(niveles) == 0 {
+		return NivelReorden{}, fmt.Errorf("producto [%s] no encontrado", productoUUID)
+	}
+	return niveles[0], nil
+}
+
+// ObtenerProductosPorReabastecer lista los productos que llegaron a su punto de reorden contando lo
+// que viene en camino, los de menos días de venta cubiertos primero.
+func (d *Db) ObtenerProductosPorReabastecer() ([]NivelReorden, error) {
+	niveles, err := d.consultarNivelesReorden(sqlNivelesReorden)
+	if err != nil {
+		return nil, err
+	}
+	porPedir := make([]NivelReorden, 0)
+	for _, n := range niveles {
+		if n.CantidadSugerida > 0 {
+			porPedir = append(porPedir, n)
+		}
+	}
+	sort.SliceStable(porPedir, func(i, j int) bool {
+		return porPedir[i].diasCubiertos() < porPedir[j].diasCubiertos()
+	})
+	return porPedir, nil
+}
+
+// ObtenerSugerenciasCompra arma un pedido sugerido por proveedor con los productos por reabastecer.
+// Los productos sin proveedor conocido quedan en una sugerencia sin proveedor, al final.
+func (d *Db) ObtenerSugerenciasCompra() ([]SugerenciaCompra, error) {
+	porPedir, err := d.ObtenerProductosPorReabastecer()
+	if err != nil {
+		return nil, err
+	}
+
+	sugerencias := make([]SugerenciaCompra, 0)
+	indice := make(map[string]int)
+	for _, n := range porPedir {
+		i, ok := indice[n.ProveedorUUID]
+		if !ok {
+			i = len(sugerencias)
+			indice[n.ProveedorUUID] = i
+			sugerencias = append(sugerencias, SugerenciaCompra{
+				Proveedor: Proveedor{UUID: n.ProveedorUUID, Nombre: n.ProveedorNombre, DiasEntrega: n.DiasEntrega},
+			})
+		}
+		sugerencias[i].Items = append(sugerencias[i].Items, n)
+		sugerencias[i].Total += float64(n.CantidadSugerida) * n.CostoUnitario
+	}
+	for i := range sugerencias {
+		sugerencias[i].Total = redondearMoneda(sugerencias[i].Total)
+	}
+	sort.SliceStable(sugerencias, func(i, j int) bool {
+		if (sugerencias[i].Proveedor.UUID == "") != (sugerencias[j].Proveedor.UUID == "") {
+			return sugerencias[j].Proveedor.UUID == ""
+		}
+		return sugerencias[i].Proveedor.Nombre < sugerencias[j].Proveedor.Nombre
+	})
+	return sugerencias, nil
+}
+
+// GuardarNivelReorden configura a mano o en automático los niveles de reabastecimiento de un producto.
+func (d *Db) GuardarNivelReorden(req NivelReordenRequest) (NivelReorden, error) {
+	if req.ProductoUUID == "" {
+		return NivelReorden{}, errors.New("se requiere el producto")
+	}
+	if req.Automatico {
+		req.StockMinimo, req.StockMaximo = 0, 0
+	} else {
+		if req.StockMinimo < 0 || req.StockMaximo < 0 {
+			return NivelReorden{}, errors.New("los niveles de stock no pueden ser negativos")
+		}
+		if req.StockMaximo < req.StockMinimo {
+			return NivelReorden{}, fmt.Errorf("el stock máximo (%d) no puede ser menor que el punto de reorden (%d)", req.StockMaximo, req.StockMinimo)
+		}
+	}
+
+	var existe int
+	if err := d.LocalDB.QueryRow(`SELECT COUNT(1) FROM productos WHERE uuid = ? AND deleted_at IS NULL`, req.ProductoUUID).Scan(&existe); err != nil {
+		return NivelReorden{}, fmt.Errorf("error validando producto: %w", err)
+	}
+	if existe == 0 {
+		return NivelReorden{}, fmt.Errorf("producto [%s] no encontrado", req.ProductoUUID)
+	}
+	if req.ProveedorUUID != "" {
+		if err := d.LocalDB.QueryRow(`SELECT COUNT(1) FROM proveedors WHERE uuid = ? AND deleted_at IS NULL`, req.ProveedorUUID).Scan(&existe); err != nil {
+			return NivelReorden{}, fmt.Errorf("error validando proveedor: %w", err)
+		}
+		if existe == 0 {
+			return NivelReorden{}, fmt.Errorf("proveedor [%s] no encontrado", req.ProveedorUUID)
+		}
+	}
+
+	now := time.Now()
+	var nivelUUID string
+	err := d.LocalDB.QueryRow(`SELECT uuid FROM niveles_reorden WHERE producto_uuid = ?`, req.ProductoUUID).Scan(&nivelUUID)
+	switch {
+	case err == nil:
+		_, err = d.LocalDB.Exec(`
+			UPDATE niveles_reorden SET automatico = ?, stock_minimo = ?, stock_maximo = ?, proveedor_uuid = ?, deleted_at = NULL, updated_at = ?
+			WHERE uuid = ?`,
+			req.Automatico, req.StockMinimo, req.StockMaximo, nullableString(req.ProveedorUUID), now, nivelUUID)
+	case errors.Is(err, sql.ErrNoRows):
+		nivelUUID = uuid.New().String()
+		_, err = d.LocalDB.Exec(`
+			INSERT INTO niveles_reorden (uuid, producto_uuid, automatico, stock_minimo, stock_maximo, proveedor_uuid, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			nivelUUID, req.ProductoUUID, req.Automatico, req.StockMinimo, req.StockMaximo, nullableString(req.ProveedorUUID), now, now)
+	}
+	if err != nil {
+		return NivelReorden{}, fmt.Errorf("error al guardar niveles de reorden: %w", err)
+	}
+
+	go d.syncNivelReordenToRemote(nivelUUID)
+	return d.ObtenerNivelReorden(req.ProductoUUID)
+}
+
+// GuardarDiasEntregaProveedor registra el tiempo de entrega de un proveedor, con el que se calcula el
+// punto de reorden automático de los productos que surte y la fecha esperada de sus pedidos.
+func (d *Db) GuardarDiasEntregaProveedor(proveedorUUID string, dias int) error {
+	if dias < 0 {
+		return errors.New("el tiempo de entrega no puede ser negativo")
+	}
+	res, err := d.LocalDB.Exec(`UPDATE proveedors SET dias_entrega = ?, updated_at = ? WHERE uuid = ? AND deleted_at IS NULL`, dias, time.Now(), proveedorUUID)
+	if err != nil {
+		return fmt.Errorf("error al guardar tiempo de entrega: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("proveedor [%s] no encontrado", proveedorUUID)
+	}
+
+	go d.SincronizacionInteligente()
+	return nil
+}
+
+// CrearPedidoProveedor registra un pedido, normalmente a partir de una sugerencia de compra. Sus
+// unidades cuentan como mercancía en camino hasta que llega la compra o se anula.
+func (d *Db) CrearPedidoProveedor(req PedidoProveedorRequest) (PedidoProveedor, error) {
+	if req.ProveedorUUID == "" || req.VendedorUUID == "" {
+		return PedidoProveedor{}, errors.New("se requiere el proveedor y el vendedor que registra el pedido")
+	}
+	if len(req.Items) == 0 {
+		return PedidoProveedor{}, errors.New("el pedido no tiene productos")
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return PedidoProveedor{}, fmt.Errorf("error al iniciar transacción del pedido: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[LOCAL] - Error durante [CrearPedidoProveedor] rollback %v", rErr)
+		}
+	}()
+
+	var existe, diasEntrega int
+	if err := tx.QueryRow(`SELECT COUNT(1) FROM vendedors WHERE uuid = ? AND deleted_at IS NULL`, req.VendedorUUID).Scan(&existe); err != nil {
+		return PedidoProveedor{}, fmt.Errorf("error validando vendedor: %w", err)
+	}
+	if existe == 0 {
+		return PedidoProveedor{}, fmt.Errorf("vendedor [%s] no encontrado", req.VendedorUUID)
+	}
+	err = tx.QueryRow(`SELECT COALESCE(dias_entrega, 0) FROM proveedors WHERE uuid = ? AND deleted_at IS NULL`, req.ProveedorUUID).Scan(&diasEntrega)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PedidoProveedor{}, fmt.Errorf("proveedor [%s] no encontrado", req.ProveedorUUID)
+	}
+	if err != nil {
+		return PedidoProveedor{}, fmt.Errorf("error validando proveedor: %w", err)
+	}
+
+	now := time.Now()
+	pedido := PedidoProveedor{
+		UUID:          uuid.New().String(),
+		ProveedorUUID: req.ProveedorUUID,
+		VendedorUUID:  req.VendedorUUID,
+		Fecha:         now,
+		FechaEsperada: now.AddDate(0, 0, diasEntrega).Format("2006-01-02"),
+		Estado:        PedidoPendiente,
+		Observacion:   strings.TrimSpace(req.Observacion),
+	}
+	_, err = tx.Exec(`
+		INSERT INTO pedidos_proveedor (uuid, proveedor_uuid, vendedor_uuid, fecha, fecha_esperada, estado, observacion, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		pedido.UUID, pedido.ProveedorUUID, pedido.VendedorUUID, now, pedido.FechaEsperada, pedido.Estado, nullableString(pedido.Observacion), now, now)
+	if err != nil {
+		return PedidoProveedor{}, fmt.Errorf("error al crear el pedido: %w", err)
+	}
+
+	// El mismo producto en varias líneas se acumula en un solo ítem
+	cantidades := make(map[string]int)
+	var orden []string
+	for _, it := range req.Items {
+		if it.Cantidad <= 0 {
+			return PedidoProveedor{}, fmt.Errorf("la cantidad pedida del producto [%s] debe ser mayor que cero", it.ProductoUUID)
+		}
+		if _, ok := cantidades[it.ProductoUUID]; !ok {
+			orden = append(orden, it.ProductoUUID)
+		}
+		cantidades[it.ProductoUUID] += it.Cantidad
+	}
+	for _, productoUUID := range orden {
+		var costo float64
+		err := tx.QueryRow(`SELECT COALESCE(costo_promedio, 0) FROM productos WHERE uuid = ? AND deleted_at IS NULL`, productoUUID).Scan(&costo)
+		if errors.Is(err, sql.ErrNoRows) {
+			return PedidoProveedor{}, fmt.Errorf("producto [%s] no encontrado", productoUUID)
+		}
+		if err != nil {
+			return PedidoProveedor{}, fmt.Errorf("error consultando producto: %w", err)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO items_pedido_proveedor (uuid, pedido_uuid, producto_uuid, cantidad, costo_unitario, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			uuid.New().String(), pedido.UUID, productoUUID, cantidades[productoUUID], costo, now, now); err != nil {
+			return PedidoProveedor{}, fmt.Errorf("error al crear ítem del pedido: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PedidoProveedor{}, fmt.Errorf("error al confirmar el pedido: %w", err)
+	}
+	d.Log.Infof("[PEDIDO] Pedido %s registrado al proveedor %s", pedido.UUID, pedido.ProveedorUUID)
+
+	go d.syncPedidoProveedorToRemote(pedido.UUID)
+	return d.cargarPedidoProveedor(pedido.UUID)
+}
+
+// ObtenerPedidosProveedor lista los pedidos en un estado (todos si es vacío), los más recientes primero.
+func (d *Db) ObtenerPedidosProveedor(estado string) ([]PedidoProveedor, error) {
+	rows, err := d.LocalDB.Query(`
+		SELECT uuid FROM pedidos_proveedor
+		WHERE deleted_at IS NULL AND (? = '' OR estado = ?)
+		ORDER BY fecha DESC`, estado, estado)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener pedidos: %w", err)
+	}
+	var uuids []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error al escanear pedido: %w", err)
+		}
+		uuids = append(uuids, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pedidos := make([]PedidoProveedor, 0, len(uuids))
+	for _, u := range uuids {
+		p, err := d.cargarPedidoProveedor(u)
+		if err != nil {
+			return nil, err
+		}
+		pedidos = append(pedidos, p)
+	}
+	return pedidos, nil
+}
+
+// AnularPedidoProveedor anula un pedido pendiente; sus unidades dejan de contar como mercancía en camino.
+func (d *Db) AnularPedidoProveedor(pedidoUUID string) error {
+	res, err := d.LocalDB.Exec(`UPDATE pedidos_proveedor SET estado = ?, updated_at = ? WHERE uuid = ? AND estado = ?`,
+		PedidoAnulado, time.Now(), pedidoUUID, PedidoPendiente)
+	if err != nil {
+		return fmt.Errorf("error al anular pedido: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("el pedido [%s] no existe o ya no está pendiente", pedidoUUID)
+	}
+	go d.syncPedidoProveedorToRemote(pedidoUUID)
+	return nil
+}
+
+// recibirPedidoProveedor marca como recibido, dentro de la transacción de la compra, el pedido que la
+// compra ingresa al inventario.
+func recibirPedidoProveedor(tx *sql.Tx, pedidoUUID string, compra Compra, now time.Time) error {
+	var proveedorUUID, estado string
+	err := tx.QueryRow(`SELECT proveedor_uuid, estado FROM pedidos_proveedor WHERE uuid = ? AND deleted_at IS NULL`, pedidoUUID).Scan(&proveedorUUID, &estado)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("pedido [%s] no encontrado", pedidoUUID)
+	}
+	if err != nil {
+		return fmt.Errorf("error consultando pedido: %w", err)
+	}
+	if estado != PedidoPendiente {
+		return fmt.Errorf("el pedido [%s] está %s y no se puede recibir", pedidoUUID, estado)
+	}
+	if proveedorUUID != compra.ProveedorUUID {
+		return fmt.Errorf("el pedido [%s] es de otro proveedor", pedidoUUID)
+	}
+	if _, err := tx.Exec(`UPDATE pedidos_proveedor SET estado = ?, compra_uuid = ?, updated_at = ? WHERE uuid = ?`,
+		PedidoRecibido, compra.UUID, now, pedidoUUID); err != nil {
+		return fmt.Errorf("error al recibir pedido: %w", err)
+	}
+	return nil
+}
+
+// cargarPedidoProveedor devuelve el pedido con su proveedor y sus ítems.
+func (d *Db) cargarPedidoProveedor(pedidoUUID string) (PedidoProveedor, error) {
+	var p PedidoProveedor
+	err := d.LocalDB.QueryRow(`
+		SELECT pe.uuid, pe.proveedor_uuid, pr.nombre, COALESCE(pr.dias_entrega, 0), pe.vendedor_uuid, pe.fecha,
+			strftime('%Y-%m-%d', pe.fecha_esperada), pe.estado, COALESCE(pe.compra_uuid, ''), COALESCE(pe.observacion, ''),
+			pe.created_at, pe.updated_at
+		FROM pedidos_proveedor pe
+		JOIN proveedors pr ON pr.uuid = pe.proveedor_uuid
+		WHERE pe.uuid = ?`, pedidoUUID).Scan(
+		&p.UUID, &p.ProveedorUUID, &p.Proveedor.Nombre, &p.Proveedor.DiasEntrega, &p.VendedorUUID, &p.Fecha,
+		&p.FechaEsperada, &p.Estado, &p.CompraUUID, &p.Observacion, &p.CreatedAt, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PedidoProveedor{}, fmt.Errorf("pedido [%s] no encontrado", pedidoUUID)
+	}
+	if err != nil {
+		return PedidoProveedor{}, fmt.Errorf("error consultando pedido: %w", err)
+	}
+	p.Proveedor.UUID = p.ProveedorUUID
+
+	rows, err := d.LocalDB.Query(`
+		SELECT i.uuid, i.pedido_uuid, i.producto_uuid, p.codigo, p.nombre, i.cantidad, i.costo_unitario
+		FROM items_pedido_proveedor i
+		JOIN productos p ON p.uuid = i.producto_uuid
+		WHERE i.pedido_uuid = ? AND i.deleted_at IS NULL
+		ORDER BY p.nombre ASC`, pedidoUUID)
+	if err != nil {
+		return PedidoProveedor{}, fmt.Errorf("error consultando ítems del pedido: %w", err)
+	}
+	defer rows.Close()
+	p.Items = make([]ItemPedidoProveedor, 0)
+	for rows.Next() {
+		var it ItemPedidoProveedor
+		if err := rows.Scan(&it.UUID, &it.PedidoUUID, &it.ProductoUUID, &it.ProductoCodigo, &it.ProductoNombre, &it.Cantidad, &it.CostoUnitario); err != nil {
+			return PedidoProveedor{}, fmt.Errorf("error al escanear ítem del pedido: %w", err)
+		}
+		p.Total += float64(it.Cantidad) * it.CostoUnitario
+		p.Items = append(p.Items, it)
+	}
+	p.Total = redondearMoneda(p.Total)
+	return p, rows.Err()
+}
+
+func (d *Db) consultarNivelesReorden(query string, args ...any) ([]NivelReorden, error) {
+	desde := time.Now().AddDate(0, 0, -DiasHistorialVentas)
+	rows, err := d.LocalDB.Query(query, append([]any{desde}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener niveles de reorden: %w", err)
+	}
+	defer rows.Close()
+
+	niveles := make([]NivelReorden, 0)
+	for rows.Next() {
+		var n NivelReorden
+		var vendidas int
+		if err := rows.Scan(&n.ProductoUUID, &n.ProductoCodigo, &n.ProductoNombre, &n.Stock, &n.CostoUnitario,
+			&n.UUID, &n.Automatico, &n.StockMinimo, &n.StockMaximo,
+			&n.ProveedorUUID, &n.ProveedorNombre, &n.DiasEntrega, &n.EnCamino, &vendidas); err != nil {
+			return nil, fmt.Errorf("error al escanear nivel de reorden: %w", err)
+		}
+		n.calcular(vendidas)
+		niveles = append(niveles, n)
+	}
+	return niveles, rows.Err()
+}
+
+// calcular deriva la venta diaria y, en automático, los niveles: el punto de reorden cubre el tiempo
+// de entrega más el stock de seguridad y el máximo agrega la cobertura de un pedido. Se sugiere pedir
+// hasta el máximo cuando el stock más lo que viene en camino no supera el punto de reorden. Un
+// automático sin ventas en el periodo queda en cero, pero si está agotado y tiene proveedor conocido se
+// sugiere reponer al menos una unidad.
+func (n *NivelReorden) calcular(vendidas int) {
+	if vendidas > 0 {
+		n.VentaDiaria = math.Round(float64(vendidas)/DiasHistorialVentas*100) / 100
+	}
+	if n.Automatico {
+		venta := float64(vendidas) / DiasHistorialVentas
+		n.StockMinimo = int(math.Ceil(venta * float64(n.DiasEntrega+DiasStockSeguridad)))
+		n.StockMaximo = n.StockMinimo + int(math.Ceil(venta*DiasCoberturaPedido))
+	}
+	disponible := n.Stock + n.EnCamino
+	n.CantidadSugerida = 0
+	switch {
+	case n.StockMaximo > 0 && disponible <= n.StockMinimo:
+		n.CantidadSugerida = n.StockMaximo - disponible
+	case n.Automatico && disponible <= 0 && n.ProveedorUUID != "":
+		n.CantidadSugerida = 1 - disponible
+	}
+}
+
+// diasCubiertos estima cuántos días de venta alcanza el stock con lo que viene en camino.
+func (n NivelReorden) diasCubiertos() float64 {
+	if n.VentaDiaria <= 0 {
+		return math.Inf(1)
+	}
+	return float64(n.Stock+n.EnCamino) / n.VentaDiaria
+}
@@ -0,0 +1,199 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// sqlLineasRentabilidad trae las líneas vendidas en un rango de fechas con lo que queda de ellas después
+// de devoluciones: la base sin IVA y el costo se prorratean por las unidades no devueltas.
+const sqlLineasRentabilidad = `
+	SELECT f.fecha_emision, df.producto_uuid, p.nombre, f.vendedor_uuid, TRIM(v.nombre || ' ' || COALESCE(v.apellido, '')),
+		(df.cantidad - COALESCE(dev.devuelta, 0)) * df.factor_conversion AS unidades,
+		df.base_impuesto * (df.cantidad - COALESCE(dev.devuelta, 0)) / df.cantidad AS base,
+		df.costo_unitario * (df.cantidad - COALESCE(dev.devuelta, 0)) AS costo
+	FROM detalle_facturas df
+	JOIN facturas f ON f.uuid = df.factura_uuid
+	JOIN productos p ON p.uuid = df.producto_uuid
+	JOIN vendedors v ON v.uuid = f.vendedor_uuid
+	LEFT JOIN (
+		SELECT detalle_factura_uuid, SUM(cantidad) AS devuelta
+		FROM detalle_notas_credito
+		GROUP BY detalle_factura_uuid
+	) dev ON dev.detalle_factura_uuid = df.uuid
+	WHERE f.fecha_emision BETWEEN ? AND ? AND COALESCE(f.estado, '') != 'ANULADA' AND df.cantidad > 0`
+
+// nuevaRentabilidad calcula utilidad, margen y markup a partir de la venta neta y su costo.
+func nuevaRentabilidad(ventas, costo float64) Rentabilidad {
+	r := Rentabilidad{
+		Ventas:   redondearMoneda(ventas),
+		Costo:    redondearMoneda(costo),
+		Utilidad: redondearMoneda(ventas - costo),
+	}
+	if r.Ventas > 0 {
+		r.Margen = redondearMoneda(r.Utilidad / r.Ventas * 100)
+	}
+	if r.Costo > 0 {
+		r.Markup = redondearMoneda(r.Utilidad / r.Costo * 100)
+	}
+	return r
+}
+
+// rentabilidadFactura suma la utilidad de las líneas de una factura descontando lo devuelto.
+func rentabilidadFactura(detalles []DetalleFactura) Rentabilidad {
+	var ventas, costo float64
+	for _, det := range detalles {
+		if det.Cantidad <= 0 {
+			continue
+		}
+		vigente := det.Cantidad - det.CantidadDevuelta
+		ventas += det.BaseImpuesto * float64(vigente) / float64(det.Cantidad)
+		costo += det.CostoUnitario * float64(vigente)
+	}
+	return nuevaRentabilidad(ventas, costo)
+}
+
+// actualizarCostoPromedio pondera el costo actual del producto con el de la compra. Debe llamarse
+// antes de ingresar las unidades al stock; si no hay stock positivo el costo pasa a ser el de la compra.
+// El precio de compra es por presentación y sin IVA, así que se lleva a la unidad mínima.
+func actualizarCostoPromedio(tx *sql.Tx, det DetalleCompra, now time.Time) error {
+	factor := det.FactorConversion
+	if factor < 1 {
+		factor = 1
+	}
+	unidades := det.Cantidad * factor
+	if unidades <= 0 {
+		return nil
+	}
+	costoCompra := det.PrecioCompraUnitario / float64(factor)
+
+	var stock int
+	var costoActual float64
+	if err := tx.QueryRow(`SELECT COALESCE(stock, 0), costo_promedio FROM productos WHERE uuid = ?`, det.ProductoUUID).Scan(&stock, &costoActual); err != nil {
+		return fmt.Errorf("error consultando costo del producto [%s]: %w", det.ProductoUUID, err)
+	}
+
+	nuevoCosto := costoCompra
+	if stock > 0 {
+		nuevoCosto = (float64(stock)*costoActual + float64(unidades)*costoCompra) / float64(stock+unidades)
+	}
+
+	if _, err := tx.Exec(`UPDATE productos SET costo_promedio = ?, updated_at = ? WHERE uuid = ?`, nuevoCosto, now, det.ProductoUUID); err != nil {
+		return fmt.Errorf("error actualizando costo promedio del producto [%s]: %w", det.ProductoUUID, err)
+	}
+	return nil
+}
+
+// rentabilidadEntre totaliza la utilidad bruta de las ventas entre dos instantes.
+func (d *Db) rentabilidadEntre(inicio, fin time.Time) (Rentabilidad, error) {
+	var ventas, costo float64
+	err := d.LocalDB.QueryRow(`SELECT COALESCE(SUM(base), 0), COALESCE(SUM(costo), 0) FROM (`+sqlLineasRentabilidad+`)`,
+		inicio, fin).Scan(&ventas, &costo)
+	if err != nil {
+		return Rentabilidad{}, fmt.Errorf("error al calcular la utilidad bruta: %w", err)
+	}
+	return nuevaRentabilidad(ventas, costo), nil
+}
+
+// ObtenerReporteRentabilidad agrupa por día, producto y vendedor la utilidad bruta de las ventas entre
+// dos fechas (AAAA-MM-DD). Sin fechas se reporta el mes en curso. Las ventas se cuentan el día en que se
+// emitieron, aunque la devolución haya ocurrido después.
+func (d *Db) ObtenerReporteRentabilidad(fechaInicio, fechaFin string) (ReporteRentabilidad, error) {
+	now := time.Now()
+	if fechaInicio == "" {
+		fechaInicio = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+	}
+	if fechaFin == "" {
+		fechaFin = now.Format("2006-01-02")
+	}
+	inicio, err := time.ParseInLocation("2006-01-02", fechaInicio, time.Local)
+	if err != nil {
+		return ReporteRentabilidad{}, fmt.Errorf("formato de fecha inválido: %w", err)
+	}
+	fin, err := time.ParseInLocation("2006-01-02", fechaFin, time.Local)
+	if err != nil {
+		return ReporteRentabilidad{}, fmt.Errorf("formato de fecha inválido: %w", err)
+	}
+	if fin.Before(inicio) {
+		return ReporteRentabilidad{}, fmt.Errorf("la fecha final (%s) es anterior a la inicial (%s)", fechaFin, fechaInicio)
+	}
+	fin = fin.Add(24*time.Hour - time.Nanosecond)
+
+	rows, err := d.LocalDB.QueryContext(d.ctx, sqlLineasRentabilidad, inicio, fin)
+	if err != nil {
+		return ReporteRentabilidad{}, fmt.Errorf("error al obtener ventas para rentabilidad: %w", err)
+	}
+	defer rows.Close()
+
+	type acumulado struct {
+		nombre        string
+		unidades      int
+		ventas, costo float64
+	}
+	porDia := make(map[string]*acumulado)
+	porProducto := make(map[string]*acumulado)
+	porVendedor := make(map[string]*acumulado)
+	sumar := func(grupo map[string]*acumulado, clave, nombre string, unidades int, ventas, costo float64) {
+		a, ok := grupo[clave]
+		if !ok {
+			a = &acumulado{nombre: nombre}
+			grupo[clave] = a
+		}
+		a.unidades += unidades
+		a.ventas += ventas
+		a.costo += costo
+	}
+
+	var totalVentas, totalCosto float64
+	for rows.Next() {
+		var fecha time.Time
+		var productoUUID, producto, vendedorUUID, vendedor string
+		var unidades int
+		var ventas, costo float64
+		if err := rows.Scan(&fecha, &productoUUID, &producto, &vendedorUUID, &vendedor, &unidades, &ventas, &costo); err != nil {
+			return ReporteRentabilidad{}, fmt.Errorf("error al escanear línea de venta: %w", err)
+		}
+		dia := fecha.In(time.Local).Format("2006-01-02")
+		sumar(porDia, dia, dia, unidades, ventas, costo)
+		sumar(porProducto, productoUUID, producto, unidades, ventas, costo)
+		sumar(porVendedor, vendedorUUID, vendedor, unidades, ventas, costo)
+		totalVentas += ventas
+		totalCosto += costo
+	}
+	if err := rows.Err(); err != nil {
+		return ReporteRentabilidad{}, err
+	}
+
+	resumir := func(grupo map[string]*acumulado) []ResumenRentabilidad {
+		resumen := make([]ResumenRentabilidad, 0, len(grupo))
+		for clave, a := range grupo {
+			resumen = append(resumen, ResumenRentabilidad{
+				Clave:        clave,
+				Nombre:       a.nombre,
+				Cantidad:     a.unidades,
+				Rentabilidad: nuevaRentabilidad(a.ventas, a.costo),
+			})
+		}
+		return resumen
+	}
+
+	reporte := ReporteRentabilidad{
+		FechaInicio: fechaInicio,
+		FechaFin:    fechaFin,
+		Totales:     nuevaRentabilidad(totalVentas, totalCosto),
+		PorDia:      resumir(porDia),
+		PorProducto: resumir(porProducto),
+		PorVendedor: resumir(porVendedor),
+	}
+	// Días en orden cronológico; productos y vendedores de mayor a menor utilidad
+	sort.Slice(reporte.PorDia, func(i, j int) bool { return reporte.PorDia[i].Clave < reporte.PorDia[j].Clave })
+	sort.Slice(reporte.PorProducto, func(i, j int) bool {
+		return reporte.PorProducto[i].Utilidad > reporte.PorProducto[j].Utilidad
+	})
+	sort.Slice(reporte.PorVendedor, func(i, j int) bool {
+		return reporte.PorVendedor[i].Utilidad > reporte.PorVendedor[j].Utilidad
+	})
+	return reporte, nil
+}
@@ -0,0 +1,59 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SustitutosDashboard es el número de equivalentes sugeridos por cada producto agotado del dashboard.
+const SustitutosDashboard = 3
+
+// ObtenerSustitutos lista los productos con existencias equivalentes a uno dado: el mismo principio
+// activo y, si el producto las tiene registradas, la misma concentración y forma farmacéutica. Vienen
+// del más barato al más caro para ofrecerlos en la venta cuando la marca está agotada.
+func (d *Db) ObtenerSustitutos(productoUUID string) ([]Producto, error) {
+	p, err := d.ObtenerProductoPorUUID(productoUUID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(p.PrincipioActivo) == "" {
+		return nil, fmt.Errorf("el producto %s no tiene principio activo registrado para buscar equivalentes", p.Nombre)
+	}
+	return d.consultarSustitutos(p, 0)
+}
+
+// consultarSustitutos busca los equivalentes en stock de un producto; limite 0 los devuelve todos.
+func (d *Db) consultarSustitutos(p Producto, limite int) ([]Producto, error) {
+	// Se comparan sin mayúsculas ni espacios ("500 mg" = "500MG"), normalizados en SQL a ambos lados
+	concentracion := strings.ReplaceAll(p.Concentracion, " ", "")
+	forma := strings.TrimSpace(p.FormaFarmaceutica)
+
+	query := `
+		SELECT uuid, codigo, nombre, precio_venta, stock, COALESCE(impuesto_codigo, ''), COALESCE(categoria, ''), costo_promedio,
+			controlado, requiere_formula, ` + sqlDatosFarmaceuticos + `
+		FROM productos
+		WHERE deleted_at IS NULL AND uuid <> ? AND stock > 0
+			AND LOWER(TRIM(principio_activo)) = LOWER(TRIM(?))
+			AND (? = '' OR LOWER(REPLACE(concentracion, ' ', '')) = LOWER(?))
+			AND (? = '' OR LOWER(TRIM(forma_farmaceutica)) = LOWER(?))
+		ORDER BY precio_venta ASC, nombre ASC`
+	if limite > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limite)
+	}
+	rows, err := d.LocalDB.QueryContext(d.ctx, query, p.UUID, p.PrincipioActivo, concentracion, concentracion, forma, forma)
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar sustitutos de %s: %w", p.Nombre, err)
+	}
+	defer rows.Close()
+
+	sustitutos := make([]Producto, 0)
+	for rows.Next() {
+		var s Producto
+		if err := rows.Scan(append([]any{&s.UUID, &s.Codigo, &s.Nombre, &s.PrecioVenta, &s.Stock, &s.ImpuestoCodigo, &s.Categoria, &s.CostoPromedio, &s.Controlado, &s.RequiereFormula},
+			s.DatosFarmaceuticos.destinos()...)...); err != nil {
+			return nil, fmt.Errorf("error al escanear sustituto: %w", err)
+		}
+		sustitutos = append(sustitutos, s)
+	}
+	return sustitutos, rows.Err()
+}
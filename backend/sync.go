@@ -18,7 +18,6 @@ import (
 
 const (
 	IDConstraintName            = "facturas_pkey"
-	UUIDConstraintName          = "facturas_uuid_unique"
 	NumeroFacturaConstraintName = "uni_facturas_numero_factura"
 )
 
@@ -56,10 +55,25 @@ func (d *Db) SincronizacionInteligente() {
 		uniqueCol string
 		cols      []string
 	}{
-		{"vendedors", "cedula", []string{"created_at", "updated_at", "deleted_at", "uuid", "nombre", "apellido", "cedula", "email", "contrasena", "mfa_enabled", "mfa_secret"}},
-		{"clientes", "numero_id", []string{"created_at", "updated_at", "deleted_at", "uuid", "nombre", "apellido", "tipo_id", "numero_id", "telefono", "email", "direccion"}},
-		{"proveedors", "nombre", []string{"created_at", "updated_at", "deleted_at", "uuid", "nombre", "telefono", "email"}},
-		{"productos", "codigo", []string{"created_at", "updated_at", "deleted_at", "uuid", "nombre", "codigo", "precio_venta", "stock"}},
+		{"vendedors", "cedula", []string{"created_at", "updated_at", "deleted_at", "uuid", "nombre", "apellido", "cedula", "email", "contrasena", "mfa_enabled", "mfa_secret", "rol"}},
+		{"clientes", "numero_id", []string{"created_at", "updated_at", "deleted_at", "uuid", "nombre", "apellido", "tipo_id", "numero_id", "telefono", "email", "direccion", "limite_credito"}},
+		{"proveedors", "nombre", []string{"created_at", "updated_at", "deleted_at", "uuid", "nombre", "telefono", "email", "dias_entrega"}},
+		{"productos", "codigo", []string{"created_at", "updated_at", "deleted_at", "uuid", "nombre", "codigo", "precio_venta", "stock", "impuesto_codigo", "categoria", "costo_promedio", "controlado", "requiere_formula",
+			"principio_activo", "concentracion", "forma_farmaceutica", "laboratorio", "registro_invima", "vencimiento_invima", "codigo_atc"}},
+		{"categorias_producto", "nombre", []string{"created_at", "updated_at", "deleted_at", "uuid", "nombre", "categoria_padre"}},
+		{"impuestos", "codigo", []string{"created_at", "updated_at", "deleted_at", "uuid", "codigo", "nombre", "tarifa"}},
+		{"reglas_puntos", "codigo", []string{"created_at", "updated_at", "deleted_at", "uuid", "codigo", "nombre", "categoria", "monto_por_punto"}},
+		{"presentaciones_producto", "uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "producto_uuid", "nombre", "factor", "precio_venta", "codigo_barras"}},
+		{"codigos_barras_producto", "uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "codigo", "producto_uuid", "presentacion_uuid", "descripcion"}},
+		{"lotes", "uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "producto_uuid", "numero_lote", "fecha_vencimiento", "estado", "observacion"}},
+		{"formulas_medicas", "uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "numero_formula", "fecha_formula", "medico", "registro_medico", "paciente_id", "paciente_nombre", "cliente_uuid"}},
+		{"items_formula", "uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "formula_uuid", "producto_uuid", "cantidad_prescrita"}},
+		{"niveles_reorden", "producto_uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "producto_uuid", "automatico", "stock_minimo", "stock_maximo", "proveedor_uuid"}},
+		{"pedidos_proveedor", "uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "proveedor_uuid", "vendedor_uuid", "fecha", "fecha_esperada", "estado", "compra_uuid", "observacion"}},
+		{"items_pedido_proveedor", "uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "pedido_uuid", "producto_uuid", "cantidad", "costo_unitario"}},
+		{"conteos_inventario", "uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "descripcion", "categoria", "estado", "vendedor_uuid", "aprobado_por", "fecha_apertura", "fecha_cierre", "observacion"}},
+		{"items_conteo_inventario", "uuid", []string{"created_at", "updated_at", "deleted_at", "uuid", "conteo_uuid", "producto_uuid", "lote_uuid", "stock_esperado", "costo_unitario"}},
+		{"registros_conteo_inventario", "uuid", columnasRegistrosConteo},
 	}
 
 	for _, m := range models {
@@ -79,9 +93,18 @@ func (d *Db) SincronizacionInteligente() {
 	if err := d.sincronizarOperacionesStockHaciaLocal(); err != nil {
 		d.Log.Errorf("Error sincronizando operaciones de stock hacia local: %v", err)
 	}
+	if err := d.sincronizarMovimientosPuntosHaciaLocal(); err != nil {
+		d.Log.Errorf("Error sincronizando movimientos de puntos hacia local: %v", err)
+	}
 
-	// Subir operaciones locales pendientes (marcado atómico)
+	// Subir documentos y operaciones locales pendientes (marcado atómico)
+	d.SincronizarSesionesCajaHaciaRemoto()
+	d.SincronizarNotasCreditoHaciaRemoto()
+	d.SincronizarAbonosHaciaRemoto()
 	d.SincronizarOperacionesStockHaciaRemoto()
+	d.SincronizarMovimientosPuntosHaciaRemoto()
+	d.SincronizarLibroControladosHaciaRemoto()
+	d.asegurarRangoFacturacion()
 	runtime.EventsEmit(d.ctx, "sync:finish", "Sincronización completada exitosamente.")
 
 	d.Log.Info("[FIN]: Sincronización Inteligente")
@@ -172,13 +195,12 @@ func (d *Db) syncGenericModel(ctx context.Context, tableName, uniqueCol string,
 			continue
 		}
 
-		// Convertir UUID si viene en bytes
+		// Convertir UUID si viene en bytes (incluye referencias como producto_uuid)
 		for i, colName := range cols {
+			if v, ok := rawVals[i].([16]uint8); ok {
+				rawVals[i] = uuid.UUID(v).String()
+			}
 			if colName == "uuid" {
-				switch v := rawVals[i].(type) {
-				case [16]uint8:
-					rawVals[i] = uuid.UUID(v).String()
-				}
 				downloadedUUIDs = append(downloadedUUIDs, fmt.Sprintf("%v", rawVals[i]))
 			}
 		}
@@ -313,7 +335,7 @@ func (d *Db) SincronizarOperacionesStockHaciaRemoto() {
 
 	const selectPendientes = `
 	SELECT uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante,
-		   vendedor_uuid, factura_uuid, timestamp
+		   vendedor_uuid, factura_uuid, COALESCE(lote_uuid, ''), timestamp
 	FROM operacion_stocks 
 	WHERE sincronizado = 0
 	`
@@ -341,7 +363,7 @@ func (d *Db) SincronizarOperacionesStockHaciaRemoto() {
 
 		if err := rows.Scan(
 			&op.UUID, &op.ProductoUUID, &op.TipoOperacion, &op.CantidadCambio,
-			&stockResult, &vendedorUUID, &facturaUUID, &op.Timestamp,
+			&stockResult, &vendedorUUID, &facturaUUID, &op.LoteUUID, &op.Timestamp,
 		); err != nil {
 			d.Log.Warnf("[SYNC] Error leyendo operación de stock, saltando: %v", err)
 			continue
@@ -385,7 +407,7 @@ func (d *Db) SincronizarOperacionesStockHaciaRemoto() {
 		d.ctx,
 		pgx.Identifier{"operacion_stocks"},
 		[]string{"uuid", "producto_uuid", "tipo_operacion", "cantidad_cambio", "stock_resultante",
-			"vendedor_uuid", "factura_uuid", "timestamp"},
+			"vendedor_uuid", "factura_uuid", "lote_uuid", "timestamp"},
 		pgx.CopyFromSlice(len(pendientes), func(i int) ([]any, error) {
 			o := pendientes[i].op
 			var vendedor any
@@ -398,7 +420,7 @@ func (d *Db) SincronizarOperacionesStockHaciaRemoto() {
 			}
 			return []any{
 				o.UUID, o.ProductoUUID, o.TipoOperacion, o.CantidadCambio,
-				o.StockResultante, vendedor, factura, o.Timestamp,
+				o.StockResultante, vendedor, factura, nullableString(o.LoteUUID), o.Timestamp,
 			}, nil
 		}),
 	)
@@ -473,6 +495,39 @@ func (d *Db) ForzarResincronizacionLocalDesdeRemoto() error {
 	if _, err := tx.Exec("DELETE FROM operacion_stocks"); err != nil {
 		return err
 	}
+	if _, err := tx.Exec("DELETE FROM movimientos_puntos"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM conteos_caja"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM sesiones_caja"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM abono_facturas"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM abonos"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM detalle_notas_credito"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM notas_credito"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM pagos_factura"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM facturas_electronicas"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM cambios_precio"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM factura_impuestos"); err != nil {
+		return err
+	}
 	if _, err := tx.Exec("DELETE FROM detalle_facturas"); err != nil {
 		return err
 	}
@@ -507,7 +562,7 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 	// -------------------------------------------------
 	var lastFacturaTimeStr sql.NullString
 	var lastFacturaTime time.Time
-	queryFact := `SELECT MAX(created_at) FROM facturas`
+	queryFact := `SELECT MAX(COALESCE(updated_at, created_at)) FROM facturas`
 
 	if err := d.LocalDB.QueryRowContext(ctx, queryFact).Scan(&lastFacturaTimeStr); err != nil {
 		return fmt.Errorf("error al obtener fecha de última factura local: %w", err)
@@ -553,6 +608,55 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 		}
 	}
 
+	// -------------------------------------------------
+	// 2.b) LECTURA DE ÚLTIMA FECHA - NOTAS CRÉDITO (FUERA DE TX)
+	// -------------------------------------------------
+	var lastNotaTimeStr sql.NullString
+	lastNotaTime := time.Unix(0, 0)
+	if err := d.LocalDB.QueryRowContext(ctx, `SELECT MAX(created_at) FROM notas_credito WHERE sincronizado = 1`).Scan(&lastNotaTimeStr); err != nil {
+		return fmt.Errorf("error al obtener fecha de última nota crédito local: %w", err)
+	}
+	if lastNotaTimeStr.Valid && lastNotaTimeStr.String != "" {
+		if parsedTime, parseErr := parseFlexibleTime(lastNotaTimeStr.String); parseErr == nil {
+			lastNotaTime = parsedTime
+		} else {
+			d.Log.Warnf("No se pudo parsear la fecha de última nota crédito local '%s': %v. Realizando carga inicial completa.", lastNotaTimeStr.String, parseErr)
+		}
+	}
+
+	// -------------------------------------------------
+	// 2.c) LECTURA DE ÚLTIMA FECHA - SESIONES DE CAJA (FUERA DE TX)
+	// -------------------------------------------------
+	// Se usa updated_at porque la sesión se sube al abrir y otra vez al cerrar.
+	var lastSesionTimeStr sql.NullString
+	lastSesionTime := time.Unix(0, 0)
+	if err := d.LocalDB.QueryRowContext(ctx, `SELECT MAX(updated_at) FROM sesiones_caja WHERE sincronizado = 1`).Scan(&lastSesionTimeStr); err != nil {
+		return fmt.Errorf("error al obtener fecha de última sesión de caja local: %w", err)
+	}
+	if lastSesionTimeStr.Valid && lastSesionTimeStr.String != "" {
+		if parsedTime, parseErr := parseFlexibleTime(lastSesionTimeStr.String); parseErr == nil {
+			lastSesionTime = parsedTime
+		} else {
+			d.Log.Warnf("No se pudo parsear la fecha de última sesión de caja local '%s': %v. Realizando carga inicial completa.", lastSesionTimeStr.String, parseErr)
+		}
+	}
+
+	// -------------------------------------------------
+	// 2.d) LECTURA DE ÚLTIMA FECHA - ABONOS (FUERA DE TX)
+	// -------------------------------------------------
+	var lastAbonoTimeStr sql.NullString
+	lastAbonoTime := time.Unix(0, 0)
+	if err := d.LocalDB.QueryRowContext(ctx, `SELECT MAX(updated_at) FROM abonos WHERE sincronizado = 1`).Scan(&lastAbonoTimeStr); err != nil {
+		return fmt.Errorf("error al obtener fecha de último abono local: %w", err)
+	}
+	if lastAbonoTimeStr.Valid && lastAbonoTimeStr.String != "" {
+		if parsedTime, parseErr := parseFlexibleTime(lastAbonoTimeStr.String); parseErr == nil {
+			lastAbonoTime = parsedTime
+		} else {
+			d.Log.Warnf("No se pudo parsear la fecha de último abono local '%s': %v. Realizando carga inicial completa.", lastAbonoTimeStr.String, parseErr)
+		}
+	}
+
 	// -------------------------------------------------
 	// 3️⃣ INICIO DE TRANSACCIÓN LOCAL (SÓLO PARA ESCRITURAS)
 	// -------------------------------------------------
@@ -570,11 +674,14 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 	// -------------------------------------------------
 	// 4️⃣ OBTENER E INSERTAR FACTURAS
 	// -------------------------------------------------
+	// Se usa updated_at para recibir también los cambios de estado (ej. anulaciones hechas en otra terminal).
 	facturasRemotasQuery := `
 		SELECT uuid, numero_factura, fecha_emision, vendedor_uuid, cliente_uuid, subtotal, iva, total,
-		       estado, metodo_pago, created_at, updated_at
+		       estado, metodo_pago, COALESCE(motivo_anulacion, ''), fecha_anulacion, COALESCE(anulada_por_uuid::text, ''),
+		       COALESCE(sesion_caja_anulacion_uuid::text, ''), valor_bruto, descuento, descuento_factura, COALESCE(motivo_descuento, ''), COALESCE(descuento_autorizado_por::text, ''),
+		       COALESCE(sesion_caja_uuid::text, ''), COALESCE(formula_uuid::text, ''), created_at, updated_at
 		FROM facturas
-		WHERE COALESCE(created_at, '1970-01-01T00:00:00Z') > $1
+		WHERE COALESCE(updated_at, created_at, '1970-01-01T00:00:00Z') > $1
 		ORDER BY created_at ASC`
 
 	args = append(args, lastFacturaTime)
@@ -588,10 +695,19 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 	insertFactSQL := `
 		INSERT INTO facturas (
 			uuid, numero_factura, fecha_emision, vendedor_uuid, cliente_uuid, subtotal, iva, total,
-			estado, metodo_pago, created_at, updated_at
+			estado, metodo_pago, motivo_anulacion, fecha_anulacion, anulada_por_uuid, sesion_caja_anulacion_uuid,
+			valor_bruto, descuento, descuento_factura, motivo_descuento, descuento_autorizado_por, sesion_caja_uuid,
+			formula_uuid, created_at, updated_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(uuid) DO NOTHING`
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(uuid) DO UPDATE SET
+			estado = excluded.estado,
+			motivo_anulacion = excluded.motivo_anulacion,
+			fecha_anulacion = excluded.fecha_anulacion,
+			anulada_por_uuid = excluded.anulada_por_uuid,
+			sesion_caja_anulacion_uuid = excluded.sesion_caja_anulacion_uuid,
+			updated_at = excluded.updated_at
+		WHERE excluded.updated_at > facturas.updated_at`
 	stmtFact, err := tx.PrepareContext(ctx, insertFactSQL)
 	if err != nil {
 		return fmt.Errorf("error preparando statement de facturas: %w", err)
@@ -605,7 +721,10 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 		var f Factura
 		if err := rows.Scan(
 			&f.UUID, &f.NumeroFactura, &f.FechaEmision, &f.VendedorUUID, &f.ClienteUUID,
-			&f.Subtotal, &f.IVA, &f.Total, &f.Estado, &f.MetodoPago, &f.CreatedAt, &f.UpdatedAt,
+			&f.Subtotal, &f.IVA, &f.Total, &f.Estado, &f.MetodoPago,
+			&f.MotivoAnulacion, &f.FechaAnulacion, &f.AnuladaPorUUID, &f.SesionCajaAnulacion,
+			&f.ValorBruto, &f.Descuento, &f.DescuentoFactura, &f.MotivoDescuento, &f.DescuentoAutorizadoPor,
+			&f.SesionCajaUUID, &f.FormulaUUID, &f.CreatedAt, &f.UpdatedAt,
 		); err != nil {
 			d.Log.Errorf("Error al escanear factura remota: %v", err)
 			continue
@@ -613,7 +732,10 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 
 		if _, err := stmtFact.ExecContext(ctx,
 			f.UUID, f.NumeroFactura, f.FechaEmision, f.VendedorUUID, f.ClienteUUID,
-			f.Subtotal, f.IVA, f.Total, f.Estado, f.MetodoPago, f.CreatedAt, f.UpdatedAt); err != nil {
+			f.Subtotal, f.IVA, f.Total, f.Estado, f.MetodoPago,
+			nullableString(f.MotivoAnulacion), f.FechaAnulacion, nullableString(f.AnuladaPorUUID), nullableString(f.SesionCajaAnulacion),
+			f.ValorBruto, f.Descuento, f.DescuentoFactura, nullableString(f.MotivoDescuento), nullableString(f.DescuentoAutorizadoPor),
+			nullableString(f.SesionCajaUUID), nullableString(f.FormulaUUID), f.CreatedAt, f.UpdatedAt); err != nil {
 			d.Log.Errorf("Error insertando factura local (UUID %s): %v", f.UUID, err)
 			continue
 		}
@@ -639,7 +761,9 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 
 		queryDetalles := fmt.Sprintf(`
 			SELECT uuid, factura_uuid, producto_uuid, cantidad, precio_unitario,
-			       precio_total, created_at, updated_at
+			       precio_total, COALESCE(impuesto_codigo, ''), base_impuesto, tarifa_impuesto, valor_impuesto,
+			       valor_bruto, descuento, COALESCE(motivo_descuento, ''), COALESCE(presentacion_uuid::text, ''),
+			       COALESCE(presentacion, ''), factor_conversion, costo_unitario, created_at, updated_at
 			FROM detalle_facturas
 			WHERE factura_uuid IN (%s)
 			ORDER BY created_at ASC`, strings.Join(placeholders, ","))
@@ -652,9 +776,11 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 
 		insertDetalleSQL := `
 			INSERT INTO detalle_facturas (
-				uuid, factura_uuid, producto_uuid, cantidad, precio_unitario, precio_total, created_at, updated_at
+				uuid, factura_uuid, producto_uuid, cantidad, precio_unitario, precio_total,
+				impuesto_codigo, base_impuesto, tarifa_impuesto, valor_impuesto, valor_bruto, descuento, motivo_descuento,
+				presentacion_uuid, presentacion, factor_conversion, costo_unitario, created_at, updated_at
 			)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(uuid) DO NOTHING`
 		stmtDetalle, err := tx.PrepareContext(ctx, insertDetalleSQL)
 		if err != nil {
@@ -667,7 +793,10 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 			var df DetalleFactura
 			if err := detalleRows.Scan(
 				&df.UUID, &df.FacturaUUID, &df.ProductoUUID, &df.Cantidad,
-				&df.PrecioUnitario, &df.PrecioTotal, &df.CreatedAt, &df.UpdatedAt,
+				&df.PrecioUnitario, &df.PrecioTotal, &df.ImpuestoCodigo, &df.BaseImpuesto,
+				&df.TarifaImpuesto, &df.ValorImpuesto, &df.ValorBruto, &df.Descuento, &df.MotivoDescuento,
+				&df.PresentacionUUID, &df.Presentacion, &df.FactorConversion, &df.CostoUnitario,
+				&df.CreatedAt, &df.UpdatedAt,
 			); err != nil {
 				d.Log.Errorf("Error al escanear detalle de factura remoto: %v", err)
 				continue
@@ -675,7 +804,10 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 
 			if _, err := stmtDetalle.ExecContext(ctx,
 				df.UUID, df.FacturaUUID, df.ProductoUUID, df.Cantidad,
-				df.PrecioUnitario, df.PrecioTotal, df.CreatedAt, df.UpdatedAt); err != nil {
+				df.PrecioUnitario, df.PrecioTotal, nullableString(df.ImpuestoCodigo), df.BaseImpuesto,
+				df.TarifaImpuesto, df.ValorImpuesto, df.ValorBruto, df.Descuento, nullableString(df.MotivoDescuento),
+				nullableString(df.PresentacionUUID), nullableString(df.Presentacion), df.FactorConversion, df.CostoUnitario,
+				df.CreatedAt, df.UpdatedAt); err != nil {
 				d.Log.Errorf("Error insertando detalle de factura (UUID %s): %v", df.UUID, err)
 				continue
 			}
@@ -684,6 +816,148 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 		// Cerrar detalleRows explícitamente
 		detalleRows.Close()
 		d.Log.Infof("Sincronizados %d nuevos detalles de factura.", detalleCount)
+
+		// Desglose de impuestos de las mismas facturas
+		impRows, err := d.RemoteDB.Query(ctx, `
+			SELECT uuid, factura_uuid, COALESCE(impuesto_codigo, ''), tarifa, base, valor, created_at, updated_at
+			FROM factura_impuestos
+			WHERE factura_uuid = ANY($1)`, facturaUUIDsRemotos)
+		if err != nil {
+			return fmt.Errorf("error obteniendo impuestos de factura remotos: %w", err)
+		}
+		defer impRows.Close()
+
+		stmtImp, err := tx.PrepareContext(ctx, `
+			INSERT INTO factura_impuestos (uuid, factura_uuid, impuesto_codigo, tarifa, base, valor, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(uuid) DO NOTHING`)
+		if err != nil {
+			return fmt.Errorf("error preparando statement de factura_impuestos: %w", err)
+		}
+		defer stmtImp.Close()
+
+		for impRows.Next() {
+			var fi FacturaImpuesto
+			if err := impRows.Scan(&fi.UUID, &fi.FacturaUUID, &fi.ImpuestoCodigo, &fi.Tarifa, &fi.Base, &fi.Valor, &fi.CreatedAt, &fi.UpdatedAt); err != nil {
+				d.Log.Errorf("Error al escanear impuesto de factura remoto: %v", err)
+				continue
+			}
+			if _, err := stmtImp.ExecContext(ctx,
+				fi.UUID, fi.FacturaUUID, nullableString(fi.ImpuestoCodigo), fi.Tarifa, fi.Base, fi.Valor, fi.CreatedAt, fi.UpdatedAt); err != nil {
+				d.Log.Errorf("Error insertando impuesto de factura (UUID %s): %v", fi.UUID, err)
+			}
+		}
+		impRows.Close()
+
+		// Pagos de las mismas facturas
+		pagoRows, err := d.RemoteDB.Query(ctx, `
+			SELECT uuid, factura_uuid, metodo_pago, monto, COALESCE(referencia, ''), efectivo_recibido, cambio, created_at, updated_at
+			FROM pagos_factura
+			WHERE factura_uuid = ANY($1)`, facturaUUIDsRemotos)
+		if err != nil {
+			return fmt.Errorf("error obteniendo pagos de factura remotos: %w", err)
+		}
+		defer pagoRows.Close()
+
+		stmtPago, err := tx.PrepareContext(ctx, `
+			INSERT INTO pagos_factura (uuid, factura_uuid, metodo_pago, monto, referencia, efectivo_recibido, cambio, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(uuid) DO NOTHING`)
+		if err != nil {
+			return fmt.Errorf("error preparando statement de pagos_factura: %w", err)
+		}
+		defer stmtPago.Close()
+
+		pagoCount := 0
+		for pagoRows.Next() {
+			var p PagoFactura
+			if err := pagoRows.Scan(&p.UUID, &p.FacturaUUID, &p.MetodoPago, &p.Monto, &p.Referencia, &p.EfectivoRecibido, &p.Cambio, &p.CreatedAt, &p.UpdatedAt); err != nil {
+				d.Log.Errorf("Error al escanear pago de factura remoto: %v", err)
+				continue
+			}
+			if _, err := stmtPago.ExecContext(ctx,
+				p.UUID, p.FacturaUUID, p.MetodoPago, p.Monto, nullableString(p.Referencia), p.EfectivoRecibido, p.Cambio, p.CreatedAt, p.UpdatedAt); err != nil {
+				d.Log.Errorf("Error insertando pago de factura (UUID %s): %v", p.UUID, err)
+				continue
+			}
+			pagoCount++
+		}
+		pagoRows.Close()
+		d.Log.Infof("Sincronizados %d nuevos pagos de factura.", pagoCount)
+
+		// Cambios de precio autorizados en las mismas facturas
+		cambioRows, err := d.RemoteDB.Query(ctx, `
+			SELECT uuid, factura_uuid, detalle_factura_uuid, producto_uuid, cantidad, precio_original, precio_nuevo,
+				vendedor_uuid, autorizado_por_uuid, motivo, created_at, updated_at
+			FROM cambios_precio
+			WHERE factura_uuid = ANY($1)`, facturaUUIDsRemotos)
+		if err != nil {
+			return fmt.Errorf("error obteniendo cambios de precio remotos: %w", err)
+		}
+		defer cambioRows.Close()
+
+		stmtCambio, err := tx.PrepareContext(ctx, `
+			INSERT INTO cambios_precio (uuid, factura_uuid, detalle_factura_uuid, producto_uuid, cantidad, precio_original, precio_nuevo,
+				vendedor_uuid, autorizado_por_uuid, motivo, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(uuid) DO NOTHING`)
+		if err != nil {
+			return fmt.Errorf("error preparando statement de cambios_precio: %w", err)
+		}
+		defer stmtCambio.Close()
+
+		for cambioRows.Next() {
+			var c CambioPrecio
+			if err := cambioRows.Scan(&c.UUID, &c.FacturaUUID, &c.DetalleFacturaUUID, &c.ProductoUUID, &c.Cantidad, &c.PrecioOriginal, &c.PrecioNuevo,
+				&c.VendedorUUID, &c.AutorizadoPorUUID, &c.Motivo, &c.CreatedAt, &c.UpdatedAt); err != nil {
+				d.Log.Errorf("Error al escanear cambio de precio remoto: %v", err)
+				continue
+			}
+			if _, err := stmtCambio.ExecContext(ctx,
+				c.UUID, c.FacturaUUID, c.DetalleFacturaUUID, c.ProductoUUID, c.Cantidad, c.PrecioOriginal, c.PrecioNuevo,
+				c.VendedorUUID, c.AutorizadoPorUUID, c.Motivo, c.CreatedAt, c.UpdatedAt); err != nil {
+				d.Log.Errorf("Error insertando cambio de precio (UUID %s): %v", c.UUID, err)
+			}
+		}
+		cambioRows.Close()
+
+		// Facturas electrónicas de las mismas facturas
+		feRows, err := d.RemoteDB.Query(ctx, `
+			SELECT uuid, factura_uuid, numero_factura, cufe, COALESCE(qr, ''), xml, ambiente, estado,
+				COALESCE(mensaje_dian, ''), fecha_envio, created_at, updated_at
+			FROM facturas_electronicas
+			WHERE factura_uuid = ANY($1)`, facturaUUIDsRemotos)
+		if err != nil {
+			return fmt.Errorf("error obteniendo facturas electrónicas remotas: %w", err)
+		}
+		defer feRows.Close()
+
+		stmtFE, err := tx.PrepareContext(ctx, `
+			INSERT INTO facturas_electronicas (uuid, factura_uuid, numero_factura, cufe, qr, xml, ambiente, estado, mensaje_dian, fecha_envio, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(factura_uuid) DO UPDATE SET
+				cufe = excluded.cufe, qr = excluded.qr, xml = excluded.xml, ambiente = excluded.ambiente, estado = excluded.estado,
+				mensaje_dian = excluded.mensaje_dian, fecha_envio = excluded.fecha_envio, updated_at = excluded.updated_at
+			WHERE excluded.updated_at > facturas_electronicas.updated_at`)
+		if err != nil {
+			return fmt.Errorf("error preparando statement de facturas_electronicas: %w", err)
+		}
+		defer stmtFE.Close()
+
+		for feRows.Next() {
+			var fe FacturaElectronica
+			if err := feRows.Scan(&fe.UUID, &fe.FacturaUUID, &fe.NumeroFactura, &fe.CUFE, &fe.QR, &fe.XML, &fe.Ambiente, &fe.Estado,
+				&fe.MensajeDIAN, &fe.FechaEnvio, &fe.CreatedAt, &fe.UpdatedAt); err != nil {
+				d.Log.Errorf("Error al escanear factura electrónica remota: %v", err)
+				continue
+			}
+			if _, err := stmtFE.ExecContext(ctx,
+				fe.UUID, fe.FacturaUUID, fe.NumeroFactura, fe.CUFE, nullableString(fe.QR), fe.XML, fe.Ambiente, fe.Estado,
+				nullableString(fe.MensajeDIAN), fe.FechaEnvio, fe.CreatedAt, fe.UpdatedAt); err != nil {
+				d.Log.Errorf("Error insertando factura electrónica %s: %v", fe.NumeroFactura, err)
+			}
+		}
+		feRows.Close()
 	}
 
 	// -------------------------------------------------
@@ -691,7 +965,7 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 	// -------------------------------------------------
 	args = args[:0] // Limpiar slice de argumentos
 	comprasQuery := `
-		SELECT uuid, fecha, proveedor_uuid, factura_numero, total, created_at, updated_at
+		SELECT uuid, fecha, proveedor_uuid, COALESCE(vendedor_uuid::text, ''), COALESCE(factura_numero, ''), total, created_at, updated_at
 		FROM compras
 		WHERE COALESCE(created_at, '1970-01-01T00:00:00Z') > $1
 		ORDER BY created_at ASC`
@@ -705,9 +979,9 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 
 	insertCompraSQL := `
 		INSERT INTO compras (
-			uuid, fecha, proveedor_uuid, factura_numero, total, created_at, updated_at
+			uuid, fecha, proveedor_uuid, vendedor_uuid, factura_numero, total, created_at, updated_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(uuid) DO NOTHING`
 	stmtCompra, err := tx.PrepareContext(ctx, insertCompraSQL)
 	if err != nil {
@@ -716,25 +990,326 @@ func (d *Db) sincronizarTransaccionesHaciaLocal() error {
 	defer stmtCompra.Close()
 
 	compraCount := 0
+	var compraUUIDsRemotas []any
 	for compraRows.Next() {
 		var c Compra
 		if err := compraRows.Scan(
-			&c.UUID, &c.Fecha, &c.ProveedorUUID, &c.FacturaNumero, &c.Total, &c.CreatedAt, &c.UpdatedAt,
+			&c.UUID, &c.Fecha, &c.ProveedorUUID, &c.VendedorUUID, &c.FacturaNumero, &c.Total, &c.CreatedAt, &c.UpdatedAt,
 		); err != nil {
 			d.Log.Errorf("Error al escanear compra remota: %v", err)
 			continue
 		}
 
 		if _, err := stmtCompra.ExecContext(ctx,
-			c.UUID, c.Fecha, c.ProveedorUUID, c.FacturaNumero, c.Total, c.CreatedAt, c.UpdatedAt); err != nil {
+			c.UUID, c.Fecha, c.ProveedorUUID, nullableString(c.VendedorUUID), c.FacturaNumero, c.Total, c.CreatedAt, c.UpdatedAt); err != nil {
 			d.Log.Errorf("Error insertando compra local (UUID %s): %v", c.UUID, err)
 			continue
 		}
+		compraUUIDsRemotas = append(compraUUIDsRemotas, c.UUID)
 		compraCount++
 	}
 	compraRows.Close() // Cerrar explícitamente
 	d.Log.Infof("Sincronizadas %d nuevas compras.", compraCount)
 
+	// 5.a) Detalles de las compras descargadas
+	if len(compraUUIDsRemotas) > 0 {
+		placeholders := make([]string, len(compraUUIDsRemotas))
+		for i := range compraUUIDsRemotas {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		}
+		detCompraRows, err := d.RemoteDB.Query(ctx, fmt.Sprintf(`
+			SELECT uuid, compra_uuid, producto_uuid, cantidad, precio_compra_unitario,
+				COALESCE(presentacion_uuid::text, ''), factor_conversion, COALESCE(lote_uuid::text, '')
+			FROM detalle_compras
+			WHERE compra_uuid IN (%s)`, strings.Join(placeholders, ",")), compraUUIDsRemotas...)
+		if err != nil {
+			return fmt.Errorf("error obteniendo detalles de compra remotos: %w", err)
+		}
+		detCompraCount := 0
+		for detCompraRows.Next() {
+			var det DetalleCompra
+			if err := detCompraRows.Scan(&det.UUID, &det.CompraUUID, &det.ProductoUUID, &det.Cantidad, &det.PrecioCompraUnitario,
+				&det.PresentacionUUID, &det.FactorConversion, &det.LoteUUID); err != nil {
+				d.Log.Errorf("Error al escanear detalle de compra remoto: %v", err)
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO detalle_compras (uuid, compra_uuid, producto_uuid, cantidad, precio_compra_unitario, presentacion_uuid, factor_conversion, lote_uuid)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(uuid) DO NOTHING`,
+				det.UUID, det.CompraUUID, det.ProductoUUID, det.Cantidad, det.PrecioCompraUnitario,
+				nullableString(det.PresentacionUUID), det.FactorConversion, nullableString(det.LoteUUID)); err != nil {
+				d.Log.Errorf("Error insertando detalle de compra (UUID %s): %v", det.UUID, err)
+				continue
+			}
+			detCompraCount++
+		}
+		detCompraRows.Close()
+		d.Log.Infof("Sincronizados %d nuevos detalles de compra.", detCompraCount)
+	}
+
+	// -------------------------------------------------
+	// 5.b) NOTAS CRÉDITO Y SUS DETALLES (Dentro de la misma TX)
+	// -------------------------------------------------
+	notaRows, err := d.RemoteDB.Query(ctx, `
+		SELECT uuid, numero_nota, factura_uuid, fecha_emision, vendedor_uuid, cliente_uuid,
+		       COALESCE(motivo, ''), subtotal, iva, total, aplicado_cartera, COALESCE(sesion_caja_uuid::text, ''), created_at, updated_at
+		FROM notas_credito
+		WHERE COALESCE(created_at, '1970-01-01T00:00:00Z') > $1
+		ORDER BY created_at ASC`, lastNotaTime)
+	if err != nil {
+		return fmt.Errorf("error obteniendo notas crédito remotas: %w", err)
+	}
+	defer notaRows.Close()
+
+	stmtNota, err := tx.PrepareContext(ctx, `
+		INSERT INTO notas_credito (
+			uuid, numero_nota, factura_uuid, fecha_emision, vendedor_uuid, cliente_uuid,
+			motivo, subtotal, iva, total, aplicado_cartera, sesion_caja_uuid, created_at, updated_at, sincronizado
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT(uuid) DO NOTHING`)
+	if err != nil {
+		return fmt.Errorf("error preparando statement de notas_credito: %w", err)
+	}
+	defer stmtNota.Close()
+
+	var notaUUIDsRemotos []string
+	for notaRows.Next() {
+		var n NotaCredito
+		if err := notaRows.Scan(
+			&n.UUID, &n.NumeroNota, &n.FacturaUUID, &n.FechaEmision, &n.VendedorUUID, &n.ClienteUUID,
+			&n.Motivo, &n.Subtotal, &n.IVA, &n.Total, &n.AplicadoCartera, &n.SesionCajaUUID, &n.CreatedAt, &n.UpdatedAt,
+		); err != nil {
+			d.Log.Errorf("Error al escanear nota crédito remota: %v", err)
+			continue
+		}
+		if _, err := stmtNota.ExecContext(ctx,
+			n.UUID, n.NumeroNota, n.FacturaUUID, n.FechaEmision, n.VendedorUUID, n.ClienteUUID,
+			n.Motivo, n.Subtotal, n.IVA, n.Total, n.AplicadoCartera, nullableString(n.SesionCajaUUID), n.CreatedAt, n.UpdatedAt); err != nil {
+			d.Log.Errorf("Error insertando nota crédito local (UUID %s): %v", n.UUID, err)
+			continue
+		}
+		notaUUIDsRemotos = append(notaUUIDsRemotos, n.UUID)
+	}
+	notaRows.Close()
+
+	if len(notaUUIDsRemotos) > 0 {
+		detNotaRows, err := d.RemoteDB.Query(ctx, `
+			SELECT uuid, nota_credito_uuid, detalle_factura_uuid, producto_uuid, cantidad,
+			       precio_unitario, precio_total, created_at, updated_at
+			FROM detalle_notas_credito
+			WHERE nota_credito_uuid = ANY($1)`, notaUUIDsRemotos)
+		if err != nil {
+			return fmt.Errorf("error obteniendo detalles de notas crédito remotos: %w", err)
+		}
+		defer detNotaRows.Close()
+
+		stmtDetNota, err := tx.PrepareContext(ctx, `
+			INSERT INTO detalle_notas_credito (
+				uuid, nota_credito_uuid, detalle_factura_uuid, producto_uuid, cantidad,
+				precio_unitario, precio_total, created_at, updated_at
+			)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(uuid) DO NOTHING`)
+		if err != nil {
+			return fmt.Errorf("error preparando statement de detalle_notas_credito: %w", err)
+		}
+		defer stmtDetNota.Close()
+
+		for detNotaRows.Next() {
+			var dn DetalleNotaCredito
+			if err := detNotaRows.Scan(
+				&dn.UUID, &dn.NotaCreditoUUID, &dn.DetalleFacturaUUID, &dn.ProductoUUID, &dn.Cantidad,
+				&dn.PrecioUnitario, &dn.PrecioTotal, &dn.CreatedAt, &dn.UpdatedAt,
+			); err != nil {
+				d.Log.Errorf("Error al escanear detalle de nota crédito remoto: %v", err)
+				continue
+			}
+			if _, err := stmtDetNota.ExecContext(ctx,
+				dn.UUID, dn.NotaCreditoUUID, dn.DetalleFacturaUUID, dn.ProductoUUID, dn.Cantidad,
+				dn.PrecioUnitario, dn.PrecioTotal, dn.CreatedAt, dn.UpdatedAt); err != nil {
+				d.Log.Errorf("Error insertando detalle de nota crédito (UUID %s): %v", dn.UUID, err)
+			}
+		}
+		detNotaRows.Close()
+	}
+	d.Log.Infof("Sincronizadas %d nuevas notas crédito.", len(notaUUIDsRemotos))
+
+	// -------------------------------------------------
+	// 5.c) SESIONES DE CAJA Y SUS CONTEOS (Dentro de la misma TX)
+	// -------------------------------------------------
+	sesionRows, err := d.RemoteDB.Query(ctx, `
+		SELECT uuid, vendedor_uuid, fecha_apertura, monto_apertura, fecha_cierre, estado,
+		       total_esperado, total_contado, diferencia, COALESCE(observaciones, ''), created_at, updated_at
+		FROM sesiones_caja
+		WHERE COALESCE(updated_at, created_at, '1970-01-01T00:00:00Z') > $1
+		ORDER BY created_at ASC`, lastSesionTime)
+	if err != nil {
+		return fmt.Errorf("error obteniendo sesiones de caja remotas: %w", err)
+	}
+	defer sesionRows.Close()
+
+	stmtSesion, err := tx.PrepareContext(ctx, `
+		INSERT INTO sesiones_caja (
+			uuid, vendedor_uuid, fecha_apertura, monto_apertura, fecha_cierre, estado,
+			total_esperado, total_contado, diferencia, observaciones, created_at, updated_at, sincronizado
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT(uuid) DO UPDATE SET
+			fecha_cierre = excluded.fecha_cierre,
+			estado = excluded.estado,
+			total_esperado = excluded.total_esperado,
+			total_contado = excluded.total_contado,
+			diferencia = excluded.diferencia,
+			observaciones = excluded.observaciones,
+			updated_at = excluded.updated_at,
+			sincronizado = 1
+		WHERE excluded.updated_at > sesiones_caja.updated_at`)
+	if err != nil {
+		return fmt.Errorf("error preparando statement de sesiones_caja: %w", err)
+	}
+	defer stmtSesion.Close()
+
+	var sesionUUIDsRemotos []string
+	for sesionRows.Next() {
+		var s SesionCaja
+		if err := sesionRows.Scan(
+			&s.UUID, &s.VendedorUUID, &s.FechaApertura, &s.MontoApertura, &s.FechaCierre, &s.Estado,
+			&s.TotalEsperado, &s.TotalContado, &s.Diferencia, &s.Observaciones, &s.CreatedAt, &s.UpdatedAt,
+		); err != nil {
+			d.Log.Errorf("Error al escanear sesión de caja remota: %v", err)
+			continue
+		}
+		if _, err := stmtSesion.ExecContext(ctx,
+			s.UUID, s.VendedorUUID, s.FechaApertura, s.MontoApertura, s.FechaCierre, s.Estado,
+			s.TotalEsperado, s.TotalContado, s.Diferencia, nullableString(s.Observaciones), s.CreatedAt, s.UpdatedAt); err != nil {
+			d.Log.Errorf("Error insertando sesión de caja local (UUID %s): %v", s.UUID, err)
+			continue
+		}
+		sesionUUIDsRemotos = append(sesionUUIDsRemotos, s.UUID)
+	}
+	sesionRows.Close()
+
+	if len(sesionUUIDsRemotos) > 0 {
+		conteoRows, err := d.RemoteDB.Query(ctx, `
+			SELECT uuid, sesion_caja_uuid, metodo_pago, esperado, contado, diferencia, created_at, updated_at
+			FROM conteos_caja
+			WHERE sesion_caja_uuid = ANY($1)`, sesionUUIDsRemotos)
+		if err != nil {
+			return fmt.Errorf("error obteniendo conteos de caja remotos: %w", err)
+		}
+		defer conteoRows.Close()
+
+		stmtConteo, err := tx.PrepareContext(ctx, `
+			INSERT INTO conteos_caja (uuid, sesion_caja_uuid, metodo_pago, esperado, contado, diferencia, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT DO NOTHING`)
+		if err != nil {
+			return fmt.Errorf("error preparando statement de conteos_caja: %w", err)
+		}
+		defer stmtConteo.Close()
+
+		for conteoRows.Next() {
+			var c ConteoCaja
+			if err := conteoRows.Scan(
+				&c.UUID, &c.SesionCajaUUID, &c.MetodoPago, &c.Esperado, &c.Contado, &c.Diferencia, &c.CreatedAt, &c.UpdatedAt,
+			); err != nil {
+				d.Log.Errorf("Error al escanear conteo de caja remoto: %v", err)
+				continue
+			}
+			if _, err := stmtConteo.ExecContext(ctx,
+				c.UUID, c.SesionCajaUUID, c.MetodoPago, c.Esperado, c.Contado, c.Diferencia, c.CreatedAt, c.UpdatedAt); err != nil {
+				d.Log.Errorf("Error insertando conteo de caja (UUID %s): %v", c.UUID, err)
+			}
+		}
+		conteoRows.Close()
+	}
+	d.Log.Infof("Sincronizadas %d sesiones de caja.", len(sesionUUIDsRemotos))
+
+	// -------------------------------------------------
+	// 5.d) ABONOS DE CARTERA Y SU APLICACIÓN A FACTURAS (Dentro de la misma TX)
+	// -------------------------------------------------
+	abonoRows, err := d.RemoteDB.Query(ctx, `
+		SELECT uuid, numero_abono, cliente_uuid, vendedor_uuid, COALESCE(sesion_caja_uuid::text, ''), fecha, monto, metodo_pago,
+		       COALESCE(referencia, ''), COALESCE(observaciones, ''), created_at, updated_at, deleted_at
+		FROM abonos
+		WHERE COALESCE(updated_at, created_at, '1970-01-01T00:00:00Z') > $1
+		ORDER BY created_at ASC`, lastAbonoTime)
+	if err != nil {
+		return fmt.Errorf("error obteniendo abonos remotos: %w", err)
+	}
+	defer abonoRows.Close()
+
+	stmtAbono, err := tx.PrepareContext(ctx, `
+		INSERT INTO abonos (
+			uuid, numero_abono, cliente_uuid, vendedor_uuid, sesion_caja_uuid, fecha, monto, metodo_pago,
+			referencia, observaciones, created_at, updated_at, deleted_at, sincronizado
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT(uuid) DO UPDATE SET
+			deleted_at = excluded.deleted_at,
+			updated_at = excluded.updated_at,
+			sincronizado = 1
+		WHERE excluded.updated_at > abonos.updated_at`)
+	if err != nil {
+		return fmt.Errorf("error preparando statement de abonos: %w", err)
+	}
+	defer stmtAbono.Close()
+
+	var abonoUUIDsRemotos []string
+	for abonoRows.Next() {
+		var a Abono
+		if err := abonoRows.Scan(
+			&a.UUID, &a.NumeroAbono, &a.ClienteUUID, &a.VendedorUUID, &a.SesionCajaUUID, &a.Fecha, &a.Monto, &a.MetodoPago,
+			&a.Referencia, &a.Observaciones, &a.CreatedAt, &a.UpdatedAt, &a.DeletedAt,
+		); err != nil {
+			d.Log.Errorf("Error al escanear abono remoto: %v", err)
+			continue
+		}
+		if _, err := stmtAbono.ExecContext(ctx,
+			a.UUID, a.NumeroAbono, a.ClienteUUID, a.VendedorUUID, nullableString(a.SesionCajaUUID), a.Fecha, a.Monto, a.MetodoPago,
+			nullableString(a.Referencia), nullableString(a.Observaciones), a.CreatedAt, a.UpdatedAt, a.DeletedAt); err != nil {
+			d.Log.Errorf("Error insertando abono local (UUID %s): %v", a.UUID, err)
+			continue
+		}
+		abonoUUIDsRemotos = append(abonoUUIDsRemotos, a.UUID)
+	}
+	abonoRows.Close()
+
+	if len(abonoUUIDsRemotos) > 0 {
+		abonoFactRows, err := d.RemoteDB.Query(ctx, `
+			SELECT uuid, abono_uuid, factura_uuid, monto, created_at, updated_at
+			FROM abono_facturas
+			WHERE abono_uuid = ANY($1)`, abonoUUIDsRemotos)
+		if err != nil {
+			return fmt.Errorf("error obteniendo aplicación de abonos remotos: %w", err)
+		}
+		defer abonoFactRows.Close()
+
+		stmtAbonoFact, err := tx.PrepareContext(ctx, `
+			INSERT INTO abono_facturas (uuid, abono_uuid, factura_uuid, monto, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(uuid) DO NOTHING`)
+		if err != nil {
+			return fmt.Errorf("error preparando statement de abono_facturas: %w", err)
+		}
+		defer stmtAbonoFact.Close()
+
+		for abonoFactRows.Next() {
+			var af AbonoFactura
+			if err := abonoFactRows.Scan(&af.UUID, &af.AbonoUUID, &af.FacturaUUID, &af.Monto, &af.CreatedAt, &af.UpdatedAt); err != nil {
+				d.Log.Errorf("Error al escanear aplicación de abono remota: %v", err)
+				continue
+			}
+			if _, err := stmtAbonoFact.ExecContext(ctx, af.UUID, af.AbonoUUID, af.FacturaUUID, af.Monto, af.CreatedAt, af.UpdatedAt); err != nil {
+				d.Log.Errorf("Error insertando aplicación de abono (UUID %s): %v", af.UUID, err)
+			}
+		}
+		abonoFactRows.Close()
+	}
+	d.Log.Infof("Sincronizados %d abonos de cartera.", len(abonoUUIDsRemotos))
+
 	// -------------------------------------------------
 	// ✅ 6️⃣ COMMIT FINAL
 	// -------------------------------------------------
@@ -754,26 +1329,27 @@ func (d *Db) syncVendedorToRemote(uuid string) {
 		return
 	}
 	var v Vendedor
-	query := `SELECT uuid, created_at, updated_at, deleted_at, nombre, apellido, cedula, email, contrasena, mfa_enabled FROM vendedors WHERE uuid = ?`
-	err := d.LocalDB.QueryRowContext(d.ctx, query, uuid).Scan(&v.UUID, &v.CreatedAt, &v.UpdatedAt, &v.DeletedAt, &v.Nombre, &v.Apellido, &v.Cedula, &v.Email, &v.Contrasena, &v.MFAEnabled)
+	query := `SELECT uuid, created_at, updated_at, deleted_at, nombre, apellido, cedula, email, contrasena, mfa_enabled, rol FROM vendedors WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, uuid).Scan(&v.UUID, &v.CreatedAt, &v.UpdatedAt, &v.DeletedAt, &v.Nombre, &v.Apellido, &v.Cedula, &v.Email, &v.Contrasena, &v.MFAEnabled, &v.Rol)
 	if err != nil {
 		d.Log.Errorf("[LOCAL] syncVendedorToRemote: no se encontró vendedor local UUID %s: %v", uuid, err)
 		return
 	}
 
 	upsertSQL := `
-		INSERT INTO vendedors (uuid, created_at, updated_at, deleted_at, nombre, apellido, cedula, email, contrasena, mfa_enabled)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO vendedors (uuid, created_at, updated_at, deleted_at, nombre, apellido, cedula, email, contrasena, mfa_enabled, rol)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		ON CONFLICT (cedula) DO UPDATE SET
 			nombre = EXCLUDED.nombre,
 			apellido = EXCLUDED.apellido,
 			email = EXCLUDED.email,
 			contrasena = EXCLUDED.contrasena,
 			mfa_enabled = EXCLUDED.mfa_enabled,
+			rol = EXCLUDED.rol,
 			updated_at = EXCLUDED.updated_at,
 			deleted_at = EXCLUDED.deleted_at;`
 
-	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, v.UUID, v.CreatedAt, v.UpdatedAt, v.DeletedAt, v.Nombre, v.Apellido, v.Cedula, v.Email, v.Contrasena, v.MFAEnabled)
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, v.UUID, v.CreatedAt, v.UpdatedAt, v.DeletedAt, v.Nombre, v.Apellido, v.Cedula, v.Email, v.Contrasena, v.MFAEnabled, v.Rol)
 	if err != nil {
 		d.Log.Errorf("Error en UPSERT de vendedor remoto UUID %s: %v", uuid, err)
 		return
@@ -787,21 +1363,22 @@ func (d *Db) syncClienteToRemote(uuid string) {
 		return
 	}
 	var c Cliente
-	query := `SELECT uuid, created_at, updated_at, deleted_at, nombre, apellido, tipo_id, numero_id, telefono, email, direccion FROM clientes WHERE uuid = ?`
-	err := d.LocalDB.QueryRowContext(d.ctx, query, uuid).Scan(&c.UUID, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt, &c.Nombre, &c.Apellido, &c.TipoID, &c.NumeroID, &c.Telefono, &c.Email, &c.Direccion)
+	query := `SELECT uuid, created_at, updated_at, deleted_at, nombre, apellido, tipo_id, numero_id, telefono, email, direccion, limite_credito FROM clientes WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, uuid).Scan(&c.UUID, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt, &c.Nombre, &c.Apellido, &c.TipoID, &c.NumeroID, &c.Telefono, &c.Email, &c.Direccion, &c.LimiteCredito)
 	if err != nil {
 		d.Log.Errorf("syncClienteToRemote: no se encontró cliente local UUID %s: %v", uuid, err)
 		return
 	}
 
 	upsertSQL := `
-		INSERT INTO clientes (uuid, created_at, updated_at, deleted_at, nombre, apellido, tipo_id, numero_id, telefono, email, direccion)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO clientes (uuid, created_at, updated_at, deleted_at, nombre, apellido, tipo_id, numero_id, telefono, email, direccion, limite_credito)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		ON CONFLICT (numero_id) DO UPDATE SET
 			nombre = EXCLUDED.nombre, apellido = EXCLUDED.apellido, tipo_id = EXCLUDED.tipo_id, telefono = EXCLUDED.telefono, email = EXCLUDED.email, direccion = EXCLUDED.direccion,
+			limite_credito = EXCLUDED.limite_credito,
 			updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`
 
-	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, c.UUID, c.CreatedAt, c.UpdatedAt, c.DeletedAt, c.Nombre, c.Apellido, c.TipoID, c.NumeroID, c.Telefono, c.Email, c.Direccion)
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, c.UUID, c.CreatedAt, c.UpdatedAt, c.DeletedAt, c.Nombre, c.Apellido, c.TipoID, c.NumeroID, c.Telefono, c.Email, c.Direccion, c.LimiteCredito)
 	if err != nil {
 		d.Log.Errorf("Error en UPSERT de cliente remoto UUID %s: %v", uuid, err)
 		return
@@ -815,23 +1392,43 @@ func (d *Db) syncProductoToRemote(p_uuid string) {
 		return
 	}
 	var p Producto
-	query := `SELECT uuid, created_at, updated_at, deleted_at, nombre, codigo, precio_venta FROM productos WHERE uuid = ?`
-	err := d.LocalDB.QueryRowContext(d.ctx, query, p_uuid).Scan(&p.UUID, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt, &p.Nombre, &p.Codigo, &p.PrecioVenta)
+	query := `SELECT uuid, created_at, updated_at, deleted_at, nombre, codigo, precio_venta, COALESCE(impuesto_codigo, ''), COALESCE(categoria, ''), costo_promedio, controlado, requiere_formula, ` +
+		sqlDatosFarmaceuticos + ` FROM productos WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, p_uuid).Scan(append([]any{&p.UUID, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt, &p.Nombre, &p.Codigo, &p.PrecioVenta, &p.ImpuestoCodigo, &p.Categoria, &p.CostoPromedio, &p.Controlado, &p.RequiereFormula},
+		p.DatosFarmaceuticos.destinos()...)...)
 	if err != nil {
 		d.Log.Errorf("syncProductoToRemote: no se encontró producto local UUID %s: %v", p_uuid, err)
 		return
 	}
 
 	upsertSQL := `
-		INSERT INTO productos (uuid, created_at, updated_at, deleted_at, nombre, codigo, precio_venta)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO productos (uuid, created_at, updated_at, deleted_at, nombre, codigo, precio_venta, impuesto_codigo, categoria, costo_promedio, controlado, requiere_formula,
+			principio_activo, concentracion, forma_farmaceutica, laboratorio, registro_invima, vencimiento_invima, codigo_atc)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 		ON CONFLICT (codigo) DO UPDATE SET
 			nombre = EXCLUDED.nombre, 
 			precio_venta = EXCLUDED.precio_venta,
+			impuesto_codigo = EXCLUDED.impuesto_codigo,
+			categoria = EXCLUDED.categoria,
+			costo_promedio = EXCLUDED.costo_promedio,
+			controlado = EXCLUDED.controlado,
+			requiere_formula = EXCLUDED.requiere_formula,
+			principio_activo = EXCLUDED.principio_activo,
+			concentracion = EXCLUDED.concentracion,
+			forma_farmaceutica = EXCLUDED.forma_farmaceutica,
+			laboratorio = EXCLUDED.laboratorio,
+			registro_invima = EXCLUDED.registro_invima,
+			vencimiento_invima = EXCLUDED.vencimiento_invima,
+			codigo_atc = EXCLUDED.codigo_atc,
 			updated_at = EXCLUDED.updated_at, 
 			deleted_at = EXCLUDED.deleted_at;`
 
-	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, p.UUID, p.CreatedAt, p.UpdatedAt, p.DeletedAt, p.Nombre, p.Codigo, p.PrecioVenta)
+	if p.ImpuestoCodigo == "" {
+		p.ImpuestoCodigo = ImpuestoPorDefecto
+	}
+	args := append([]any{p.UUID, p.CreatedAt, p.UpdatedAt, p.DeletedAt, p.Nombre, p.Codigo, p.PrecioVenta, p.ImpuestoCodigo, nullableString(p.Categoria), p.CostoPromedio, p.Controlado, p.RequiereFormula},
+		p.DatosFarmaceuticos.valores()...)
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, args...)
 	if err != nil {
 		d.Log.Errorf("Error en UPSERT de datos maestros del producto remoto UUID %s: %v", p_uuid, err)
 		return
@@ -867,6 +1464,449 @@ func (d *Db) syncProveedorToRemote(p_uuid string) {
 	d.Log.Infof("Sincronizado proveedor individual UUID %s hacia el remoto.", p_uuid)
 }
 
+func (d *Db) syncImpuestoToRemote(i_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var i Impuesto
+	query := `SELECT uuid, created_at, updated_at, deleted_at, codigo, COALESCE(nombre, ''), tarifa FROM impuestos WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, i_uuid).Scan(&i.UUID, &i.CreatedAt, &i.UpdatedAt, &i.DeletedAt, &i.Codigo, &i.Nombre, &i.Tarifa)
+	if err != nil {
+		d.Log.Errorf("syncImpuestoToRemote: no se encontró impuesto local UUID %s: %v", i_uuid, err)
+		return
+	}
+
+	upsertSQL := `
+		INSERT INTO impuestos (uuid, created_at, updated_at, deleted_at, codigo, nombre, tarifa)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (codigo) DO UPDATE SET
+			nombre = EXCLUDED.nombre, tarifa = EXCLUDED.tarifa,
+			updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`
+
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, i.UUID, i.CreatedAt, i.UpdatedAt, i.DeletedAt, i.Codigo, i.Nombre, i.Tarifa)
+	if err != nil {
+		d.Log.Errorf("Error en UPSERT de impuesto remoto UUID %s: %v", i_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizado impuesto %s hacia el remoto.", i.Codigo)
+}
+
+func (d *Db) syncCategoriaToRemote(c_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var c CategoriaProducto
+	query := `SELECT uuid, created_at, updated_at, deleted_at, nombre, COALESCE(categoria_padre, '') FROM categorias_producto WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, c_uuid).Scan(&c.UUID, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt, &c.Nombre, &c.CategoriaPadre)
+	if err != nil {
+		d.Log.Errorf("syncCategoriaToRemote: no se encontró categoría local UUID %s: %v", c_uuid, err)
+		return
+	}
+
+	upsertSQL := `
+		INSERT INTO categorias_producto (uuid, created_at, updated_at, deleted_at, nombre, categoria_padre)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (nombre) DO UPDATE SET
+			categoria_padre = EXCLUDED.categoria_padre,
+			updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`
+
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, c.UUID, c.CreatedAt, c.UpdatedAt, c.DeletedAt, c.Nombre, nullableString(c.CategoriaPadre))
+	if err != nil {
+		d.Log.Errorf("Error en UPSERT de categoría remota UUID %s: %v", c_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizada categoría %s hacia el remoto.", c.Nombre)
+}
+
+func (d *Db) syncReglaPuntosToRemote(r_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var r ReglaPuntos
+	query := `SELECT uuid, created_at, updated_at, deleted_at, codigo, COALESCE(nombre, ''), COALESCE(categoria, ''), monto_por_punto FROM reglas_puntos WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, r_uuid).Scan(&r.UUID, &r.CreatedAt, &r.UpdatedAt, &r.DeletedAt, &r.Codigo, &r.Nombre, &r.Categoria, &r.MontoPorPunto)
+	if err != nil {
+		d.Log.Errorf("syncReglaPuntosToRemote: no se encontró regla de puntos local UUID %s: %v", r_uuid, err)
+		return
+	}
+
+	upsertSQL := `
+		INSERT INTO reglas_puntos (uuid, created_at, updated_at, deleted_at, codigo, nombre, categoria, monto_por_punto)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (codigo) DO UPDATE SET
+			nombre = EXCLUDED.nombre, categoria = EXCLUDED.categoria, monto_por_punto = EXCLUDED.monto_por_punto,
+			updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`
+
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, r.UUID, r.CreatedAt, r.UpdatedAt, r.DeletedAt, r.Codigo, r.Nombre, nullableString(r.Categoria), r.MontoPorPunto)
+	if err != nil {
+		d.Log.Errorf("Error en UPSERT de regla de puntos remota UUID %s: %v", r_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizada regla de puntos %s hacia el remoto.", r.Codigo)
+}
+
+func (d *Db) syncPresentacionToRemote(p_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var pp PresentacionProducto
+	query := `SELECT uuid, created_at, updated_at, deleted_at, producto_uuid, nombre, factor, precio_venta, COALESCE(codigo_barras, '') FROM presentaciones_producto WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, p_uuid).Scan(&pp.UUID, &pp.CreatedAt, &pp.UpdatedAt, &pp.DeletedAt, &pp.ProductoUUID, &pp.Nombre, &pp.Factor, &pp.PrecioVenta, &pp.CodigoBarras)
+	if err != nil {
+		d.Log.Errorf("syncPresentacionToRemote: no se encontró presentación local UUID %s: %v", p_uuid, err)
+		return
+	}
+
+	// La presentación referencia al producto en remoto
+	d.syncProductoToRemote(pp.ProductoUUID)
+
+	upsertSQL := `
+		INSERT INTO presentaciones_producto (uuid, created_at, updated_at, deleted_at, producto_uuid, nombre, factor, precio_venta, codigo_barras)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (uuid) DO UPDATE SET
+			nombre = EXCLUDED.nombre, factor = EXCLUDED.factor, precio_venta = EXCLUDED.precio_venta, codigo_barras = EXCLUDED.codigo_barras,
+			updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`
+
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, pp.UUID, pp.CreatedAt, pp.UpdatedAt, pp.DeletedAt, pp.ProductoUUID, pp.Nombre, pp.Factor, pp.PrecioVenta, nullableString(pp.CodigoBarras))
+	if err != nil {
+		d.Log.Errorf("Error en UPSERT de presentación remota UUID %s: %v", p_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizada presentación %s hacia el remoto.", pp.Nombre)
+}
+
+func (d *Db) syncCodigoBarrasToRemote(c_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var cb CodigoBarrasProducto
+	query := `SELECT uuid, created_at, updated_at, deleted_at, codigo, producto_uuid, COALESCE(presentacion_uuid, ''), COALESCE(descripcion, '') FROM codigos_barras_producto WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, c_uuid).Scan(&cb.UUID, &cb.CreatedAt, &cb.UpdatedAt, &cb.DeletedAt, &cb.Codigo, &cb.ProductoUUID, &cb.PresentacionUUID, &cb.Descripcion)
+	if err != nil {
+		d.Log.Errorf("syncCodigoBarrasToRemote: no se encontró código de barras local UUID %s: %v", c_uuid, err)
+		return
+	}
+
+	// El código referencia al producto y, si la tiene, a la presentación en remoto
+	if cb.PresentacionUUID != "" {
+		d.syncPresentacionToRemote(cb.PresentacionUUID)
+	} else {
+		d.syncProductoToRemote(cb.ProductoUUID)
+	}
+
+	upsertSQL := `
+		INSERT INTO codigos_barras_producto (uuid, created_at, updated_at, deleted_at, codigo, producto_uuid, presentacion_uuid, descripcion)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (uuid) DO UPDATE SET
+			codigo = EXCLUDED.codigo, producto_uuid = EXCLUDED.producto_uuid, presentacion_uuid = EXCLUDED.presentacion_uuid,
+			descripcion = EXCLUDED.descripcion, updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`
+
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, cb.UUID, cb.CreatedAt, cb.UpdatedAt, cb.DeletedAt, cb.Codigo, cb.ProductoUUID, nullableString(cb.PresentacionUUID), nullableString(cb.Descripcion))
+	if err != nil {
+		d.Log.Errorf("Error en UPSERT de código de barras remoto UUID %s: %v", c_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizado código de barras %s hacia el remoto.", cb.Codigo)
+}
+
+func (d *Db) syncLoteToRemote(l_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var l Lote
+	query := `SELECT uuid, created_at, updated_at, deleted_at, producto_uuid, numero_lote, strftime('%Y-%m-%d', fecha_vencimiento), estado, COALESCE(observacion, '') FROM lotes WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, l_uuid).Scan(&l.UUID, &l.CreatedAt, &l.UpdatedAt, &l.DeletedAt, &l.ProductoUUID, &l.NumeroLote, &l.FechaVencimiento, &l.Estado, &l.Observacion)
+	if err != nil {
+		d.Log.Errorf("syncLoteToRemote: no se encontró lote local UUID %s: %v", l_uuid, err)
+		return
+	}
+
+	// El lote referencia al producto en remoto
+	d.syncProductoToRemote(l.ProductoUUID)
+
+	upsertSQL := `
+		INSERT INTO lotes (uuid, created_at, updated_at, deleted_at, producto_uuid, numero_lote, fecha_vencimiento, estado, observacion)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (uuid) DO UPDATE SET
+			numero_lote = EXCLUDED.numero_lote, fecha_vencimiento = EXCLUDED.fecha_vencimiento,
+			estado = EXCLUDED.estado, observacion = EXCLUDED.observacion,
+			updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`
+
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, l.UUID, l.CreatedAt, l.UpdatedAt, l.DeletedAt, l.ProductoUUID, l.NumeroLote, l.FechaVencimiento, l.Estado, nullableString(l.Observacion))
+	if err != nil {
+		d.Log.Errorf("Error en UPSERT de lote remoto UUID %s: %v", l_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizado lote %s hacia el remoto.", l.NumeroLote)
+}
+
+func (d *Db) syncFormulaToRemote(f_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var f Formula
+	query := `SELECT uuid, created_at, updated_at, deleted_at, numero_formula, strftime('%Y-%m-%d', fecha_formula), medico, registro_medico, paciente_id, COALESCE(paciente_nombre, ''), COALESCE(cliente_uuid, '') FROM formulas_medicas WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, f_uuid).Scan(&f.UUID, &f.CreatedAt, &f.UpdatedAt, &f.DeletedAt, &f.NumeroFormula, &f.FechaFormula, &f.Medico, &f.RegistroMedico, &f.PacienteID, &f.PacienteNombre, &f.ClienteUUID)
+	if err != nil {
+		d.Log.Errorf("syncFormulaToRemote: no se encontró fórmula local UUID %s: %v", f_uuid, err)
+		return
+	}
+
+	rows, err := d.LocalDB.QueryContext(d.ctx, `SELECT uuid, producto_uuid, cantidad_prescrita FROM items_formula WHERE formula_uuid = ?`, f_uuid)
+	if err != nil {
+		d.Log.Errorf("syncFormulaToRemote: error consultando ítems de la fórmula %s: %v", f_uuid, err)
+		return
+	}
+	var items []ItemFormula
+	for rows.Next() {
+		var it ItemFormula
+		if err := rows.Scan(&it.UUID, &it.ProductoUUID, &it.CantidadPrescrita); err != nil {
+			rows.Close()
+			d.Log.Errorf("syncFormulaToRemote: error leyendo ítem de la fórmula %s: %v", f_uuid, err)
+			return
+		}
+		items = append(items, it)
+	}
+	rows.Close()
+
+	// Los ítems se registran junto con la fórmula y no cambian
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		INSERT INTO formulas_medicas (uuid, created_at, updated_at, deleted_at, numero_formula, fecha_formula, medico, registro_medico, paciente_id, paciente_nombre, cliente_uuid)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (uuid) DO UPDATE SET
+			medico = EXCLUDED.medico, registro_medico = EXCLUDED.registro_medico,
+			paciente_id = EXCLUDED.paciente_id, paciente_nombre = EXCLUDED.paciente_nombre,
+			updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`,
+		f.UUID, f.CreatedAt, f.UpdatedAt, f.DeletedAt, f.NumeroFormula, f.FechaFormula, f.Medico, f.RegistroMedico,
+		f.PacienteID, nullableString(f.PacienteNombre), nullableString(f.ClienteUUID))
+	for _, it := range items {
+		batch.Queue(`
+			INSERT INTO items_formula (uuid, formula_uuid, producto_uuid, cantidad_prescrita, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (uuid) DO NOTHING;`,
+			it.UUID, f.UUID, it.ProductoUUID, it.CantidadPrescrita, f.CreatedAt, f.CreatedAt)
+	}
+	if err := d.RemoteDB.SendBatch(d.ctx, batch).Close(); err != nil {
+		d.Log.Errorf("Error en UPSERT de fórmula remota UUID %s: %v", f_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizada fórmula %s hacia el remoto.", f.NumeroFormula)
+}
+
+func (d *Db) syncNivelReordenToRemote(n_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var n NivelReorden
+	var createdAt, updatedAt time.Time
+	var deletedAt *time.Time
+	query := `SELECT uuid, created_at, updated_at, deleted_at, producto_uuid, automatico, stock_minimo, stock_maximo, COALESCE(proveedor_uuid, '') FROM niveles_reorden WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, n_uuid).Scan(&n.UUID, &createdAt, &updatedAt, &deletedAt, &n.ProductoUUID, &n.Automatico, &n.StockMinimo, &n.StockMaximo, &n.ProveedorUUID)
+	if err != nil {
+		d.Log.Errorf("syncNivelReordenToRemote: no se encontraron niveles de reorden locales UUID %s: %v", n_uuid, err)
+		return
+	}
+
+	// Los niveles referencian al producto en remoto
+	d.syncProductoToRemote(n.ProductoUUID)
+
+	upsertSQL := `
+		INSERT INTO niveles_reorden (uuid, created_at, updated_at, deleted_at, producto_uuid, automatico, stock_minimo, stock_maximo, proveedor_uuid)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (producto_uuid) DO UPDATE SET
+			automatico = EXCLUDED.automatico, stock_minimo = EXCLUDED.stock_minimo, stock_maximo = EXCLUDED.stock_maximo,
+			proveedor_uuid = EXCLUDED.proveedor_uuid, updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`
+
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, n.UUID, createdAt, updatedAt, deletedAt, n.ProductoUUID, n.Automatico, n.StockMinimo, n.StockMaximo, nullableString(n.ProveedorUUID))
+	if err != nil {
+		d.Log.Errorf("Error en UPSERT de niveles de reorden remotos UUID %s: %v", n_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizados niveles de reorden del producto %s hacia el remoto.", n.ProductoUUID)
+}
+
+func (d *Db) syncPedidoProveedorToRemote(p_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var p PedidoProveedor
+	query := `SELECT uuid, created_at, updated_at, deleted_at, proveedor_uuid, vendedor_uuid, fecha, strftime('%Y-%m-%d', fecha_esperada), estado, COALESCE(compra_uuid, ''), COALESCE(observacion, '') FROM pedidos_proveedor WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, p_uuid).Scan(&p.UUID, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt, &p.ProveedorUUID, &p.VendedorUUID, &p.Fecha, &p.FechaEsperada, &p.Estado, &p.CompraUUID, &p.Observacion)
+	if err != nil {
+		d.Log.Errorf("syncPedidoProveedorToRemote: no se encontró pedido local UUID %s: %v", p_uuid, err)
+		return
+	}
+
+	rows, err := d.LocalDB.QueryContext(d.ctx, `SELECT uuid, producto_uuid, cantidad, costo_unitario FROM items_pedido_proveedor WHERE pedido_uuid = ?`, p_uuid)
+	if err != nil {
+		d.Log.Errorf("syncPedidoProveedorToRemote: error consultando ítems del pedido %s: %v", p_uuid, err)
+		return
+	}
+	var items []ItemPedidoProveedor
+	for rows.Next() {
+		var it ItemPedidoProveedor
+		if err := rows.Scan(&it.UUID, &it.ProductoUUID, &it.Cantidad, &it.CostoUnitario); err != nil {
+			rows.Close()
+			d.Log.Errorf("syncPedidoProveedorToRemote: error leyendo ítem del pedido %s: %v", p_uuid, err)
+			return
+		}
+		items = append(items, it)
+	}
+	rows.Close()
+
+	// Los ítems se registran junto con el pedido y no cambian; del pedido solo cambia el estado
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		INSERT INTO pedidos_proveedor (uuid, created_at, updated_at, deleted_at, proveedor_uuid, vendedor_uuid, fecha, fecha_esperada, estado, compra_uuid, observacion)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (uuid) DO UPDATE SET
+			estado = EXCLUDED.estado, compra_uuid = EXCLUDED.compra_uuid,
+			updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`,
+		p.UUID, p.CreatedAt, p.UpdatedAt, p.DeletedAt, p.ProveedorUUID, p.VendedorUUID, p.Fecha, p.FechaEsperada, p.Estado,
+		nullableString(p.CompraUUID), nullableString(p.Observacion))
+	for _, it := range items {
+		batch.Queue(`
+			INSERT INTO items_pedido_proveedor (uuid, pedido_uuid, producto_uuid, cantidad, costo_unitario, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (uuid) DO NOTHING;`,
+			it.UUID, p.UUID, it.ProductoUUID, it.Cantidad, it.CostoUnitario, p.CreatedAt, p.CreatedAt)
+	}
+	if err := d.RemoteDB.SendBatch(d.ctx, batch).Close(); err != nil {
+		d.Log.Errorf("Error en UPSERT de pedido remoto UUID %s: %v", p_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizado pedido %s hacia el remoto.", p.UUID)
+}
+
+func (d *Db) syncConteoInventarioToRemote(c_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var c ConteoInventario
+	query := `SELECT uuid, created_at, updated_at, deleted_at, descripcion, COALESCE(categoria, ''), estado, vendedor_uuid, COALESCE(aprobado_por, ''), fecha_apertura, fecha_cierre, COALESCE(observacion, '') FROM conteos_inventario WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, c_uuid).Scan(&c.UUID, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt, &c.Descripcion, &c.Categoria, &c.Estado, &c.VendedorUUID, &c.AprobadoPor, &c.FechaApertura, &c.FechaCierre, &c.Observacion)
+	if err != nil {
+		d.Log.Errorf("syncConteoInventarioToRemote: no se encontró conteo local UUID %s: %v", c_uuid, err)
+		return
+	}
+
+	type itemEsperado struct {
+		uuid, productoUUID, loteUUID string
+		stock                        int
+		costo                        float64
+	}
+	rows, err := d.LocalDB.QueryContext(d.ctx, `SELECT uuid, producto_uuid, lote_uuid, stock_esperado, costo_unitario FROM items_conteo_inventario WHERE conteo_uuid = ?`, c_uuid)
+	if err != nil {
+		d.Log.Errorf("syncConteoInventarioToRemote: error consultando ítems del conteo %s: %v", c_uuid, err)
+		return
+	}
+	var items []itemEsperado
+	for rows.Next() {
+		var it itemEsperado
+		if err := rows.Scan(&it.uuid, &it.productoUUID, &it.loteUUID, &it.stock, &it.costo); err != nil {
+			rows.Close()
+			d.Log.Errorf("syncConteoInventarioToRemote: error leyendo ítem del conteo %s: %v", c_uuid, err)
+			return
+		}
+		items = append(items, it)
+	}
+	rows.Close()
+
+	// Lo esperado se fija al abrir el conteo y no cambia; del conteo solo cambian estado y cierre, y un
+	// conteo ya cerrado en el remoto no se sobrescribe (la aprobación se reserva allí)
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		INSERT INTO conteos_inventario (uuid, created_at, updated_at, deleted_at, descripcion, categoria, estado, vendedor_uuid, aprobado_por, fecha_apertura, fecha_cierre, observacion)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (uuid) DO UPDATE SET
+			estado = EXCLUDED.estado, aprobado_por = EXCLUDED.aprobado_por, fecha_cierre = EXCLUDED.fecha_cierre,
+			observacion = EXCLUDED.observacion, updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at
+		WHERE conteos_inventario.estado = $13;`,
+		c.UUID, c.CreatedAt, c.UpdatedAt, c.DeletedAt, c.Descripcion, nullableString(c.Categoria), c.Estado, c.VendedorUUID,
+		nullableString(c.AprobadoPor), c.FechaApertura, c.FechaCierre, nullableString(c.Observacion), ConteoAbierto)
+	for _, it := range items {
+		batch.Queue(`
+			INSERT INTO items_conteo_inventario (uuid, conteo_uuid, producto_uuid, lote_uuid, stock_esperado, costo_unitario, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (uuid) DO NOTHING;`,
+			it.uuid, c.UUID, it.productoUUID, it.loteUUID, it.stock, it.costo, c.CreatedAt, c.CreatedAt)
+	}
+	if err := d.RemoteDB.SendBatch(d.ctx, batch).Close(); err != nil {
+		d.Log.Errorf("Error en UPSERT de conteo remoto UUID %s: %v", c_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizado conteo %s hacia el remoto.", c.Descripcion)
+}
+
+func (d *Db) syncRegistroConteoToRemote(r_uuid string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	var r RegistroConteo
+	var updatedAt time.Time
+	var deletedAt *time.Time
+	query := `SELECT uuid, created_at, updated_at, deleted_at, conteo_uuid, producto_uuid, lote_uuid, cantidad, vendedor_uuid, COALESCE(dispositivo, '') FROM registros_conteo_inventario WHERE uuid = ?`
+	err := d.LocalDB.QueryRowContext(d.ctx, query, r_uuid).Scan(&r.UUID, &r.CreatedAt, &updatedAt, &deletedAt, &r.ConteoUUID, &r.ProductoUUID, &r.LoteUUID, &r.Cantidad, &r.VendedorUUID, &r.Dispositivo)
+	if err != nil {
+		d.Log.Errorf("syncRegistroConteoToRemote: no se encontró registro de conteo local UUID %s: %v", r_uuid, err)
+		return
+	}
+
+	upsertSQL := `
+		INSERT INTO registros_conteo_inventario (uuid, created_at, updated_at, deleted_at, conteo_uuid, producto_uuid, lote_uuid, cantidad, vendedor_uuid, dispositivo)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (uuid) DO UPDATE SET
+			updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at;`
+
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, r.UUID, r.CreatedAt, updatedAt, deletedAt, r.ConteoUUID, r.ProductoUUID, r.LoteUUID, r.Cantidad, r.VendedorUUID, nullableString(r.Dispositivo))
+	if err != nil {
+		d.Log.Errorf("Error en UPSERT de registro de conteo remoto UUID %s: %v", r_uuid, err)
+		return
+	}
+	d.Log.Infof("Sincronizado registro de conteo %s hacia el remoto.", r.UUID)
+}
+
+func (d *Db) syncFacturaElectronicaToRemote(facturaUUID string) {
+	if !d.isRemoteDBAvailable() {
+		d.Log.Warn("[REMOTO] la base de datos no está disponible, se omite la sincronización.")
+		return
+	}
+	fe, err := d.ObtenerFacturaElectronica(facturaUUID)
+	if err != nil {
+		d.Log.Errorf("syncFacturaElectronicaToRemote: no se encontró factura electrónica local de la factura %s: %v", facturaUUID, err)
+		return
+	}
+
+	upsertSQL := `
+		INSERT INTO facturas_electronicas (uuid, factura_uuid, numero_factura, cufe, qr, xml, ambiente, estado, mensaje_dian, fecha_envio, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (factura_uuid) DO UPDATE SET
+			numero_factura = EXCLUDED.numero_factura, cufe = EXCLUDED.cufe, qr = EXCLUDED.qr, xml = EXCLUDED.xml,
+			ambiente = EXCLUDED.ambiente, estado = EXCLUDED.estado, mensaje_dian = EXCLUDED.mensaje_dian,
+			fecha_envio = EXCLUDED.fecha_envio, updated_at = EXCLUDED.updated_at
+		WHERE EXCLUDED.updated_at > facturas_electronicas.updated_at;`
+
+	_, err = d.RemoteDB.Exec(d.ctx, upsertSQL, fe.UUID, fe.FacturaUUID, fe.NumeroFactura, fe.CUFE, nullableString(fe.QR), fe.XML,
+		fe.Ambiente, fe.Estado, nullableString(fe.MensajeDIAN), fe.FechaEnvio, fe.CreatedAt, fe.UpdatedAt)
+	if err != nil {
+		d.Log.Errorf("Error en UPSERT de factura electrónica remota %s: %v", fe.NumeroFactura, err)
+		return
+	}
+	d.Log.Infof("Sincronizada factura electrónica %s hacia el remoto.", fe.NumeroFactura)
+}
+
 // syncVentaToRemote: sincroniza una factura + detalles + operaciones de stock relacionadas
 // de forma atómica usando la estrategia EAFP (Es más fácil pedir perdón que permiso).
 func (d *Db) syncVentaToRemote(facturaUUID string) error {
@@ -879,10 +1919,16 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 	// 1a) Obtener factura local
 	var f Factura
 	err := d.LocalDB.QueryRowContext(ctx, `
-		SELECT uuid, numero_factura, fecha_emision, vendedor_uuid, cliente_uuid, subtotal, iva, total, estado, metodo_pago, created_at, updated_at
+		SELECT uuid, numero_factura, fecha_emision, vendedor_uuid, cliente_uuid, subtotal, iva, total, estado, metodo_pago,
+			COALESCE(motivo_anulacion, ''), fecha_anulacion, COALESCE(anulada_por_uuid, ''), COALESCE(sesion_caja_anulacion_uuid, ''),
+			valor_bruto, descuento, descuento_factura, COALESCE(motivo_descuento, ''), COALESCE(descuento_autorizado_por, ''),
+			COALESCE(sesion_caja_uuid, ''), COALESCE(formula_uuid, ''), created_at, updated_at
 		FROM facturas WHERE uuid = ?`, facturaUUID).Scan(
 		&f.UUID, &f.NumeroFactura, &f.FechaEmision, &f.VendedorUUID, &f.ClienteUUID,
-		&f.Subtotal, &f.IVA, &f.Total, &f.Estado, &f.MetodoPago, &f.CreatedAt, &f.UpdatedAt)
+		&f.Subtotal, &f.IVA, &f.Total, &f.Estado, &f.MetodoPago,
+		&f.MotivoAnulacion, &f.FechaAnulacion, &f.AnuladaPorUUID, &f.SesionCajaAnulacion,
+		&f.ValorBruto, &f.Descuento, &f.DescuentoFactura, &f.MotivoDescuento, &f.DescuentoAutorizadoPor,
+		&f.SesionCajaUUID, &f.FormulaUUID, &f.CreatedAt, &f.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			d.Log.Warnf("[LOCAL] - No se encontró la factura UUID [%s] para sincronizar. Omitiendo.", facturaUUID)
@@ -894,14 +1940,20 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 	// 1b) Obtener detalles locales
 	var detallesLocales []DetalleFactura
 	rowsDetalles, err := d.LocalDB.QueryContext(ctx, `
-		SELECT uuid, factura_uuid, producto_uuid, cantidad, precio_unitario, precio_total, created_at, updated_at 
+		SELECT uuid, factura_uuid, producto_uuid, cantidad, precio_unitario, precio_total,
+			COALESCE(impuesto_codigo, ''), base_impuesto, tarifa_impuesto, valor_impuesto,
+			valor_bruto, descuento, COALESCE(motivo_descuento, ''), COALESCE(presentacion_uuid, ''),
+			COALESCE(presentacion, ''), factor_conversion, costo_unitario, created_at, updated_at
 		FROM detalle_facturas WHERE factura_uuid = ?`, facturaUUID)
 	if err != nil {
 		return fmt.Errorf("error obteniendo detalles locales: %w", err)
 	}
 	for rowsDetalles.Next() {
 		var df DetalleFactura
-		if err := rowsDetalles.Scan(&df.UUID, &df.FacturaUUID, &df.ProductoUUID, &df.Cantidad, &df.PrecioUnitario, &df.PrecioTotal, &df.CreatedAt, &df.UpdatedAt); err != nil {
+		if err := rowsDetalles.Scan(&df.UUID, &df.FacturaUUID, &df.ProductoUUID, &df.Cantidad, &df.PrecioUnitario, &df.PrecioTotal,
+			&df.ImpuestoCodigo, &df.BaseImpuesto, &df.TarifaImpuesto, &df.ValorImpuesto,
+			&df.ValorBruto, &df.Descuento, &df.MotivoDescuento, &df.PresentacionUUID, &df.Presentacion, &df.FactorConversion, &df.CostoUnitario,
+			&df.CreatedAt, &df.UpdatedAt); err != nil {
 			d.Log.Errorf("Error al escanear detalle_factura local: %v", err)
 			rowsDetalles.Close()
 			return err
@@ -910,10 +1962,24 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 	}
 	rowsDetalles.Close()
 
+	// 1b.2) Obtener desglose de impuestos y pagos locales
+	impuestosLocales, err := d.obtenerImpuestosFactura(facturaUUID)
+	if err != nil {
+		return err
+	}
+	pagosLocales, err := d.obtenerPagosFactura(facturaUUID)
+	if err != nil {
+		return err
+	}
+	cambiosPrecioLocales, err := d.obtenerCambiosPrecioFactura(facturaUUID)
+	if err != nil {
+		return err
+	}
+
 	// 1c) Obtener operaciones de stock locales
 	var operacionesLocales []OperacionStock
 	rowsOps, err := d.LocalDB.QueryContext(ctx, `
-		SELECT uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, timestamp 
+		SELECT uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, COALESCE(lote_uuid, ''), timestamp 
 		FROM operacion_stocks WHERE factura_uuid = ?`, facturaUUID)
 	if err != nil {
 		return fmt.Errorf("error obteniendo operaciones de stock locales: %w", err)
@@ -921,7 +1987,7 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 	for rowsOps.Next() {
 		var op OperacionStock
 		var stockResultante sql.NullInt64
-		if err := rowsOps.Scan(&op.UUID, &op.ProductoUUID, &op.TipoOperacion, &op.CantidadCambio, &stockResultante, &op.VendedorUUID, &op.FacturaUUID, &op.Timestamp); err != nil {
+		if err := rowsOps.Scan(&op.UUID, &op.ProductoUUID, &op.TipoOperacion, &op.CantidadCambio, &stockResultante, &op.VendedorUUID, &op.FacturaUUID, &op.LoteUUID, &op.Timestamp); err != nil {
 			d.Log.Errorf("Error al escanear operacion_stock local: %v", err)
 			rowsOps.Close()
 			return err
@@ -930,6 +1996,11 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 	}
 	rowsOps.Close()
 
+	// La factura referencia a su fórmula médica en remoto
+	if f.FormulaUUID != "" {
+		d.syncFormulaToRemote(f.FormulaUUID)
+	}
+
 	// --- 2) INICIAR TRANSACCIÓN REMOTA ATÓMICA ---
 	rtx, err := d.RemoteDB.Begin(ctx)
 	if err != nil {
@@ -947,13 +2018,28 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 	var needsLocalUpdate bool
 	finalNumeroFactura := f.NumeroFactura
 
+	// Si la factura ya existe (mismo UUID) solo se actualiza su estado, lo que permite
+	// propagar anulaciones por el mismo camino que la venta original.
 	insertFacturaSQL := `
-		INSERT INTO facturas (uuid, numero_factura, fecha_emision, vendedor_uuid, cliente_uuid, subtotal, iva, total, estado, metodo_pago, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO facturas (uuid, numero_factura, fecha_emision, vendedor_uuid, cliente_uuid, subtotal, iva, total, estado, metodo_pago,
+			motivo_anulacion, fecha_anulacion, anulada_por_uuid, sesion_caja_anulacion_uuid,
+			valor_bruto, descuento, descuento_factura, motivo_descuento, descuento_autorizado_por, sesion_caja_uuid, formula_uuid, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		ON CONFLICT (uuid) DO UPDATE SET
+			estado = EXCLUDED.estado,
+			motivo_anulacion = EXCLUDED.motivo_anulacion,
+			fecha_anulacion = EXCLUDED.fecha_anulacion,
+			anulada_por_uuid = EXCLUDED.anulada_por_uuid,
+			sesion_caja_anulacion_uuid = EXCLUDED.sesion_caja_anulacion_uuid,
+			updated_at = EXCLUDED.updated_at
+		WHERE EXCLUDED.updated_at > facturas.updated_at
 	`
 	_, err = rtx.Exec(ctx, insertFacturaSQL,
 		f.UUID, f.NumeroFactura, f.FechaEmision, f.VendedorUUID, f.ClienteUUID,
-		f.Subtotal, f.IVA, f.Total, f.Estado, f.MetodoPago, f.CreatedAt, f.UpdatedAt,
+		f.Subtotal, f.IVA, f.Total, f.Estado, f.MetodoPago,
+		nullableString(f.MotivoAnulacion), f.FechaAnulacion, nullableString(f.AnuladaPorUUID), nullableString(f.SesionCajaAnulacion),
+		f.ValorBruto, f.Descuento, f.DescuentoFactura, nullableString(f.MotivoDescuento), nullableString(f.DescuentoAutorizadoPor),
+		nullableString(f.SesionCajaUUID), nullableString(f.FormulaUUID), f.CreatedAt, f.UpdatedAt,
 	)
 
 	if err == nil {
@@ -964,33 +2050,59 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // 23505 = unique_violation
 
 			switch pgErr.ConstraintName {
-			case UUIDConstraintName:
-				d.Log.Infof("[REMOTO] - Factura UUID [%s] ya existe. No se insertará, pero se marcará como sincronizada.", facturaUUID)
-				facturaFueInsertada = false
-
 			case NumeroFacturaConstraintName:
 				d.Log.Warnf("[REMOTO] - Colisión de numero_factura [%s]. Buscando nuevo número...", f.NumeroFactura)
+				// Con el CUFE calculado el número ya quedó en el XML: renumerar dejaría un documento
+				// que no corresponde a la factura. Se marca para que un administrador lo resuelva.
+				var cufe string
+				errCufe := d.LocalDB.QueryRowContext(ctx, `SELECT cufe FROM facturas_electronicas WHERE factura_uuid = ?`, f.UUID).Scan(&cufe)
+				if errCufe == nil {
+					motivo := fmt.Sprintf("el número %s ya existe en el servidor y la factura ya tiene CUFE %s: no se puede renumerar", f.NumeroFactura, cufe)
+					if errMarca := d.marcarRevisionManual(f.UUID, motivo); errMarca != nil {
+						d.Log.Errorf("[REMOTO] - %v", errMarca)
+					}
+					d.Log.Errorf("[REMOTO] - Factura %s requiere revisión manual: %s", f.UUID, motivo)
+					return fmt.Errorf("[REMOTO] - colisión de numero_factura '%s' en factura con CUFE, requiere revisión manual", f.NumeroFactura)
+				}
+				if !errors.Is(errCufe, sql.ErrNoRows) {
+					return fmt.Errorf("error consultando CUFE de la factura %s tras colisión: %w", f.NumeroFactura, errCufe)
+				}
 				prefix, _, parseErr := parseNumeroFactura(f.NumeroFactura)
 				if parseErr != nil {
 					return fmt.Errorf("[REMOTO] - Colisión de numero_factura ('%s') formato inválido: %w", f.NumeroFactura, parseErr)
 				}
+				// El nuevo número sale después de los rangos ya reservados a las terminales,
+				// para no invadir la numeración que otra terminal puede estar usando sin conexión.
 				var maxNum int
 				maxQuery := `
-					SELECT COALESCE(MAX(CAST(SUBSTRING(numero_factura FROM '(\d+)$') AS INTEGER)), 0)
-					FROM facturas 
-					WHERE numero_factura LIKE $1`
+					SELECT GREATEST(
+						COALESCE((SELECT MAX(CAST(SUBSTRING(numero_factura FROM '(\d+)$') AS INTEGER)) FROM facturas WHERE numero_factura LIKE $1), 0),
+						COALESCE((SELECT siguiente_numero - 1 FROM series_facturacion WHERE prefijo = $2), 0)
+					)`
 
-				if errMax := rtx.QueryRow(ctx, maxQuery, prefix+"%").Scan(&maxNum); errMax != nil {
+				if errMax := rtx.QueryRow(ctx, maxQuery, prefix+"%", prefix).Scan(&maxNum); errMax != nil {
 					return fmt.Errorf("error obteniendo max numero_factura tras colisión: %w", errMax)
 				}
 
 				newNum := maxNum + 1
+				resolucion := d.configDIAN
+				if resolucion.RangoHasta > 0 && (int64(newNum) < resolucion.RangoDesde || int64(newNum) > resolucion.RangoHasta) {
+					return fmt.Errorf("[REMOTO] - colisión de numero_factura '%s': el número %d está fuera del rango de la resolución %s (%d a %d)",
+						f.NumeroFactura, newNum, resolucion.NumeroResolucion, resolucion.RangoDesde, resolucion.RangoHasta)
+				}
+				if _, errSerie := rtx.Exec(ctx, `UPDATE series_facturacion SET siguiente_numero = $1, updated_at = NOW() WHERE prefijo = $2 AND siguiente_numero <= $1`,
+					newNum+1, prefix); errSerie != nil {
+					return fmt.Errorf("error avanzando serie %s tras colisión: %w", prefix, errSerie)
+				}
 				numeroParaInsertar := fmt.Sprintf("%s%d", prefix, newNum)
 				d.Log.Infof("[REMOTO] - Nuevo número asignado: %s", numeroParaInsertar)
 
 				_, errInsert2 := rtx.Exec(ctx, insertFacturaSQL,
 					f.UUID, numeroParaInsertar, f.FechaEmision, f.VendedorUUID, f.ClienteUUID,
-					f.Subtotal, f.IVA, f.Total, f.Estado, f.MetodoPago, f.CreatedAt, f.UpdatedAt,
+					f.Subtotal, f.IVA, f.Total, f.Estado, f.MetodoPago,
+					nullableString(f.MotivoAnulacion), f.FechaAnulacion, nullableString(f.AnuladaPorUUID), nullableString(f.SesionCajaAnulacion),
+					f.ValorBruto, f.Descuento, f.DescuentoFactura, nullableString(f.MotivoDescuento), nullableString(f.DescuentoAutorizadoPor),
+					nullableString(f.SesionCajaUUID), nullableString(f.FormulaUUID), f.CreatedAt, f.UpdatedAt,
 				)
 
 				if errInsert2 != nil {
@@ -1019,12 +2131,42 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 		batchDetalles := &pgx.Batch{}
 		for _, df := range detallesLocales {
 			batchDetalles.Queue(`
-				INSERT INTO detalle_facturas (uuid, factura_uuid, producto_uuid, cantidad, precio_unitario, precio_total, created_at, updated_at)
-				VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+				INSERT INTO detalle_facturas (uuid, factura_uuid, producto_uuid, cantidad, precio_unitario, precio_total,
+					impuesto_codigo, base_impuesto, tarifa_impuesto, valor_impuesto, valor_bruto, descuento, motivo_descuento,
+					presentacion_uuid, presentacion, factor_conversion, costo_unitario, created_at, updated_at)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)
 				ON CONFLICT (uuid) DO UPDATE 
 				SET updated_at = EXCLUDED.updated_at
 				WHERE EXCLUDED.updated_at > detalle_facturas.updated_at`,
-				df.UUID, df.FacturaUUID, df.ProductoUUID, df.Cantidad, df.PrecioUnitario, df.PrecioTotal, df.CreatedAt, df.UpdatedAt)
+				df.UUID, df.FacturaUUID, df.ProductoUUID, df.Cantidad, df.PrecioUnitario, df.PrecioTotal,
+				nullableString(df.ImpuestoCodigo), df.BaseImpuesto, df.TarifaImpuesto, df.ValorImpuesto,
+				df.ValorBruto, df.Descuento, nullableString(df.MotivoDescuento),
+				nullableString(df.PresentacionUUID), nullableString(df.Presentacion), df.FactorConversion, df.CostoUnitario, df.CreatedAt, df.UpdatedAt)
+		}
+		for _, fi := range impuestosLocales {
+			batchDetalles.Queue(`
+				INSERT INTO factura_impuestos (uuid, factura_uuid, impuesto_codigo, tarifa, base, valor, created_at, updated_at)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+				ON CONFLICT (uuid) DO NOTHING`,
+				fi.UUID, fi.FacturaUUID, nullableString(fi.ImpuestoCodigo), fi.Tarifa, fi.Base, fi.Valor, fi.CreatedAt, fi.UpdatedAt)
+		}
+		for _, p := range pagosLocales {
+			batchDetalles.Queue(`
+				INSERT INTO pagos_factura (uuid, factura_uuid, metodo_pago, monto, referencia, efectivo_recibido, cambio, created_at, updated_at)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+				ON CONFLICT (uuid) DO UPDATE
+				SET updated_at = EXCLUDED.updated_at
+				WHERE EXCLUDED.updated_at > pagos_factura.updated_at`,
+				p.UUID, p.FacturaUUID, p.MetodoPago, p.Monto, nullableString(p.Referencia), p.EfectivoRecibido, p.Cambio, p.CreatedAt, p.UpdatedAt)
+		}
+		for _, c := range cambiosPrecioLocales {
+			batchDetalles.Queue(`
+				INSERT INTO cambios_precio (uuid, factura_uuid, detalle_factura_uuid, producto_uuid, cantidad, precio_original, precio_nuevo,
+					vendedor_uuid, autorizado_por_uuid, motivo, created_at, updated_at)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+				ON CONFLICT (uuid) DO NOTHING`,
+				c.UUID, c.FacturaUUID, c.DetalleFacturaUUID, c.ProductoUUID, c.Cantidad, c.PrecioOriginal, c.PrecioNuevo,
+				c.VendedorUUID, c.AutorizadoPorUUID, c.Motivo, c.CreatedAt, c.UpdatedAt)
 		}
 
 		br := rtx.SendBatch(ctx, batchDetalles)
@@ -1038,10 +2180,10 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 		batchOps := &pgx.Batch{}
 		for _, op := range operacionesLocales {
 			batchOps.Queue(`
-				INSERT INTO operacion_stocks (uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, timestamp)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				INSERT INTO operacion_stocks (uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, lote_uuid, timestamp)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 				ON CONFLICT (uuid) DO NOTHING`,
-				op.UUID, op.ProductoUUID, op.TipoOperacion, op.CantidadCambio, op.StockResultante, op.VendedorUUID, op.FacturaUUID, op.Timestamp)
+				op.UUID, op.ProductoUUID, op.TipoOperacion, op.CantidadCambio, op.StockResultante, op.VendedorUUID, op.FacturaUUID, nullableString(op.LoteUUID), op.Timestamp)
 		}
 
 		brOps := rtx.SendBatch(ctx, batchOps)
@@ -1079,6 +2221,8 @@ func (d *Db) syncVentaToRemote(facturaUUID string) error {
 	d.Log.Infof("[LOCAL -> REMOTO] - Venta %s y sus hijos sincronizados correctamente.", f.UUID)
 	runtime.EventsEmit(d.ctx, "sync:finish", facturaUUID)
 	go d.SincronizarOperacionesStockHaciaRemoto()
+	go d.SincronizarMovimientosPuntosHaciaRemoto()
+	go d.SincronizarLibroControladosHaciaRemoto()
 	return nil
 }
 
@@ -1092,8 +2236,8 @@ func (d *Db) syncCompraToRemote(c_uuid string) {
 
 	// Obtener compra y detalles desde local
 	var c Compra
-	err := d.LocalDB.QueryRowContext(d.ctx, "SELECT uuid, fecha, proveedor_uuid, factura_numero, total, created_at, updated_at FROM compras WHERE uuid = ?", c_uuid).
-		Scan(&c.UUID, &c.Fecha, &c.ProveedorUUID, &c.FacturaNumero, &c.Total, &c.CreatedAt, &c.UpdatedAt)
+	err := d.LocalDB.QueryRowContext(d.ctx, "SELECT uuid, fecha, proveedor_uuid, COALESCE(vendedor_uuid, ''), COALESCE(factura_numero, ''), total, created_at, updated_at FROM compras WHERE uuid = ?", c_uuid).
+		Scan(&c.UUID, &c.Fecha, &c.ProveedorUUID, &c.VendedorUUID, &c.FacturaNumero, &c.Total, &c.CreatedAt, &c.UpdatedAt)
 	if err != nil {
 		d.Log.Errorf("syncCompraToRemote: no se encontró compra local UUID %s: %v", c_uuid, err)
 		return
@@ -1103,11 +2247,11 @@ func (d *Db) syncCompraToRemote(c_uuid string) {
 	d.syncProveedorToRemote(c.ProveedorUUID)
 
 	// Recolectar detalles
-	rows, err := d.LocalDB.QueryContext(d.ctx, "SELECT producto_uuid, cantidad, precio_compra_unitario FROM detalle_compras WHERE compra_uuid = ?", c_uuid)
+	rows, err := d.LocalDB.QueryContext(d.ctx, "SELECT uuid, producto_uuid, cantidad, precio_compra_unitario, COALESCE(presentacion_uuid, ''), factor_conversion, COALESCE(lote_uuid, '') FROM detalle_compras WHERE compra_uuid = ?", c_uuid)
 	if err == nil {
 		for rows.Next() {
 			var det DetalleCompra
-			if err := rows.Scan(&det.ProductoUUID, &det.Cantidad, &det.PrecioCompraUnitario); err != nil {
+			if err := rows.Scan(&det.UUID, &det.ProductoUUID, &det.Cantidad, &det.PrecioCompraUnitario, &det.PresentacionUUID, &det.FactorConversion, &det.LoteUUID); err != nil {
 				d.Log.Errorf("syncCompraToRemote: error scanning detalle: %v", err)
 				continue
 			}
@@ -1129,10 +2273,10 @@ func (d *Db) syncCompraToRemote(c_uuid string) {
 	}()
 
 	_, err = rtx.Exec(d.ctx, `
-		INSERT INTO compras (fecha, proveedor_uuid, factura_numero, total, created_at, updated_at)
-		VALUES ($1,$2,$3,$4,$5,$6)
-		ON CONFLICT (uuid) DO UPDATE SET fecha = EXCLUDED.fecha, proveedor_uuid=EXCLUDED.proveedor_uuid, factura_numero=EXCLUDED.factura_numero, total=EXCLUDED.total, updated_at=EXCLUDED.updated_at
-	`, c.Fecha, c.ProveedorUUID, c.FacturaNumero, c.Total, c.CreatedAt, c.UpdatedAt)
+		INSERT INTO compras (uuid, fecha, proveedor_uuid, vendedor_uuid, factura_numero, total, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		ON CONFLICT (uuid) DO UPDATE SET fecha = EXCLUDED.fecha, proveedor_uuid=EXCLUDED.proveedor_uuid, vendedor_uuid=EXCLUDED.vendedor_uuid, factura_numero=EXCLUDED.factura_numero, total=EXCLUDED.total, updated_at=EXCLUDED.updated_at
+	`, c.UUID, c.Fecha, c.ProveedorUUID, nullableString(c.VendedorUUID), c.FacturaNumero, c.Total, c.CreatedAt, c.UpdatedAt)
 
 	if err != nil {
 		d.Log.Errorf("syncCompraToRemote: error upserting compra remota: %v", err)
@@ -1145,10 +2289,10 @@ func (d *Db) syncCompraToRemote(c_uuid string) {
 	if len(c.Detalles) > 0 {
 		_, err := rtx.CopyFrom(d.ctx,
 			pgx.Identifier{"detalle_compras"},
-			[]string{"compra_uuid", "producto_uuid", "cantidad", "precio_compra_unitario"},
+			[]string{"uuid", "compra_uuid", "producto_uuid", "cantidad", "precio_compra_unitario", "presentacion_uuid", "factor_conversion", "lote_uuid"},
 			pgx.CopyFromSlice(len(c.Detalles), func(i int) ([]any, error) {
 				det := c.Detalles[i]
-				return []any{c.UUID, det.ProductoUUID, det.Cantidad, det.PrecioCompraUnitario}, nil
+				return []any{det.UUID, c.UUID, det.ProductoUUID, det.Cantidad, det.PrecioCompraUnitario, nullableString(det.PresentacionUUID), det.FactorConversion, nullableString(det.LoteUUID)}, nil
 			}),
 		)
 		if err != nil {
@@ -1157,12 +2301,12 @@ func (d *Db) syncCompraToRemote(c_uuid string) {
 	}
 
 	// Sincronizar operaciones de stock de la compra (si existieran)
-	opsRows, err := d.LocalDB.QueryContext(d.ctx, "SELECT uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, timestamp FROM operacion_stocks WHERE factura_uuid IS NULL AND tipo_operacion = 'COMPRA' AND sincronizado = 0")
+	opsRows, err := d.LocalDB.QueryContext(d.ctx, "SELECT uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, vendedor_uuid, factura_uuid, COALESCE(lote_uuid, ''), timestamp FROM operacion_stocks WHERE factura_uuid IS NULL AND tipo_operacion = 'COMPRA' AND sincronizado = 0")
 	if err == nil {
 		var localOps []OperacionStock
 		for opsRows.Next() {
 			var op OperacionStock
-			if err := opsRows.Scan(&op.UUID, &op.ProductoUUID, &op.TipoOperacion, &op.CantidadCambio, &op.StockResultante, &op.VendedorUUID, &op.FacturaUUID, &op.Timestamp); err != nil {
+			if err := opsRows.Scan(&op.UUID, &op.ProductoUUID, &op.TipoOperacion, &op.CantidadCambio, &op.StockResultante, &op.VendedorUUID, &op.FacturaUUID, &op.LoteUUID, &op.Timestamp); err != nil {
 				d.Log.Errorf("syncCompraToRemote: error scanning operacion local: %v", err)
 				continue
 			}
@@ -1173,7 +2317,7 @@ func (d *Db) syncCompraToRemote(c_uuid string) {
 		if len(localOps) > 0 {
 			_, err := rtx.CopyFrom(d.ctx,
 				pgx.Identifier{"operacion_stocks"},
-				[]string{"uuid", "producto_uuid", "tipo_operacion", "cantidad_cambio", "stock_resultante", "vendedor_uuid", "factura_uuid", "timestamp"},
+				[]string{"uuid", "producto_uuid", "tipo_operacion", "cantidad_cambio", "stock_resultante", "vendedor_uuid", "factura_uuid", "lote_uuid", "timestamp"},
 				pgx.CopyFromSlice(len(localOps), func(i int) ([]any, error) {
 					op := localOps[i]
 					var facturaID interface{}
@@ -1182,7 +2326,7 @@ func (d *Db) syncCompraToRemote(c_uuid string) {
 					} else {
 						facturaID = nil
 					}
-					return []any{op.UUID, op.ProductoUUID, op.TipoOperacion, op.CantidadCambio, op.StockResultante, op.VendedorUUID, facturaID, op.Timestamp}, nil
+					return []any{op.UUID, op.ProductoUUID, op.TipoOperacion, op.CantidadCambio, op.StockResultante, op.VendedorUUID, facturaID, nullableString(op.LoteUUID), op.Timestamp}, nil
 				}),
 			)
 			if err != nil && !strings.Contains(err.Error(), "duplicate key") {
@@ -1213,14 +2357,17 @@ func (d *Db) syncCompraToRemote(c_uuid string) {
 
 func (d *Db) syncVendedorToLocal(v Vendedor) {
 	// upsert pattern for sqlite: try update, if rows affected==0 then insert
-	res, err := d.LocalDB.Exec("UPDATE vendedors SET nombre=?, apellido=?, cedula=?, email=?, contrasena=?, mfa_enabled=?, updated_at=? WHERE uuid=?", v.Nombre, v.Apellido, v.Cedula, v.Email, v.Contrasena, v.MFAEnabled, time.Now(), v.UUID)
+	res, err := d.LocalDB.Exec("UPDATE vendedors SET nombre=?, apellido=?, cedula=?, email=?, contrasena=?, mfa_enabled=?, rol=COALESCE(NULLIF(?, ''), rol), updated_at=? WHERE uuid=?", v.Nombre, v.Apellido, v.Cedula, v.Email, v.Contrasena, v.MFAEnabled, v.Rol, time.Now(), v.UUID)
 	if err != nil {
 		d.Log.Errorf("syncVendedorToLocal: error updating local vendedor UUID %s: %v", v.UUID, err)
 		return
 	}
 	r, _ := res.RowsAffected()
 	if r == 0 {
-		_, err = d.LocalDB.Exec("INSERT INTO vendedors (uuid, nombre, apellido, cedula, email, contrasena, mfa_enabled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", v.UUID, v.Nombre, v.Apellido, v.Cedula, v.Email, v.Contrasena, v.MFAEnabled, time.Now(), time.Now())
+		if v.Rol == "" {
+			v.Rol = RolVendedor
+		}
+		_, err = d.LocalDB.Exec("INSERT INTO vendedors (uuid, nombre, apellido, cedula, email, contrasena, mfa_enabled, rol, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", v.UUID, v.Nombre, v.Apellido, v.Cedula, v.Email, v.Contrasena, v.MFAEnabled, v.Rol, time.Now(), time.Now())
 		if err != nil {
 			d.Log.Errorf("syncVendedorToLocal: error inserting local vendedor UUID %s: %v", v.UUID, err)
 			return
@@ -1259,7 +2406,7 @@ func (d *Db) sincronizarOperacionesStockHaciaLocal() error {
 	// 2. Obtener operaciones remotas (Corregido: Query unificada con COALESCE)
 	remoteOpsQuery := `
         SELECT uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante, 
-               vendedor_uuid, factura_uuid, timestamp
+               vendedor_uuid, factura_uuid, COALESCE(lote_uuid::text, ''), timestamp
         FROM operacion_stocks
         WHERE COALESCE(timestamp, '1970-01-01T00:00:00Z') > $1
         ORDER BY timestamp ASC`
@@ -1291,7 +2438,8 @@ func (d *Db) sincronizarOperacionesStockHaciaLocal() error {
 			&stockResultante, // dest[4] - Corregido
 			&vendedorUUID,    // dest[5] - Corregido
 			&facturaUUID,     // dest[6] - Corregido
-			&opTimestamp,     // dest[7] - Corregido
+			&op.LoteUUID,     // dest[7] - vacío si no tiene lote
+			&opTimestamp,     // dest[8] - Corregido
 		); err != nil {
 			// Este error ahora solo debería saltar por problemas inesperados, no por NULLs
 			d.Log.Warnf("Error al escanear operación remota: %v", err)
@@ -1341,8 +2489,8 @@ func (d *Db) sincronizarOperacionesStockHaciaLocal() error {
 	stmt, err := tx.PrepareContext(ctx, `
         INSERT INTO operacion_stocks (
             uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante,
-            vendedor_uuid, factura_uuid, timestamp, sincronizado
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)
+            vendedor_uuid, factura_uuid, lote_uuid, timestamp, sincronizado
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
         ON CONFLICT(uuid) DO NOTHING`)
 	if err != nil {
 		return fmt.Errorf("error al preparar statement local: %w", err)
@@ -1352,7 +2500,7 @@ func (d *Db) sincronizarOperacionesStockHaciaLocal() error {
 	for _, op := range newOps {
 		if _, err := stmt.ExecContext(ctx,
 			op.UUID, op.ProductoUUID, op.TipoOperacion, op.CantidadCambio,
-			op.StockResultante, op.VendedorUUID, op.FacturaUUID, op.Timestamp,
+			op.StockResultante, op.VendedorUUID, op.FacturaUUID, nullableString(op.LoteUUID), op.Timestamp,
 		); err != nil {
 			failatempt++
 			d.Log.Errorf("Error al insertar op. stock local (UUID: %s): %v", op.UUID, err)
@@ -1424,6 +2572,8 @@ func (d *Db) sanitizeRow(tableName string, cols []string, values []any) error {
 		uniqueKey = "cedula"
 	case "proveedors":
 		uniqueKey = "nombre"
+	case "impuestos", "reglas_puntos":
+		uniqueKey = "codigo"
 	}
 	if uniqueKey != "" && checkRequired(uniqueKey) {
 		return fmt.Errorf("[%s] fila sin '%s', registro ignorado", tableName, uniqueKey)
@@ -1434,14 +2584,88 @@ func (d *Db) sanitizeRow(tableName string, cols []string, values []any) error {
 	setDefault("nombre", "SIN NOMBRE")
 
 	// Asignar valores por defecto específicos de la tabla
+	if tableName == "vendedors" {
+		setDefault("rol", RolVendedor)
+	}
+	if tableName == "clientes" {
+		setDefault("limite_credito", 0.0)
+	}
 	if tableName == "productos" {
 		setDefault("precio_venta", 0.0)
 		setDefault("stock", 0)
+		setDefault("impuesto_codigo", ImpuestoPorDefecto)
+		setDefault("costo_promedio", 0.0)
+		setDefault("controlado", false)
+		setDefault("requiere_formula", false)
+	}
+	if tableName == "impuestos" {
+		setDefault("tarifa", 0.0)
+	}
+	if tableName == "reglas_puntos" {
+		setDefault("monto_por_punto", 0.0)
+	}
+	if tableName == "presentaciones_producto" {
+		setDefault("factor", 1)
+		setDefault("precio_venta", 0.0)
+	}
+	if tableName == "lotes" {
+		setDefault("estado", LoteVigente)
+	}
+	if tableName == "proveedors" {
+		setDefault("dias_entrega", 0)
+	}
+	if tableName == "niveles_reorden" {
+		setDefault("automatico", false)
+		setDefault("stock_minimo", 0)
+		setDefault("stock_maximo", 0)
+	}
+	if tableName == "pedidos_proveedor" {
+		setDefault("estado", PedidoPendiente)
+	}
+	if tableName == "items_pedido_proveedor" {
+		setDefault("costo_unitario", 0.0)
+	}
+	if tableName == "conteos_inventario" {
+		setDefault("estado", ConteoAbierto)
+	}
+	if tableName == "items_conteo_inventario" {
+		setDefault("lote_uuid", "")
+		setDefault("costo_unitario", 0.0)
+	}
+	if tableName == "registros_conteo_inventario" {
+		setDefault("lote_uuid", "")
 	}
 
 	return nil
 }
 
+// nullableString convierte una cadena vacía en NULL para columnas opcionales (ej. UUIDs en PostgreSQL).
+func nullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// uuidsPendientes lista los uuid locales que devuelve una consulta de registros por subir al remoto.
+func (d *Db) uuidsPendientes(query string, args ...any) ([]string, error) {
+	rows, err := d.LocalDB.QueryContext(d.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uuids []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		uuids = append(uuids, u)
+	}
+	return uuids, rows.Err()
+}
+
 func parseFlexibleTime(dateStr string) (time.Time, error) {
 	layouts := []string{
 		"2006-01-02 15:04:05.999999-07:00", // Formato de SQLite con zona horaria
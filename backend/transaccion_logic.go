@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -65,6 +66,20 @@ func calcularStockRealLocal(tx *sql.Tx, productoUUID string) (int, error) {
 // ---- LÓGICA DE TRANSACCIONES (VENTAS) REFACTORIZADA ----
 
 func (d *Db) RegistrarVenta(req VentaRequest) (Factura, error) {
+	// 0️⃣ La autorización del supervisor se valida antes de abrir la transacción local
+	var autorizadoPor string
+	if req.Autorizacion != nil {
+		var err error
+		if autorizadoPor, err = d.validarAutorizacionSupervisor(req.Autorizacion); err != nil {
+			return Factura{}, err
+		}
+	}
+
+	// 0.b Si la terminal se quedó sin números, el rango se reserva antes de abrir la transacción local
+	if err := d.prepararRangoFacturacion(); err != nil {
+		return Factura{}, fmt.Errorf("error al generar número de factura: %w", err)
+	}
+
 	tx, err := d.LocalDB.Begin()
 	if err != nil {
 		return Factura{}, fmt.Errorf("error al iniciar transacción: %w", err)
@@ -75,7 +90,16 @@ func (d *Db) RegistrarVenta(req VentaRequest) (Factura, error) {
 		}
 	}()
 
-	// 1️⃣ Generar número de factura
+	// 1️⃣ Toda venta queda asociada a la caja abierta del vendedor
+	sesionCajaUUID, err := sesionCajaAbierta(tx, req.VendedorUUID)
+	if err != nil {
+		return Factura{}, err
+	}
+	if sesionCajaUUID == "" {
+		return Factura{}, fmt.Errorf("el vendedor no tiene una caja abierta: debe abrir caja antes de registrar ventas")
+	}
+
+	// 1.b Generar número de factura
 	numeroFactura, err := d.generarNumeroFactura(tx)
 	if err != nil {
 		return Factura{}, fmt.Errorf("error al generar número de factura: %w", err)
@@ -84,20 +108,36 @@ func (d *Db) RegistrarVenta(req VentaRequest) (Factura, error) {
 
 	now := time.Now()
 	factura := Factura{
-		UUID:          uuid.New().String(),
-		NumeroFactura: numeroFactura,
-		FechaEmision:  now,
-		VendedorUUID:  req.VendedorUUID,
-		ClienteUUID:   req.ClienteUUID,
-		Estado:        "PAGADA",
-		MetodoPago:    req.MetodoPago,
+		UUID:           uuid.New().String(),
+		NumeroFactura:  numeroFactura,
+		FechaEmision:   now,
+		VendedorUUID:   req.VendedorUUID,
+		ClienteUUID:    req.ClienteUUID,
+		SesionCajaUUID: sesionCajaUUID,
+		Estado:         EstadoFacturaPagada,
+		MetodoPago:     req.MetodoPago,
+	}
+
+	// 1.c Fórmula médica de la venta: una registrada con entregas pendientes o una nueva
+	formulaVenta, err := resolverFormulaVenta(tx, req, now)
+	if err != nil {
+		return Factura{}, err
+	}
+	if formulaVenta != nil {
+		factura.FormulaUUID = formulaVenta.UUID
 	}
 
-	var subtotal float64
+	var subtotal, iva, valorBruto, maxPorcentajeLinea float64
 	var detalles []DetalleFactura
+	var cambiosPrecio []CambioPrecio
+	categorias := make(map[string]string)
 
 	// 2️⃣ Procesar productos
-	stmtProd, err := tx.Prepare(`SELECT nombre, precio_venta FROM productos WHERE uuid = ?`)
+	stmtProd, err := tx.Prepare(`
+		SELECT p.nombre, p.precio_venta, COALESCE(p.impuesto_codigo, ''), COALESCE(i.tarifa, 0), COALESCE(p.categoria, ''), p.costo_promedio, p.controlado, p.requiere_formula
+		FROM productos p
+		LEFT JOIN impuestos i ON i.codigo = p.impuesto_codigo AND i.deleted_at IS NULL
+		WHERE p.uuid = ?`)
 	if err != nil {
 		return Factura{}, fmt.Errorf("error preparando consulta productos: %w", err)
 	}
@@ -105,48 +145,182 @@ func (d *Db) RegistrarVenta(req VentaRequest) (Factura, error) {
 
 	for _, item := range req.Productos {
 		var nombre string
-		var precioVenta float64
-		if err := stmtProd.QueryRow(item.ProductoUUID).Scan(&nombre, &precioVenta); err != nil {
+		var precioVenta, tarifa, costoPromedio float64
+		var impuestoCodigo, categoria string
+		var controlado, requiereFormula bool
+		if err := stmtProd.QueryRow(item.ProductoUUID).Scan(&nombre, &precioVenta, &impuestoCodigo, &tarifa, &categoria, &costoPromedio, &controlado, &requiereFormula); err != nil {
 			return Factura{}, fmt.Errorf("producto [%s] no encontrado: %w", item.ProductoUUID, err)
 		}
+		categorias[item.ProductoUUID] = categoria
+
+		// Cantidad y precio vienen en la presentación elegida; el stock se mueve en unidades mínimas
+		presentacion, err := resolverPresentacion(tx, item.ProductoUUID, item.PresentacionUUID, precioVenta)
+		if err != nil {
+			return Factura{}, err
+		}
+		nombre = nombreConPresentacion(nombre, presentacion.Nombre)
+		if item.Cantidad <= 0 {
+			return Factura{}, fmt.Errorf("la cantidad de [%s] debe ser mayor que cero", nombre)
+		}
+
+		// Lo que se vende bajo fórmula se descuenta de lo pendiente de la fórmula de la venta; sin ella,
+		// un medicamento de control puede traer los datos de la prescripción en la línea
+		if requiereFormula || (controlado && formulaVenta != nil) {
+			if err := dispensarFormula(formulaVenta, item.ProductoUUID, item.Cantidad*presentacion.Factor, nombre); err != nil {
+				return Factura{}, err
+			}
+		}
+		if controlado && item.Formula == nil && formulaVenta != nil {
+			item.Formula = formulaVenta.formulaMedica()
+		}
+
+		// Los medicamentos de control no se dispensan sin fórmula médica
+		var formula *FormulaMedica
+		if controlado {
+			if formula, err = validarFormulaMedica(item.Formula, nombre); err != nil {
+				return Factura{}, err
+			}
+		}
 
-		// 2.a Registrar operación de stock centralizada ✅
-		if err := d.CrearOperacionStock(
+		// Se factura el precio de lista; un precio distinto requiere autorización de supervisor
+		precioUnitario, cambio, err := resolverPrecioVenta(item, nombre, presentacion.PrecioVenta, req.VendedorUUID, autorizadoPor)
+		if err != nil {
+			return Factura{}, err
+		}
+
+		// 2.a Registrar operación de stock centralizada ✅ (FEFO salvo lote elegido)
+		if err := d.descontarStockFEFO(
 			tx,
 			item.ProductoUUID,
 			"VENTA",
-			item.Cantidad,
+			item.Cantidad*presentacion.Factor,
+			item.LoteUUID,
 			req.VendedorUUID,
 			&factura.UUID,
+			false,
+			formula,
 		); err != nil {
 			return Factura{}, fmt.Errorf("error registrando operación de stock [%s]: %w", nombre, err)
 		}
 
-		precioTotal := float64(item.Cantidad) * item.PrecioUnitario
-		subtotal += precioTotal
+		// 2.b Descuento de la línea sobre el valor bruto
+		bruto := redondearMoneda(float64(item.Cantidad) * precioUnitario)
+		descuentoLinea, err := calcularDescuento(item.DescuentoTipo, item.Descuento, bruto)
+		if err != nil {
+			return Factura{}, fmt.Errorf("descuento inválido en [%s]: %w", nombre, err)
+		}
+		if pct := porcentajeDescuento(descuentoLinea, bruto); pct > maxPorcentajeLinea {
+			maxPorcentajeLinea = pct
+		}
+		valorBruto += bruto
 
 		detalles = append(detalles, DetalleFactura{
-			UUID:           uuid.New().String(),
-			ProductoUUID:   item.ProductoUUID,
-			Cantidad:       item.Cantidad,
-			PrecioUnitario: item.PrecioUnitario,
-			PrecioTotal:    precioTotal,
+			UUID:             uuid.New().String(),
+			ProductoUUID:     item.ProductoUUID,
+			Cantidad:         item.Cantidad,
+			PrecioUnitario:   precioUnitario,
+			ValorBruto:       bruto,
+			Descuento:        descuentoLinea,
+			MotivoDescuento:  strings.TrimSpace(item.MotivoDescuento),
+			PrecioTotal:      redondearMoneda(bruto - descuentoLinea),
+			ImpuestoCodigo:   impuestoCodigo,
+			TarifaImpuesto:   tarifa,
+			PresentacionUUID: presentacion.UUID,
+			Presentacion:     presentacion.Nombre,
+			FactorConversion: presentacion.Factor,
+			CostoUnitario:    redondearMoneda(costoPromedio * float64(presentacion.Factor)),
 		})
+		if cambio != nil {
+			cambio.FacturaUUID = factura.UUID
+			cambio.DetalleFacturaUUID = detalles[len(detalles)-1].UUID
+			cambio.CreatedAt, cambio.UpdatedAt = now, now
+			cambiosPrecio = append(cambiosPrecio, *cambio)
+			d.Log.Infof("[VENTA] Precio de %s cambiado de %.2f a %.2f, autorizado por supervisor %s", nombre, cambio.PrecioOriginal, cambio.PrecioNuevo, autorizadoPor)
+		}
+	}
+
+	// 2.c Descuento global de la factura, prorrateado entre las líneas
+	var netoLineas float64
+	for _, det := range detalles {
+		netoLineas += det.PrecioTotal
+	}
+	descuentoFactura, err := calcularDescuento(req.DescuentoTipo, req.Descuento, netoLineas)
+	if err != nil {
+		return Factura{}, fmt.Errorf("descuento de factura inválido: %w", err)
+	}
+	// Los puntos redimidos como descuento se suman al descuento global y no cuentan para el máximo
+	var descuentoPuntos float64
+	if req.PuntosDescuento < 0 {
+		return Factura{}, fmt.Errorf("los puntos a redimir no pueden ser negativos")
+	}
+	if req.PuntosDescuento > 0 {
+		descuentoPuntos = redondearMoneda(float64(req.PuntosDescuento) * d.valorPunto)
+		if descuentoFactura+descuentoPuntos > netoLineas {
+			return Factura{}, fmt.Errorf("el descuento con puntos (%.2f) supera el valor de la venta", descuentoPuntos)
+		}
+		descuentoFactura += descuentoPuntos
+	}
+	prorratearDescuento(detalles, descuentoFactura)
+
+	var descuentoTotal float64
+	for _, det := range detalles {
+		descuentoTotal += det.Descuento
+	}
+
+	// 2.d Descuentos por encima del máximo configurado requieren un supervisor
+	pctFactura := porcentajeDescuento(descuentoTotal-descuentoPuntos, valorBruto)
+	if maxPorcentajeLinea > d.descuentoMaximo || pctFactura > d.descuentoMaximo {
+		if autorizadoPor == "" {
+			return Factura{}, fmt.Errorf("el descuento (%.2f%%) supera el máximo permitido (%.2f%%) y requiere autorización de un supervisor",
+				math.Max(maxPorcentajeLinea, pctFactura), d.descuentoMaximo)
+		}
+		factura.DescuentoAutorizadoPor = autorizadoPor
+		d.Log.Infof("[VENTA] Descuento de %.2f%% autorizado por supervisor %s", math.Max(maxPorcentajeLinea, pctFactura), autorizadoPor)
+	}
+
+	// 2.e Los precios incluyen IVA: se separa la base y el impuesto del neto de cada línea
+	for i := range detalles {
+		detalles[i].BaseImpuesto, detalles[i].ValorImpuesto = calcularImpuestoIncluido(detalles[i].PrecioTotal, detalles[i].TarifaImpuesto)
+		subtotal += detalles[i].BaseImpuesto
+		iva += detalles[i].ValorImpuesto
 	}
 
-	factura.Subtotal = subtotal
-	factura.IVA = subtotal * 0.0 // configurable si aplica
+	factura.ValorBruto = redondearMoneda(valorBruto)
+	factura.Descuento = redondearMoneda(descuentoTotal)
+	factura.DescuentoFactura = descuentoFactura
+	factura.MotivoDescuento = strings.TrimSpace(req.MotivoDescuento)
+	factura.Subtotal = redondearMoneda(subtotal)
+	factura.IVA = redondearMoneda(iva)
 	factura.Total = factura.Subtotal + factura.IVA
+	factura.Impuestos = agruparImpuestos(factura.UUID, detalles, now)
+
+	// 2.f Medios de pago: deben sumar exactamente el total
+	factura.Pagos, factura.MetodoPago, err = construirPagos(factura.UUID, req, factura.Total, now)
+	if err != nil {
+		return Factura{}, err
+	}
+
+	// 2.g La parte a crédito debe caber en el cupo del cliente y deja la factura pendiente
+	if credito := montoCredito(factura.Pagos); credito > 0 {
+		if err := validarCupoCredito(tx, factura.ClienteUUID, credito); err != nil {
+			return Factura{}, err
+		}
+		factura.Estado = EstadoFacturaPendiente
+		d.Log.Infof("[VENTA] Venta a crédito por %.2f al cliente %s", credito, factura.ClienteUUID)
+	}
 
 	// 3️⃣ Insertar factura
 	_, err = tx.Exec(`
 		INSERT INTO facturas (
 			uuid, numero_factura, fecha_emision, vendedor_uuid, cliente_uuid,
-			subtotal, iva, total, estado, metodo_pago, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			valor_bruto, descuento, descuento_factura, motivo_descuento, descuento_autorizado_por,
+			subtotal, iva, total, estado, metodo_pago, sesion_caja_uuid, formula_uuid, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		factura.UUID, factura.NumeroFactura, factura.FechaEmision, factura.VendedorUUID,
-		factura.ClienteUUID, factura.Subtotal, factura.IVA, factura.Total,
-		factura.Estado, factura.MetodoPago, now, now)
+		factura.ClienteUUID, factura.ValorBruto, factura.Descuento, factura.DescuentoFactura,
+		nullableString(factura.MotivoDescuento), nullableString(factura.DescuentoAutorizadoPor),
+		factura.Subtotal, factura.IVA, factura.Total,
+		factura.Estado, factura.MetodoPago, factura.SesionCajaUUID, nullableString(factura.FormulaUUID), now, now)
 	if err != nil {
 		return Factura{}, fmt.Errorf("error insertando factura: %w", err)
 	}
@@ -154,9 +328,10 @@ func (d *Db) RegistrarVenta(req VentaRequest) (Factura, error) {
 	// 4️⃣ Insertar detalles
 	stmtDet, err := tx.Prepare(`
 		INSERT INTO detalle_facturas (
-			uuid, factura_uuid, producto_uuid, cantidad, precio_unitario, precio_total,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+			uuid, factura_uuid, producto_uuid, cantidad, precio_unitario, valor_bruto, descuento, motivo_descuento,
+			precio_total, impuesto_codigo, base_impuesto, tarifa_impuesto, valor_impuesto,
+			presentacion_uuid, presentacion, factor_conversion, costo_unitario, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return Factura{}, fmt.Errorf("error preparando statement detalle_facturas: %w", err)
 	}
@@ -164,11 +339,43 @@ func (d *Db) RegistrarVenta(req VentaRequest) (Factura, error) {
 
 	for _, det := range detalles {
 		if _, err := stmtDet.Exec(det.UUID, factura.UUID, det.ProductoUUID,
-			det.Cantidad, det.PrecioUnitario, det.PrecioTotal, now, now); err != nil {
+			det.Cantidad, det.PrecioUnitario, det.ValorBruto, det.Descuento, nullableString(det.MotivoDescuento),
+			det.PrecioTotal, det.ImpuestoCodigo, det.BaseImpuesto, det.TarifaImpuesto, det.ValorImpuesto,
+			nullableString(det.PresentacionUUID), det.Presentacion, det.FactorConversion, det.CostoUnitario, now, now); err != nil {
 			return Factura{}, fmt.Errorf("error insertando detalle %s: %w", det.ProductoUUID, err)
 		}
 	}
 
+	// 4.b Insertar desglose de impuestos por tarifa
+	for _, fi := range factura.Impuestos {
+		if _, err := tx.Exec(`
+			INSERT INTO factura_impuestos (uuid, factura_uuid, impuesto_codigo, tarifa, base, valor, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			fi.UUID, fi.FacturaUUID, nullableString(fi.ImpuestoCodigo), fi.Tarifa, fi.Base, fi.Valor, now, now); err != nil {
+			return Factura{}, fmt.Errorf("error insertando impuesto de factura (tarifa %.2f): %w", fi.Tarifa, err)
+		}
+	}
+
+	// 4.c Insertar pagos
+	for _, p := range factura.Pagos {
+		if _, err := tx.Exec(`
+			INSERT INTO pagos_factura (uuid, factura_uuid, metodo_pago, monto, referencia, efectivo_recibido, cambio, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			p.UUID, p.FacturaUUID, p.MetodoPago, p.Monto, nullableString(p.Referencia), p.EfectivoRecibido, p.Cambio, now, now); err != nil {
+			return Factura{}, fmt.Errorf("error insertando pago %s: %w", p.MetodoPago, err)
+		}
+	}
+
+	// 4.d Registrar los cambios de precio autorizados
+	if err := insertarCambiosPrecio(tx, cambiosPrecio); err != nil {
+		return Factura{}, err
+	}
+
+	// 4.e Puntos del cliente: redención y acumulación en el libro de puntos
+	if err := d.registrarPuntosVenta(tx, &factura, detalles, categorias, req.PuntosDescuento); err != nil {
+		return Factura{}, err
+	}
+
 	// 5️⃣ Commit ✅
 	if err := tx.Commit(); err != nil {
 		return Factura{}, fmt.Errorf("error confirmando transacción de venta: %w", err)
@@ -179,11 +386,168 @@ func (d *Db) RegistrarVenta(req VentaRequest) (Factura, error) {
 		if err := d.syncVentaToRemote(factura.UUID); err != nil {
 			d.Log.Errorf("[SYNC] Error sincronizando venta %s: %v", factura.UUID, err)
 		}
+		d.asegurarRangoFacturacion()
+		// Con la facturación electrónica configurada, el CUFE queda listo para el recibo.
+		if d.configDIAN.validar() == nil {
+			if _, err := d.GenerarFacturaElectronica(factura.UUID); err != nil {
+				d.Log.Errorf("[DIAN] Error generando factura electrónica %s: %v", factura.NumeroFactura, err)
+			}
+		}
 	}()
 
 	return d.ObtenerDetalleFactura(factura.UUID)
 }
 
+// AnularFactura marca una factura como ANULADA y devuelve al inventario las cantidades vendidas
+// mediante operaciones de stock compensatorias, una por cada línea de detalle.
+func (d *Db) AnularFactura(facturaUUID, motivo, vendedorUUID string) (Factura, error) {
+	motivo = strings.TrimSpace(motivo)
+	if facturaUUID == "" || vendedorUUID == "" {
+		return Factura{}, fmt.Errorf("se requiere la factura y el vendedor que realiza la anulación")
+	}
+	if motivo == "" {
+		return Factura{}, fmt.Errorf("se requiere un motivo de anulación")
+	}
+
+	tx, err := d.LocalDB.Begin()
+	if err != nil {
+		return Factura{}, fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer func() {
+		if rErr := tx.Rollback(); rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			d.Log.Errorf("[AnularFactura] rollback: %v", rErr)
+		}
+	}()
+
+	// 1️⃣ Validar estado actual
+	var estado sql.NullString
+	err = tx.QueryRow(`SELECT estado FROM facturas WHERE uuid = ?`, facturaUUID).Scan(&estado)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Factura{}, fmt.Errorf("factura [%s] no encontrada", facturaUUID)
+		}
+		return Factura{}, fmt.Errorf("error consultando factura: %w", err)
+	}
+	if strings.EqualFold(estado.String, "ANULADA") {
+		return Factura{}, fmt.Errorf("la factura ya se encuentra anulada")
+	}
+
+	// 1.a Una factura electrónica con CUFE ya existe para la DIAN: se revierte con nota crédito
+	var cufe string
+	err = tx.QueryRow(`SELECT cufe FROM facturas_electronicas WHERE factura_uuid = ?`, facturaUUID).Scan(&cufe)
+	if err == nil {
+		return Factura{}, fmt.Errorf("la factura ya tiene CUFE ante la DIAN y no se puede anular: registre una devolución total con nota crédito")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Factura{}, fmt.Errorf("error consultando factura electrónica: %w", err)
+	}
+
+	// 1.b Los abonos ya recibidos no se pueden reversar anulando la factura
+	var abonos int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM abono_facturas af JOIN abonos a ON a.uuid = af.abono_uuid
+		WHERE af.factura_uuid = ? AND a.deleted_at IS NULL`, facturaUUID).Scan(&abonos); err != nil {
+		return Factura{}, fmt.Errorf("error consultando abonos de la factura: %w", err)
+	}
+	if abonos > 0 {
+		return Factura{}, fmt.Errorf("la factura tiene abonos registrados y no se puede anular: registre una devolución")
+	}
+
+	// Tampoco se anula una factura con devoluciones: su reembolso ya salió de caja y la anulación lo
+	// devolvería de nuevo. El resto se devuelve con otra nota crédito.
+	var notas int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM notas_credito WHERE factura_uuid = ? AND deleted_at IS NULL`, facturaUUID).Scan(&notas); err != nil {
+		return Factura{}, fmt.Errorf("error consultando devoluciones de la factura: %w", err)
+	}
+	if notas > 0 {
+		return Factura{}, fmt.Errorf("la factura tiene devoluciones registradas y no se puede anular: registre una devolución del resto")
+	}
+
+	// 1.c Lo pagado por caja (sin crédito ni puntos) se reembolsa desde la caja abierta de quien anula
+	var reembolso float64
+	if err := tx.QueryRow(`
+		SELECT COALESCE(SUM(monto), 0) FROM pagos_factura
+		WHERE factura_uuid = ? AND deleted_at IS NULL AND LOWER(metodo_pago) NOT IN (?, ?)`,
+		facturaUUID, MetodoPagoCredito, MetodoPagoPuntos).Scan(&reembolso); err != nil {
+		return Factura{}, fmt.Errorf("error consultando pagos de la factura: %w", err)
+	}
+	sesionCajaUUID, err := sesionCajaAbierta(tx, vendedorUUID)
+	if err != nil {
+		return Factura{}, err
+	}
+	if reembolso > 0 && sesionCajaUUID == "" {
+		return Factura{}, fmt.Errorf("el vendedor no tiene una caja abierta: debe abrir caja para reembolsar la anulación")
+	}
+
+	// 2️⃣ Reversar el stock de cada detalle (descontando lo ya devuelto con notas crédito), en unidades mínimas.
+	// Cada operación ANULACION_VENTA de un medicamento de control deja su contraasiento en el libro con la
+	// fórmula de la venta; lo dispensado de una fórmula médica se calcula sin las facturas anuladas, así que
+	// vuelve a quedar pendiente al marcar la factura.
+	rows, err := tx.Query(`
+		SELECT d.producto_uuid,
+			(d.cantidad - (SELECT COALESCE(SUM(dn.cantidad), 0) FROM detalle_notas_credito dn WHERE dn.detalle_factura_uuid = d.uuid)) * d.factor_conversion
+		FROM detalle_facturas d
+		WHERE d.factura_uuid = ?`, facturaUUID)
+	if err != nil {
+		return Factura{}, fmt.Errorf("error consultando detalles de la factura: %w", err)
+	}
+	var detalles []DetalleFactura
+	for rows.Next() {
+		var det DetalleFactura
+		if err := rows.Scan(&det.ProductoUUID, &det.Cantidad); err != nil {
+			rows.Close()
+			return Factura{}, fmt.Errorf("error leyendo detalle de la factura: %w", err)
+		}
+		detalles = append(detalles, det)
+	}
+	rows.Close()
+
+	for _, det := range detalles {
+		if det.Cantidad <= 0 {
+			continue
+		}
+		if err := d.reintegrarStockLotes(
+			tx,
+			det.ProductoUUID,
+			"ANULACION_VENTA",
+			det.Cantidad,
+			vendedorUUID,
+			facturaUUID,
+		); err != nil {
+			return Factura{}, fmt.Errorf("error reversando stock del producto [%s]: %w", det.ProductoUUID, err)
+		}
+	}
+
+	// 2.b Devolver los puntos redimidos y retirar los acumulados con la venta
+	if err := d.reversarPuntosFactura(tx, facturaUUID, vendedorUUID); err != nil {
+		return Factura{}, err
+	}
+
+	// 3️⃣ Marcar la factura como anulada
+	now := time.Now()
+	_, err = tx.Exec(`
+		UPDATE facturas
+		SET estado = 'ANULADA', motivo_anulacion = ?, fecha_anulacion = ?, anulada_por_uuid = ?, sesion_caja_anulacion_uuid = ?, updated_at = ?
+		WHERE uuid = ?`,
+		motivo, now, vendedorUUID, nullableString(sesionCajaUUID), now, facturaUUID)
+	if err != nil {
+		return Factura{}, fmt.Errorf("error anulando factura: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Factura{}, fmt.Errorf("error confirmando anulación: %w", err)
+	}
+	d.Log.Infof("[ANULACION] Factura %s anulada por %s: %s", facturaUUID, vendedorUUID, motivo)
+
+	go func() {
+		if err := d.syncVentaToRemote(facturaUUID); err != nil {
+			d.Log.Errorf("[SYNC] Error sincronizando anulación %s: %v", facturaUUID, err)
+		}
+	}()
+
+	return d.ObtenerDetalleFactura(facturaUUID)
+}
+
 // Calcula el stock previo, el resultante y actualiza el producto dentro de la misma transacción.
 func (d *Db) CrearOperacionStock(
 	tx *sql.Tx,
@@ -193,6 +557,21 @@ func (d *Db) CrearOperacionStock(
 	vendedorUUID string,
 	facturaUUID *string,
 ) error {
+	return d.crearOperacionStockLote(tx, productoUUID, tipoOperacion, cambio, vendedorUUID, facturaUUID, "", nil)
+}
+
+// crearOperacionStockLote es CrearOperacionStock imputando el movimiento a un lote (vacío: stock sin lote).
+// La fórmula solo se usa en ventas de medicamentos de control, que la exigen para su asiento en el libro.
+func (d *Db) crearOperacionStockLote(
+	tx *sql.Tx,
+	productoUUID string,
+	tipoOperacion string,
+	cambio int,
+	vendedorUUID string,
+	facturaUUID *string,
+	loteUUID string,
+	formula *FormulaMedica,
+) error {
 
 	if productoUUID == "" {
 		return fmt.Errorf("[CrearOperacionStock] productoUUID vacío")
@@ -204,36 +583,51 @@ func (d *Db) CrearOperacionStock(
 		return fmt.Errorf("[CrearOperacionStock] error obteniendo stock previo: %w", err)
 	}
 
-	// 2️⃣ Calcular nuevo stock resultante según tipo de operación
+	// 2️⃣ Calcular nuevo stock resultante según tipo de operación.
+	// cantidad_cambio se guarda con signo para que SUM(cantidad_cambio) sea siempre el stock real.
 	var stockResultante int
-	switch tipoOperacion {
-	case "VENTA", "AJUSTE_NEGATIVO", "DEVOLUCION_CLIENTE":
-		stockResultante = stockPrevio - cambio
+	cantidadCambio := cambio
+	if esSalidaStock(tipoOperacion) {
+		cantidadCambio = -cambio
+		stockResultante = stockPrevio + cantidadCambio
 		if stockResultante < 0 {
 			return fmt.Errorf("stock insuficiente [%s] disponible %d solicitado %d",
 				productoUUID, stockPrevio, cambio)
 		}
-	default: // COMPRA, AJUSTE_POSITIVO, DEVOLUCION_PROVEEDOR
-		stockResultante = stockPrevio + cambio
+	} else { // COMPRA, AJUSTE_POSITIVO, DEVOLUCION_CLIENTE, ANULACION_VENTA
+		stockResultante = stockPrevio + cantidadCambio
+	}
+
+	// Un lote nunca queda con existencias negativas
+	if loteUUID != "" && cantidadCambio < 0 {
+		stockLote, err := stockLoteLocal(tx, loteUUID)
+		if err != nil {
+			return fmt.Errorf("[CrearOperacionStock] error obteniendo stock del lote: %w", err)
+		}
+		if stockLote+cantidadCambio < 0 {
+			return fmt.Errorf("stock insuficiente en el lote [%s] disponible %d solicitado %d", loteUUID, stockLote, -cantidadCambio)
+		}
 	}
 
 	// 3️⃣ Insertar operación de stock
+	operacionUUID := uuid.New().String()
 	insertSQL := `
 		INSERT INTO operacion_stocks (
 			uuid, producto_uuid, tipo_operacion, cantidad_cambio, stock_resultante,
-			vendedor_uuid, factura_uuid, timestamp, sincronizado
+			vendedor_uuid, factura_uuid, lote_uuid, timestamp, sincronizado
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err = tx.Exec(insertSQL,
-		uuid.New().String(),
+		operacionUUID,
 		productoUUID,
 		tipoOperacion,
-		cambio,
+		cantidadCambio,
 		stockResultante,
 		vendedorUUID,
 		facturaUUID,
+		nullableString(loteUUID),
 		time.Now(),
 		false,
 	)
@@ -253,27 +647,55 @@ func (d *Db) CrearOperacionStock(
 		return fmt.Errorf("[CrearOperacionStock] error actualizando stock producto: %w", err)
 	}
 
+	// 5️⃣ Los medicamentos de control dejan asiento en su libro
+	mov := MovimientoControlado{
+		ProductoUUID:       productoUUID,
+		OperacionStockUUID: operacionUUID,
+		TipoOperacion:      tipoOperacion,
+		Cantidad:           cantidadCambio,
+		Saldo:              stockResultante,
+		LoteUUID:           loteUUID,
+		VendedorUUID:       vendedorUUID,
+	}
+	if facturaUUID != nil {
+		mov.FacturaUUID = *facturaUUID
+	}
+	if err := asentarLibroControlado(tx, mov, stockPrevio, formula); err != nil {
+		return err
+	}
+
 	d.Log.Debugf("[CrearOperacionStock] %s -> Stock previo %d, cambio %+d, nuevo %d",
-		productoUUID, stockPrevio, cambio, stockResultante)
+		productoUUID, stockPrevio, cantidadCambio, stockResultante)
 
 	return nil
 }
 
-func (d *Db) generarNumeroFactura(tx *sql.Tx) (string, error) {
+// esSalidaStock indica si el tipo de operación recibe la cantidad en positivo y la descuenta del stock.
+func esSalidaStock(tipoOperacion string) bool {
+	switch tipoOperacion {
+	case "VENTA", "AJUSTE_NEGATIVO", "DEVOLUCION_PROVEEDOR", "CUARENTENA", "DESTRUCCION":
+		return true
+	}
+	return false
+}
+
+// generarConsecutivo obtiene el siguiente número de un documento con formato PREFIJO-N
+// (ej. "FAC-1000", "NC-1000") a partir del máximo existente en la tabla indicada.
+func generarConsecutivo(tx *sql.Tx, tabla, columna, prefijo string) (string, error) {
 	var maxNum sql.NullInt64 // Usamos NullInt64 para manejar el caso de que la tabla esté vacía
 
-	// Esta consulta extrae la parte numérica (asumiendo el prefijo "FAC-")
+	// Esta consulta extrae la parte numérica (después del prefijo),
 	// la convierte a INTEGER y encuentra el máximo.
-	// COALESCE devuelve 0 si no se encuentran facturas (ej. tabla vacía).
-	query := `
-		SELECT COALESCE(MAX(CAST(SUBSTR(numero_factura, 5) AS INTEGER)), 0) 
-		FROM facturas 
-		WHERE numero_factura LIKE 'FAC-%'`
+	// COALESCE devuelve 0 si no se encuentran documentos (ej. tabla vacía).
+	query := fmt.Sprintf(`
+		SELECT COALESCE(MAX(CAST(SUBSTR(%s, %d) AS INTEGER)), 0) 
+		FROM %s 
+		WHERE %s LIKE ?`, columna, len(prefijo)+1, tabla, columna)
 
-	err := tx.QueryRow(query).Scan(&maxNum)
+	err := tx.QueryRow(query, prefijo+"%").Scan(&maxNum)
 	if err != nil {
 		// Si falla la consulta (que no debería, por COALESCE), retornamos error.
-		return "", fmt.Errorf("error al consultar max numero_factura: %w", err)
+		return "", fmt.Errorf("error al consultar max %s: %w", columna, err)
 	}
 
 	nuevoNumero := 1000 // Número base inicial
@@ -288,7 +710,7 @@ func (d *Db) generarNumeroFactura(tx *sql.Tx) (string, error) {
 		nuevoNumero = 1000
 	}
 
-	return fmt.Sprintf("FAC-%d", nuevoNumero), nil
+	return fmt.Sprintf("%s%d", prefijo, nuevoNumero), nil
 }
 
 func (d *Db) ObtenerFacturasPaginado(page, pageSize int, search, sortBy, sortOrder string) (PaginatedResult, error) {
@@ -331,6 +753,7 @@ func (d *Db) ObtenerFacturasPaginado(page, pageSize int, search, sortBy, sortOrd
 		"Cliente":       "c.nombre",
 		"Vendedor":      "v.nombre",
 		"Total":         "f.total",
+		"Estado":        "f.estado",
 	}
 
 	orderBy := "ORDER BY f.fecha_emision DESC, f.numero_factura DESC"
@@ -353,6 +776,7 @@ func (d *Db) ObtenerFacturasPaginado(page, pageSize int, search, sortBy, sortOrd
 			f.numero_factura,
 			f.fecha_emision,
 			f.total,
+			COALESCE(f.estado, ''),
 			c.uuid,
 			c.nombre,
 			v.uuid,
@@ -368,7 +792,7 @@ func (d *Db) ObtenerFacturasPaginado(page, pageSize int, search, sortBy, sortOrd
 	for rows.Next() {
 		var f Factura
 		if err := rows.Scan(
-			&f.UUID, &f.NumeroFactura, &f.FechaEmision, &f.Total,
+			&f.UUID, &f.NumeroFactura, &f.FechaEmision, &f.Total, &f.Estado,
 			&f.Cliente.UUID, &f.Cliente.Nombre,
 			&f.Vendedor.UUID, &f.Vendedor.Nombre,
 		); err != nil {
@@ -390,11 +814,23 @@ func (d *Db) ObtenerDetalleFactura(facturaUUID string) (Factura, error) {
 						f.uuid,
 						f.numero_factura,
 						f.fecha_emision,
+						f.valor_bruto,
+						f.descuento,
+						f.descuento_factura,
+						COALESCE(f.motivo_descuento, ''),
+						COALESCE(f.descuento_autorizado_por, ''),
 						f.subtotal,
 						f.iva,
 						f.total,
 						f.estado,
 						f.metodo_pago,
+						COALESCE(f.sesion_caja_uuid, ''),
+						COALESCE(f.motivo_anulacion, ''),
+						f.fecha_anulacion,
+						COALESCE(f.anulada_por_uuid, ''),
+						COALESCE(f.sesion_caja_anulacion_uuid, ''),
+						COALESCE((SELECT fe.cufe FROM facturas_electronicas fe WHERE fe.factura_uuid = f.uuid), ''),
+						COALESCE(f.formula_uuid, ''),
 						f.cliente_uuid,
 						c.uuid,
 						c.nombre,
@@ -413,7 +849,10 @@ func (d *Db) ObtenerDetalleFactura(facturaUUID string) (Factura, error) {
 	`
 	// Escaneamos los IDs y también los datos anidados para tener el objeto completo
 	err := d.LocalDB.QueryRow(queryFactura, facturaUUID).Scan(
-		&factura.UUID, &factura.NumeroFactura, &factura.FechaEmision, &factura.Subtotal, &factura.IVA, &factura.Total, &factura.Estado, &factura.MetodoPago,
+		&factura.UUID, &factura.NumeroFactura, &factura.FechaEmision,
+		&factura.ValorBruto, &factura.Descuento, &factura.DescuentoFactura, &factura.MotivoDescuento, &factura.DescuentoAutorizadoPor,
+		&factura.Subtotal, &factura.IVA, &factura.Total, &factura.Estado, &factura.MetodoPago, &factura.SesionCajaUUID,
+		&factura.MotivoAnulacion, &factura.FechaAnulacion, &factura.AnuladaPorUUID, &factura.SesionCajaAnulacion, &factura.CUFE, &factura.FormulaUUID,
 		&factura.ClienteUUID, &factura.Cliente.UUID, &factura.Cliente.Nombre, &factura.Cliente.Apellido, &factura.Cliente.NumeroID,
 		&factura.VendedorUUID, &factura.Vendedor.UUID, &factura.Vendedor.Nombre, &factura.Vendedor.Apellido,
 	)
@@ -426,7 +865,10 @@ func (d *Db) ObtenerDetalleFactura(facturaUUID string) (Factura, error) {
 
 	// 2. Obtener los detalles de la factura (productos)
 	queryDetalles := `
-		SELECT d.uuid, d.cantidad, d.precio_unitario, d.precio_total,
+		SELECT d.uuid, d.cantidad, d.precio_unitario, d.valor_bruto, d.descuento, COALESCE(d.motivo_descuento, ''), d.precio_total,
+			COALESCE(d.impuesto_codigo, ''), d.base_impuesto, d.tarifa_impuesto, d.valor_impuesto,
+			(SELECT COALESCE(SUM(dn.cantidad), 0) FROM detalle_notas_credito dn WHERE dn.detalle_factura_uuid = d.uuid),
+			COALESCE(d.presentacion_uuid, ''), COALESCE(d.presentacion, ''), d.factor_conversion, d.costo_unitario,
 			p.uuid, p.codigo, p.nombre
 		FROM detalle_facturas d
 		JOIN productos p ON d.producto_uuid = p.uuid
@@ -443,7 +885,11 @@ func (d *Db) ObtenerDetalleFactura(facturaUUID string) (Factura, error) {
 	for rows.Next() {
 		var detalle DetalleFactura
 		err := rows.Scan(
-			&detalle.UUID, &detalle.Cantidad, &detalle.PrecioUnitario, &detalle.PrecioTotal,
+			&detalle.UUID, &detalle.Cantidad, &detalle.PrecioUnitario,
+			&detalle.ValorBruto, &detalle.Descuento, &detalle.MotivoDescuento, &detalle.PrecioTotal,
+			&detalle.ImpuestoCodigo, &detalle.BaseImpuesto, &detalle.TarifaImpuesto, &detalle.ValorImpuesto,
+			&detalle.CantidadDevuelta,
+			&detalle.PresentacionUUID, &detalle.Presentacion, &detalle.FactorConversion, &detalle.CostoUnitario,
 			&detalle.Producto.UUID, &detalle.Producto.Codigo, &detalle.Producto.Nombre,
 		)
 		if err != nil {
@@ -461,76 +907,45 @@ func (d *Db) ObtenerDetalleFactura(facturaUUID string) (Factura, error) {
 
 	d.Log.Infof("Se encontraron y adjuntaron %d detalles para la Factura UUID: %s", detailCount, facturaUUID)
 
-	return factura, nil
-}
-
-func (d *Db) RegistrarCompra(req CompraRequest) (Compra, error) {
-	tx, err := d.LocalDB.Begin()
+	// 3. Desglose de impuestos por tarifa
+	factura.Impuestos, err = d.obtenerImpuestosFactura(facturaUUID)
 	if err != nil {
-		return Compra{}, fmt.Errorf("error al iniciar transacción de compra: %w", err)
-	}
-	defer func() {
-		if rErr := tx.Rollback(); err != nil && !errors.Is(rErr, sql.ErrTxDone) {
-			d.Log.Errorf("[LOCAL] - Error durante [RegistrarCompra] rollback %v", err)
-		}
-	}()
-
-	var totalCompra float64
-	for _, p := range req.Productos {
-		totalCompra += p.PrecioCompraUnitario * float64(p.Cantidad)
-	}
-
-	compra := Compra{
-		Fecha:         time.Now(),
-		ProveedorUUID: req.ProveedorUUID,
-		FacturaNumero: req.FacturaNumero,
-		Total:         totalCompra,
+		d.Log.Errorf("Error al obtener impuestos de la factura UUID %s: %v", facturaUUID, err)
+		return factura, err
 	}
 
-	_, err = tx.Exec("INSERT INTO compras (uuid, fecha, proveedor_uuid, factura_numero, total) VALUES (?, ?, ?, ?, ?)",
-		compra.UUID, compra.Fecha, compra.ProveedorUUID, compra.FacturaNumero, compra.Total)
+	// 4. Medios de pago
+	factura.Pagos, err = d.obtenerPagosFactura(facturaUUID)
 	if err != nil {
-		return Compra{}, fmt.Errorf("error al crear la compra: %w", err)
+		d.Log.Errorf("Error al obtener pagos de la factura UUID %s: %v", facturaUUID, err)
+		return factura, err
 	}
 
-	// Preparar statements para inserciones masivas
-	stmtDetalles, err := tx.Prepare("INSERT INTO detalle_compras (compra_uuid, producto_uuid, cantidad, precio_compra_unitario) VALUES (?, ?, ?, ?)")
+	// 5. Notas crédito emitidas contra la factura
+	factura.NotasCredito, err = d.obtenerNotasCreditoFactura(facturaUUID)
 	if err != nil {
-		return Compra{}, err
+		d.Log.Errorf("Error al obtener notas crédito de la factura UUID %s: %v", facturaUUID, err)
+		return factura, err
 	}
-	defer stmtDetalles.Close()
 
-	stmtOps, err := tx.Prepare("INSERT INTO operacion_stocks (uuid, producto_uuid, tipo_operacion, cantidad_cambio, vendedor_uuid, timestamp) VALUES (?, ?, ?, ?, ?, ?)")
-	if err != nil {
-		return Compra{}, err
+	// 6. Puntos ganados y redimidos, y saldo del cliente para el recibo
+	if err := d.obtenerPuntosFactura(&factura); err != nil {
+		d.Log.Errorf("Error al obtener puntos de la factura UUID %s: %v", facturaUUID, err)
+		return factura, err
 	}
-	defer stmtOps.Close()
 
-	for _, p := range req.Productos {
-		// Insertar detalle de compra
-		_, err := stmtDetalles.Exec(compra.UUID, p.ProductoUUID, p.Cantidad, p.PrecioCompraUnitario)
-		if err != nil {
-			return Compra{}, fmt.Errorf("error al crear detalle de compra: %w", err)
-		}
+	// 7. Utilidad bruta de lo que queda vendido después de devoluciones
+	factura.Rentabilidad = rentabilidadFactura(factura.Detalles)
 
-		// Insertar operación de stock
-		_, err = stmtOps.Exec(uuid.New().String(), p.ProductoUUID, "COMPRA", p.Cantidad, "SYSTEM-ADMIN", time.Now())
+	// 8. Fórmula médica dispensada, con lo que queda pendiente
+	if factura.FormulaUUID != "" {
+		formula, err := cargarFormula(d.LocalDB, factura.FormulaUUID)
 		if err != nil {
-			return Compra{}, fmt.Errorf("error creando operación de stock por compra: %w", err)
-		}
-
-		// Recalcular stock para el producto afectado
-		if err := RecalcularYActualizarStock(tx, p.ProductoUUID); err != nil {
-			return Compra{}, fmt.Errorf("error al actualizar stock por compra: %w", err)
+			d.Log.Errorf("Error al obtener la fórmula de la factura UUID %s: %v", facturaUUID, err)
+			return factura, err
 		}
+		factura.Formula = &formula
 	}
 
-	if err := tx.Commit(); err != nil {
-		return Compra{}, fmt.Errorf("error al confirmar transacción de compra: %w", err)
-	}
-
-	go d.syncCompraToRemote(compra.UUID)
-
-	// Aquí se debería devolver la compra completa, similar a ObtenerDetalleFactura
-	return compra, nil
+	return factura, nil
 }
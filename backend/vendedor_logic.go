@@ -27,6 +27,9 @@ func (d *Db) RegistrarVendedor(vendedor Vendedor) (Vendedor, error) {
 	vendedor.UpdatedAt = txTimestamp
 	vendedor.Contrasena = hashedPassword
 
+	// El rol nunca viene de la petición: solo un ADMIN puede cambiarlo con CambiarRolVendedor
+	vendedor.Rol = d.rolInicialVendedor(vendedor.Cedula)
+
 	ctx := d.ctx
 	tx, err := d.LocalDB.BeginTx(ctx, nil)
 	if err != nil {
@@ -50,8 +53,9 @@ func (d *Db) RegistrarVendedor(vendedor Vendedor) (Vendedor, error) {
 
 	if existenteUUID.Valid {
 		if deletedAt.Valid {
-			_, err = tx.Exec("UPDATE vendedors SET nombre = ?, apellido = ?, email = ?, contrasena = ?, deleted_at = NULL, updated_at = ? WHERE uuid = ?",
-				vendedor.Nombre, vendedor.Apellido, vendedor.Email, vendedor.Contrasena, vendedor.UpdatedAt, existenteUUID.String)
+			// Reactivar un vendedor eliminado no le devuelve el rol que tenía
+			_, err = tx.Exec("UPDATE vendedors SET nombre = ?, apellido = ?, email = ?, contrasena = ?, rol = ?, deleted_at = NULL, updated_at = ? WHERE uuid = ?",
+				vendedor.Nombre, vendedor.Apellido, vendedor.Email, vendedor.Contrasena, vendedor.Rol, vendedor.UpdatedAt, existenteUUID.String)
 			if err != nil {
 				return Vendedor{}, err
 			}
@@ -60,8 +64,8 @@ func (d *Db) RegistrarVendedor(vendedor Vendedor) (Vendedor, error) {
 			return Vendedor{}, fmt.Errorf("la cédula o el email ya están registrados en un vendedor activo")
 		}
 	} else {
-		_, err := tx.Exec("INSERT INTO vendedors (uuid, nombre, apellido, cedula, email, contrasena, mfa_enabled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-			vendedor.UUID, vendedor.Nombre, vendedor.Apellido, vendedor.Cedula, vendedor.Email, vendedor.Contrasena, vendedor.MFAEnabled, vendedor.CreatedAt, vendedor.UpdatedAt)
+		_, err := tx.Exec("INSERT INTO vendedors (uuid, nombre, apellido, cedula, email, contrasena, mfa_enabled, rol, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			vendedor.UUID, vendedor.Nombre, vendedor.Apellido, vendedor.Cedula, vendedor.Email, vendedor.Contrasena, vendedor.MFAEnabled, vendedor.Rol, vendedor.CreatedAt, vendedor.UpdatedAt)
 		if err != nil {
 			return Vendedor{}, err
 		}
@@ -79,6 +83,17 @@ func (d *Db) RegistrarVendedor(vendedor Vendedor) (Vendedor, error) {
 	return vendedor, nil
 }
 
+// rolInicialVendedor define el rol de un vendedor que se registra. El registro es público, así que solo
+// entra como ADMIN la cédula configurada en ADMIN_CEDULA; los demás entran como VENDEDOR. En las
+// instalaciones anteriores a los roles el vendedor más antiguo quedó como ADMIN en la migración.
+func (d *Db) rolInicialVendedor(cedula string) string {
+	if d.adminCedula != "" && strings.TrimSpace(cedula) == d.adminCedula {
+		d.Log.Infof("[VENDEDOR] la cédula %s está configurada en ADMIN_CEDULA: el vendedor queda como ADMIN", d.adminCedula)
+		return RolAdmin
+	}
+	return RolVendedor
+}
+
 func (d *Db) LoginVendedor(req LoginRequest) (LoginResponse, error) {
 	d.Log.Infof("Intento log con %s", req)
 	var vendedor Vendedor
@@ -90,13 +105,13 @@ func (d *Db) LoginVendedor(req LoginRequest) (LoginResponse, error) {
 		defer cancel()
 
 		row := d.RemoteDB.QueryRow(ctx, `
-			SELECT uuid, nombre, apellido, cedula, email, contrasena, mfa_enabled
+			SELECT uuid, nombre, apellido, cedula, email, contrasena, mfa_enabled, rol
 			FROM vendedors
 			WHERE email = $1 AND deleted_at IS NULL
 		`, req.Email)
 
 		err = row.Scan(&vendedor.UUID, &vendedor.Nombre, &vendedor.Apellido,
-			&vendedor.Cedula, &vendedor.Email, &vendedor.Contrasena, &vendedor.MFAEnabled)
+			&vendedor.Cedula, &vendedor.Email, &vendedor.Contrasena, &vendedor.MFAEnabled, &vendedor.Rol)
 
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
@@ -109,12 +124,12 @@ func (d *Db) LoginVendedor(req LoginRequest) (LoginResponse, error) {
 
 	if err != nil {
 		row := d.LocalDB.QueryRow(`
-			SELECT uuid, nombre, apellido, cedula, email, contrasena, mfa_enabled
+			SELECT uuid, nombre, apellido, cedula, email, contrasena, mfa_enabled, rol
 			FROM vendedors
 			WHERE email = ? AND deleted_at IS NULL
 		`, req.Email)
 		err = row.Scan(&vendedor.UUID, &vendedor.Nombre, &vendedor.Apellido,
-			&vendedor.Cedula, &vendedor.Email, &vendedor.Contrasena, &vendedor.MFAEnabled)
+			&vendedor.Cedula, &vendedor.Email, &vendedor.Contrasena, &vendedor.MFAEnabled, &vendedor.Rol)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return response, errors.New("vendedor no encontrado o credenciales incorrectas")
@@ -220,6 +235,21 @@ func (d *Db) ActualizarVendedor(vendedor Vendedor) (Vendedor, error) {
 	ctx, cancel := context.WithTimeout(d.ctx, 5*time.Second)
 	defer cancel()
 
+	// El rol solo cambia con CambiarRolVendedor; aquí se rechaza cualquier cambio.
+	var rolActual string
+	err := d.LocalDB.QueryRowContext(ctx, `SELECT rol FROM vendedors WHERE uuid = ? AND deleted_at IS NULL`, vendedor.UUID).Scan(&rolActual)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Vendedor{}, errors.New("no se encontró el vendedor para actualizar")
+	}
+	if err != nil {
+		return Vendedor{}, fmt.Errorf("error al consultar vendedor: %w", err)
+	}
+	rol := strings.ToUpper(strings.TrimSpace(vendedor.Rol))
+	if rol != "" && rol != rolActual {
+		return Vendedor{}, errors.New("el rol no se puede cambiar desde la edición del vendedor: requiere autorización de un administrador")
+	}
+	vendedor.Rol = rolActual
+
 	query := `
 		UPDATE vendedors
 		SET nombre = ?, apellido = ?, cedula = ?, email = ?, updated_at = ?
@@ -252,6 +282,56 @@ func (d *Db) ActualizarVendedor(vendedor Vendedor) (Vendedor, error) {
 	return vendedor, nil
 }
 
+// CambiarRolVendedor asigna el rol de un vendedor con la autorización de un ADMIN. El último ADMIN
+// activo no puede quedar sin ese rol, para que siempre haya quien administre los roles.
+func (d *Db) CambiarRolVendedor(req CambioRolRequest) (Vendedor, error) {
+	rol := strings.ToUpper(strings.TrimSpace(req.Rol))
+	if !rolValido(rol) {
+		return Vendedor{}, fmt.Errorf("rol de vendedor inválido: %s", req.Rol)
+	}
+	adminUUID, err := d.validarAutorizacionAdmin(req.Autorizacion)
+	if err != nil {
+		return Vendedor{}, err
+	}
+
+	var v Vendedor
+	err = d.LocalDB.QueryRow(`
+		SELECT uuid, nombre, apellido, cedula, email, mfa_enabled, rol, created_at
+		FROM vendedors WHERE uuid = ? AND deleted_at IS NULL`, req.VendedorUUID).Scan(
+		&v.UUID, &v.Nombre, &v.Apellido, &v.Cedula, &v.Email, &v.MFAEnabled, &v.Rol, &v.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Vendedor{}, fmt.Errorf("vendedor [%s] no encontrado", req.VendedorUUID)
+	}
+	if err != nil {
+		return Vendedor{}, fmt.Errorf("error al consultar vendedor: %w", err)
+	}
+	if v.Rol == rol {
+		return v, nil
+	}
+
+	if v.Rol == RolAdmin {
+		var admins int
+		if err := d.LocalDB.QueryRow(`SELECT COUNT(1) FROM vendedors WHERE rol = ? AND deleted_at IS NULL`, RolAdmin).Scan(&admins); err != nil {
+			return Vendedor{}, fmt.Errorf("error verificando administradores: %w", err)
+		}
+		if admins <= 1 {
+			return Vendedor{}, errors.New("no se puede quitar el rol al único ADMIN activo")
+		}
+	}
+
+	v.UpdatedAt = time.Now()
+	if _, err := d.LocalDB.Exec(`UPDATE vendedors SET rol = ?, updated_at = ? WHERE uuid = ?`, rol, v.UpdatedAt, v.UUID); err != nil {
+		return Vendedor{}, fmt.Errorf("error al cambiar el rol del vendedor: %w", err)
+	}
+	d.Log.Infof("[VENDEDOR] Rol de %s cambiado de %s a %s, autorizado por %s", v.Email, v.Rol, rol, adminUUID)
+	v.Rol = rol
+
+	if d.isRemoteDBAvailable() {
+		go d.syncVendedorToRemote(v.UUID)
+	}
+	return v, nil
+}
+
 func (d *Db) ObtenerVendedoresPaginado(page, pageSize int, search, sortBy, sortOrder string) (PaginatedResult, error) {
 	var result PaginatedResult
 	var vendedores []Vendedor
@@ -266,7 +346,7 @@ func (d *Db) ObtenerVendedoresPaginado(page, pageSize int, search, sortBy, sortO
 
 	// Construcción dinámica del query SQL
 	baseQuery := `
-		SELECT uuid, nombre, apellido, cedula, email, mfa_enabled, created_at, updated_at
+		SELECT uuid, nombre, apellido, cedula, email, mfa_enabled, rol, created_at, updated_at
 		FROM vendedors
 		WHERE deleted_at IS NULL
 	`
@@ -308,7 +388,7 @@ func (d *Db) ObtenerVendedoresPaginado(page, pageSize int, search, sortBy, sortO
 
 	for rows.Next() {
 		var v Vendedor
-		if err := rows.Scan(&v.UUID, &v.Nombre, &v.Apellido, &v.Cedula, &v.Email, &v.MFAEnabled, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		if err := rows.Scan(&v.UUID, &v.Nombre, &v.Apellido, &v.Cedula, &v.Email, &v.MFAEnabled, &v.Rol, &v.CreatedAt, &v.UpdatedAt); err != nil {
 			d.Log.Errorf("error al escanear vendedor: %v", err)
 			continue
 		}
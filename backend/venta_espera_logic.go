@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PonerVentaEnEspera guarda el carrito actual como venta en espera para poder atender a otro cliente.
+// La venta se guarda en la base local, no reserva stock y no se sincroniza con el remoto.
+func (d *Db) PonerVentaEnEspera(req VentaRequest, descripcion string) (VentaEnEspera, error) {
+	if req.VendedorUUID == "" {
+		return VentaEnEspera{}, fmt.Errorf("se requiere el vendedor que pone la venta en espera")
+	}
+	if len(req.Productos) == 0 {
+		return VentaEnEspera{}, fmt.Errorf("la venta no contiene productos")
+	}
+
+	// Las credenciales del supervisor nunca se guardan; se piden de nuevo al retomar la venta.
+	req.Autorizacion = nil
+
+	var total float64
+	var items int
+	for _, item := range req.Productos {
+		total += float64(item.Cantidad) * item.PrecioUnitario
+		items += item.Cantidad
+	}
+
+	venta, err := json.Marshal(req)
+	if err != nil {
+		return VentaEnEspera{}, fmt.Errorf("error serializando venta en espera: %w", err)
+	}
+
+	now := time.Now()
+	espera := VentaEnEspera{
+		UUID:          uuid.New().String(),
+		VendedorUUID:  req.VendedorUUID,
+		ClienteUUID:   req.ClienteUUID,
+		Descripcion:   strings.TrimSpace(descripcion),
+		Venta:         req,
+		TotalEstimado: redondearMoneda(total),
+		NumeroItems:   items,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	_, err = d.LocalDB.Exec(`
+		INSERT INTO ventas_en_espera (uuid, vendedor_uuid, cliente_uuid, descripcion, venta, total_estimado, numero_items, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		espera.UUID, espera.VendedorUUID, nullableString(espera.ClienteUUID), nullableString(espera.Descripcion),
+		string(venta), espera.TotalEstimado, espera.NumeroItems, now, now)
+	if err != nil {
+		return VentaEnEspera{}, fmt.Errorf("error guardando venta en espera: %w", err)
+	}
+
+	d.Log.Infof("[VENTA EN ESPERA] Venta %s guardada por vendedor %s (%d ítems)", espera.UUID, espera.VendedorUUID, espera.NumeroItems)
+	return espera, nil
+}
+
+// ObtenerVentasEnEspera lista las ventas en espera de la terminal, las más antiguas primero.
+// Si se indica un vendedor solo se devuelven las suyas.
+func (d *Db) ObtenerVentasEnEspera(vendedorUUID string) ([]VentaEnEspera, error) {
+	query := `
+		SELECT ve.uuid, ve.vendedor_uuid, COALESCE(ve.cliente_uuid, ''), COALESCE(ve.descripcion, ''), ve.venta,
+			ve.total_estimado, ve.numero_items, ve.created_at, ve.updated_at,
+			COALESCE(c.nombre, ''), COALESCE(c.apellido, ''), COALESCE(c.numero_id, '')
+		FROM ventas_en_espera ve
+		LEFT JOIN clientes c ON c.uuid = ve.cliente_uuid`
+	var args []any
+	if vendedorUUID != "" {
+		query += ` WHERE ve.vendedor_uuid = ?`
+		args = append(args, vendedorUUID)
+	}
+	query += ` ORDER BY ve.created_at ASC`
+
+	rows, err := d.LocalDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando ventas en espera: %w", err)
+	}
+	defer rows.Close()
+
+	ventas := make([]VentaEnEspera, 0)
+	for rows.Next() {
+		var v VentaEnEspera
+		var venta string
+		if err := rows.Scan(&v.UUID, &v.VendedorUUID, &v.ClienteUUID, &v.Descripcion, &venta,
+			&v.TotalEstimado, &v.NumeroItems, &v.CreatedAt, &v.UpdatedAt,
+			&v.Cliente.Nombre, &v.Cliente.Apellido, &v.Cliente.NumeroID); err != nil {
+			return nil, fmt.Errorf("error escaneando venta en espera: %w", err)
+		}
+		if err := json.Unmarshal([]byte(venta), &v.Venta); err != nil {
+			d.Log.Errorf("[VENTA EN ESPERA] Venta %s con contenido inválido: %v", v.UUID, err)
+			continue
+		}
+		v.Cliente.UUID = v.ClienteUUID
+		ventas = append(ventas, v)
+	}
+	return ventas, rows.Err()
+}
+
+// RetomarVentaEnEspera saca una venta de la lista de espera y la devuelve lista para el carrito.
+// Como la venta no reservó stock, se revalidan los precios y la disponibilidad de cada producto.
+func (d *Db) RetomarVentaEnEspera(esperaUUID string) (VentaRetomada, error) {
+	var venta string
+	err := d.LocalDB.QueryRow(`SELECT venta FROM ventas_en_espera WHERE uuid = ?`, esperaUUID).Scan(&venta)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return VentaRetomada{}, fmt.Errorf("venta en espera [%s] no encontrada", esperaUUID)
+		}
+		return VentaRetomada{}, fmt.Errorf("error consultando venta en espera: %w", err)
+	}
+
+	var req VentaRequest
+	if err := json.Unmarshal([]byte(venta), &req); err != nil {
+		return VentaRetomada{}, fmt.Errorf("la venta en espera [%s] tiene un contenido inválido: %w", esperaUUID, err)
+	}
+
+	resultado := VentaRetomada{Advertencias: make([]string, 0)}
+
+	// 1️⃣ Revalidar cada producto contra el catálogo actual
+	stmtProd, err := d.LocalDB.Prepare(`SELECT COALESCE(nombre, ''), COALESCE(precio_venta, 0), COALESCE(stock, 0) FROM productos WHERE uuid = ? AND deleted_at IS NULL`)
+	if err != nil {
+		return VentaRetomada{}, fmt.Errorf("error preparando consulta productos: %w", err)
+	}
+	defer stmtProd.Close()
+
+	productos := make([]ProductoVenta, 0, len(req.Productos))
+	solicitado := make(map[string]int, len(req.Productos))
+	preciosCambiaron := false
+	for _, item := range req.Productos {
+		var nombre string
+		var precioVenta float64
+		var stock int
+		if err := stmtProd.QueryRow(item.ProductoUUID).Scan(&nombre, &precioVenta, &stock); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				resultado.Advertencias = append(resultado.Advertencias,
+					fmt.Sprintf("El producto [%s] ya no está disponible y se retiró de la venta", item.ProductoUUID))
+				preciosCambiaron = true
+				continue
+			}
+			return VentaRetomada{}, fmt.Errorf("error consultando producto [%s]: %w", item.ProductoUUID, err)
+		}
+
+		presentacion, err := resolverPresentacion(d.LocalDB, item.ProductoUUID, item.PresentacionUUID, precioVenta)
+		if err != nil {
+			resultado.Advertencias = append(resultado.Advertencias,
+				fmt.Sprintf("La presentación de %s ya no está disponible y se retiró de la venta", nombre))
+			preciosCambiaron = true
+			continue
+		}
+		nombre = nombreConPresentacion(nombre, presentacion.Nombre)
+
+		if !esPrecioDeLista(item.PrecioUnitario, presentacion.PrecioVenta) {
+			resultado.Advertencias = append(resultado.Advertencias,
+				fmt.Sprintf("El precio de %s cambió de %.2f a %.2f", nombre, item.PrecioUnitario, presentacion.PrecioVenta))
+			item.PrecioUnitario = presentacion.PrecioVenta
+			preciosCambiaron = true
+		}
+
+		// El stock se compara en unidades mínimas, sumando todas las presentaciones del producto
+		solicitado[item.ProductoUUID] += item.Cantidad * presentacion.Factor
+		if solicitado[item.ProductoUUID] > stock {
+			resultado.Advertencias = append(resultado.Advertencias,
+				fmt.Sprintf("Stock insuficiente para %s: se piden %d y hay %d disponibles", nombre, solicitado[item.ProductoUUID], stock))
+		}
+		productos = append(productos, item)
+	}
+	req.Productos = productos
+
+	// 2️⃣ Si el total cambió, los pagos registrados ya no cuadran y deben capturarse de nuevo
+	if preciosCambiaron && len(req.Pagos) > 0 {
+		req.Pagos = nil
+		resultado.Advertencias = append(resultado.Advertencias, "El total de la venta cambió; registre nuevamente los pagos")
+	}
+	resultado.Venta = req
+
+	// 3️⃣ La venta vuelve al carrito, por lo que sale de la lista de espera
+	if _, err := d.LocalDB.Exec(`DELETE FROM ventas_en_espera WHERE uuid = ?`, esperaUUID); err != nil {
+		return VentaRetomada{}, fmt.Errorf("error retirando venta en espera: %w", err)
+	}
+
+	d.Log.Infof("[VENTA EN ESPERA] Venta %s retomada con %d advertencias", esperaUUID, len(resultado.Advertencias))
+	return resultado, nil
+}
+
+// DescartarVentaEnEspera elimina una venta en espera que el cliente no va a llevar.
+func (d *Db) DescartarVentaEnEspera(esperaUUID string) error {
+	res, err := d.LocalDB.Exec(`DELETE FROM ventas_en_espera WHERE uuid = ?`, esperaUUID)
+	if err != nil {
+		return fmt.Errorf("error descartando venta en espera: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("venta en espera [%s] no encontrada", esperaUUID)
+	}
+	d.Log.Infof("[VENTA EN ESPERA] Venta %s descartada", esperaUUID)
+	return nil
+}